@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Coordenadas padrão da base da empresa (Monte Carmelo - MG)
+const (
+	defaultCompanyBaseLat = -18.7247
+	defaultCompanyBaseLng = -47.4986
+)
+
+// GetCompanyBase retorna as coordenadas da base da empresa, de onde partem
+// as rotas dos técnicos. Pode ser sobrescrita por COMPANY_BASE_LAT/COMPANY_BASE_LNG.
+func GetCompanyBase() (float64, float64) {
+	lat := envFloat("COMPANY_BASE_LAT", defaultCompanyBaseLat)
+	lng := envFloat("COMPANY_BASE_LNG", defaultCompanyBaseLng)
+	return lat, lng
+}
+
+func envFloat(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// Prazo padrão de garantia, usado quando o tipo de garantia não define o seu
+const defaultWarrantyMonths = 12
+
+// GetWarrantyMonths retorna o prazo padrão de cobertura da garantia em meses.
+// Pode ser sobrescrito por WARRANTY_MONTHS.
+func GetWarrantyMonths() int {
+	months := int(envFloat("WARRANTY_MONTHS", defaultWarrantyMonths))
+	if months < 0 {
+		return defaultWarrantyMonths
+	}
+	return months
+}
+
+// Endereço público do sistema, usado nos links enviados por e-mail
+const defaultAppBaseURL = "http://localhost:8090"
+
+// GetAppBaseURL retorna o endereço público do sistema, sem barra final.
+// Pode ser sobrescrito por APP_BASE_URL.
+func GetAppBaseURL() string {
+	if url := strings.TrimRight(os.Getenv("APP_BASE_URL"), "/"); url != "" {
+		return url
+	}
+	return defaultAppBaseURL
+}
+
+// RequireAdminTwoFactor indica se gestores são obrigados a usar a
+// verificação em duas etapas (REQUIRE_ADMIN_2FA=true). Sem a variável ela é
+// opcional.
+func RequireAdminTwoFactor() bool {
+	return os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+}
+
+// Prazo de guarda dos contratos assinados depois que o cliente pede a
+// exclusão dos dados (prescrição das ações de cobrança e de garantia)
+const defaultContractRetentionYears = 5
+
+// GetContractRetentionYears retorna por quantos anos, contados da última
+// assinatura, os contratos de uma conta anonimizada são guardados. Pode ser
+// sobrescrito por CONTRACT_RETENTION_YEARS.
+func GetContractRetentionYears() int {
+	years := int(envFloat("CONTRACT_RETENTION_YEARS", defaultContractRetentionYears))
+	if years < 0 {
+		return defaultContractRetentionYears
+	}
+	return years
+}
+
+const defaultRequestTrashRetentionDays = 30
+
+// GetRequestTrashRetentionDays retorna por quantos dias uma solicitação
+// excluída fica na lixeira antes de ser eliminada. Pode ser sobrescrito por
+// REQUEST_TRASH_RETENTION_DAYS.
+func GetRequestTrashRetentionDays() int {
+	days := int(envFloat("REQUEST_TRASH_RETENTION_DAYS", defaultRequestTrashRetentionDays))
+	if days < 1 {
+		return defaultRequestTrashRetentionDays
+	}
+	return days
+}
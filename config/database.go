@@ -165,6 +165,458 @@ func createTables() {
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);`
 
+	// Observações do cliente sobre o contrato enviado para assinatura
+	contractObservationsTable := `
+	CREATE TABLE IF NOT EXISTS contract_client_observations (
+		id SERIAL PRIMARY KEY,
+		contract_id INTEGER NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id),
+		observation TEXT NOT NULL,
+		resolved BOOLEAN DEFAULT false,
+		resolved_at TIMESTAMP,
+		resolved_by INTEGER REFERENCES users(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Tabela de poços (ficha técnica do poço perfurado)
+	wellsTable := `
+	CREATE TABLE IF NOT EXISTS wells (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id),
+		service_request_id INTEGER UNIQUE REFERENCES service_requests(id) ON DELETE SET NULL,
+		contract_id INTEGER REFERENCES contracts(id) ON DELETE SET NULL,
+		identification VARCHAR(100) NOT NULL,
+		depth_m DECIMAL(8,2),
+		diameter_mm DECIMAL(8,2),
+		static_level_m DECIMAL(8,2),
+		dynamic_level_m DECIMAL(8,2),
+		flow_rate_m3h DECIMAL(10,3),
+		casing_material VARCHAR(100),
+		pump_model VARCHAR(150),
+		latitude DOUBLE PRECISION,
+		longitude DOUBLE PRECISION,
+		drilling_date DATE,
+		notes TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Perfil litológico do poço (camadas por intervalo de profundidade)
+	wellDrillingLogTable := `
+	CREATE TABLE IF NOT EXISTS well_drilling_log (
+		id SERIAL PRIMARY KEY,
+		well_id INTEGER NOT NULL REFERENCES wells(id) ON DELETE CASCADE,
+		depth_from_m DECIMAL(8,2) NOT NULL,
+		depth_to_m DECIMAL(8,2) NOT NULL,
+		lithology VARCHAR(100) NOT NULL,
+		water_entry BOOLEAN DEFAULT false,
+		observations TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		CHECK (depth_to_m > depth_from_m)
+	);`
+
+	// Análises de qualidade da água (laudo laboratorial)
+	waterAnalysesTable := `
+	CREATE TABLE IF NOT EXISTS water_analyses (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id),
+		service_request_id INTEGER UNIQUE REFERENCES service_requests(id) ON DELETE SET NULL,
+		well_id INTEGER REFERENCES wells(id) ON DELETE SET NULL,
+		sample_date DATE NOT NULL,
+		laboratory VARCHAR(150),
+		report_number VARCHAR(50),
+		notes TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Resultados por parâmetro, com os limites de referência vigentes na data do laudo
+	waterAnalysisResultsTable := `
+	CREATE TABLE IF NOT EXISTS water_analysis_results (
+		id SERIAL PRIMARY KEY,
+		analysis_id INTEGER NOT NULL REFERENCES water_analyses(id) ON DELETE CASCADE,
+		parameter_code VARCHAR(30) NOT NULL,
+		value DECIMAL(12,4) NOT NULL,
+		unit VARCHAR(20) NOT NULL,
+		min_limit DECIMAL(12,4),
+		max_limit DECIMAL(12,4),
+		UNIQUE (analysis_id, parameter_code)
+	);`
+
+	// Planos de manutenção preventiva por poço
+	maintenancePlansTable := `
+	CREATE TABLE IF NOT EXISTS maintenance_plans (
+		id SERIAL PRIMARY KEY,
+		well_id INTEGER NOT NULL REFERENCES wells(id) ON DELETE CASCADE,
+		title VARCHAR(150) NOT NULL,
+		interval_months INTEGER NOT NULL CHECK (interval_months > 0),
+		checklist TEXT,
+		next_due_date DATE NOT NULL,
+		reminder_days INTEGER NOT NULL DEFAULT 7 CHECK (reminder_days >= 0),
+		last_reminder_for DATE,
+		last_request_id INTEGER REFERENCES service_requests(id) ON DELETE SET NULL,
+		active BOOLEAN DEFAULT true,
+		notes TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Reclamações de garantia abertas pelo cliente a partir de um contrato assinado
+	warrantyClaimsTable := `
+	CREATE TABLE IF NOT EXISTS warranty_claims (
+		id SERIAL PRIMARY KEY,
+		contract_id INTEGER NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id),
+		description TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'ABERTA',
+		coverage_note TEXT,
+		decision_reason TEXT,
+		decided_by INTEGER REFERENCES users(id),
+		decided_at TIMESTAMP,
+		follow_up_request_id INTEGER REFERENCES service_requests(id) ON DELETE SET NULL,
+		follow_up_contract_id INTEGER REFERENCES contracts(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Catálogo de materiais (tubos, revestimento, bombas, cabos...) com saldo em estoque
+	materialsTable := `
+	CREATE TABLE IF NOT EXISTS materials (
+		id SERIAL PRIMARY KEY,
+		sku VARCHAR(50) UNIQUE NOT NULL,
+		name VARCHAR(150) NOT NULL,
+		unit VARCHAR(10) NOT NULL,
+		unit_cost DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (unit_cost >= 0),
+		sale_price DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (sale_price >= 0),
+		stock_quantity DECIMAL(12,3) NOT NULL DEFAULT 0,
+		min_stock DECIMAL(12,3) NOT NULL DEFAULT 0 CHECK (min_stock >= 0),
+		active BOOLEAN DEFAULT true,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Movimentações de estoque (quantidade com sinal: positiva entra, negativa sai)
+	stockMovementsTable := `
+	CREATE TABLE IF NOT EXISTS stock_movements (
+		id SERIAL PRIMARY KEY,
+		material_id INTEGER NOT NULL REFERENCES materials(id) ON DELETE CASCADE,
+		movement_type VARCHAR(20) NOT NULL,
+		quantity DECIMAL(12,3) NOT NULL,
+		balance_after DECIMAL(12,3) NOT NULL,
+		contract_id INTEGER REFERENCES contracts(id) ON DELETE SET NULL,
+		notes TEXT,
+		created_by INTEGER REFERENCES users(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Itens de material do contrato, com preço e custo congelados na inclusão
+	contractItemsTable := `
+	CREATE TABLE IF NOT EXISTS contract_items (
+		id SERIAL PRIMARY KEY,
+		contract_id INTEGER NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+		material_id INTEGER NOT NULL REFERENCES materials(id),
+		quantity DECIMAL(12,3) NOT NULL CHECK (quantity > 0),
+		unit_price DECIMAL(12,2) NOT NULL,
+		unit_cost DECIMAL(12,2) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (contract_id, material_id)
+	);`
+
+	// Orçamentos detalhados emitidos após a vistoria, antes do contrato
+	quotesTable := `
+	CREATE TABLE IF NOT EXISTS quotes (
+		id SERIAL PRIMARY KEY,
+		service_request_id INTEGER NOT NULL REFERENCES service_requests(id) ON DELETE CASCADE,
+		quote_number VARCHAR(50) UNIQUE NOT NULL,
+		status VARCHAR(20) NOT NULL DEFAULT 'RASCUNHO',
+		valid_until DATE NOT NULL,
+		discount_value DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (discount_value >= 0),
+		tax_percent DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (tax_percent >= 0),
+		notes TEXT,
+		client_response TEXT,
+		sent_at TIMESTAMP,
+		responded_at TIMESTAMP,
+		contract_id INTEGER REFERENCES contracts(id) ON DELETE SET NULL,
+		created_by INTEGER REFERENCES users(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	quoteItemsTable := `
+	CREATE TABLE IF NOT EXISTS quote_items (
+		id SERIAL PRIMARY KEY,
+		quote_id INTEGER NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+		item_type VARCHAR(20) NOT NULL,
+		description VARCHAR(200) NOT NULL,
+		quantity DECIMAL(12,3) NOT NULL CHECK (quantity > 0),
+		unit VARCHAR(10) NOT NULL,
+		unit_price DECIMAL(12,2) NOT NULL CHECK (unit_price >= 0),
+		material_id INTEGER REFERENCES materials(id),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Tabela de preços por tipo de serviço (taxa fixa e deslocamento)
+	priceTablesTable := `
+	CREATE TABLE IF NOT EXISTS price_tables (
+		id SERIAL PRIMARY KEY,
+		service_type_id INTEGER UNIQUE NOT NULL REFERENCES service_types(id),
+		name VARCHAR(100) NOT NULL,
+		base_fee DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (base_fee >= 0),
+		travel_rate_per_km DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (travel_rate_per_km >= 0),
+		free_km DECIMAL(8,1) NOT NULL DEFAULT 0 CHECK (free_km >= 0),
+		round_trip BOOLEAN NOT NULL DEFAULT true,
+		active BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Regras da tabela: faixas de profundidade (R$/m), multiplicadores de diâmetro e de terreno
+	priceRulesTable := `
+	CREATE TABLE IF NOT EXISTS price_rules (
+		id SERIAL PRIMARY KEY,
+		price_table_id INTEGER NOT NULL REFERENCES price_tables(id) ON DELETE CASCADE,
+		rule_type VARCHAR(20) NOT NULL CHECK (rule_type IN ('PROFUNDIDADE', 'DIAMETRO', 'TERRENO')),
+		min_value DECIMAL(10,2),
+		max_value DECIMAL(10,2),
+		terrain VARCHAR(20),
+		value DECIMAL(12,4) NOT NULL CHECK (value >= 0),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Distância rodoviária da base da empresa até cada cidade atendida
+	priceCityDistancesTable := `
+	CREATE TABLE IF NOT EXISTS price_city_distances (
+		id SERIAL PRIMARY KEY,
+		cidade VARCHAR(100) NOT NULL,
+		estado VARCHAR(2) NOT NULL,
+		distance_km DECIMAL(8,1) NOT NULL CHECK (distance_km >= 0),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(cidade, estado)
+	);`
+
+	// Tokens de acesso à API: pessoais (longa duração) e pares acesso/refresh.
+	// Só o hash SHA-256 do token é armazenado.
+	apiTokensTable := `
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		parent_id INTEGER REFERENCES api_tokens(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		kind VARCHAR(10) NOT NULL CHECK (kind IN ('PESSOAL', 'ACESSO', 'REFRESH')),
+		token_hash CHAR(64) UNIQUE NOT NULL,
+		token_prefix VARCHAR(16) NOT NULL,
+		scopes TEXT NOT NULL DEFAULT '',
+		expires_at TIMESTAMP,
+		last_used_at TIMESTAMP,
+		revoked_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Papéis e permissões. Cada usuário tem um papel (users.role_id); o
+	// user_type do papel define se ele usa o painel (gestor) ou o portal (cliente).
+	rolesTable := `
+	CREATE TABLE IF NOT EXISTS roles (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(30) UNIQUE NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		description TEXT,
+		user_type VARCHAR(20) NOT NULL CHECK (user_type IN ('gestor', 'cliente')),
+		display_order INTEGER DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	permissionsTable := `
+	CREATE TABLE IF NOT EXISTS permissions (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(50) UNIQUE NOT NULL,
+		name VARCHAR(150) NOT NULL,
+		display_order INTEGER DEFAULT 0
+	);`
+
+	rolePermissionsTable := `
+	CREATE TABLE IF NOT EXISTS role_permissions (
+		role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+		permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+		PRIMARY KEY (role_id, permission_id)
+	);`
+
+	// Links de uso único enviados por e-mail (redefinição de senha,
+	// verificação e troca do e-mail). Só o hash do token é guardado.
+	userTokensTable := `
+	CREATE TABLE IF NOT EXISTS user_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('RESET_SENHA', 'VERIFICAR_EMAIL', 'TROCAR_EMAIL')),
+		token_hash CHAR(64) UNIQUE NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);`
+
+	// Códigos de uso único enviados por WhatsApp (login sem senha e
+	// verificação do telefone). Guarda só o hash; ip alimenta o limite por origem.
+	phoneCodesTable := `
+	CREATE TABLE IF NOT EXISTS phone_codes (
+		id SERIAL PRIMARY KEY,
+		phone VARCHAR(20) NOT NULL,
+		purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('LOGIN', 'VERIFICAR_TELEFONE')),
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		code_hash CHAR(64) NOT NULL,
+		ip VARCHAR(45),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_phone_codes_phone ON phone_codes(phone, created_at);
+	CREATE INDEX IF NOT EXISTS idx_phone_codes_ip ON phone_codes(ip, created_at);`
+
+	// Registro de cada tentativa de login (senha, WhatsApp e API). Alimenta
+	// o bloqueio progressivo por identificador (e-mail ou telefone) e por IP.
+	loginAttemptsTable := `
+	CREATE TABLE IF NOT EXISTS login_attempts (
+		id SERIAL PRIMARY KEY,
+		identifier VARCHAR(255) NOT NULL,
+		user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		ip VARCHAR(45),
+		user_agent TEXT,
+		method VARCHAR(10) NOT NULL CHECK (method IN ('SENHA', 'WHATSAPP', 'API', 'ADMIN')),
+		success BOOLEAN NOT NULL,
+		reason VARCHAR(30) NOT NULL,
+		actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_login_attempts_identifier ON login_attempts(identifier, created_at);
+	CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts(ip, created_at);`
+
+	// Códigos de recuperação da verificação em duas etapas (só o hash é
+	// guardado; cada código vale uma vez)
+	recoveryCodesTable := `
+	CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_user ON two_factor_recovery_codes(user_id);`
+
+	// Sessões do site. O cookie leva apenas o identificador assinado; aqui
+	// fica o hash dele, os valores da sessão e o dispositivo de origem.
+	sessionsTable := `
+	CREATE TABLE IF NOT EXISTS sessions (
+		id VARCHAR(64) PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		data BYTEA NOT NULL,
+		ip VARCHAR(45),
+		user_agent TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+	CREATE INDEX IF NOT EXISTS idx_sessions_expires ON sessions(expires_at);`
+
+	// Endereços de imóveis do cliente, reaproveitados nas solicitações
+	userAddressesTable := `
+	CREATE TABLE IF NOT EXISTS user_addresses (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		label VARCHAR(60) NOT NULL,
+		cep VARCHAR(9) NOT NULL,
+		logradouro VARCHAR(200) NOT NULL,
+		numero VARCHAR(20) NOT NULL,
+		bairro VARCHAR(100) NOT NULL,
+		cidade VARCHAR(100) NOT NULL,
+		estado VARCHAR(2) NOT NULL,
+		is_default BOOLEAN NOT NULL DEFAULT false,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_user_addresses_user ON user_addresses(user_id);`
+
+	// Histórico de alterações feitas pelo próprio usuário no perfil
+	profileChangesTable := `
+	CREATE TABLE IF NOT EXISTS user_profile_changes (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		field VARCHAR(30) NOT NULL,
+		old_value TEXT,
+		new_value TEXT,
+		ip VARCHAR(45),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_user_profile_changes_user ON user_profile_changes(user_id, created_at DESC);`
+
+	// Pedidos de exclusão de dados (LGPD). Aprovado o pedido, a conta é
+	// anonimizada; solicitações com contrato assinado ficam guardadas até
+	// retain_until e só então são limpas (purged_at).
+	erasureRequestsTable := `
+	CREATE TABLE IF NOT EXISTS data_erasure_requests (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'PENDENTE' CHECK (status IN ('PENDENTE', 'APROVADA', 'RECUSADA', 'CANCELADA')),
+		reason TEXT,
+		decision_note TEXT,
+		decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		decided_at TIMESTAMP,
+		retain_until DATE,
+		purged_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_data_erasure_requests_pending ON data_erasure_requests(user_id) WHERE status = 'PENDENTE';`
+
+	// Trilha de auditoria dos pedidos do titular: exportações, pedidos de
+	// exclusão, decisões e anonimizações
+	privacyEventsTable := `
+	CREATE TABLE IF NOT EXISTS privacy_events (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		erasure_request_id INTEGER REFERENCES data_erasure_requests(id) ON DELETE SET NULL,
+		action VARCHAR(20) NOT NULL,
+		actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		details TEXT,
+		ip VARCHAR(45),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_privacy_events_user ON privacy_events(user_id, created_at DESC);`
+
+	// Registro de auditoria das ações administrativas: quem fez o quê, em
+	// qual registro, com o estado antes e depois da alteração
+	auditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		action VARCHAR(30) NOT NULL,
+		entity_type VARCHAR(30) NOT NULL,
+		entity_id INTEGER,
+		before_data JSONB,
+		after_data JSONB,
+		ip VARCHAR(45),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id);
+	CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at DESC);`
+
+	// Campos adicionais do formulário de solicitação, por tipo de serviço.
+	// As respostas ficam em service_requests.custom_fields, pela field_key.
+	serviceTypeFieldsTable := `
+	CREATE TABLE IF NOT EXISTS service_type_fields (
+		id SERIAL PRIMARY KEY,
+		service_type_id INTEGER NOT NULL REFERENCES service_types(id) ON DELETE CASCADE,
+		field_key VARCHAR(50) NOT NULL,
+		label VARCHAR(100) NOT NULL,
+		field_type VARCHAR(20) NOT NULL CHECK (field_type IN ('text', 'number', 'select', 'checkbox')),
+		options TEXT NOT NULL DEFAULT '',
+		help_text VARCHAR(255) NOT NULL DEFAULT '',
+		required BOOLEAN NOT NULL DEFAULT false,
+		display_order INTEGER NOT NULL DEFAULT 0,
+		active BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (service_type_id, field_key)
+	);`
+
 	// Criar tabelas na ordem correta
 	tables := []struct {
 		name  string
@@ -179,6 +631,36 @@ func createTables() {
 		{"service_requests", serviceRequestTable},
 		{"contracts", contractsTable},
 		{"contract_history", contractHistoryTable},
+		{"contract_client_observations", contractObservationsTable},
+		{"wells", wellsTable},
+		{"well_drilling_log", wellDrillingLogTable},
+		{"water_analyses", waterAnalysesTable},
+		{"water_analysis_results", waterAnalysisResultsTable},
+		{"maintenance_plans", maintenancePlansTable},
+		{"warranty_claims", warrantyClaimsTable},
+		{"materials", materialsTable},
+		{"stock_movements", stockMovementsTable},
+		{"contract_items", contractItemsTable},
+		{"quotes", quotesTable},
+		{"quote_items", quoteItemsTable},
+		{"price_tables", priceTablesTable},
+		{"price_rules", priceRulesTable},
+		{"price_city_distances", priceCityDistancesTable},
+		{"api_tokens", apiTokensTable},
+		{"roles", rolesTable},
+		{"permissions", permissionsTable},
+		{"role_permissions", rolePermissionsTable},
+		{"user_tokens", userTokensTable},
+		{"phone_codes", phoneCodesTable},
+		{"login_attempts", loginAttemptsTable},
+		{"two_factor_recovery_codes", recoveryCodesTable},
+		{"sessions", sessionsTable},
+		{"user_addresses", userAddressesTable},
+		{"user_profile_changes", profileChangesTable},
+		{"data_erasure_requests", erasureRequestsTable},
+		{"privacy_events", privacyEventsTable},
+		{"audit_log", auditLogTable},
+		{"service_type_fields", serviceTypeFieldsTable},
 	}
 
 	for _, table := range tables {
@@ -187,6 +669,9 @@ func createTables() {
 		}
 	}
 
+	// Colunas adicionadas após a criação inicial das tabelas
+	runMigrations()
+
 	// Inserir dados padrão
 	insertDefaultUserTypes()
 	insertDefaultServiceTypes()
@@ -194,6 +679,67 @@ func createTables() {
 	insertDefaultGuaranteeTypes()  // NOVA
 	insertDefaultContractStatus()  // NOVA
 	createDefaultAdmin()
+	insertDefaultRoles()
+}
+
+// runMigrations aplica alterações incrementais em tabelas já existentes
+func runMigrations() {
+	migrations := []struct {
+		name  string
+		query string
+	}{
+		// Coordenadas do local e técnico responsável (roteirização de visitas)
+		{"service_requests.latitude", `ALTER TABLE service_requests ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION`},
+		{"service_requests.longitude", `ALTER TABLE service_requests ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION`},
+		{"service_requests.technician_id", `ALTER TABLE service_requests ADD COLUMN IF NOT EXISTS technician_id INTEGER REFERENCES users(id)`},
+		// Solicitações geradas automaticamente por planos de manutenção
+		{"service_requests.maintenance_plan_id", `ALTER TABLE service_requests ADD COLUMN IF NOT EXISTS maintenance_plan_id INTEGER REFERENCES maintenance_plans(id) ON DELETE SET NULL`},
+		// Prazo de cobertura por tipo de garantia (NULL usa WARRANTY_MONTHS)
+		{"guarantee_types.warranty_months", `ALTER TABLE guarantee_types ADD COLUMN IF NOT EXISTS warranty_months INTEGER CHECK (warranty_months >= 0)`},
+		// Baixa de estoque dos itens do contrato (feita uma única vez, na assinatura)
+		{"contracts.stock_deducted_at", `ALTER TABLE contracts ADD COLUMN IF NOT EXISTS stock_deducted_at TIMESTAMP`},
+		// Papel do usuário (permissões)
+		{"users.role_id", `ALTER TABLE users ADD COLUMN IF NOT EXISTS role_id INTEGER REFERENCES roles(id)`},
+		// Verificação do e-mail. Contas anteriores à coluna entram como
+		// verificadas (o DEFAULT só preenche as linhas existentes ao criá-la).
+		{"users.email_verified_at", `ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP`},
+		{"users.email_verified_at.default", `ALTER TABLE users ALTER COLUMN email_verified_at DROP DEFAULT`},
+		// Telefone confirmado por código via WhatsApp
+		{"users.phone_verified_at", `ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP`},
+		// Verificação em duas etapas (TOTP). O segredo é gravado no início do
+		// cadastro e só passa a valer quando totp_enabled_at é preenchido.
+		{"users.totp_secret", `ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64)`},
+		{"users.totp_enabled_at", `ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP`},
+		{"users.totp_last_counter", `ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0`},
+		// Novo e-mail aguardando confirmação pelo link enviado a ele
+		{"users.pending_email", `ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email VARCHAR(100)`},
+		{"user_tokens.purpose_check", `ALTER TABLE user_tokens DROP CONSTRAINT IF EXISTS user_tokens_purpose_check;
+			ALTER TABLE user_tokens ADD CONSTRAINT user_tokens_purpose_check
+			CHECK (purpose IN ('RESET_SENHA', 'VERIFICAR_EMAIL', 'TROCAR_EMAIL'))`},
+		// Avisos por WhatsApp que o cliente aceita receber
+		{"users.notify_request_updates", `ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_request_updates BOOLEAN NOT NULL DEFAULT true`},
+		{"users.notify_quotes", `ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_quotes BOOLEAN NOT NULL DEFAULT true`},
+		{"users.notify_warranty", `ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_warranty BOOLEAN NOT NULL DEFAULT true`},
+		{"users.notify_maintenance", `ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_maintenance BOOLEAN NOT NULL DEFAULT true`},
+		// Conta anonimizada a pedido do titular (LGPD)
+		{"users.anonymized_at", `ALTER TABLE users ADD COLUMN IF NOT EXISTS anonymized_at TIMESTAMP`},
+		// Lixeira de solicitações: excluídas ficam ocultas até o expurgo
+		{"service_requests.deleted_at", `ALTER TABLE service_requests ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`},
+		{"service_requests.deleted_by", `ALTER TABLE service_requests ADD COLUMN IF NOT EXISTS deleted_by INTEGER REFERENCES users(id) ON DELETE SET NULL`},
+		{"service_requests.idx_deleted", `CREATE INDEX IF NOT EXISTS idx_service_requests_deleted ON service_requests(deleted_at) WHERE deleted_at IS NOT NULL`},
+		// Cadastros administráveis: ordem dos tipos de serviço e ícones do Bootstrap Icons
+		{"service_types.display_order", `ALTER TABLE service_types ADD COLUMN IF NOT EXISTS display_order INTEGER`},
+		{"service_types.display_order_fill", `UPDATE service_types SET display_order = id WHERE display_order IS NULL`},
+		{"service_types.bootstrap_icons", `UPDATE service_types SET icon = CASE icon WHEN 'construction' THEN 'arrow-down-circle' WHEN 'droplets' THEN 'droplet-half' END WHERE icon IN ('construction', 'droplets')`},
+		// Respostas aos campos adicionais do tipo de serviço (service_type_fields)
+		{"service_requests.custom_fields", `ALTER TABLE service_requests ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}'`},
+	}
+
+	for _, migration := range migrations {
+		if _, err := DB.Exec(migration.query); err != nil {
+			log.Fatalf("Error applying migration %s: %v", migration.name, err)
+		}
+	}
 }
 
 func insertDefaultUserTypes() {
@@ -218,10 +764,10 @@ func insertDefaultServiceTypes() {
 	
 	if count == 0 {
 		_, err := DB.Exec(`
-			INSERT INTO service_types (code, name, description, icon) VALUES 
-			('perfuracao', 'Perfuração de Poços', 'Perfuração de poços artesianos', 'construction'),
-			('analise', 'Análise da Água', 'Análise de qualidade da água', 'droplets'),
-			('manutencao', 'Manutenção', 'Manutenção de poços existentes', 'wrench')`)
+			INSERT INTO service_types (code, name, description, icon, display_order) VALUES 
+			('perfuracao', 'Perfuração de Poços', 'Perfuração de poços artesianos', 'arrow-down-circle', 1),
+			('analise', 'Análise da Água', 'Análise de qualidade da água', 'droplet-half', 2),
+			('manutencao', 'Manutenção', 'Manutenção de poços existentes', 'tools', 3)`)
 		if err != nil {
 			log.Fatal("Error inserting default service types:", err)
 		}
@@ -304,8 +850,8 @@ func createDefaultAdmin() {
 
 		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.DefaultCost)
 		_, err = DB.Exec(`
-			INSERT INTO users (name, email, password, user_type_id, phone) 
-			VALUES ($1, $2, $3, $4, $5)`,
+			INSERT INTO users (name, email, password, user_type_id, phone, email_verified_at) 
+			VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)`,
 			"Administrador", "admin@martinspocos.com", string(hashedPassword), gestorTypeID, "(34) 9999-9999")
 		if err != nil {
 			log.Println("Error creating default admin:", err)
@@ -313,4 +859,122 @@ func createDefaultAdmin() {
 			log.Println("Default admin created: admin@martinspocos.com / admin123")
 		}
 	}
-}
\ No newline at end of file
+}
+
+// Catálogo de permissões, na ordem de exibição
+var defaultPermissions = []struct{ code, name string }{
+	{"requests.view", "Ver solicitações e o painel"},
+	{"requests.manage", "Editar solicitações e alterar status"},
+	{"requests.delete", "Excluir solicitações"},
+	{"routes.manage", "Roteirizar visitas"},
+	{"contracts.view", "Ver contratos"},
+	{"contracts.manage", "Criar e editar contratos e enviar para assinatura"},
+	{"contracts.sign_company", "Assinar contratos pela empresa"},
+	{"quotes.manage", "Emitir e gerenciar orçamentos"},
+	{"pricing.manage", "Gerenciar tabelas de preço"},
+	{"wells.manage", "Gerenciar poços e perfis de perfuração"},
+	{"analyses.manage", "Registrar análises da água"},
+	{"maintenance.manage", "Gerenciar planos de manutenção"},
+	{"materials.manage", "Gerenciar materiais e estoque"},
+	{"warranty.manage", "Decidir reclamações de garantia"},
+	{"users.manage_roles", "Atribuir papéis e permissões"},
+	{"client.portal", "Acessar o portal do cliente"},
+	{"users.security", "Ver tentativas de login e desbloquear contas"},
+	{"privacy.manage", "Decidir pedidos de exclusão de dados (LGPD)"},
+	{"audit.view", "Ver o registro de auditoria"},
+	{"catalogs.manage", "Gerenciar tipos de serviço, status e tipos de garantia"},
+}
+
+// Permissões padrão de cada papel (ver insertDefaultRoles)
+var defaultRolePermissions = map[string][]string{
+	"PROPRIETARIO": {
+		"requests.view", "requests.manage", "requests.delete", "routes.manage",
+		"contracts.view", "contracts.manage", "contracts.sign_company",
+		"quotes.manage", "pricing.manage", "wells.manage", "analyses.manage",
+		"maintenance.manage", "materials.manage", "warranty.manage", "users.manage_roles",
+		"users.security", "privacy.manage", "audit.view", "catalogs.manage",
+	},
+	"ESCRITORIO": {
+		"requests.view", "requests.manage", "requests.delete", "routes.manage",
+		"contracts.view", "contracts.manage", "contracts.sign_company",
+		"quotes.manage", "pricing.manage", "wells.manage", "analyses.manage",
+		"maintenance.manage", "materials.manage", "warranty.manage", "users.security",
+	},
+	"TECNICO": {
+		"requests.view", "routes.manage", "wells.manage", "analyses.manage",
+		"maintenance.manage", "materials.manage",
+	},
+	"FINANCEIRO": {
+		"requests.view", "contracts.view", "contracts.manage", "contracts.sign_company",
+		"quotes.manage", "pricing.manage",
+	},
+	"CLIENTE": {"client.portal"},
+}
+
+// insertDefaultRoles cria papéis e permissões que ainda não existem e
+// atribui um papel aos usuários sem papel, conforme o tipo de usuário
+func insertDefaultRoles() {
+	_, err := DB.Exec(`
+		INSERT INTO roles (code, name, description, user_type, display_order) VALUES
+		('PROPRIETARIO', 'Proprietário', 'Acesso total, inclusive à atribuição de papéis', 'gestor', 1),
+		('ESCRITORIO', 'Administrativo', 'Rotina do escritório: solicitações, contratos, orçamentos e estoque', 'gestor', 2),
+		('TECNICO', 'Técnico', 'Visitas, poços, análises e manutenções', 'gestor', 3),
+		('FINANCEIRO', 'Financeiro', 'Orçamentos, preços e contratos', 'gestor', 4),
+		('CLIENTE', 'Cliente', 'Portal do cliente', 'cliente', 5)
+		ON CONFLICT (code) DO NOTHING`)
+	if err != nil {
+		log.Fatal("Error inserting default roles:", err)
+	}
+
+	// Permissões criadas nesta execução (instalação nova ou permissão
+	// adicionada numa versão posterior)
+	created := map[string]bool{}
+	for i, permission := range defaultPermissions {
+		var id int
+		err := DB.QueryRow(`
+			INSERT INTO permissions (code, name, display_order) VALUES ($1, $2, $3)
+			ON CONFLICT (code) DO NOTHING
+			RETURNING id`, permission.code, permission.name, i+1).Scan(&id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			log.Fatal("Error inserting default permissions:", err)
+		}
+		created[permission.code] = true
+	}
+
+	// Papéis sem nenhuma permissão recebem o conjunto padrão; os demais só
+	// recebem as permissões novas, preservando os ajustes feitos na tela de papéis
+	for roleCode, permissions := range defaultRolePermissions {
+		var count int
+		DB.QueryRow(`SELECT COUNT(*) FROM role_permissions rp JOIN roles r ON rp.role_id = r.id
+			WHERE r.code = $1`, roleCode).Scan(&count)
+		for _, permission := range permissions {
+			if count > 0 && !created[permission] {
+				continue
+			}
+			_, err := DB.Exec(`
+				INSERT INTO role_permissions (role_id, permission_id)
+				SELECT r.id, p.id FROM roles r, permissions p WHERE r.code = $1 AND p.code = $2
+				ON CONFLICT DO NOTHING`, roleCode, permission)
+			if err != nil {
+				log.Fatal("Error inserting default role permissions:", err)
+			}
+		}
+	}
+
+	// Usuários anteriores aos papéis: gestores viram proprietários (mantêm o
+	// acesso total que tinham) e os demais, clientes
+	result, err := DB.Exec(`
+		UPDATE users u SET role_id = r.id
+		FROM user_types ut, roles r
+		WHERE u.user_type_id = ut.id AND u.role_id IS NULL
+		  AND r.code = CASE ut.type_name WHEN 'gestor' THEN 'PROPRIETARIO' ELSE 'CLIENTE' END`)
+	if err != nil {
+		log.Fatal("Error assigning default roles:", err)
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		log.Printf("Default roles assigned to %d users", rows)
+	}
+}
@@ -1,20 +1,257 @@
 package config
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
+
+	"martins-pocos/utils"
+)
+
+const (
+	sessionMaxAge = 86400 * 7 // 7 days
+	// Sessões sem usuário (ex.: aguardando o segundo fator) expiram antes no servidor
+	anonymousSessionTTL = 24 * time.Hour
+	// Intervalo mínimo entre atualizações de last_seen_at
+	sessionTouchInterval = time.Minute
+	// Intervalo da limpeza de sessões expiradas
+	sessionCleanupInterval = time.Hour
 )
 
-var store *sessions.CookieStore
+var store *SessionStore
+
+// SessionStore guarda as sessões do site no PostgreSQL (tabela sessions).
+// O cookie leva apenas o identificador assinado, então encerrar a sessão no
+// banco encerra o acesso do dispositivo, mesmo que o cookie continue nele.
+type SessionStore struct {
+	DB      *sql.DB
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+}
 
 func InitSession() {
-	store = sessions.NewCookieStore([]byte("martins-pocos-secret-key"))
-	store.Options = &sessions.Options{
-		Path:     "/",
-		MaxAge:   86400 * 7, // 7 days
-		HttpOnly: true,
+	secret := os.Getenv("SESSION_SECRET")
+	if secret == "" {
+		log.Println("⚠️ SESSION_SECRET não definido, usando a chave padrão de desenvolvimento")
+		secret = "martins-pocos-secret-key"
+	}
+
+	codecs := securecookie.CodecsFromPairs([]byte(secret))
+	for _, codec := range codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(sessionMaxAge)
+		}
 	}
+
+	store = &SessionStore{
+		DB:     GetDB(),
+		Codecs: codecs,
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   sessionMaxAge,
+			HttpOnly: true,
+		},
+	}
+
+	go store.cleanupExpired()
 }
 
-func GetSessionStore() *sessions.CookieStore {
+func GetSessionStore() *SessionStore {
 	return store
-}
\ No newline at end of file
+}
+
+// SessionKey é o identificador gravado no banco para o identificador do
+// cookie (hash, para que um vazamento da tabela não permita usar as sessões)
+func SessionKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// CurrentSessionKey retorna a chave (ver SessionKey) da sessão da
+// requisição, ou "" se ela ainda não foi gravada
+func CurrentSessionKey(r *http.Request) string {
+	session, _ := store.Get(r, "session")
+	if session.ID == "" {
+		return ""
+	}
+	return SessionKey(session.ID)
+}
+
+// Get retorna a sessão da requisição, carregada uma única vez por requisição
+func (s *SessionStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New carrega a sessão indicada pelo cookie. Cookies inválidos, sessões
+// expiradas ou encerradas resultam em uma sessão nova e vazia.
+func (s *SessionStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &id, s.Codecs...); err != nil {
+		return session, nil
+	}
+
+	found, err := s.load(r, session, id)
+	if err != nil {
+		return session, err
+	}
+	if found {
+		session.ID = id
+		session.IsNew = false
+	}
+	return session, nil
+}
+
+// Save grava a sessão no banco e envia o cookie. MaxAge negativo encerra a
+// sessão. Uma sessão encerrada em outro lugar durante a requisição não é
+// recriada.
+func (s *SessionStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if _, err := s.DB.Exec(`DELETE FROM sessions WHERE id = $1`, SessionKey(session.ID)); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := securecookie.GobEncoder{}.Serialize(session.Values)
+	if err != nil {
+		return err
+	}
+
+	var userID sql.NullInt64
+	if id, ok := session.Values["user_id"].(int); ok {
+		userID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+
+	ttl := time.Duration(session.Options.MaxAge) * time.Second
+	if !userID.Valid && ttl > anonymousSessionTTL {
+		ttl = anonymousSessionTTL
+	}
+	expires := fmt.Sprintf("%d seconds", int(ttl.Seconds()))
+	ip := utils.ClientIP(r)
+	userAgent := r.UserAgent()
+
+	if session.IsNew || session.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		_, err = s.DB.Exec(`
+			INSERT INTO sessions (id, user_id, data, ip, user_agent, expires_at)
+			VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP + $6::interval)`,
+			SessionKey(id), userID, data, ip, userAgent, expires)
+		if err != nil {
+			return err
+		}
+		session.ID = id
+		session.IsNew = false
+	} else {
+		result, err := s.DB.Exec(`
+			UPDATE sessions SET user_id = $2, data = $3, ip = $4, user_agent = $5,
+			       last_seen_at = CURRENT_TIMESTAMP, expires_at = CURRENT_TIMESTAMP + $6::interval
+			WHERE id = $1`,
+			SessionKey(session.ID), userID, data, ip, userAgent, expires)
+		if err != nil {
+			return err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return nil
+		}
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// RenewID descarta o identificador atual da sessão, mantendo os valores. O
+// próximo Save grava a sessão com um identificador novo. Usado no login,
+// para que um identificador conhecido antes dele não dê acesso à conta.
+func (s *SessionStore) RenewID(session *sessions.Session) error {
+	if session.ID != "" {
+		if _, err := s.DB.Exec(`DELETE FROM sessions WHERE id = $1`, SessionKey(session.ID)); err != nil {
+			return err
+		}
+	}
+	session.ID = ""
+	session.IsNew = true
+	return nil
+}
+
+// load lê os valores da sessão e atualiza o último acesso
+func (s *SessionStore) load(r *http.Request, session *sessions.Session, id string) (bool, error) {
+	key := SessionKey(id)
+
+	var data []byte
+	var stale bool
+	err := s.DB.QueryRow(`
+		SELECT data, last_seen_at < CURRENT_TIMESTAMP - $2::interval
+		FROM sessions WHERE id = $1 AND expires_at > CURRENT_TIMESTAMP`,
+		key, fmt.Sprintf("%d seconds", int(sessionTouchInterval.Seconds()))).Scan(&data, &stale)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return false, nil
+	}
+
+	if stale {
+		_, err := s.DB.Exec(`
+			UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP, ip = $2, user_agent = $3
+			WHERE id = $1`, key, utils.ClientIP(r), r.UserAgent())
+		if err != nil {
+			log.Printf("❌ Erro ao atualizar último acesso da sessão: %v", err)
+		}
+	}
+	return true, nil
+}
+
+// cleanupExpired remove periodicamente as sessões expiradas
+func (s *SessionStore) cleanupExpired() {
+	for {
+		if _, err := s.DB.Exec(`DELETE FROM sessions WHERE expires_at <= CURRENT_TIMESTAMP`); err != nil {
+			log.Printf("❌ Erro ao limpar sessões expiradas: %v", err)
+		}
+		time.Sleep(sessionCleanupInterval)
+	}
+}
+
+func newSessionID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
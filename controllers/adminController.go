@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"database/sql"
 	"fmt"
 	"html/template"
 	"net/http"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"martins-pocos/config"
@@ -18,12 +20,14 @@ import (
 type AdminController struct {
 	ServiceModel    *models.ServiceModel
 	WhatsAppService *services.WhatsAppService
+	MaterialModel   *models.MaterialModel
 }
 
-func NewAdminController(serviceModel *models.ServiceModel, whatsappService *services.WhatsAppService) *AdminController {
+func NewAdminController(serviceModel *models.ServiceModel, whatsappService *services.WhatsAppService, materialModel *models.MaterialModel) *AdminController {
 	return &AdminController{
 		ServiceModel:    serviceModel,
 		WhatsAppService: whatsappService,
+		MaterialModel:   materialModel,
 	}
 }
 
@@ -82,8 +86,10 @@ func (c *AdminController) AdminDashboard(w http.ResponseWriter, r *http.Request)
 		stats = make(map[string]int)
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	// Alertas de estoque baixo
+	lowStock, _ := c.MaterialModel.GetLowStock()
+
+	userName := currentUser(r).UserName
 
 	data := struct {
 		UserName          string
@@ -94,6 +100,7 @@ func (c *AdminController) AdminDashboard(w http.ResponseWriter, r *http.Request)
 		ConfirmadaCount   int
 		RealizadaCount    int
 		CanceladaCount    int
+		LowStock          []models.Material
 		CurrentPage       int
 		TotalPages        int
 		TotalCount        int
@@ -103,6 +110,7 @@ func (c *AdminController) AdminDashboard(w http.ResponseWriter, r *http.Request)
 		ServiceTypeFilter string
 		SearchQuery       string
 		SuccessMsg        string
+		ErrorMsg          string
 		PageTitle         string
 		CustomCSS         string
 		CustomJS          string
@@ -118,6 +126,7 @@ func (c *AdminController) AdminDashboard(w http.ResponseWriter, r *http.Request)
 		ConfirmadaCount:   stats["confirmada"],
 		RealizadaCount:    stats["realizada"],
 		CanceladaCount:    stats["cancelada"],
+		LowStock:          lowStock,
 		CurrentPage:       page,
 		TotalPages:        totalPages,
 		TotalCount:        totalCount,
@@ -127,6 +136,7 @@ func (c *AdminController) AdminDashboard(w http.ResponseWriter, r *http.Request)
 		ServiceTypeFilter: serviceTypeFilter,
 		SearchQuery:       searchQuery,
 		SuccessMsg:        c.getSuccessMessage(r),
+		ErrorMsg:          c.getErrorMessage(r),
 		PageTitle:         "Dashboard Administrativo",
 		CustomCSS:         "/static/css/admin.css",
 		CustomJS:          "/static/js/admin.js",
@@ -135,7 +145,7 @@ func (c *AdminController) AdminDashboard(w http.ResponseWriter, r *http.Request)
 		IsAdmin:		  true,
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -166,12 +176,18 @@ func (c *AdminController) VerSolicitacaoAdmin(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	customFields, err := c.ServiceModel.CustomFieldAnswers(service)
+	if err != nil {
+		http.Error(w, "Erro ao carregar informações adicionais", http.StatusInternalServerError)
+		return
+	}
+
+	userName := currentUser(r).UserName
 
 	data := struct {
 		Service           *models.ServiceRequest
 		Statuses          []models.RequestStatus
+		CustomFields      []models.CustomFieldAnswer
 		UserName          string
 		PageTitle         string
 		CustomCSS         string
@@ -182,6 +198,7 @@ func (c *AdminController) VerSolicitacaoAdmin(w http.ResponseWriter, r *http.Req
 	}{
 		Service:           service,
 		Statuses:          statuses,
+		CustomFields:      customFields,
 		UserName:          userName,
 		PageTitle:         "Detalhes da Solicitação",
 		CustomCSS:         "/static/css/admin.css",
@@ -191,7 +208,7 @@ func (c *AdminController) VerSolicitacaoAdmin(w http.ResponseWriter, r *http.Req
 		IsAdmin:		  true,
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -253,7 +270,7 @@ func (c *AdminController) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Atualizar o status
-	if err := c.ServiceModel.UpdateStatusByID(requestID, statusID); err != nil {
+	if err := c.ServiceModel.UpdateStatusByID(requestID, statusID, auditActor(r)); err != nil {
 		http.Error(w, "Erro ao atualizar status", http.StatusInternalServerError)
 		return
 	}
@@ -268,7 +285,7 @@ func (c *AdminController) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/dashboard/admin?success=status_updated", http.StatusFound)
 }
 
-// DeletarSolicitacao - Deletar solicitação
+// DeletarSolicitacao - Move a solicitação para a lixeira
 func (c *AdminController) DeletarSolicitacao(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -282,7 +299,16 @@ func (c *AdminController) DeletarSolicitacao(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := c.ServiceModel.Delete(requestID); err != nil {
+	err = c.ServiceModel.Delete(requestID, auditActor(r))
+	if err == models.ErrRequestHasSignedContract {
+		http.Redirect(w, r, "/dashboard/admin?error=signed_contract", http.StatusFound)
+		return
+	}
+	if err == sql.ErrNoRows {
+		http.Error(w, "Solicitação não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Erro ao deletar solicitação", http.StatusInternalServerError)
 		return
 	}
@@ -290,6 +316,72 @@ func (c *AdminController) DeletarSolicitacao(w http.ResponseWriter, r *http.Requ
 	http.Redirect(w, r, "/dashboard/admin?success=deleted", http.StatusFound)
 }
 
+// Lixeira - Solicitações excluídas, restauráveis até o expurgo automático
+func (c *AdminController) Lixeira(w http.ResponseWriter, r *http.Request) {
+	requests, err := c.ServiceModel.GetDeleted()
+	if err != nil {
+		http.Error(w, "Erro ao buscar lixeira", http.StatusInternalServerError)
+		return
+	}
+
+	user := currentUser(r)
+
+	data := struct {
+		Requests          []models.ServiceRequest
+		RetentionDays     int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Requests:          requests,
+		RetentionDays:     config.GetRequestTrashRetentionDays(),
+		UserName:          user.UserName,
+		PageTitle:         "Lixeira de Solicitações",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMessage(r),
+		ErrorMsg:          c.getErrorMessage(r),
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_lixeira.html",
+	}, data)
+}
+
+// RestaurarSolicitacao - Tira a solicitação da lixeira
+func (c *AdminController) RestaurarSolicitacao(w http.ResponseWriter, r *http.Request) {
+	requestID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.ServiceModel.Restore(requestID, auditActor(r))
+	if err == sql.ErrNoRows {
+		http.Redirect(w, r, "/admin/lixeira?error=not_found", http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao restaurar solicitação", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/lixeira?success=restored", http.StatusFound)
+}
+
 // Helper methods
 
 func (c *AdminController) getSuccessMessage(r *http.Request) string {
@@ -299,7 +391,9 @@ func (c *AdminController) getSuccessMessage(r *http.Request) string {
 	case "updated":
 		return "Solicitação atualizada com sucesso!"
 	case "deleted":
-		return "Solicitação deletada com sucesso!"
+		return "Solicitação movida para a lixeira."
+	case "restored":
+		return "Solicitação restaurada com sucesso!"
 	case "no_change":
 		return "Nenhuma alteração foi feita"
 	default:
@@ -307,9 +401,20 @@ func (c *AdminController) getSuccessMessage(r *http.Request) string {
 	}
 }
 
-func (c *AdminController) renderTemplate(w http.ResponseWriter, templatePaths []string, data interface{}) {
+func (c *AdminController) getErrorMessage(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "signed_contract":
+		return "A solicitação tem contrato assinado e não pode ser excluída."
+	case "not_found":
+		return "A solicitação não está mais na lixeira."
+	default:
+		return ""
+	}
+}
+
+func (c *AdminController) renderTemplate(w http.ResponseWriter, r *http.Request, templatePaths []string, data interface{}) {
 	// Criar template com funções auxiliares
-	tmpl := template.New("").Funcs(GetTemplateFuncs())
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
 	
 	// Parsear todos os templates
 	var err error
@@ -334,7 +439,40 @@ func (c *AdminController) getUserByID(userID int) (*models.User, error) {
 	return userModel.GetByID(userID)
 }
 
+// getTechnicians retorna os técnicos que podem ser designados para visitas,
+// mantendo na lista o já designado (currentID)
+func (c *AdminController) getTechnicians(currentID int) ([]models.User, error) {
+	userModel := models.NewUserModel(config.GetDB())
+	return userModel.GetTechnicians(currentID)
+}
+
+// parseCoordinates valida latitude/longitude opcionais do formulário
+func parseCoordinates(latStr, lngStr string) (sql.NullFloat64, sql.NullFloat64, error) {
+	latStr = strings.TrimSpace(strings.Replace(latStr, ",", ".", 1))
+	lngStr = strings.TrimSpace(strings.Replace(lngStr, ",", ".", 1))
+
+	if latStr == "" && lngStr == "" {
+		return sql.NullFloat64{}, sql.NullFloat64{}, nil
+	}
+
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return sql.NullFloat64{}, sql.NullFloat64{}, fmt.Errorf("Latitude inválida")
+	}
+
+	lng, err := strconv.ParseFloat(lngStr, 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return sql.NullFloat64{}, sql.NullFloat64{}, fmt.Errorf("Longitude inválida")
+	}
+
+	return sql.NullFloat64{Float64: lat, Valid: true}, sql.NullFloat64{Float64: lng, Valid: true}, nil
+}
+
 func (c *AdminController) sendWhatsAppNotification(service *models.ServiceRequest, user *models.User, newStatusID int) {
+	if !wantsNotification(user.ID, models.NotifyRequestUpdates) {
+		return
+	}
+
 	var message string
 
 	switch newStatusID {
@@ -387,13 +525,20 @@ func (c *AdminController) showAdminEditForm(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	technicians, err := c.getTechnicians(int(service.TechnicianID.Int64))
+	if err != nil {
+		http.Error(w, "Erro ao carregar técnicos", http.StatusInternalServerError)
+		return
+	}
+
+	userName := currentUser(r).UserName
 
 	data := struct {
 		Service           *models.ServiceRequest
 		ServiceTypes      []models.ServiceType
 		Statuses          []models.RequestStatus
+		Technicians       []models.User
+		TechnicianID      int
 		UserName          string
 		PageTitle         string
 		CustomCSS         string
@@ -405,6 +550,8 @@ func (c *AdminController) showAdminEditForm(w http.ResponseWriter, r *http.Reque
 		Service:           service,
 		ServiceTypes:      serviceTypes,
 		Statuses:          statuses,
+		Technicians:       technicians,
+		TechnicianID:      int(service.TechnicianID.Int64),
 		UserName:          userName,
 		PageTitle:         "Editar Solicitação",
 		CustomCSS:         "/static/css/admin.css",
@@ -414,7 +561,7 @@ func (c *AdminController) showAdminEditForm(w http.ResponseWriter, r *http.Reque
 		IsAdmin:		  true,
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -449,6 +596,17 @@ func (c *AdminController) updateAdminServiceRequest(w http.ResponseWriter, r *ht
 		return
 	}
 
+	latitude, longitude, err := parseCoordinates(r.FormValue("latitude"), r.FormValue("longitude"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var technicianID sql.NullInt64
+	if id, err := strconv.Atoi(r.FormValue("technician_id")); err == nil && id > 0 {
+		technicianID = sql.NullInt64{Int64: int64(id), Valid: true}
+	}
+
 	service := &models.ServiceRequest{
 		ID:            requestID,
 		FullName:      r.FormValue("full_name"),
@@ -463,9 +621,12 @@ func (c *AdminController) updateAdminServiceRequest(w http.ResponseWriter, r *ht
 		PreferredDate: preferredDate,
 		PreferredTime: r.FormValue("preferred_time"),
 		StatusID:      statusID,
+		Latitude:      latitude,
+		Longitude:     longitude,
+		TechnicianID:  technicianID,
 	}
 
-	if err := c.ServiceModel.AdminUpdate(service); err != nil {
+	if err := c.ServiceModel.AdminUpdate(service, auditActor(r)); err != nil {
 		http.Error(w, "Erro ao atualizar solicitação", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,1009 @@
+package controllers
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"martins-pocos/config"
+	"martins-pocos/constants"
+	"martins-pocos/models"
+	"martins-pocos/utils"
+)
+
+// Documento OpenAPI 3 da API v1, embutido no binário
+//
+//go:embed openapi_v1.json
+var openAPIV1 []byte
+
+const (
+	apiDefaultLimit = 20
+	apiMaxLimit     = 100
+	apiMaxBodyBytes = 5 << 20 // assinaturas chegam como data URL em base64
+)
+
+type APIController struct {
+	ServiceModel      *models.ServiceModel
+	ContractModel     *models.ContractModel
+	UserModel         *models.UserModel
+	TokenModel        *models.APITokenModel
+	LoginAttemptModel *models.LoginAttemptModel
+	TwoFactorModel    *models.TwoFactorModel
+}
+
+func NewAPIController(serviceModel *models.ServiceModel, contractModel *models.ContractModel, userModel *models.UserModel, tokenModel *models.APITokenModel, loginAttemptModel *models.LoginAttemptModel, twoFactorModel *models.TwoFactorModel) *APIController {
+	return &APIController{
+		ServiceModel:      serviceModel,
+		ContractModel:     contractModel,
+		UserModel:         userModel,
+		TokenModel:        tokenModel,
+		LoginAttemptModel: loginAttemptModel,
+		TwoFactorModel:    twoFactorModel,
+	}
+}
+
+// ============================================
+// REPRESENTAÇÕES JSON
+// ============================================
+
+// Os modelos usam sql.Null*, que serializam como objetos {String, Valid};
+// a API expõe versões planas, com null quando o valor não existe.
+
+type apiCode struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+type apiServiceRequest struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"user_id"`
+	FullName       string    `json:"full_name"`
+	ServiceType    apiCode   `json:"service_type"`
+	Description    string    `json:"description"`
+	CEP            string    `json:"cep"`
+	Logradouro     string    `json:"logradouro"`
+	Numero         string    `json:"numero"`
+	Bairro         string    `json:"bairro"`
+	Cidade         string    `json:"cidade"`
+	Estado         string    `json:"estado"`
+	PreferredDate  string    `json:"preferred_date"`
+	PreferredTime  string    `json:"preferred_time"`
+	Status         apiCode   `json:"status"`
+	Latitude       *float64  `json:"latitude"`
+	Longitude      *float64  `json:"longitude"`
+	TechnicianName string    `json:"technician_name,omitempty"`
+	UserName       string    `json:"user_name,omitempty"`
+	UserEmail      string    `json:"user_email,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	CustomFields models.CustomFieldValues `json:"custom_fields,omitempty"`
+}
+
+type apiContract struct {
+	ID                 int        `json:"id"`
+	ServiceRequestID   int        `json:"service_request_id"`
+	ContractNumber     string     `json:"contract_number"`
+	TotalValue         float64    `json:"total_value"`
+	PaymentConditions  string     `json:"payment_conditions"`
+	GuaranteeType      apiCode    `json:"guarantee_type"`
+	GuaranteeCustom    *string    `json:"guarantee_custom"`
+	ClientRequirements *string    `json:"client_requirements"`
+	MaterialsUsed      *string    `json:"materials_used"`
+	AdditionalNotes    *string    `json:"additional_notes"`
+	Status             apiCode    `json:"status"`
+	ClientSigned       bool       `json:"client_signed"`
+	ClientSignedAt     *time.Time `json:"client_signed_at"`
+	CompanySigned      bool       `json:"company_signed"`
+	CompanySignedAt    *time.Time `json:"company_signed_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type apiObservation struct {
+	ID           int        `json:"id"`
+	ContractID   int        `json:"contract_id"`
+	UserID       int        `json:"user_id"`
+	UserName     string     `json:"user_name"`
+	Observation  string     `json:"observation"`
+	Resolved     bool       `json:"resolved"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	ResolverName string     `json:"resolver_name,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// apiServiceRequestInput é o corpo aceito na criação e edição de solicitações
+type apiServiceRequestInput struct {
+	ServiceType   string `json:"service_type"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	CEP           string `json:"cep"`
+	Logradouro    string `json:"logradouro"`
+	Numero        string `json:"numero"`
+	Bairro        string `json:"bairro"`
+	Cidade        string `json:"cidade"`
+	Estado        string `json:"estado"`
+	PreferredDate string `json:"preferred_date"`
+	PreferredTime string `json:"preferred_time"`
+
+	// Respostas aos campos adicionais do tipo de serviço, pela chave do campo
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+func toAPIServiceRequest(s models.ServiceRequest) apiServiceRequest {
+	out := apiServiceRequest{
+		ID:             s.ID,
+		UserID:         s.UserID,
+		FullName:       s.FullName,
+		ServiceType:    apiCode{Code: s.ServiceTypeCode, Name: s.ServiceTypeName},
+		Description:    s.Description,
+		CEP:            s.CEP,
+		Logradouro:     s.Logradouro,
+		Numero:         s.Numero,
+		Bairro:         s.Bairro,
+		Cidade:         s.Cidade,
+		Estado:         s.Estado,
+		PreferredDate:  s.PreferredDate.Format("2006-01-02"),
+		PreferredTime:  s.PreferredTime,
+		Status:         apiCode{Code: s.StatusCode, Name: s.StatusName},
+		TechnicianName: s.TechnicianName,
+		UserName:       s.UserName,
+		UserEmail:      s.UserEmail,
+		CreatedAt:      s.CreatedAt,
+		UpdatedAt:      s.UpdatedAt,
+		CustomFields:   s.CustomFields,
+	}
+	if s.Latitude.Valid {
+		out.Latitude = &s.Latitude.Float64
+	}
+	if s.Longitude.Valid {
+		out.Longitude = &s.Longitude.Float64
+	}
+	return out
+}
+
+func toAPIContract(c models.Contract) apiContract {
+	out := apiContract{
+		ID:                 c.ID,
+		ServiceRequestID:   c.ServiceRequestID,
+		ContractNumber:     c.ContractNumber,
+		TotalValue:         c.TotalValue,
+		PaymentConditions:  c.PaymentConditions,
+		GuaranteeCustom:    nullStringPtr(c.GuaranteeCustom),
+		ClientRequirements: nullStringPtr(c.ClientRequirements),
+		MaterialsUsed:      nullStringPtr(c.MaterialsUsed),
+		AdditionalNotes:    nullStringPtr(c.AdditionalNotes),
+		ClientSigned:       c.ClientSigned,
+		ClientSignedAt:     nullTimePtr(c.ClientSignedAt),
+		CompanySigned:      c.CompanySigned,
+		CompanySignedAt:    nullTimePtr(c.CompanySignedAt),
+		CreatedAt:          c.CreatedAt,
+		UpdatedAt:          c.UpdatedAt,
+	}
+	if c.GuaranteeType != nil {
+		out.GuaranteeType = apiCode{Code: c.GuaranteeType.Code, Name: c.GuaranteeType.Name}
+	}
+	if c.Status != nil {
+		out.Status = apiCode{Code: c.Status.Code, Name: c.Status.Name}
+	}
+	return out
+}
+
+func toAPIObservation(o models.ContractObservation) apiObservation {
+	return apiObservation{
+		ID:           o.ID,
+		ContractID:   o.ContractID,
+		UserID:       o.UserID,
+		UserName:     o.UserName,
+		Observation:  o.Observation,
+		Resolved:     o.Resolved,
+		ResolvedAt:   nullTimePtr(o.ResolvedAt),
+		ResolverName: o.ResolverName,
+		CreatedAt:    o.CreatedAt,
+	}
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
+
+func nullTimePtr(v sql.NullTime) *time.Time {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Time
+}
+
+// ============================================
+// DOCUMENTAÇÃO E CATÁLOGOS
+// ============================================
+
+// OpenAPI serve o documento OpenAPI 3 da API v1
+func (c *APIController) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIV1)
+}
+
+// ServiceTypes lista os tipos de serviço ativos
+func (c *APIController) ServiceTypes(w http.ResponseWriter, r *http.Request) {
+	types, err := c.ServiceModel.GetAllServiceTypes()
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar tipos de serviço", http.StatusInternalServerError)
+		return
+	}
+	if types == nil {
+		types = []models.ServiceType{}
+	}
+	utils.SendSuccessResponse(w, "", types)
+}
+
+// Statuses lista as situações possíveis de solicitações e contratos
+func (c *APIController) Statuses(w http.ResponseWriter, r *http.Request) {
+	requestStatuses, err := c.ServiceModel.GetAllRequestStatus()
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar status", http.StatusInternalServerError)
+		return
+	}
+	contractStatuses, err := c.ContractModel.GetAllContractStatuses()
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar status", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		ServiceRequests []models.RequestStatus  `json:"service_requests"`
+		Contracts       []models.ContractStatus `json:"contracts"`
+	}{requestStatuses, contractStatuses}
+	if data.ServiceRequests == nil {
+		data.ServiceRequests = []models.RequestStatus{}
+	}
+	if data.Contracts == nil {
+		data.Contracts = []models.ContractStatus{}
+	}
+	utils.SendSuccessResponse(w, "", data)
+}
+
+// ============================================
+// AUTENTICAÇÃO POR TOKEN
+// ============================================
+
+type apiTokenResponse struct {
+	TokenType        string   `json:"token_type"`
+	AccessToken      string   `json:"access_token"`
+	ExpiresIn        int      `json:"expires_in"`
+	RefreshToken     string   `json:"refresh_token"`
+	RefreshExpiresIn int      `json:"refresh_expires_in"`
+	Scopes           []string `json:"scopes"`
+}
+
+func toAPITokenResponse(pair *models.TokenPair) apiTokenResponse {
+	return apiTokenResponse{
+		TokenType:        "Bearer",
+		AccessToken:      pair.AccessToken,
+		ExpiresIn:        int(time.Until(pair.AccessExpiresAt).Seconds()),
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresIn: int(time.Until(pair.RefreshExpiresAt).Seconds()),
+		Scopes:           pair.Scopes,
+	}
+}
+
+// IssueToken autentica por e-mail e senha e emite um par de tokens
+// acesso/refresh para aplicativos e integrações
+func (c *APIController) IssueToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email      string   `json:"email"`
+		Password   string   `json:"password"`
+		TOTPCode   string   `json:"totp_code"`
+		Scopes     []string `json:"scopes"`
+		ClientName string   `json:"client_name"`
+	}
+	if !decodeJSONBody(w, r, &input) {
+		return
+	}
+
+	scopes, err := models.ParseScopes(input.Scopes)
+	if err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	email := strings.TrimSpace(input.Email)
+	if msg, ok := checkLoginThrottle(c.LoginAttemptModel, w, r, email, models.LoginMethodAPI); !ok {
+		utils.SendErrorResponse(w, msg, http.StatusTooManyRequests)
+		return
+	}
+
+	user, err := c.UserModel.Authenticate(email, input.Password)
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		reason := models.LoginWrongPassword
+		if user == nil {
+			reason = models.LoginUnknownUser
+		}
+		recordLogin(c.LoginAttemptModel, r, email, models.LoginMethodAPI, user, reason)
+		utils.SendErrorResponse(w, "E-mail ou senha inválidos", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao autenticar", http.StatusInternalServerError)
+		return
+	}
+	if !user.IsEmailVerified() {
+		recordLogin(c.LoginAttemptModel, r, email, models.LoginMethodAPI, user, models.LoginEmailNotVerified)
+		utils.SendErrorResponse(w, "Confirme seu e-mail antes de emitir tokens", http.StatusForbidden)
+		return
+	}
+
+	// Com a verificação em duas etapas, o código do aplicativo também é exigido
+	if user.HasTwoFactor() {
+		if strings.TrimSpace(input.TOTPCode) == "" {
+			utils.SendErrorResponse(w, "Informe totp_code, o código do aplicativo autenticador", http.StatusUnauthorized)
+			return
+		}
+		_, err := c.TwoFactorModel.Verify(user.ID, input.TOTPCode)
+		if errors.Is(err, models.ErrInvalidTwoFactorCode) {
+			recordLogin(c.LoginAttemptModel, r, email, models.LoginMethodAPI, user, models.LoginInvalidCode)
+			utils.SendErrorResponse(w, "Código de verificação inválido", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			utils.SendErrorResponse(w, "Erro ao verificar código", http.StatusInternalServerError)
+			return
+		}
+	} else if config.RequireAdminTwoFactor() && user.UserType == "gestor" {
+		utils.SendErrorResponse(w, "Ative a verificação em duas etapas no site antes de emitir tokens", http.StatusForbidden)
+		return
+	}
+	recordLogin(c.LoginAttemptModel, r, email, models.LoginMethodAPI, user, models.LoginOK)
+
+	clientName := strings.TrimSpace(input.ClientName)
+	if clientName == "" {
+		clientName = "Aplicativo"
+	}
+
+	pair, err := c.TokenModel.IssuePair(user.ID, clientName, scopes)
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao emitir token", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "", toAPITokenResponse(pair))
+}
+
+// RefreshToken troca um token de refresh por um novo par (o anterior é revogado)
+func (c *APIController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if !decodeJSONBody(w, r, &input) {
+		return
+	}
+
+	pair, err := c.TokenModel.Refresh(input.RefreshToken)
+	if errors.Is(err, models.ErrInvalidToken) {
+		utils.SendErrorResponse(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao renovar token", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "", toAPITokenResponse(pair))
+}
+
+// RevokeToken revoga o token informado. Responde 200 mesmo que o token não
+// exista, para não revelar quais tokens são válidos.
+func (c *APIController) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+	if !decodeJSONBody(w, r, &input) {
+		return
+	}
+
+	if err := c.TokenModel.RevokeByValue(input.Token); err != nil {
+		utils.SendErrorResponse(w, "Erro ao revogar token", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "Token revogado", nil)
+}
+
+// Me retorna o usuário autenticado e, se for o caso, os escopos do token
+func (c *APIController) Me(w http.ResponseWriter, r *http.Request) {
+	principal := currentUser(r)
+
+	data := struct {
+		ID       int      `json:"id"`
+		Name     string   `json:"name"`
+		UserType string   `json:"user_type"`
+		Auth     string   `json:"auth"`
+		Scopes   []string `json:"scopes,omitempty"`
+	}{principal.UserID, principal.UserName, principal.UserType, "session", nil}
+	if principal.ViaToken() {
+		data.Auth = "token"
+		data.Scopes = principal.Scopes
+	}
+	utils.SendSuccessResponse(w, "", data)
+}
+
+// ============================================
+// SOLICITAÇÕES DE SERVIÇO
+// ============================================
+
+// ListServiceRequests lista as solicitações: o cliente vê apenas as suas,
+// o gestor vê todas e pode buscar por nome, cidade ou e-mail (q).
+func (c *APIController) ListServiceRequests(w http.ResponseWriter, r *http.Request) {
+	userID, isAdmin := apiCurrentUser(r)
+	limit, offset, ok := apiPagination(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var (
+		requests []models.ServiceRequest
+		total    int
+		err      error
+	)
+	if isAdmin {
+		requests, total, err = c.ServiceModel.GetAllWithFilters(q.Get("status"), q.Get("service_type"), strings.TrimSpace(q.Get("q")), limit, offset)
+	} else {
+		requests, total, err = c.ServiceModel.GetByUserIDWithFilters(userID, q.Get("status"), q.Get("service_type"), limit, offset)
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar solicitações", http.StatusInternalServerError)
+		return
+	}
+
+	data := make([]apiServiceRequest, 0, len(requests))
+	for _, req := range requests {
+		if !isAdmin {
+			req.UserID = userID
+		}
+		data = append(data, toAPIServiceRequest(req))
+	}
+	utils.SendPaginatedResponse(w, data, utils.PageMeta{Limit: limit, Offset: offset, Total: total})
+}
+
+// GetServiceRequest retorna uma solicitação
+func (c *APIController) GetServiceRequest(w http.ResponseWriter, r *http.Request) {
+	service, ok := c.loadServiceRequest(w, r)
+	if !ok {
+		return
+	}
+	utils.SendSuccessResponse(w, "", toAPIServiceRequest(*service))
+}
+
+// CreateServiceRequest abre uma nova solicitação em nome do cliente autenticado
+func (c *APIController) CreateServiceRequest(w http.ResponseWriter, r *http.Request) {
+	userID, isAdmin := apiCurrentUser(r)
+	if isAdmin {
+		utils.SendErrorResponse(w, "Solicitações são abertas pelo cliente", http.StatusForbidden)
+		return
+	}
+
+	var input apiServiceRequestInput
+	if !decodeJSONBody(w, r, &input) {
+		return
+	}
+
+	service, err := c.serviceRequestFromInput(input, userID)
+	if err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if service.CustomFields, err = c.customFieldsFromInput(input, service.ServiceTypeID); err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := c.ServiceModel.Create(service); err != nil {
+		utils.SendErrorResponse(w, "Erro ao criar solicitação", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := c.ServiceModel.GetByIDAndUser(service.ID, userID)
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar solicitação", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/service-requests/"+strconv.Itoa(service.ID))
+	utils.SendCreatedResponse(w, "Solicitação criada com sucesso", toAPIServiceRequest(*created))
+}
+
+// UpdateServiceRequest altera os dados de uma solicitação. O cliente só
+// edita as suas enquanto estão em SOLICITADA; o gestor edita qualquer uma
+// sem alterar status, coordenadas ou técnico.
+func (c *APIController) UpdateServiceRequest(w http.ResponseWriter, r *http.Request) {
+	current, ok := c.loadServiceRequest(w, r)
+	if !ok {
+		return
+	}
+	userID, isAdmin := apiCurrentUser(r)
+	if isAdmin && !apiHasPermission(w, r, models.PermRequestsManage) {
+		return
+	}
+
+	var input apiServiceRequestInput
+	if !decodeJSONBody(w, r, &input) {
+		return
+	}
+
+	service, err := c.serviceRequestFromInput(input, current.UserID)
+	if err != nil {
+		utils.SendErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	service.ID = current.ID
+
+	if isAdmin {
+		service.StatusID = current.StatusID
+		service.Latitude = current.Latitude
+		service.Longitude = current.Longitude
+		service.TechnicianID = current.TechnicianID
+		err = c.ServiceModel.AdminUpdate(service, auditActor(r))
+	} else {
+		if current.StatusID != constants.StatusSolicitada {
+			utils.SendErrorResponse(w, "Solicitação não pode mais ser editada", http.StatusConflict)
+			return
+		}
+		service.UserID = userID
+		if service.CustomFields, err = c.customFieldsFromInput(input, service.ServiceTypeID); err != nil {
+			utils.SendErrorResponse(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		err = c.ServiceModel.Update(service)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendErrorResponse(w, "Solicitação não pode mais ser editada", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao atualizar solicitação", http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := c.ServiceModel.GetByID(current.ID)
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar solicitação", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "Solicitação atualizada com sucesso", toAPIServiceRequest(*updated))
+}
+
+// CancelServiceRequest cancela uma solicitação do cliente ainda em SOLICITADA
+func (c *APIController) CancelServiceRequest(w http.ResponseWriter, r *http.Request) {
+	userID, isAdmin := apiCurrentUser(r)
+	if isAdmin {
+		utils.SendErrorResponse(w, "Use a atualização de status do painel para cancelar como gestor", http.StatusForbidden)
+		return
+	}
+
+	current, ok := c.loadServiceRequest(w, r)
+	if !ok {
+		return
+	}
+
+	err := c.ServiceModel.Cancel(current.ID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendErrorResponse(w, "Solicitação não pode mais ser cancelada", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao cancelar solicitação", http.StatusInternalServerError)
+		return
+	}
+
+	cancelled, err := c.ServiceModel.GetByIDAndUser(current.ID, userID)
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar solicitação", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "Solicitação cancelada com sucesso", toAPIServiceRequest(*cancelled))
+}
+
+// DeleteServiceRequest move uma solicitação para a lixeira (apenas gestor)
+func (c *APIController) DeleteServiceRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.SendErrorResponse(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.ServiceModel.Delete(id, auditActor(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendErrorResponse(w, "Solicitação não encontrada", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrRequestHasSignedContract) {
+		utils.SendErrorResponse(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao deletar solicitação", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "Solicitação movida para a lixeira", nil)
+}
+
+// loadServiceRequest busca a solicitação do path respeitando a posse:
+// para o cliente, solicitações de outros usuários respondem 404.
+func (c *APIController) loadServiceRequest(w http.ResponseWriter, r *http.Request) (*models.ServiceRequest, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.SendErrorResponse(w, "ID inválido", http.StatusBadRequest)
+		return nil, false
+	}
+
+	userID, isAdmin := apiCurrentUser(r)
+	service, err := c.ServiceModel.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !isAdmin && service.UserID != userID) {
+		utils.SendErrorResponse(w, "Solicitação não encontrada", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar solicitação", http.StatusInternalServerError)
+		return nil, false
+	}
+	return service, true
+}
+
+// serviceRequestFromInput valida o corpo com as mesmas regras do formulário
+func (c *APIController) serviceRequestFromInput(input apiServiceRequestInput, userID int) (*models.ServiceRequest, error) {
+	if strings.TrimSpace(input.FullName) == "" {
+		return nil, errors.New("full_name é obrigatório")
+	}
+
+	serviceType, err := c.ServiceModel.GetServiceTypeByCode(input.ServiceType)
+	if err != nil {
+		return nil, errors.New("service_type inválido")
+	}
+
+	preferredDate, err := time.Parse("2006-01-02", input.PreferredDate)
+	if err != nil {
+		return nil, errors.New("preferred_date deve estar no formato AAAA-MM-DD")
+	}
+
+	preferredTime, err := time.Parse("15:04", input.PreferredTime)
+	if err != nil {
+		return nil, errors.New("preferred_time deve estar no formato HH:MM")
+	}
+
+	return &models.ServiceRequest{
+		UserID:        userID,
+		FullName:      strings.TrimSpace(input.FullName),
+		ServiceTypeID: serviceType.ID,
+		Description:   input.Description,
+		CEP:           input.CEP,
+		Logradouro:    input.Logradouro,
+		Numero:        input.Numero,
+		Bairro:        input.Bairro,
+		Cidade:        input.Cidade,
+		Estado:        input.Estado,
+		PreferredDate: preferredDate,
+		PreferredTime: preferredTime.Format("15:04"),
+	}, nil
+}
+
+// customFieldsFromInput valida as respostas aos campos adicionais do tipo
+// de serviço. Aceita texto, número ou booleano em cada chave, como o
+// formulário, que envia tudo como texto.
+func (c *APIController) customFieldsFromInput(input apiServiceRequestInput, serviceTypeID int) (models.CustomFieldValues, error) {
+	fields, err := c.ServiceModel.GetActiveServiceFields(serviceTypeID)
+	if err != nil {
+		return nil, errors.New("Erro ao carregar campos do tipo de serviço")
+	}
+
+	raw := map[string]string{}
+	for key, value := range input.CustomFields {
+		switch v := value.(type) {
+		case string:
+			raw[key] = v
+		case float64:
+			raw[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			raw[key] = strconv.FormatBool(v)
+		case nil:
+		default:
+			return nil, errors.New("custom_fields." + key + " deve ser texto, número ou booleano")
+		}
+	}
+	return models.ParseCustomFields(fields, raw)
+}
+
+// ============================================
+// CONTRATOS
+// ============================================
+
+// ListContracts lista os contratos visíveis ao usuário, com filtro por status
+func (c *APIController) ListContracts(w http.ResponseWriter, r *http.Request) {
+	userID, isAdmin := apiCurrentUser(r)
+	limit, offset, ok := apiPagination(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	var (
+		contracts []models.Contract
+		total     int
+		err       error
+	)
+	if isAdmin {
+		contracts, total, err = c.ContractModel.GetAllWithDetails(status, limit, offset)
+	} else {
+		contracts, total, err = c.ContractModel.GetAllByUserID(userID, status, limit, offset)
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar contratos", http.StatusInternalServerError)
+		return
+	}
+
+	data := make([]apiContract, 0, len(contracts))
+	for _, contract := range contracts {
+		data = append(data, toAPIContract(contract))
+	}
+	utils.SendPaginatedResponse(w, data, utils.PageMeta{Limit: limit, Offset: offset, Total: total})
+}
+
+// GetContract retorna um contrato
+func (c *APIController) GetContract(w http.ResponseWriter, r *http.Request) {
+	contract, ok := c.loadContract(w, r)
+	if !ok {
+		return
+	}
+	utils.SendSuccessResponse(w, "", toAPIContract(*contract))
+}
+
+// ListObservations lista as observações do cliente sobre o contrato
+func (c *APIController) ListObservations(w http.ResponseWriter, r *http.Request) {
+	contract, ok := c.loadContract(w, r)
+	if !ok {
+		return
+	}
+
+	observations, err := c.ContractModel.GetObservationsByContract(contract.ID)
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar observações", http.StatusInternalServerError)
+		return
+	}
+
+	data := make([]apiObservation, 0, len(observations))
+	for _, obs := range observations {
+		data = append(data, toAPIObservation(obs))
+	}
+	utils.SendSuccessResponse(w, "", data)
+}
+
+// AddObservation registra uma observação do cliente antes das assinaturas
+func (c *APIController) AddObservation(w http.ResponseWriter, r *http.Request) {
+	userID, isAdmin := apiCurrentUser(r)
+	if isAdmin {
+		utils.SendErrorResponse(w, "Observações são registradas pelo cliente", http.StatusForbidden)
+		return
+	}
+
+	contract, ok := c.loadContract(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Observation string `json:"observation"`
+	}
+	if !decodeJSONBody(w, r, &input) {
+		return
+	}
+	input.Observation = strings.TrimSpace(input.Observation)
+	if input.Observation == "" {
+		utils.SendErrorResponse(w, "Observação não pode ser vazia", http.StatusUnprocessableEntity)
+		return
+	}
+
+	canAdd, err := c.ContractModel.CanAddObservation(contract.ID)
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao verificar contrato", http.StatusInternalServerError)
+		return
+	}
+	if !canAdd {
+		utils.SendErrorResponse(w, "Não é possível adicionar observações após assinatura", http.StatusConflict)
+		return
+	}
+
+	if err := c.ContractModel.CreateObservation(contract.ID, userID, input.Observation); err != nil {
+		utils.SendErrorResponse(w, "Erro ao salvar observação", http.StatusInternalServerError)
+		return
+	}
+	c.ContractModel.AddHistory(contract.ID, userID, "OBSERVACAO_ADICIONADA", "Cliente adicionou observação")
+
+	utils.SendCreatedResponse(w, "Observação registrada com sucesso", nil)
+}
+
+// DeleteObservation remove uma observação ainda não resolvida do próprio cliente
+func (c *APIController) DeleteObservation(w http.ResponseWriter, r *http.Request) {
+	userID, isAdmin := apiCurrentUser(r)
+	if isAdmin {
+		utils.SendErrorResponse(w, "Observações são removidas pelo cliente", http.StatusForbidden)
+		return
+	}
+
+	if _, ok := c.loadContract(w, r); !ok {
+		return
+	}
+
+	obsID, err := strconv.Atoi(mux.Vars(r)["obs_id"])
+	if err != nil {
+		utils.SendErrorResponse(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	err = c.ContractModel.DeleteObservation(obsID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendErrorResponse(w, "Observação não encontrada ou já resolvida", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao remover observação", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "Observação removida com sucesso", nil)
+}
+
+// SignContract assina o contrato pela parte do usuário autenticado: cliente
+// ou empresa, conforme o perfil. O corpo traz a assinatura como data URL.
+func (c *APIController) SignContract(w http.ResponseWriter, r *http.Request) {
+	contract, ok := c.loadContract(w, r)
+	if !ok {
+		return
+	}
+	userID, isAdmin := apiCurrentUser(r)
+	if isAdmin && !apiHasPermission(w, r, models.PermContractsSignCompany) {
+		return
+	}
+
+	var input struct {
+		Signature string `json:"signature"`
+	}
+	if !decodeJSONBody(w, r, &input) {
+		return
+	}
+	if input.Signature == "" {
+		utils.SendErrorResponse(w, "Assinatura obrigatória", http.StatusUnprocessableEntity)
+		return
+	}
+	if !validateBase64(extractBase64(input.Signature)) {
+		utils.SendErrorResponse(w, "Assinatura inválida", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if contract.Status == nil || contract.Status.Code != "AGUARDANDO_ASSINATURAS" {
+		utils.SendErrorResponse(w, "Contrato não está aguardando assinaturas", http.StatusConflict)
+		return
+	}
+	if (isAdmin && contract.CompanySigned) || (!isAdmin && contract.ClientSigned) {
+		utils.SendErrorResponse(w, "Contrato já assinado por esta parte", http.StatusConflict)
+		return
+	}
+
+	var err error
+	if isAdmin {
+		err = c.ContractModel.SignByCompany(contract.ID, input.Signature, auditActor(r))
+	} else {
+		err = c.ContractModel.SignByClient(contract.ID, input.Signature)
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao assinar", http.StatusInternalServerError)
+		return
+	}
+
+	if isAdmin {
+		c.ContractModel.AddHistory(contract.ID, userID, "ASSINADO_EMPRESA", "Assinado pela empresa")
+	} else {
+		c.ContractModel.AddHistory(contract.ID, userID, "ASSINADO_CLIENTE", "Assinado pelo cliente")
+	}
+
+	signed, err := c.ContractModel.GetByID(contract.ID)
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar contrato", http.StatusInternalServerError)
+		return
+	}
+	utils.SendSuccessResponse(w, "Contrato assinado com sucesso", toAPIContract(*signed))
+}
+
+// loadContract busca o contrato do path; para o cliente, contratos de
+// solicitações de outros usuários respondem 404.
+func (c *APIController) loadContract(w http.ResponseWriter, r *http.Request) (*models.Contract, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		utils.SendErrorResponse(w, "ID inválido", http.StatusBadRequest)
+		return nil, false
+	}
+
+	contract, err := c.ContractModel.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.SendErrorResponse(w, "Contrato não encontrado", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		utils.SendErrorResponse(w, "Erro ao carregar contrato", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	userID, isAdmin := apiCurrentUser(r)
+	if !isAdmin {
+		service, err := c.ServiceModel.GetByIDAndUser(contract.ServiceRequestID, userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.SendErrorResponse(w, "Contrato não encontrado", http.StatusNotFound)
+			return nil, false
+		}
+		if err != nil || service == nil {
+			utils.SendErrorResponse(w, "Erro ao carregar contrato", http.StatusInternalServerError)
+			return nil, false
+		}
+	}
+	return contract, true
+}
+
+// ============================================
+// HELPERS
+// ============================================
+
+// apiCurrentUser retorna o usuário autenticado (sessão ou token) e se ele é gestor
+func apiCurrentUser(r *http.Request) (int, bool) {
+	user := currentUser(r)
+	return user.UserID, user.IsAdmin()
+}
+
+// apiHasPermission verifica a permissão do papel nas ações que dependem do
+// perfil dentro do mesmo endpoint; responde 403 se faltar
+func apiHasPermission(w http.ResponseWriter, r *http.Request, permission string) bool {
+	if !currentUser(r).HasPermission(permission) {
+		utils.SendErrorResponse(w, "Acesso negado", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// apiPagination lê limit/offset da query string; responde 400 se inválidos
+func apiPagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	limit, offset := apiDefaultLimit, 0
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > apiMaxLimit {
+			utils.SendErrorResponse(w, "limit deve estar entre 1 e "+strconv.Itoa(apiMaxLimit), http.StatusBadRequest)
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			utils.SendErrorResponse(w, "offset inválido", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// decodeJSONBody decodifica o corpo JSON da requisição; responde 400 se inválido
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, apiMaxBodyBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		utils.SendErrorResponse(w, "JSON inválido: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"martins-pocos/models"
+)
+
+// Validades oferecidas para tokens pessoais, em dias (0 = sem expiração)
+var personalTokenExpirations = []int{30, 90, 365, 0}
+
+type APITokenController struct {
+	TokenModel *models.APITokenModel
+}
+
+func NewAPITokenController(tokenModel *models.APITokenModel) *APITokenController {
+	return &APITokenController{TokenModel: tokenModel}
+}
+
+// ListTokens - Tokens pessoais e sessões de aplicativo da conta
+func (c *APITokenController) ListTokens(w http.ResponseWriter, r *http.Request) {
+	c.renderTokens(w, r, "", "")
+}
+
+// CreateToken - Cria um token pessoal; o valor é mostrado uma única vez
+func (c *APITokenController) CreateToken(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).UserID
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		c.renderTokens(w, r, "", "Informe um nome para identificar o token.")
+		return
+	}
+
+	scopes, err := models.ParseScopes(r.Form["scopes"])
+	if err != nil {
+		c.renderTokens(w, r, "", "Selecione ao menos uma permissão.")
+		return
+	}
+
+	var expiresAt sql.NullTime
+	days, _ := strconv.Atoi(r.FormValue("expires_in_days"))
+	if days > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, days), Valid: true}
+	}
+
+	plain, _, err := c.TokenModel.CreatePersonal(userID, name, scopes, expiresAt)
+	if err != nil {
+		http.Error(w, "Erro ao criar token", http.StatusInternalServerError)
+		return
+	}
+
+	c.renderTokens(w, r, plain, "")
+}
+
+// RevokeToken - Revoga um token ou sessão de aplicativo da conta
+func (c *APITokenController) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tokenID, _ := strconv.Atoi(vars["id"])
+
+	userID := currentUser(r).UserID
+
+	err := c.TokenModel.Revoke(tokenID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Redirect(w, r, "/conta/tokens?error=not_found", http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao revogar token", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/tokens?success=revoked", http.StatusFound)
+}
+
+func (c *APITokenController) renderTokens(w http.ResponseWriter, r *http.Request, newToken, errorMsg string) {
+	user := currentUser(r)
+
+	tokens, err := c.TokenModel.GetActiveByUserID(user.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tokens", http.StatusInternalServerError)
+		return
+	}
+
+	if errorMsg == "" {
+		errorMsg = c.getErrorMsg(r)
+	}
+
+	data := struct {
+		Tokens            []models.APIToken
+		Scopes            []models.APIScope
+		Expirations       []int
+		NewToken          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Tokens:            tokens,
+		Scopes:            models.APIScopes,
+		Expirations:       personalTokenExpirations,
+		NewToken:          newToken,
+		UserName:          user.UserName,
+		PageTitle:         "Tokens de API",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          errorMsg,
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/conta_tokens.html",
+	}, data)
+}
+
+func (c *APITokenController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "revoked":
+		return "Token revogado. Aplicativos que o utilizavam perderam o acesso."
+	default:
+		return ""
+	}
+}
+
+func (c *APITokenController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "not_found":
+		return "Token não encontrado ou já revogado."
+	default:
+		return ""
+	}
+}
+
+func (c *APITokenController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/models"
+)
+
+const (
+	// Linhas por página no visualizador
+	auditPageSize = 50
+	// Limite de linhas da exportação CSV
+	auditExportLimit = 20000
+)
+
+type AuditController struct {
+	AuditModel *models.AuditModel
+}
+
+func NewAuditController(auditModel *models.AuditModel) *AuditController {
+	return &AuditController{AuditModel: auditModel}
+}
+
+// parseAuditFilter lê os filtros da query string. Valores inválidos são
+// ignorados e voltam vazios para o formulário.
+func parseAuditFilter(r *http.Request) (models.AuditFilter, url.Values) {
+	q := r.URL.Query()
+	var filter models.AuditFilter
+	values := url.Values{}
+
+	if id, err := strconv.Atoi(q.Get("usuario")); err == nil && id > 0 {
+		filter.ActorID = id
+		values.Set("usuario", strconv.Itoa(id))
+	}
+	if action := q.Get("acao"); models.ValidAuditAction(action) {
+		filter.Action = action
+		values.Set("acao", action)
+	}
+	if entityType := q.Get("tipo"); models.ValidAuditEntityType(entityType) {
+		filter.EntityType = entityType
+		values.Set("tipo", entityType)
+	}
+	if id, err := strconv.Atoi(q.Get("registro")); err == nil && id > 0 {
+		filter.EntityID = id
+		values.Set("registro", strconv.Itoa(id))
+	}
+	if from, err := time.ParseInLocation("2006-01-02", q.Get("de"), time.Local); err == nil {
+		filter.From = from
+		values.Set("de", q.Get("de"))
+	}
+	if to, err := time.ParseInLocation("2006-01-02", q.Get("ate"), time.Local); err == nil {
+		filter.To = to
+		values.Set("ate", q.Get("ate"))
+	}
+
+	return filter, values
+}
+
+// List - Registro de auditoria com filtros e paginação
+func (c *AuditController) List(w http.ResponseWriter, r *http.Request) {
+	filter, values := parseAuditFilter(r)
+
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+
+	entries, total, err := c.AuditModel.Search(filter, auditPageSize, (page-1)*auditPageSize)
+	if err != nil {
+		http.Error(w, "Erro ao buscar registro de auditoria", http.StatusInternalServerError)
+		return
+	}
+
+	actors, err := c.AuditModel.GetActors()
+	if err != nil {
+		http.Error(w, "Erro ao buscar usuários", http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := (total + auditPageSize - 1) / auditPageSize
+	user := currentUser(r)
+
+	data := struct {
+		Entries           []models.AuditEntry
+		Actors            []models.AuditActorOption
+		Actions           []models.AuditOption
+		EntityTypes       []models.AuditOption
+		Filter            models.AuditFilter
+		Values            url.Values
+		FilterQuery       template.URL
+		Total             int
+		CurrentPage       int
+		TotalPages        int
+		HasPrevPage       bool
+		HasNextPage       bool
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Entries:           entries,
+		Actors:            actors,
+		Actions:           models.AuditActions,
+		EntityTypes:       models.AuditEntityTypes,
+		Filter:            filter,
+		Values:            values,
+		FilterQuery:       template.URL(values.Encode()),
+		Total:             total,
+		CurrentPage:       page,
+		TotalPages:        totalPages,
+		HasPrevPage:       page > 1,
+		HasNextPage:       page < totalPages,
+		UserName:          user.UserName,
+		PageTitle:         "Auditoria",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        "",
+		ErrorMsg:          "",
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_auditoria.html",
+	}, data)
+}
+
+// Export - Exporta o registro filtrado em CSV (separado por ponto e vírgula, para o Excel)
+func (c *AuditController) Export(w http.ResponseWriter, r *http.Request) {
+	filter, _ := parseAuditFilter(r)
+
+	entries, _, err := c.AuditModel.Search(filter, auditExportLimit, 0)
+	if err != nil {
+		http.Error(w, "Erro ao buscar registro de auditoria", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="auditoria-%s.csv"`, time.Now().Format("2006-01-02")))
+
+	// BOM para o Excel reconhecer UTF-8
+	w.Write([]byte("\xEF\xBB\xBF"))
+
+	out := csv.NewWriter(w)
+	out.Comma = ';'
+	out.Write([]string{"Data", "Usuário", "IP", "Ação", "Tipo", "Registro", "Alterações"})
+	for _, e := range entries {
+		actor := "Sistema"
+		if e.ActorID.Valid {
+			actor = strings.TrimSpace(e.ActorName)
+			if actor == "" {
+				actor = "#" + strconv.FormatInt(e.ActorID.Int64, 10)
+			}
+		}
+		entityID := ""
+		if e.EntityID.Valid {
+			entityID = strconv.FormatInt(e.EntityID.Int64, 10)
+		}
+		out.Write([]string{
+			e.CreatedAt.Format("02/01/2006 15:04:05"),
+			actor,
+			e.IP.String,
+			e.ActionLabel(),
+			e.EntityLabel(),
+			entityID,
+			e.ChangesSummary(),
+		})
+	}
+	out.Flush()
+}
+
+func (c *AuditController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
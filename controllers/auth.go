@@ -1,29 +1,91 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
 
 	"martins-pocos/config"
 	"martins-pocos/models"
+	"martins-pocos/services"
+	"martins-pocos/utils"
 )
 
+// Tamanho mínimo de senha (o mesmo do formulário de cadastro)
+const minPasswordLength = 6
+
 type AuthController struct {
-	UserModel *models.UserModel
+	UserModel         *models.UserModel
+	UserTokenModel    *models.UserTokenModel
+	PhoneCodeModel    *models.PhoneCodeModel
+	LoginAttemptModel *models.LoginAttemptModel
+	TwoFactorModel    *models.TwoFactorModel
+	Mailer            services.Mailer
+	WhatsAppService   *services.WhatsAppService
 }
 
-func NewAuthController(userModel *models.UserModel) *AuthController {
-	return &AuthController{UserModel: userModel}
+func NewAuthController(userModel *models.UserModel, userTokenModel *models.UserTokenModel, phoneCodeModel *models.PhoneCodeModel, loginAttemptModel *models.LoginAttemptModel, twoFactorModel *models.TwoFactorModel, mailer services.Mailer, whatsappService *services.WhatsAppService) *AuthController {
+	return &AuthController{
+		UserModel:         userModel,
+		UserTokenModel:    userTokenModel,
+		PhoneCodeModel:    phoneCodeModel,
+		LoginAttemptModel: loginAttemptModel,
+		TwoFactorModel:    twoFactorModel,
+		Mailer:            mailer,
+		WhatsAppService:   whatsappService,
+	}
+}
+
+// authPageData é usado pelas telas públicas de autenticação
+type authPageData struct {
+	SuccessMsg string
+	ErrorMsg   string
+	Email      string
+	Token      string
+	ShowResend bool
+
+	// Telas de código por WhatsApp
+	Phone       string
+	PhoneLabel  string
+	Title       string
+	CodeAction  string
+	ResendLogin bool
 }
 
 func (c *AuthController) LoginPage(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFiles("templates/login.html"))
-	tmpl.Execute(w, nil)
+	data := authPageData{}
+
+	q := r.URL.Query()
+	switch {
+	case q.Get("phone_verified") == "1":
+		data.SuccessMsg = "Telefone confirmado! Você já pode entrar pelo WhatsApp. Enviamos também um link de confirmação para o seu e-mail."
+	case q.Get("success") == "1":
+		data.SuccessMsg = "Conta criada! Enviamos um link de confirmação para o seu e-mail."
+	case q.Get("verified") == "1":
+		data.SuccessMsg = "E-mail confirmado. Você já pode entrar."
+	case q.Get("reset") == "1":
+		data.SuccessMsg = "Senha redefinida. Entre com a nova senha."
+	case q.Get("sent") == "1":
+		data.SuccessMsg = "Se o e-mail estiver cadastrado e pendente de confirmação, enviamos um novo link."
+	}
+	switch q.Get("error") {
+	case "invalid_link":
+		data.ErrorMsg = models.ErrInvalidUserToken.Error() + ". Solicite um novo abaixo."
+		data.ShowResend = true
+	case "2fa_expired":
+		data.ErrorMsg = "O tempo para informar o código de verificação acabou. Entre novamente."
+	}
+
+	c.renderPage(w, r, "templates/login.html", data)
 }
 
 func (c *AuthController) RegisterPage(w http.ResponseWriter, r *http.Request) {
-	tmpl := template.Must(template.ParseFiles("templates/register.html"))
-	tmpl.Execute(w, nil)
+	c.renderPage(w, r, "templates/register.html", authPageData{})
 }
 
 func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
@@ -32,22 +94,65 @@ func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	email := r.FormValue("email")
+	email := strings.TrimSpace(r.FormValue("email"))
 	password := r.FormValue("password")
 
-	user, err := c.UserModel.GetByEmail(email)
+	if msg, ok := checkLoginThrottle(c.LoginAttemptModel, w, r, email, models.LoginMethodPassword); !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		c.renderPage(w, r, "templates/login.html", authPageData{ErrorMsg: msg, Email: email})
+		return
+	}
+
+	// Mesma mensagem para e-mail inexistente e senha errada
+	user, err := c.UserModel.Authenticate(email, password)
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		reason := models.LoginWrongPassword
+		if user == nil {
+			reason = models.LoginUnknownUser
+		}
+		recordLogin(c.LoginAttemptModel, r, email, models.LoginMethodPassword, user, reason)
+		w.WriteHeader(http.StatusUnauthorized)
+		c.renderPage(w, r, "templates/login.html", authPageData{
+			ErrorMsg: "E-mail ou senha inválidos.",
+			Email:    email,
+		})
+		return
+	}
 	if err != nil {
-		http.Error(w, "Usuário não encontrado", http.StatusUnauthorized)
+		http.Error(w, "Erro ao autenticar", http.StatusInternalServerError)
 		return
 	}
 
-	if !c.UserModel.ValidatePassword(password, user.Password) {
-		http.Error(w, "Senha incorreta", http.StatusUnauthorized)
+	// Sem e-mail confirmado não há sessão
+	if !user.IsEmailVerified() {
+		recordLogin(c.LoginAttemptModel, r, email, models.LoginMethodPassword, user, models.LoginEmailNotVerified)
+		w.WriteHeader(http.StatusForbidden)
+		c.renderPage(w, r, "templates/login.html", authPageData{
+			ErrorMsg:   "Confirme seu e-mail antes de entrar. Verifique sua caixa de entrada ou peça um novo link.",
+			Email:      user.Email,
+			ShowResend: true,
+		})
 		return
 	}
 
-	// Create session
+	// Com a verificação em duas etapas, a senha só libera a tela do código
+	if user.HasTwoFactor() {
+		c.startTwoFactor(w, r, user)
+		return
+	}
+
+	recordLogin(c.LoginAttemptModel, r, email, models.LoginMethodPassword, user, models.LoginOK)
+	c.startSession(w, r, user)
+}
+
+// startSession grava o usuário na sessão e redireciona ao painel do tipo
+func (c *AuthController) startSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	// Create session (com identificador novo, contra fixação de sessão)
 	session, _ := config.GetSessionStore().Get(r, "session")
+	if err := config.GetSessionStore().RenewID(session); err != nil {
+		http.Error(w, "Erro ao iniciar sessão", http.StatusInternalServerError)
+		return
+	}
 	session.Values["user_id"] = user.ID
 	session.Values["user_type"] = user.UserType
 	session.Values["user_name"] = user.Name
@@ -81,6 +186,17 @@ func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Falha no envio não desfaz o cadastro: o link pode ser reenviado pelo login
+	if err := c.sendVerificationEmail(user); err != nil {
+		log.Printf("❌ Erro ao enviar verificação de e-mail para usuário %d: %v", user.ID, err)
+	}
+
+	// Com telefone informado, oferece a confirmação por código no WhatsApp
+	if phone, ok := c.sendPhoneVerification(r, user); ok {
+		http.Redirect(w, r, "/verificar-telefone?phone="+url.QueryEscape(phone), http.StatusFound)
+		return
+	}
+
 	http.Redirect(w, r, "/login?success=1", http.StatusFound)
 }
 
@@ -92,4 +208,183 @@ func (c *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
 	session.Options.MaxAge = -1
 	session.Save(r, w)
 	http.Redirect(w, r, "/", http.StatusFound)
-}
\ No newline at end of file
+}
+
+// ============================================
+// VERIFICAÇÃO DE E-MAIL
+// ============================================
+
+// VerifyEmail - Confirma o e-mail pelo link enviado no cadastro
+func (c *AuthController) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	userID, err := c.UserTokenModel.Consume(r.URL.Query().Get("token"), models.UserTokenVerifyEmail)
+	if errors.Is(err, models.ErrInvalidUserToken) {
+		http.Redirect(w, r, "/login?error=invalid_link", http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao verificar e-mail", http.StatusInternalServerError)
+		return
+	}
+
+	if err := c.UserModel.MarkEmailVerified(userID); err != nil {
+		http.Error(w, "Erro ao verificar e-mail", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/login?verified=1", http.StatusFound)
+}
+
+// ResendVerification - Reenvia o link de confirmação. A resposta é a mesma
+// exista ou não a conta, para não revelar e-mails cadastrados.
+func (c *AuthController) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.FormValue("email"))
+
+	user, err := c.UserModel.GetByEmail(email)
+	if err == nil && !user.IsEmailVerified() {
+		if err := c.sendVerificationEmail(user); err != nil {
+			log.Printf("❌ Erro ao reenviar verificação de e-mail para usuário %d: %v", user.ID, err)
+		}
+	}
+
+	http.Redirect(w, r, "/login?sent=1", http.StatusFound)
+}
+
+func (c *AuthController) sendVerificationEmail(user *models.User) error {
+	token, err := c.UserTokenModel.Create(user.ID, models.UserTokenVerifyEmail, models.VerifyEmailTTL)
+	if err != nil {
+		return err
+	}
+
+	link := config.GetAppBaseURL() + "/verificar-email?token=" + url.QueryEscape(token)
+	return c.Mailer.Send(services.Email{
+		To:      user.Email,
+		Subject: "Confirme seu e-mail - Martins Poços",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Para ativar sua conta na Martins Poços, confirme seu e-mail pelo link abaixo:\n\n%s\n\n"+
+			"O link vale por %d horas. Se você não criou esta conta, ignore esta mensagem.\n",
+			user.Name, link, int(models.VerifyEmailTTL.Hours())),
+	})
+}
+
+// ============================================
+// REDEFINIÇÃO DE SENHA
+// ============================================
+
+// ForgotPasswordPage - Formulário para pedir o link de redefinição
+func (c *AuthController) ForgotPasswordPage(w http.ResponseWriter, r *http.Request) {
+	data := authPageData{}
+	if r.URL.Query().Get("sent") == "1" {
+		data.SuccessMsg = "Se o e-mail estiver cadastrado, você receberá em instantes um link para criar uma nova senha."
+	}
+	c.renderPage(w, r, "templates/esqueci_senha.html", data)
+}
+
+// ForgotPassword - Envia o link de redefinição. A resposta é a mesma exista
+// ou não a conta, para não revelar e-mails cadastrados.
+func (c *AuthController) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.FormValue("email"))
+
+	user, err := c.UserModel.GetByEmail(email)
+	if err == nil {
+		if err := c.sendPasswordResetEmail(user); err != nil {
+			log.Printf("❌ Erro ao enviar redefinição de senha para usuário %d: %v", user.ID, err)
+		}
+	}
+
+	http.Redirect(w, r, "/esqueci-senha?sent=1", http.StatusFound)
+}
+
+// ResetPasswordPage - Formulário de nova senha aberto pelo link do e-mail
+func (c *AuthController) ResetPasswordPage(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	valid, err := c.UserTokenModel.IsValid(token, models.UserTokenPasswordReset)
+	if err != nil {
+		http.Error(w, "Erro ao validar link", http.StatusInternalServerError)
+		return
+	}
+
+	data := authPageData{Token: token}
+	if !valid {
+		data.Token = ""
+		data.ErrorMsg = models.ErrInvalidUserToken.Error() + "."
+	}
+	c.renderPage(w, r, "templates/redefinir_senha.html", data)
+}
+
+// ResetPassword - Grava a nova senha e consome o link
+func (c *AuthController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("token")
+	password := r.FormValue("password")
+
+	if len(password) < minPasswordLength {
+		c.renderPage(w, r, "templates/redefinir_senha.html", authPageData{
+			Token:    token,
+			ErrorMsg: fmt.Sprintf("A senha deve ter ao menos %d caracteres.", minPasswordLength),
+		})
+		return
+	}
+	if password != r.FormValue("password_confirm") {
+		c.renderPage(w, r, "templates/redefinir_senha.html", authPageData{
+			Token:    token,
+			ErrorMsg: "As senhas não conferem.",
+		})
+		return
+	}
+
+	userID, err := c.UserTokenModel.Consume(token, models.UserTokenPasswordReset)
+	if errors.Is(err, models.ErrInvalidUserToken) {
+		c.renderPage(w, r, "templates/redefinir_senha.html", authPageData{
+			ErrorMsg: models.ErrInvalidUserToken.Error() + ".",
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao redefinir senha", http.StatusInternalServerError)
+		return
+	}
+
+	if err := c.UserModel.UpdatePassword(userID, password); err != nil {
+		http.Error(w, "Erro ao redefinir senha", http.StatusInternalServerError)
+		return
+	}
+	if err := c.UserModel.RecordProfileChange(userID, models.ProfileFieldPassword, "", "redefinida pelo link de e-mail", utils.ClientIP(r)); err != nil {
+		log.Printf("❌ Erro ao registrar redefinição de senha do usuário %d: %v", userID, err)
+	}
+
+	// Quem recebeu o link comprovou ser dono do e-mail
+	if err := c.UserModel.MarkEmailVerified(userID); err != nil {
+		log.Printf("❌ Erro ao marcar e-mail verificado do usuário %d: %v", userID, err)
+	}
+
+	http.Redirect(w, r, "/login?reset=1", http.StatusFound)
+}
+
+func (c *AuthController) sendPasswordResetEmail(user *models.User) error {
+	token, err := c.UserTokenModel.Create(user.ID, models.UserTokenPasswordReset, models.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := config.GetAppBaseURL() + "/redefinir-senha?token=" + url.QueryEscape(token)
+	return c.Mailer.Send(services.Email{
+		To:      user.Email,
+		Subject: "Redefinição de senha - Martins Poços",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Recebemos um pedido para redefinir a senha da sua conta. Para criar uma nova senha, acesse:\n\n%s\n\n"+
+			"O link vale por %d minutos e só pode ser usado uma vez. "+
+			"Se você não fez este pedido, ignore esta mensagem; sua senha continua a mesma.\n",
+			user.Name, link, int(models.PasswordResetTTL.Minutes())),
+	})
+}
+
+func (c *AuthController) renderPage(w http.ResponseWriter, r *http.Request, path string, data authPageData) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(GetTemplateFuncs(r)).ParseFiles(path)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Execute(w, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
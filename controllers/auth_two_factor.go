@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"martins-pocos/config"
+	"martins-pocos/middleware"
+	"martins-pocos/models"
+)
+
+// Prazo para digitar o código depois de acertar a senha
+const twoFactorLoginTTL = 5 * time.Minute
+
+// Chaves da sessão enquanto o login aguarda o código
+const (
+	sessionPendingUserID = "mfa_user_id"
+	sessionPendingSince  = "mfa_started_at"
+)
+
+// ============================================
+// SEGUNDA ETAPA DO LOGIN (TOTP)
+// ============================================
+
+// startTwoFactor guarda o usuário como pendente na sessão e pede o código.
+// A sessão só é aberta por TwoFactorLogin.
+func (c *AuthController) startTwoFactor(w http.ResponseWriter, r *http.Request, user *models.User) {
+	session, _ := config.GetSessionStore().Get(r, "session")
+	session.Values[sessionPendingUserID] = user.ID
+	session.Values[sessionPendingSince] = time.Now().Unix()
+	session.Save(r, w)
+
+	http.Redirect(w, r, "/login/2fa", http.StatusFound)
+}
+
+// pendingTwoFactorUser retorna o usuário que acertou a senha e ainda não
+// informou o código, ou nil se não houver (ou se o prazo acabou)
+func (c *AuthController) pendingTwoFactorUser(r *http.Request) (*models.User, error) {
+	session, _ := config.GetSessionStore().Get(r, "session")
+	userID, ok := session.Values[sessionPendingUserID].(int)
+	since, _ := session.Values[sessionPendingSince].(int64)
+	if !ok || time.Since(time.Unix(since, 0)) > twoFactorLoginTTL {
+		return nil, nil
+	}
+
+	user, err := c.UserModel.GetByID(userID)
+	if err != nil || !user.HasTwoFactor() {
+		return nil, err
+	}
+	return user, nil
+}
+
+// TwoFactorLoginPage - Formulário do código do aplicativo autenticador
+func (c *AuthController) TwoFactorLoginPage(w http.ResponseWriter, r *http.Request) {
+	user, err := c.pendingTwoFactorUser(r)
+	if err != nil {
+		http.Error(w, "Erro ao carregar usuário", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Redirect(w, r, "/login?error=2fa_expired", http.StatusFound)
+		return
+	}
+
+	c.renderPage(w, r, "templates/login_2fa.html", authPageData{})
+}
+
+// TwoFactorLogin - Confere o código (ou um código de recuperação) e abre a sessão
+func (c *AuthController) TwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	user, err := c.pendingTwoFactorUser(r)
+	if err != nil {
+		http.Error(w, "Erro ao carregar usuário", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.Redirect(w, r, "/login?error=2fa_expired", http.StatusFound)
+		return
+	}
+
+	identifier := user.Email
+	if msg, ok := checkLoginThrottle(c.LoginAttemptModel, w, r, identifier, models.LoginMethodPassword); !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		c.renderPage(w, r, "templates/login_2fa.html", authPageData{ErrorMsg: msg})
+		return
+	}
+
+	_, err = c.TwoFactorModel.Verify(user.ID, r.FormValue("code"))
+	if errors.Is(err, models.ErrInvalidTwoFactorCode) {
+		recordLogin(c.LoginAttemptModel, r, identifier, models.LoginMethodPassword, user, models.LoginInvalidCode)
+		w.WriteHeader(http.StatusUnauthorized)
+		c.renderPage(w, r, "templates/login_2fa.html", authPageData{ErrorMsg: capitalize(err.Error()) + "."})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+
+	recordLogin(c.LoginAttemptModel, r, identifier, models.LoginMethodPassword, user, models.LoginOK)
+
+	session, _ := config.GetSessionStore().Get(r, "session")
+	delete(session.Values, sessionPendingUserID)
+	delete(session.Values, sessionPendingSince)
+	middleware.MarkTwoFactorVerified(w, r)
+
+	c.startSession(w, r, user)
+}
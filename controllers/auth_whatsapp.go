@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"unicode"
+	"unicode/utf8"
+
+	"martins-pocos/models"
+	"martins-pocos/utils"
+)
+
+// ============================================
+// LOGIN SEM SENHA POR WHATSAPP
+// ============================================
+
+// WhatsAppLoginPage - Formulário do telefone para receber o código
+func (c *AuthController) WhatsAppLoginPage(w http.ResponseWriter, r *http.Request) {
+	c.renderPage(w, r, "templates/login_whatsapp.html", authPageData{})
+}
+
+// WhatsAppSendCode - Envia o código de acesso ao WhatsApp do cliente. A
+// resposta é a mesma para telefones não cadastrados: o código é gerado (e
+// conta nos limites), mas não é enviado e nunca autentica ninguém.
+func (c *AuthController) WhatsAppSendCode(w http.ResponseWriter, r *http.Request) {
+	phone, err := models.NormalizePhone(r.FormValue("phone"))
+	if err != nil {
+		c.renderPage(w, r, "templates/login_whatsapp.html", authPageData{
+			ErrorMsg: "Informe o celular com DDD, ex.: (34) 99999-9999.",
+		})
+		return
+	}
+
+	var userID sql.NullInt64
+	user, err := c.UserModel.GetClientByPhone(phone)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Erro ao buscar usuário", http.StatusInternalServerError)
+		return
+	}
+	if user != nil {
+		userID = sql.NullInt64{Int64: int64(user.ID), Valid: true}
+	}
+
+	code, err := c.PhoneCodeModel.Create(phone, models.PhoneCodeLogin, userID, utils.ClientIP(r))
+	if errors.Is(err, models.ErrPhoneRateLimited) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		c.renderPage(w, r, "templates/login_whatsapp.html", authPageData{
+			Phone:    phone,
+			ErrorMsg: capitalize(err.Error()) + ".",
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao gerar código", http.StatusInternalServerError)
+		return
+	}
+
+	if user != nil {
+		message := fmt.Sprintf("🔐 *Martins Poços*\n\nSeu código de acesso é *%s*.\nEle vale por %d minutos. Não compartilhe este código com ninguém.",
+			code, int(models.PhoneCodeTTL.Minutes()))
+		if err := c.WhatsAppService.SendMessage(phone, message); err != nil {
+			log.Printf("❌ Erro ao enviar código de acesso para usuário %d: %v", user.ID, err)
+		}
+	}
+
+	http.Redirect(w, r, "/login/whatsapp/codigo?phone="+url.QueryEscape(phone), http.StatusFound)
+}
+
+// WhatsAppCodePage - Formulário do código recebido
+func (c *AuthController) WhatsAppCodePage(w http.ResponseWriter, r *http.Request) {
+	phone, err := models.NormalizePhone(r.URL.Query().Get("phone"))
+	if err != nil {
+		http.Redirect(w, r, "/login/whatsapp", http.StatusFound)
+		return
+	}
+	c.renderPage(w, r, "templates/codigo_whatsapp.html", whatsAppLoginData(phone, ""))
+}
+
+// WhatsAppLogin - Confere o código e abre a sessão do cliente
+func (c *AuthController) WhatsAppLogin(w http.ResponseWriter, r *http.Request) {
+	phone, err := models.NormalizePhone(r.FormValue("phone"))
+	if err != nil {
+		http.Redirect(w, r, "/login/whatsapp", http.StatusFound)
+		return
+	}
+
+	if msg, ok := checkLoginThrottle(c.LoginAttemptModel, w, r, phone, models.LoginMethodWhatsApp); !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		c.renderPage(w, r, "templates/codigo_whatsapp.html", whatsAppLoginData(phone, msg))
+		return
+	}
+
+	userID, err := c.PhoneCodeModel.Verify(phone, models.PhoneCodeLogin, r.FormValue("code"))
+	if err == nil && !userID.Valid {
+		err = models.ErrInvalidPhoneCode
+	}
+	if errors.Is(err, models.ErrInvalidPhoneCode) {
+		recordLogin(c.LoginAttemptModel, r, phone, models.LoginMethodWhatsApp, nil, models.LoginInvalidCode)
+		w.WriteHeader(http.StatusUnauthorized)
+		c.renderPage(w, r, "templates/codigo_whatsapp.html", whatsAppLoginData(phone, capitalize(err.Error())+"."))
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := c.UserModel.GetByID(int(userID.Int64))
+	if err != nil {
+		http.Error(w, "Erro ao carregar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	// O código comprovou a posse do telefone; o acesso não depende do e-mail
+	if err := c.UserModel.MarkPhoneVerified(user.ID); err != nil {
+		log.Printf("❌ Erro ao marcar telefone verificado do usuário %d: %v", user.ID, err)
+	}
+
+	recordLogin(c.LoginAttemptModel, r, phone, models.LoginMethodWhatsApp, user, models.LoginOK)
+
+	c.startSession(w, r, user)
+}
+
+func whatsAppLoginData(phone, errorMsg string) authPageData {
+	return authPageData{
+		Phone:       phone,
+		PhoneLabel:  formatPhoneLabel(phone),
+		Title:       "Entrar pelo WhatsApp",
+		CodeAction:  "/login/whatsapp/codigo",
+		ResendLogin: true,
+		ErrorMsg:    errorMsg,
+	}
+}
+
+// ============================================
+// VERIFICAÇÃO DO TELEFONE NO CADASTRO
+// ============================================
+
+// sendPhoneVerification envia o código de confirmação ao telefone do novo
+// cadastro. Retorna o telefone normalizado quando o código foi enviado.
+func (c *AuthController) sendPhoneVerification(r *http.Request, user *models.User) (string, bool) {
+	phone, err := models.NormalizePhone(user.Phone)
+	if err != nil {
+		return "", false
+	}
+
+	userID := sql.NullInt64{Int64: int64(user.ID), Valid: true}
+	code, err := c.PhoneCodeModel.Create(phone, models.PhoneCodeVerify, userID, utils.ClientIP(r))
+	if err != nil {
+		log.Printf("⚠️ Código de verificação do telefone não gerado para usuário %d: %v", user.ID, err)
+		return "", false
+	}
+
+	message := fmt.Sprintf("📱 *Martins Poços*\n\nSeu código para confirmar este telefone é *%s*.\nEle vale por %d minutos.",
+		code, int(models.PhoneCodeTTL.Minutes()))
+	if err := c.WhatsAppService.SendMessage(phone, message); err != nil {
+		log.Printf("❌ Erro ao enviar código de verificação para usuário %d: %v", user.ID, err)
+		return "", false
+	}
+
+	return phone, true
+}
+
+// VerifyPhonePage - Formulário do código de confirmação do telefone
+func (c *AuthController) VerifyPhonePage(w http.ResponseWriter, r *http.Request) {
+	phone, err := models.NormalizePhone(r.URL.Query().Get("phone"))
+	if err != nil {
+		http.Redirect(w, r, "/login?success=1", http.StatusFound)
+		return
+	}
+	c.renderPage(w, r, "templates/codigo_whatsapp.html", verifyPhoneData(phone, ""))
+}
+
+// VerifyPhone - Confere o código e marca o telefone como confirmado
+func (c *AuthController) VerifyPhone(w http.ResponseWriter, r *http.Request) {
+	phone, err := models.NormalizePhone(r.FormValue("phone"))
+	if err != nil {
+		http.Redirect(w, r, "/login?success=1", http.StatusFound)
+		return
+	}
+
+	userID, err := c.PhoneCodeModel.Verify(phone, models.PhoneCodeVerify, r.FormValue("code"))
+	if err == nil && !userID.Valid {
+		err = models.ErrInvalidPhoneCode
+	}
+	if errors.Is(err, models.ErrInvalidPhoneCode) {
+		w.WriteHeader(http.StatusUnauthorized)
+		c.renderPage(w, r, "templates/codigo_whatsapp.html", verifyPhoneData(phone, capitalize(err.Error())+"."))
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao verificar código", http.StatusInternalServerError)
+		return
+	}
+
+	if err := c.UserModel.MarkPhoneVerified(int(userID.Int64)); err != nil {
+		http.Error(w, "Erro ao confirmar telefone", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/login?phone_verified=1", http.StatusFound)
+}
+
+func verifyPhoneData(phone, errorMsg string) authPageData {
+	return authPageData{
+		Phone:      phone,
+		PhoneLabel: formatPhoneLabel(phone),
+		Title:      "Confirmar telefone",
+		CodeAction: "/verificar-telefone",
+		ErrorMsg:   errorMsg,
+	}
+}
+
+// formatPhoneLabel formata DDD + número normalizado para exibição
+func formatPhoneLabel(phone string) string {
+	switch len(phone) {
+	case 11:
+		return fmt.Sprintf("(%s) %s-%s", phone[:2], phone[2:7], phone[7:])
+	case 10:
+		return fmt.Sprintf("(%s) %s-%s", phone[:2], phone[2:6], phone[6:])
+	default:
+		return phone
+	}
+}
+
+// capitalize deixa a primeira letra maiúscula (mensagens de erro do model)
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"martins-pocos/models"
+
+	"github.com/gorilla/mux"
+)
+
+type CatalogController struct {
+	CatalogModel *models.CatalogModel
+}
+
+func NewCatalogController(catalogModel *models.CatalogModel) *CatalogController {
+	return &CatalogController{CatalogModel: catalogModel}
+}
+
+// catalogFromRequest resolve o cadastro da URL; responde 404 se não existir
+func (c *CatalogController) catalogFromRequest(w http.ResponseWriter, r *http.Request) (*models.Catalog, bool) {
+	catalog, ok := models.CatalogBySlug(mux.Vars(r)["catalog"])
+	if !ok {
+		http.Error(w, "Cadastro não encontrado", http.StatusNotFound)
+	}
+	return catalog, ok
+}
+
+func catalogURL(catalog *models.Catalog) string {
+	return "/admin/cadastros/" + catalog.Slug
+}
+
+// Index - Abre o primeiro cadastro
+func (c *CatalogController) Index(w http.ResponseWriter, r *http.Request) {
+	http.Redirect(w, r, catalogURL(models.Catalogs[0]), http.StatusFound)
+}
+
+// List - Itens do cadastro, com reordenação por arrastar e ativação
+func (c *CatalogController) List(w http.ResponseWriter, r *http.Request) {
+	catalog, ok := c.catalogFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := c.CatalogModel.List(catalog)
+	if err != nil {
+		http.Error(w, "Erro ao buscar cadastro", http.StatusInternalServerError)
+		return
+	}
+
+	user := currentUser(r)
+
+	data := struct {
+		Catalog           *models.Catalog
+		Catalogs          []*models.Catalog
+		Items             []models.CatalogItem
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Catalog:           catalog,
+		Catalogs:          models.Catalogs,
+		Items:             items,
+		UserName:          user.UserName,
+		PageTitle:         catalog.Name,
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_cadastros.html",
+	}, data)
+}
+
+// Create - Novo item do cadastro
+func (c *CatalogController) Create(w http.ResponseWriter, r *http.Request) {
+	catalog, ok := c.catalogFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	item := &models.CatalogItem{Active: true}
+	if catalog.HasIcon {
+		item.Icon = models.CatalogIcons[0]
+	}
+	if catalog.HasColor() {
+		catalog.ApplyColor(item, catalog.Colors[0].Value)
+	}
+
+	if r.Method == "GET" {
+		c.showForm(w, r, catalog, item, "")
+		return
+	}
+
+	if err := c.parseForm(r, catalog, item); err != nil {
+		c.showForm(w, r, catalog, item, err.Error())
+		return
+	}
+
+	err := c.CatalogModel.Create(catalog, item, auditActor(r))
+	if err == models.ErrCatalogCodeTaken {
+		c.showForm(w, r, catalog, item, "Já existe um item com este código.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao cadastrar: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, catalogURL(catalog)+"?success=created", http.StatusFound)
+}
+
+// Edit - Editar nome, descrição e aparência do item
+func (c *CatalogController) Edit(w http.ResponseWriter, r *http.Request) {
+	catalog, ok := c.catalogFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	item, err := c.CatalogModel.GetByID(catalog, id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Item não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar item", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == "GET" {
+		c.showForm(w, r, catalog, item, "")
+		return
+	}
+
+	if err := c.parseForm(r, catalog, item); err != nil {
+		c.showForm(w, r, catalog, item, err.Error())
+		return
+	}
+
+	if err := c.CatalogModel.Update(catalog, item, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao atualizar: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, catalogURL(catalog)+"?success=updated", http.StatusFound)
+}
+
+// SetActive - Ativa ou desativa o item
+func (c *CatalogController) SetActive(w http.ResponseWriter, r *http.Request) {
+	catalog, ok := c.catalogFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	active := r.FormValue("active") == "1"
+	err = c.CatalogModel.SetActive(catalog, id, active, auditActor(r))
+	switch {
+	case err == models.ErrCatalogSystemValue:
+		http.Redirect(w, r, catalogURL(catalog)+"?error=system", http.StatusFound)
+		return
+	case err == models.ErrCatalogInUse:
+		http.Redirect(w, r, catalogURL(catalog)+"?error=in_use", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Item não encontrado", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao alterar item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	success := "activated"
+	if !active {
+		success = "deactivated"
+	}
+	http.Redirect(w, r, catalogURL(catalog)+"?success="+success, http.StatusFound)
+}
+
+// Reorder - Grava a ordem definida arrastando as linhas da lista
+func (c *CatalogController) Reorder(w http.ResponseWriter, r *http.Request) {
+	catalog, ok := c.catalogFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	var ids []int
+	for _, value := range r.Form["ids"] {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	err := c.CatalogModel.Reorder(catalog, ids, auditActor(r))
+	if err == models.ErrCatalogInvalidOrder {
+		http.Redirect(w, r, catalogURL(catalog)+"?error=order", http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao reordenar: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, catalogURL(catalog)+"?success=reordered", http.StatusFound)
+}
+
+func (c *CatalogController) showForm(w http.ResponseWriter, r *http.Request, catalog *models.Catalog, item *models.CatalogItem, errorMsg string) {
+	user := currentUser(r)
+
+	pageTitle := "Novo " + catalog.ItemLabel
+	if item.ID > 0 {
+		pageTitle = "Editar " + catalog.ItemLabel
+	}
+
+	data := struct {
+		Catalog           *models.Catalog
+		Item              *models.CatalogItem
+		Icons             []string
+		ColorValue        string
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Catalog:           catalog,
+		Item:              item,
+		Icons:             models.CatalogIcons,
+		ColorValue:        item.ColorValue(catalog),
+		ErrorMsg:          errorMsg,
+		UserName:          user.UserName,
+		PageTitle:         pageTitle,
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_cadastro_form.html",
+	}, data)
+}
+
+// parseForm preenche o item com os dados do formulário. O código só é
+// lido no cadastro; depois não muda.
+func (c *CatalogController) parseForm(r *http.Request, catalog *models.Catalog, item *models.CatalogItem) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Erro ao processar formulário")
+	}
+
+	if item.ID == 0 {
+		item.Code = r.FormValue("code")
+	}
+	item.Name = r.FormValue("name")
+	item.Description = r.FormValue("description")
+
+	if err := item.Normalize(); err != nil {
+		return fmt.Errorf("Código inválido: use letras, números e _ (até 50 caracteres)")
+	}
+	if item.Name == "" || len([]rune(item.Name)) > 100 {
+		return fmt.Errorf("Informe o nome (até 100 caracteres)")
+	}
+
+	if catalog.HasIcon {
+		item.Icon = r.FormValue("icon")
+		validIcon := false
+		for _, icon := range models.CatalogIcons {
+			if icon == item.Icon {
+				validIcon = true
+			}
+		}
+		if !validIcon {
+			return fmt.Errorf("Escolha um ícone da lista")
+		}
+	}
+
+	if catalog.HasColor() && !catalog.ApplyColor(item, r.FormValue("color")) {
+		return fmt.Errorf("Escolha uma cor da lista")
+	}
+
+	if catalog.HasGuarantee {
+		item.RequiresCustomText = r.FormValue("requires_custom_text") == "on"
+		item.WarrantyMonths = sql.NullInt64{}
+		if months := r.FormValue("warranty_months"); months != "" {
+			value, err := strconv.Atoi(months)
+			if err != nil || value < 0 || value > 600 {
+				return fmt.Errorf("Prazo de cobertura inválido")
+			}
+			item.WarrantyMonths = sql.NullInt64{Int64: int64(value), Valid: true}
+		}
+	}
+
+	return nil
+}
+
+func (c *CatalogController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "created":
+		return "Item cadastrado com sucesso!"
+	case "updated":
+		return "Item atualizado com sucesso!"
+	case "activated":
+		return "Item ativado."
+	case "deactivated":
+		return "Item desativado. Ele não aparece mais nos formulários."
+	case "reordered":
+		return "Ordem salva."
+	default:
+		return ""
+	}
+}
+
+func (c *CatalogController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "system":
+		return "Este item é usado pelo sistema e não pode ser desativado."
+	case "in_use":
+		return "Há registros em aberto usando este item. Conclua-os ou altere-os antes de desativar."
+	case "order":
+		return "A lista mudou enquanto você reordenava. Confira a ordem e tente de novo."
+	default:
+		return ""
+	}
+}
+
+func (c *CatalogController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
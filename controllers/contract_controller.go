@@ -12,7 +12,6 @@ import (
 	"strings"
 	"time"
 
-	"martins-pocos/config"
 	"martins-pocos/models"
 
 	"github.com/gorilla/mux"
@@ -21,12 +20,14 @@ import (
 type ContractController struct {
 	ContractModel *models.ContractModel
 	ServiceModel  *models.ServiceModel
+	MaterialModel *models.MaterialModel
 }
 
-func NewContractController(contractModel *models.ContractModel, serviceModel *models.ServiceModel) *ContractController {
+func NewContractController(contractModel *models.ContractModel, serviceModel *models.ServiceModel, materialModel *models.MaterialModel) *ContractController {
 	return &ContractController{
 		ContractModel: contractModel,
 		ServiceModel:  serviceModel,
+		MaterialModel: materialModel,
 	}
 }
 
@@ -59,8 +60,7 @@ func (c *ContractController) ListContracts(w http.ResponseWriter, r *http.Reques
 
 	totalPages := (totalCount + pageSize - 1) / pageSize
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	userName := currentUser(r).UserName
 
 	data := struct {
 		Contracts     []models.Contract
@@ -96,7 +96,7 @@ func (c *ContractController) ListContracts(w http.ResponseWriter, r *http.Reques
 		AdditionalScripts: []string{},
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -137,8 +137,7 @@ func (c *ContractController) CreateContract(w http.ResponseWriter, r *http.Reque
 }
 
 func (c *ContractController) showCreateForm(w http.ResponseWriter, r *http.Request, service *models.ServiceRequest) {
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	userName := currentUser(r).UserName
 
 	// Buscar tipos de garantia disponíveis
 	guaranteeTypes, err := c.ContractModel.GetAllGuaranteeTypes()
@@ -169,7 +168,7 @@ func (c *ContractController) showCreateForm(w http.ResponseWriter, r *http.Reque
 		AdditionalScripts: []string{},
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -194,17 +193,15 @@ func (c *ContractController) processCreateContract(w http.ResponseWriter, r *htt
 		GuaranteeTypeID:    guaranteeTypeID,
 		GuaranteeCustom:    toNullString(r.FormValue("guarantee_custom")),
 		ClientRequirements: toNullString(r.FormValue("client_requirements")),
-		MaterialsUsed:      toNullString(r.FormValue("materials_used")),
 		AdditionalNotes:    toNullString(r.FormValue("additional_notes")),
 	}
 
-	if err := c.ContractModel.Create(contract); err != nil {
+	if err := c.ContractModel.Create(contract, auditActor(r)); err != nil {
 		http.Error(w, "Erro ao criar contrato: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
+	userID := currentUser(r).UserID
 	c.ContractModel.AddHistory(contract.ID, userID, "CRIADO", "Contrato criado")
 
 	http.Redirect(w, r, fmt.Sprintf("/admin/contratos/%d?success=created", contract.ID), http.StatusFound)
@@ -216,8 +213,7 @@ func (c *ContractController) AddClientObservation(w http.ResponseWriter, r *http
 	vars := mux.Vars(r)
 	contractID, _ := strconv.Atoi(vars["id"])
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
+	userID := currentUser(r).UserID
 
 	// Verificar se o contrato pertence ao usuário
 	contract, err := c.ContractModel.GetByID(contractID)
@@ -267,8 +263,7 @@ func (c *ContractController) DeleteClientObservation(w http.ResponseWriter, r *h
 	observationID, _ := strconv.Atoi(vars["obs_id"])
 	contractID, _ := strconv.Atoi(vars["id"])
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
+	userID := currentUser(r).UserID
 
 	if err := c.ContractModel.DeleteObservation(observationID, userID); err != nil {
 		http.Error(w, "Erro ao remover observação", http.StatusInternalServerError)
@@ -284,10 +279,9 @@ func (c *ContractController) ResolveObservation(w http.ResponseWriter, r *http.R
 	observationID, _ := strconv.Atoi(vars["obs_id"])
 	contractID, _ := strconv.Atoi(vars["id"])
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	adminID := session.Values["user_id"].(int)
+	adminID := currentUser(r).UserID
 
-	if err := c.ContractModel.ResolveObservation(observationID, adminID); err != nil {
+	if err := c.ContractModel.ResolveObservation(observationID, auditActor(r)); err != nil {
 		http.Error(w, "Erro ao resolver observação", http.StatusInternalServerError)
 		return
 	}
@@ -320,8 +314,11 @@ func (c *ContractController) ViewContract(w http.ResponseWriter, r *http.Request
 	observations, _ := c.ContractModel.GetObservationsByContract(contractID)
 	pendingCount, _ := c.ContractModel.GetPendingObservationsCount(contractID)
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	// Materiais do contrato e catálogo para inclusão de itens
+	items, _ := c.MaterialModel.GetContractItems(contractID)
+	materials, _ := c.MaterialModel.GetAll("", false)
+
+	userName := currentUser(r).UserName
 
 	signatureData := prepareContractForView(contract)
 	
@@ -341,6 +338,9 @@ func (c *ContractController) ViewContract(w http.ResponseWriter, r *http.Request
 		ClientSignatureData     string
 		Observations            []models.ContractObservation
 		PendingObservationsCount int
+		Items                   []models.ContractItem
+		ItemsSummary            models.ContractItemsSummary
+		Materials               []models.Material
 		UserName                string
 		PageTitle               string
 		CustomCSS               string
@@ -348,6 +348,7 @@ func (c *ContractController) ViewContract(w http.ResponseWriter, r *http.Request
 		CurrentYear             int
 		CanEdit                 bool
 		SuccessMsg              string
+		ErrorMsg                string
 		IsAdmin                 bool
 		AdditionalScripts       []string
 	}{
@@ -356,6 +357,9 @@ func (c *ContractController) ViewContract(w http.ResponseWriter, r *http.Request
 		ClientSignatureData:     clientSignatureData,
 		Observations:            observations,
 		PendingObservationsCount: pendingCount,
+		Items:                   items,
+		ItemsSummary:            models.SummarizeContractItems(items),
+		Materials:               materials,
 		UserName:                userName,
 		PageTitle:               "Contrato " + contract.ContractNumber,
 		CustomCSS:               "/static/css/contracts.css",
@@ -363,11 +367,12 @@ func (c *ContractController) ViewContract(w http.ResponseWriter, r *http.Request
 		CurrentYear:             time.Now().Year(),
 		CanEdit:                 c.ContractModel.CanEdit(contractID),
 		SuccessMsg:              c.getSuccessMsg(r),
+		ErrorMsg:                c.getErrorMsg(r),
 		IsAdmin:                 true,
 		AdditionalScripts:       []string{},
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -381,9 +386,8 @@ func (c *ContractController) ClientViewContract(w http.ResponseWriter, r *http.R
 	vars := mux.Vars(r)
 	contractID, _ := strconv.Atoi(vars["id"])
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
-	userName := session.Values["user_name"].(string)
+	userID := currentUser(r).UserID
+	userName := currentUser(r).UserName
 
 	contract, err := c.ContractModel.GetByID(contractID)
 	if err != nil {
@@ -402,6 +406,7 @@ func (c *ContractController) ClientViewContract(w http.ResponseWriter, r *http.R
 	observations, _ := c.ContractModel.GetObservationsByContract(contractID)
 	pendingCount, _ := c.ContractModel.GetPendingObservationsCount(contractID)
 	canAddObservation, _ := c.ContractModel.CanAddObservation(contractID)
+	items, _ := c.MaterialModel.GetContractItems(contractID)
 
 	signatureData := prepareContractForView(contract)
 	
@@ -422,6 +427,8 @@ func (c *ContractController) ClientViewContract(w http.ResponseWriter, r *http.R
 		Observations            []models.ContractObservation
 		PendingObservationsCount int
 		CanAddObservation       bool
+		Items                   []models.ContractItem
+		ItemsSummary            models.ContractItemsSummary
 		UserName                string
 		PageTitle               string
 		CustomCSS               string
@@ -437,6 +444,8 @@ func (c *ContractController) ClientViewContract(w http.ResponseWriter, r *http.R
 		Observations:            observations,
 		PendingObservationsCount: pendingCount,
 		CanAddObservation:       canAddObservation,
+		Items:                   items,
+		ItemsSummary:            models.SummarizeContractItems(items),
 		UserName:                userName,
 		PageTitle:               "Contrato " + contract.ContractNumber,
 		CustomCSS:               "/static/css/contracts.css",
@@ -447,7 +456,7 @@ func (c *ContractController) ClientViewContract(w http.ResponseWriter, r *http.R
 		AdditionalScripts:       []string{},
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -530,8 +539,7 @@ func (c *ContractController) showEditForm(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	userName := currentUser(r).UserName
 
 	data := struct {
 		Contract       *models.Contract
@@ -555,7 +563,7 @@ func (c *ContractController) showEditForm(w http.ResponseWriter, r *http.Request
 		AdditionalScripts: []string{},
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -581,13 +589,12 @@ func (c *ContractController) processEditContract(w http.ResponseWriter, r *http.
 	contract.MaterialsUsed = toNullString(r.FormValue("materials_used"))
 	contract.AdditionalNotes = toNullString(r.FormValue("additional_notes"))
 
-	if err := c.ContractModel.Update(contract); err != nil {
+	if err := c.ContractModel.Update(contract, auditActor(r)); err != nil {
 		http.Error(w, "Erro ao atualizar: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
+	userID := currentUser(r).UserID
 	c.ContractModel.AddHistory(contract.ID, userID, "EDITADO", "Contrato atualizado")
 
 	http.Redirect(w, r, fmt.Sprintf("/admin/contratos/%d?success=updated", contract.ID), http.StatusFound)
@@ -625,7 +632,7 @@ func (c *ContractController) SendForSignature(w http.ResponseWriter, r *http.Req
 	}
 	
 	log.Println("📤 Enviando para assinatura...")
-	err = c.ContractModel.SendForSignature(contractID)
+	err = c.ContractModel.SendForSignature(contractID, auditActor(r))
 	if err != nil {
 		log.Printf("❌ ERRO no SendForSignature: %v", err)
 		http.Error(w, "Erro ao enviar para assinatura: "+err.Error(), http.StatusInternalServerError)
@@ -633,16 +640,7 @@ func (c *ContractController) SendForSignature(w http.ResponseWriter, r *http.Req
 	}
 	log.Println("✅ Status atualizado com sucesso!")
 
-	session, err := config.GetSessionStore().Get(r, "session")
-	if err != nil {
-		log.Printf("⚠️ Erro ao obter sessão: %v", err)
-	}
-	
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
-		log.Println("⚠️ user_id não encontrado na sessão")
-		userID = 0
-	}
+	userID := currentUser(r).UserID
 	log.Printf("👤 Usuário: %d", userID)
 	
 	err = c.ContractModel.AddHistory(contractID, userID, "ENVIADO_ASSINATURA", "Enviado para assinatura")
@@ -670,13 +668,12 @@ func (c *ContractController) SignContractCompany(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if err := c.ContractModel.SignByCompany(contractID, signature); err != nil {
+	if err := c.ContractModel.SignByCompany(contractID, signature, auditActor(r)); err != nil {
 		http.Error(w, "Erro ao assinar", http.StatusInternalServerError)
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
+	userID := currentUser(r).UserID
 	c.ContractModel.AddHistory(contractID, userID, "ASSINADO_EMPRESA", "Assinado pela empresa")
 
 	http.Redirect(w, r, fmt.Sprintf("/admin/contratos/%d?success=signed", contractID), http.StatusFound)
@@ -693,13 +690,30 @@ func (c *ContractController) getSuccessMsg(r *http.Request) string {
 		return "Contrato enviado para assinatura!"
 	case "signed":
 		return "Contrato assinado com sucesso!"
+	case "item_added":
+		return "Material incluído no contrato!"
+	case "item_deleted":
+		return "Material removido do contrato!"
 	default:
 		return ""
 	}
 }
 
-func (c *ContractController) renderTemplate(w http.ResponseWriter, paths []string, data interface{}) {
-	tmpl := template.New("").Funcs(GetTemplateFuncs())
+func (c *ContractController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "item_material":
+		return "Selecione um material ativo do catálogo."
+	case "item_quantity":
+		return "Informe uma quantidade maior que zero."
+	case "item_signed":
+		return "O contrato já foi assinado e seus materiais não podem ser alterados."
+	default:
+		return ""
+	}
+}
+
+func (c *ContractController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
 	tmpl, err := tmpl.ParseFiles(paths...)
 	if err != nil {
 		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
@@ -728,9 +742,8 @@ func min(a, b int) int {
 
 // ClientContracts - Lista TODOS os contratos do cliente com filtros e paginação
 func (c *ContractController) ClientContracts(w http.ResponseWriter, r *http.Request) {
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
-	userName := session.Values["user_name"].(string)
+	userID := currentUser(r).UserID
+	userName := currentUser(r).UserName
 
 	statusFilter := r.URL.Query().Get("status")
 	pageStr := r.URL.Query().Get("page")
@@ -793,7 +806,7 @@ func (c *ContractController) ClientContracts(w http.ResponseWriter, r *http.Requ
 		AdditionalScripts: []string{},
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -807,12 +820,15 @@ func (c *ContractController) ClientSignContract(w http.ResponseWriter, r *http.R
 	vars := mux.Vars(r)
 	contractID, _ := strconv.Atoi(vars["id"])
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID := session.Values["user_id"].(int)
+	userID := currentUser(r).UserID
 
-	contract, _ := c.ContractModel.GetByID(contractID)
-	service, _ := c.ServiceModel.GetByID(contract.ServiceRequestID)
-	if service.UserID != userID {
+	contract, err := c.ContractModel.GetByID(contractID)
+	if err != nil {
+		http.Error(w, "Contrato não encontrado", http.StatusNotFound)
+		return
+	}
+	service, err := c.ServiceModel.GetByID(contract.ServiceRequestID)
+	if err != nil || service.UserID != userID {
 		http.Error(w, "Acesso negado", http.StatusForbidden)
 		return
 	}
@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	"net/http"
+
+	"martins-pocos/middleware"
+	"martins-pocos/models"
+	"martins-pocos/utils"
+)
+
+// currentUser retorna o usuário autenticado, carregado uma única vez por
+// middleware.RequireAuth. Fora de rotas autenticadas retorna um Principal
+// vazio em vez de nil, para que os handlers não precisem checar.
+func currentUser(r *http.Request) *middleware.Principal {
+	if principal := middleware.CurrentPrincipal(r); principal != nil {
+		return principal
+	}
+	return &middleware.Principal{}
+}
+
+// auditActor identifica o autor da requisição (usuário e IP) para o
+// registro de auditoria
+func auditActor(r *http.Request) models.Actor {
+	return models.Actor{UserID: currentUser(r).UserID, IP: utils.ClientIP(r)}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"database/sql"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"martins-pocos/models"
+	"martins-pocos/utils"
+)
+
+// checkLoginThrottle consulta o bloqueio do identificador e do IP antes de
+// conferir as credenciais. Se a tentativa for recusada, ela é registrada,
+// a resposta recebe o cabeçalho Retry-After e a mensagem para o usuário é
+// retornada; o chamador responde 429 com ela.
+func checkLoginThrottle(m *models.LoginAttemptModel, w http.ResponseWriter, r *http.Request, identifier, method string) (string, bool) {
+	status, err := m.Check(identifier, utils.ClientIP(r))
+	if err != nil {
+		log.Printf("❌ Erro ao consultar bloqueio de login: %v", err)
+		return "", true
+	}
+	if status.Allowed() {
+		return "", true
+	}
+
+	recordLogin(m, r, identifier, method, nil, models.LoginBlocked)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(status.RetryAfter.Seconds()))))
+	return status.Message(), false
+}
+
+// recordLogin grava a tentativa de login. Falhas no registro não impedem o login.
+func recordLogin(m *models.LoginAttemptModel, r *http.Request, identifier, method string, user *models.User, reason string) {
+	attempt := &models.LoginAttempt{
+		Identifier: identifier,
+		IP:         utils.ClientIP(r),
+		Method:     method,
+		Success:    reason == models.LoginOK,
+		Reason:     reason,
+	}
+	if user != nil {
+		attempt.UserID = sql.NullInt64{Int64: int64(user.ID), Valid: true}
+	}
+	if ua := r.UserAgent(); ua != "" {
+		attempt.UserAgent = sql.NullString{String: ua, Valid: true}
+	}
+
+	if err := m.Record(attempt); err != nil {
+		log.Printf("❌ Erro ao registrar tentativa de login: %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/models"
+	"martins-pocos/services"
+
+	"github.com/gorilla/mux"
+)
+
+type MaintenanceController struct {
+	PlanModel *models.MaintenancePlanModel
+	WellModel *models.WellModel
+	Scheduler *services.MaintenanceScheduler
+}
+
+func NewMaintenanceController(planModel *models.MaintenancePlanModel, wellModel *models.WellModel, scheduler *services.MaintenanceScheduler) *MaintenanceController {
+	return &MaintenanceController{
+		PlanModel: planModel,
+		WellModel: wellModel,
+		Scheduler: scheduler,
+	}
+}
+
+// UpcomingMaintenance - Próximas manutenções de todos os clientes (admin)
+func (c *MaintenanceController) UpcomingMaintenance(w http.ResponseWriter, r *http.Request) {
+	days := 60
+	if d, err := strconv.Atoi(r.URL.Query().Get("dias")); err == nil && d > 0 && d <= 365 {
+		days = d
+	}
+
+	plans, err := c.PlanModel.GetUpcoming(time.Now().AddDate(0, 0, days))
+	if err != nil {
+		http.Error(w, "Erro ao buscar manutenções", http.StatusInternalServerError)
+		return
+	}
+
+	overdue := 0
+	for i := range plans {
+		if plans[i].IsOverdue() {
+			overdue++
+		}
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Plans             []models.MaintenancePlan
+		Days              int
+		DayOptions        []int
+		OverdueCount      int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Plans:             plans,
+		Days:              days,
+		DayOptions:        []int{15, 30, 60, 90, 180},
+		OverdueCount:      overdue,
+		UserName:          userName,
+		PageTitle:         "Manutenções Programadas",
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_manutencoes.html",
+	}, data)
+}
+
+// CreatePlan - Criar plano de manutenção para um poço (admin)
+func (c *MaintenanceController) CreatePlan(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	well, err := c.WellModel.GetByID(wellID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	plan := &models.MaintenancePlan{
+		WellID:             well.ID,
+		WellIdentification: well.Identification,
+		OwnerName:          well.OwnerName,
+		Title:              "Manutenção da bomba",
+		IntervalMonths:     12,
+		ReminderDays:       7,
+		Active:             true,
+		NextDueDate:        models.AddMonths(time.Now(), 12),
+	}
+
+	if r.Method == "GET" {
+		c.showPlanForm(w, r, plan, well, "")
+		return
+	}
+
+	if err := c.parsePlanForm(r, plan); err != nil {
+		c.showPlanForm(w, r, plan, well, err.Error())
+		return
+	}
+
+	if err := c.PlanModel.Create(plan, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao criar plano: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?success=plan_created#manutencao", well.ID), http.StatusFound)
+}
+
+// EditPlan - Editar plano de manutenção (admin)
+func (c *MaintenanceController) EditPlan(w http.ResponseWriter, r *http.Request) {
+	planID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := c.PlanModel.GetByID(planID)
+	if err != nil {
+		http.Error(w, "Plano não encontrado", http.StatusNotFound)
+		return
+	}
+
+	well, err := c.WellModel.GetByID(plan.WellID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == "GET" {
+		c.showPlanForm(w, r, plan, well, "")
+		return
+	}
+
+	if err := c.parsePlanForm(r, plan); err != nil {
+		c.showPlanForm(w, r, plan, well, err.Error())
+		return
+	}
+
+	if err := c.PlanModel.Update(plan, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao atualizar plano: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?success=plan_updated#manutencao", well.ID), http.StatusFound)
+}
+
+// RunScheduler - Executa o agendador imediatamente (admin)
+func (c *MaintenanceController) RunScheduler(w http.ResponseWriter, r *http.Request) {
+	result := c.Scheduler.RunOnce(time.Now())
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/manutencoes?success=run&criadas=%d&lembretes=%d&erros=%d",
+		result.RequestsCreated, result.RemindersSent, len(result.Errors)), http.StatusFound)
+}
+
+func (c *MaintenanceController) showPlanForm(w http.ResponseWriter, r *http.Request, plan *models.MaintenancePlan, well *models.Well, errorMsg string) {
+	userName := currentUser(r).UserName
+
+	pageTitle := "Novo Plano de Manutenção"
+	if plan.ID > 0 {
+		pageTitle = "Editar Plano de Manutenção"
+	}
+
+	data := struct {
+		Plan              *models.MaintenancePlan
+		Well              *models.Well
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Plan:              plan,
+		Well:              well,
+		ErrorMsg:          errorMsg,
+		UserName:          userName,
+		PageTitle:         pageTitle,
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_manutencao_form.html",
+	}, data)
+}
+
+// parsePlanForm preenche o plano com os dados do formulário
+func (c *MaintenanceController) parsePlanForm(r *http.Request, plan *models.MaintenancePlan) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Erro ao processar formulário")
+	}
+
+	plan.Title = strings.TrimSpace(r.FormValue("title"))
+	plan.Checklist = toNullString(strings.TrimSpace(r.FormValue("checklist")))
+	plan.Notes = toNullString(strings.TrimSpace(r.FormValue("notes")))
+	plan.Active = r.FormValue("active") == "on"
+
+	if plan.Title == "" {
+		return fmt.Errorf("Informe o nome do plano")
+	}
+
+	interval, err := strconv.Atoi(r.FormValue("interval_months"))
+	if err != nil || interval < 1 || interval > 120 {
+		return fmt.Errorf("Intervalo deve ser entre 1 e 120 meses")
+	}
+	plan.IntervalMonths = interval
+
+	reminderDays, err := strconv.Atoi(r.FormValue("reminder_days"))
+	if err != nil || reminderDays < 0 || reminderDays > 60 {
+		return fmt.Errorf("Antecedência do lembrete deve ser entre 0 e 60 dias")
+	}
+	plan.ReminderDays = reminderDays
+
+	nextDue, err := time.Parse("2006-01-02", r.FormValue("next_due_date"))
+	if err != nil {
+		return fmt.Errorf("Data da próxima manutenção inválida")
+	}
+	plan.NextDueDate = nextDue
+
+	return nil
+}
+
+func (c *MaintenanceController) getSuccessMsg(r *http.Request) string {
+	query := r.URL.Query()
+	switch query.Get("success") {
+	case "run":
+		msg := fmt.Sprintf("Agendador executado: %s solicitação(ões) gerada(s), %s lembrete(s) enviado(s).",
+			query.Get("criadas"), query.Get("lembretes"))
+		if erros := query.Get("erros"); erros != "" && erros != "0" {
+			msg += fmt.Sprintf(" %s erro(s) — verifique os logs.", erros)
+		}
+		return msg
+	default:
+		return ""
+	}
+}
+
+func (c *MaintenanceController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/models"
+
+	"github.com/gorilla/mux"
+)
+
+type MaterialController struct {
+	MaterialModel *models.MaterialModel
+}
+
+func NewMaterialController(materialModel *models.MaterialModel) *MaterialController {
+	return &MaterialController{
+		MaterialModel: materialModel,
+	}
+}
+
+// ListMaterials - Catálogo de materiais com saldo em estoque (admin)
+func (c *MaterialController) ListMaterials(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("q")
+	includeInactive := r.URL.Query().Get("inativos") == "1"
+
+	materials, err := c.MaterialModel.GetAll(search, includeInactive)
+	if err != nil {
+		http.Error(w, "Erro ao buscar materiais", http.StatusInternalServerError)
+		return
+	}
+
+	lowStock := 0
+	stockValue := 0.0
+	for i := range materials {
+		if materials[i].Active && materials[i].IsLowStock() {
+			lowStock++
+		}
+		stockValue += materials[i].StockValue()
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Materials         []models.Material
+		Search            string
+		IncludeInactive   bool
+		LowStockCount     int
+		StockValue        float64
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Materials:         materials,
+		Search:            search,
+		IncludeInactive:   includeInactive,
+		LowStockCount:     lowStock,
+		StockValue:        stockValue,
+		UserName:          userName,
+		PageTitle:         "Materiais e Estoque",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_materiais.html",
+	}, data)
+}
+
+// CreateMaterial - Cadastrar material no catálogo (admin)
+func (c *MaterialController) CreateMaterial(w http.ResponseWriter, r *http.Request) {
+	material := &models.Material{Unit: "un", Active: true}
+
+	if r.Method == "GET" {
+		c.showMaterialForm(w, r, material, "")
+		return
+	}
+
+	if err := c.parseMaterialForm(r, material); err != nil {
+		c.showMaterialForm(w, r, material, err.Error())
+		return
+	}
+
+	if err := c.MaterialModel.Create(material, auditActor(r)); err != nil {
+		if err == models.ErrDuplicateSKU {
+			c.showMaterialForm(w, r, material, "Já existe um material com este código (SKU).")
+			return
+		}
+		http.Error(w, "Erro ao cadastrar material: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/materiais/%d?success=created", material.ID), http.StatusFound)
+}
+
+// EditMaterial - Editar material do catálogo (admin)
+func (c *MaterialController) EditMaterial(w http.ResponseWriter, r *http.Request) {
+	materialID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	material, err := c.MaterialModel.GetByID(materialID)
+	if err != nil {
+		http.Error(w, "Material não encontrado", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == "GET" {
+		c.showMaterialForm(w, r, material, "")
+		return
+	}
+
+	if err := c.parseMaterialForm(r, material); err != nil {
+		c.showMaterialForm(w, r, material, err.Error())
+		return
+	}
+
+	if err := c.MaterialModel.Update(material, auditActor(r)); err != nil {
+		if err == models.ErrDuplicateSKU {
+			c.showMaterialForm(w, r, material, "Já existe um material com este código (SKU).")
+			return
+		}
+		http.Error(w, "Erro ao atualizar material: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/materiais/%d?success=updated", material.ID), http.StatusFound)
+}
+
+// ViewMaterial - Detalhes do material com as movimentações de estoque (admin)
+func (c *MaterialController) ViewMaterial(w http.ResponseWriter, r *http.Request) {
+	materialID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	material, err := c.MaterialModel.GetByID(materialID)
+	if err != nil {
+		http.Error(w, "Material não encontrado", http.StatusNotFound)
+		return
+	}
+
+	movements, err := c.MaterialModel.GetMovements(materialID, 100)
+	if err != nil {
+		http.Error(w, "Erro ao buscar movimentações", http.StatusInternalServerError)
+		return
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Material          *models.Material
+		Movements         []models.StockMovement
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Material:          material,
+		Movements:         movements,
+		UserName:          userName,
+		PageTitle:         material.Name,
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_material.html",
+	}, data)
+}
+
+// AddMovement - Registrar entrada, saída ou ajuste de inventário (admin)
+func (c *MaterialController) AddMovement(w http.ResponseWriter, r *http.Request) {
+	materialID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/materiais/%d", materialID)
+
+	quantity, err := toNullFloat(r.FormValue("quantity"))
+	if err != nil || !quantity.Valid {
+		http.Redirect(w, r, redirectURL+"?error=quantity#movimentar", http.StatusFound)
+		return
+	}
+
+	_, err = c.MaterialModel.AddMovement(materialID, r.FormValue("movement_type"), quantity.Float64,
+		strings.TrimSpace(r.FormValue("notes")), auditActor(r))
+	switch {
+	case err == models.ErrInvalidMovement:
+		http.Redirect(w, r, redirectURL+"?error=quantity#movimentar", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Material não encontrado", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao registrar movimentação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=moved", http.StatusFound)
+}
+
+// AddContractItem - Incluir material no contrato (admin, apenas antes das assinaturas)
+func (c *MaterialController) AddContractItem(w http.ResponseWriter, r *http.Request) {
+	contractID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/contratos/%d", contractID)
+
+	materialID, err := strconv.Atoi(r.FormValue("material_id"))
+	if err != nil {
+		http.Redirect(w, r, redirectURL+"?error=item_material#materiais", http.StatusFound)
+		return
+	}
+	quantity, err := toNullFloat(r.FormValue("quantity"))
+	if err != nil || !quantity.Valid || quantity.Float64 <= 0 {
+		http.Redirect(w, r, redirectURL+"?error=item_quantity#materiais", http.StatusFound)
+		return
+	}
+
+	err = c.MaterialModel.AddContractItem(contractID, materialID, quantity.Float64, auditActor(r))
+	switch {
+	case err == models.ErrContractNotEditable:
+		http.Redirect(w, r, redirectURL+"?error=item_signed#materiais", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Redirect(w, r, redirectURL+"?error=item_material#materiais", http.StatusFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao incluir material: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=item_added#materiais", http.StatusFound)
+}
+
+// DeleteContractItem - Remover material do contrato (admin, apenas antes das assinaturas)
+func (c *MaterialController) DeleteContractItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	contractID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.Atoi(vars["item_id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/contratos/%d", contractID)
+
+	err = c.MaterialModel.DeleteContractItem(contractID, itemID, auditActor(r))
+	switch {
+	case err == models.ErrContractNotEditable:
+		http.Redirect(w, r, redirectURL+"?error=item_signed#materiais", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Item não encontrado", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao remover material: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=item_deleted#materiais", http.StatusFound)
+}
+
+func (c *MaterialController) showMaterialForm(w http.ResponseWriter, r *http.Request, material *models.Material, errorMsg string) {
+	userName := currentUser(r).UserName
+
+	pageTitle := "Novo Material"
+	if material.ID > 0 {
+		pageTitle = "Editar Material"
+	}
+
+	data := struct {
+		Material          *models.Material
+		Units             []string
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Material:          material,
+		Units:             models.MaterialUnits,
+		ErrorMsg:          errorMsg,
+		UserName:          userName,
+		PageTitle:         pageTitle,
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_material_form.html",
+	}, data)
+}
+
+// parseMaterialForm preenche o material com os dados do formulário
+func (c *MaterialController) parseMaterialForm(r *http.Request, material *models.Material) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Erro ao processar formulário")
+	}
+
+	material.SKU = strings.ToUpper(strings.TrimSpace(r.FormValue("sku")))
+	material.Name = strings.TrimSpace(r.FormValue("name"))
+	material.Unit = r.FormValue("unit")
+	material.Active = r.FormValue("active") == "on"
+
+	if material.SKU == "" || material.Name == "" {
+		return fmt.Errorf("Informe o código (SKU) e o nome do material")
+	}
+
+	validUnit := false
+	for _, unit := range models.MaterialUnits {
+		if unit == material.Unit {
+			validUnit = true
+		}
+	}
+	if !validUnit {
+		return fmt.Errorf("Unidade de medida inválida")
+	}
+
+	type amountField struct {
+		field string
+		label string
+		dest  *float64
+	}
+	amounts := []amountField{
+		{"unit_cost", "Custo unitário", &material.UnitCost},
+		{"sale_price", "Preço de venda", &material.SalePrice},
+		{"min_stock", "Estoque mínimo", &material.MinStock},
+	}
+	// O saldo inicial só é informado no cadastro; depois muda apenas por movimentações
+	if material.ID == 0 {
+		amounts = append(amounts, amountField{"stock_quantity", "Saldo inicial", &material.StockQuantity})
+	}
+
+	for _, amount := range amounts {
+		value, err := toNullFloat(r.FormValue(amount.field))
+		if err != nil || (value.Valid && value.Float64 < 0) {
+			return fmt.Errorf("%s inválido", amount.label)
+		}
+		*amount.dest = value.Float64
+	}
+
+	return nil
+}
+
+func (c *MaterialController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "created":
+		return "Material cadastrado com sucesso!"
+	case "updated":
+		return "Material atualizado com sucesso!"
+	case "moved":
+		return "Movimentação registrada e saldo atualizado!"
+	default:
+		return ""
+	}
+}
+
+func (c *MaterialController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "quantity":
+		return "Quantidade inválida para a movimentação."
+	default:
+		return ""
+	}
+}
+
+func (c *MaterialController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
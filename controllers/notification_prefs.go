@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"log"
+
+	"martins-pocos/config"
+	"martins-pocos/models"
+)
+
+// wantsNotification indica se o cliente aceita o aviso (preferências do
+// perfil). Na falha da consulta o aviso é enviado, como antes das preferências.
+func wantsNotification(userID int, kind string) bool {
+	wants, err := models.NewUserModel(config.GetDB()).WantsNotification(userID, kind)
+	if err != nil {
+		log.Printf("⚠️ Erro ao consultar avisos do usuário %d: %v", userID, err)
+		return true
+	}
+	return wants
+}
@@ -0,0 +1,558 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/config"
+	"martins-pocos/constants"
+	"martins-pocos/models"
+	"martins-pocos/services"
+
+	"github.com/gorilla/mux"
+)
+
+type PricingController struct {
+	PricingModel *models.PricingModel
+	ServiceModel *models.ServiceModel
+	WellModel    *models.WellModel
+	QuoteModel   *models.QuoteModel
+	Estimator    services.DistanceEstimator
+}
+
+func NewPricingController(pricingModel *models.PricingModel, serviceModel *models.ServiceModel, wellModel *models.WellModel, quoteModel *models.QuoteModel) *PricingController {
+	return &PricingController{
+		PricingModel: pricingModel,
+		ServiceModel: serviceModel,
+		WellModel:    wellModel,
+		QuoteModel:   quoteModel,
+		Estimator:    services.NewDistanceEstimator(),
+	}
+}
+
+// ==================== TABELAS DE PREÇO ====================
+
+// ListPriceTables - Tabelas de preço por tipo de serviço e distâncias por cidade (admin)
+func (c *PricingController) ListPriceTables(w http.ResponseWriter, r *http.Request) {
+	tables, err := c.PricingModel.GetAllTables()
+	if err != nil {
+		http.Error(w, "Erro ao buscar tabelas de preço", http.StatusInternalServerError)
+		return
+	}
+	distances, err := c.PricingModel.GetAllCityDistances()
+	if err != nil {
+		http.Error(w, "Erro ao buscar distâncias", http.StatusInternalServerError)
+		return
+	}
+	serviceTypes, _ := c.ServiceModel.GetAllServiceTypes()
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Tables            []models.PriceTable
+		Distances         []models.CityDistance
+		ServiceTypes      []models.ServiceType
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Tables:            tables,
+		Distances:         distances,
+		ServiceTypes:      serviceTypes,
+		UserName:          userName,
+		PageTitle:         "Tabelas de Preço",
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_precos.html",
+	}, data)
+}
+
+// CreatePriceTable - Cria a tabela de preços de um tipo de serviço (admin)
+func (c *PricingController) CreatePriceTable(w http.ResponseWriter, r *http.Request) {
+	serviceTypeID, err := strconv.Atoi(r.FormValue("service_type_id"))
+	if err != nil {
+		http.Redirect(w, r, "/admin/precos?error=service_type", http.StatusFound)
+		return
+	}
+
+	table, ok := parsePriceTableForm(r)
+	if !ok {
+		http.Redirect(w, r, "/admin/precos?error=table_values", http.StatusFound)
+		return
+	}
+	table.ServiceTypeID = serviceTypeID
+	table.Active = true
+
+	if err := c.PricingModel.CreateTable(table, auditActor(r)); err != nil {
+		if err == models.ErrPriceTableExists {
+			http.Redirect(w, r, "/admin/precos?error=table_exists", http.StatusFound)
+			return
+		}
+		http.Error(w, "Erro ao criar tabela: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/precos/%d?success=created", table.ID), http.StatusFound)
+}
+
+// ViewPriceTable - Valores gerais e regras da tabela (admin)
+func (c *PricingController) ViewPriceTable(w http.ResponseWriter, r *http.Request) {
+	tableID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	table, err := c.PricingModel.GetTableByID(tableID)
+	if err != nil {
+		http.Error(w, "Tabela não encontrada", http.StatusNotFound)
+		return
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Table             *models.PriceTable
+		DepthRules        []models.PriceRule
+		DiameterRules     []models.PriceRule
+		TerrainRules      []models.PriceRule
+		Terrains          []models.Terrain
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Table:             table,
+		DepthRules:        table.RulesOfType(models.PriceRuleDepth),
+		DiameterRules:     table.RulesOfType(models.PriceRuleDiameter),
+		TerrainRules:      table.RulesOfType(models.PriceRuleTerrain),
+		Terrains:          models.Terrains,
+		UserName:          userName,
+		PageTitle:         "Tabela de Preços - " + table.ServiceTypeName,
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_preco_tabela.html",
+	}, data)
+}
+
+// UpdatePriceTable - Atualiza os valores gerais da tabela (admin)
+func (c *PricingController) UpdatePriceTable(w http.ResponseWriter, r *http.Request) {
+	tableID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/precos/%d", tableID)
+
+	table, ok := parsePriceTableForm(r)
+	if !ok {
+		http.Redirect(w, r, redirectURL+"?error=table_values", http.StatusFound)
+		return
+	}
+	table.ID = tableID
+	table.Active = r.FormValue("active") == "on"
+
+	if err := c.PricingModel.UpdateTable(table, auditActor(r)); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Tabela não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Erro ao atualizar tabela: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=updated", http.StatusFound)
+}
+
+// AddPriceRule - Inclui uma regra (faixa de profundidade, diâmetro ou terreno) na tabela (admin)
+func (c *PricingController) AddPriceRule(w http.ResponseWriter, r *http.Request) {
+	tableID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/precos/%d", tableID)
+
+	rule := &models.PriceRule{
+		PriceTableID: tableID,
+		RuleType:     r.FormValue("rule_type"),
+		Terrain:      toNullString(r.FormValue("terrain")),
+	}
+	minValue, errMin := toNullFloat(r.FormValue("min_value"))
+	maxValue, errMax := toNullFloat(r.FormValue("max_value"))
+	value, errValue := toNullFloat(r.FormValue("value"))
+	if errMin != nil || errMax != nil || errValue != nil || !value.Valid {
+		http.Redirect(w, r, redirectURL+"?error=rule#"+strings.ToLower(rule.RuleType), http.StatusFound)
+		return
+	}
+	rule.MinValue = minValue
+	rule.MaxValue = maxValue
+	rule.Value = value.Float64
+
+	if err := c.PricingModel.AddRule(rule, auditActor(r)); err != nil {
+		if err == models.ErrInvalidPriceRule {
+			http.Redirect(w, r, redirectURL+"?error=rule#"+strings.ToLower(rule.RuleType), http.StatusFound)
+			return
+		}
+		http.Error(w, "Erro ao incluir regra: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=rule_added#"+strings.ToLower(rule.RuleType), http.StatusFound)
+}
+
+// DeletePriceRule - Remove uma regra da tabela (admin)
+func (c *PricingController) DeletePriceRule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tableID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+	ruleID, err := strconv.Atoi(vars["rule_id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.PricingModel.DeleteRule(tableID, ruleID, auditActor(r)); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Regra não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Erro ao remover regra: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/precos/%d?success=rule_deleted", tableID), http.StatusFound)
+}
+
+// SaveCityDistance - Cadastra ou atualiza a distância da base até uma cidade (admin)
+func (c *PricingController) SaveCityDistance(w http.ResponseWriter, r *http.Request) {
+	distanceKm, err := toNullFloat(r.FormValue("distance_km"))
+	cidade := strings.TrimSpace(r.FormValue("cidade"))
+	estado := strings.TrimSpace(r.FormValue("estado"))
+	if err != nil || !distanceKm.Valid || distanceKm.Float64 < 0 || cidade == "" || len(estado) != 2 {
+		http.Redirect(w, r, "/admin/precos?error=distance#distancias", http.StatusFound)
+		return
+	}
+
+	distance := &models.CityDistance{Cidade: cidade, Estado: estado, DistanceKm: distanceKm.Float64}
+	if err := c.PricingModel.SaveCityDistance(distance, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao salvar distância: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/precos?success=distance_saved#distancias", http.StatusFound)
+}
+
+// DeleteCityDistance - Remove a distância cadastrada (admin)
+func (c *PricingController) DeleteCityDistance(w http.ResponseWriter, r *http.Request) {
+	distanceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.PricingModel.DeleteCityDistance(distanceID, auditActor(r)); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "Distância não encontrada", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Erro ao remover distância: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/precos?success=distance_deleted#distancias", http.StatusFound)
+}
+
+// ==================== PREÇO SUGERIDO ====================
+
+// SuggestQuote - Calcula o preço sugerido da solicitação pela tabela do tipo de serviço (admin).
+// GET mostra o cálculo; POST cria um orçamento em rascunho com as linhas sugeridas.
+func (c *PricingController) SuggestQuote(w http.ResponseWriter, r *http.Request) {
+	serviceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	service, err := c.ServiceModel.GetByID(serviceID)
+	if err != nil {
+		http.Error(w, "Solicitação não encontrada", http.StatusNotFound)
+		return
+	}
+
+	table, err := c.PricingModel.GetActiveTableByServiceType(service.ServiceTypeID)
+	if err != nil && err != sql.ErrNoRows {
+		http.Error(w, "Erro ao buscar tabela de preços", http.StatusInternalServerError)
+		return
+	}
+
+	input := c.pricingInput(r, service)
+
+	var result *services.PricingResult
+	if table != nil {
+		result = services.SuggestPrice(table, input)
+		if input.DistanceSource == "" && input.DistanceKm == 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				"Distância até %s/%s não cadastrada e local sem coordenadas; informe a distância manualmente.", service.Cidade, service.Estado))
+		}
+	}
+
+	if r.Method == "POST" {
+		c.createSuggestedQuote(w, r, service, result)
+		return
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Service           *models.ServiceRequest
+		Table             *models.PriceTable
+		Input             services.PricingInput
+		Result            *services.PricingResult
+		DistanceOverride  string
+		Terrains          []models.Terrain
+		CanCreateQuote    bool
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Service:           service,
+		Table:             table,
+		Input:             input,
+		Result:            result,
+		DistanceOverride:  r.FormValue("distance_km"),
+		Terrains:          models.Terrains,
+		CanCreateQuote:    service.StatusID == constants.StatusRealizada && result != nil && len(result.Lines) > 0,
+		UserName:          userName,
+		PageTitle:         fmt.Sprintf("Preço Sugerido - Solicitação #%d", service.ID),
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_preco_sugerido.html",
+	}, data)
+}
+
+// createSuggestedQuote grava o cálculo como orçamento em rascunho para revisão do admin
+func (c *PricingController) createSuggestedQuote(w http.ResponseWriter, r *http.Request, service *models.ServiceRequest, result *services.PricingResult) {
+	redirectURL := fmt.Sprintf("/admin/solicitacao/%d/preco", service.ID)
+
+	if service.StatusID != constants.StatusRealizada {
+		http.Error(w, "O orçamento só pode ser emitido após a vistoria realizada", http.StatusBadRequest)
+		return
+	}
+	if result == nil || len(result.Lines) == 0 {
+		http.Redirect(w, r, redirectURL+"?error=no_lines", http.StatusFound)
+		return
+	}
+
+	adminID := currentUser(r).UserID
+
+	notes := fmt.Sprintf("Calculado pela tabela \"%s\": profundidade %s m, diâmetro %s mm",
+		result.Table.Name, strconv.FormatFloat(result.Input.DepthM, 'f', -1, 64),
+		strconv.FormatFloat(result.Input.DiameterMm, 'f', -1, 64))
+	if result.Input.Terrain != "" {
+		notes += ", terreno " + strings.ToLower(models.TerrainName(result.Input.Terrain))
+	}
+
+	quote := &models.Quote{
+		ServiceRequestID: service.ID,
+		ValidUntil:       time.Now().AddDate(0, 0, quoteValidityDays),
+		Notes:            toNullString(notes + "."),
+		CreatedBy:        sql.NullInt64{Int64: int64(adminID), Valid: true},
+		Items:            result.QuoteItems(),
+	}
+	if err := c.QuoteModel.Create(quote, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao criar orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/orcamentos/%d?success=created", quote.ID), http.StatusFound)
+}
+
+// pricingInput lê os parâmetros do cálculo. Sem valores no formulário, usa a
+// ficha do poço (se houver) e a distância cadastrada ou estimada até o local.
+func (c *PricingController) pricingInput(r *http.Request, service *models.ServiceRequest) services.PricingInput {
+	input := services.PricingInput{Terrain: r.FormValue("terrain")}
+
+	if well, _ := c.WellModel.GetByServiceRequestID(service.ID); well != nil {
+		input.DepthM = well.DepthM.Float64
+		input.DiameterMm = well.DiameterMm.Float64
+	}
+	if depth, err := toNullFloat(r.FormValue("depth_m")); err == nil && depth.Valid && depth.Float64 >= 0 {
+		input.DepthM = depth.Float64
+	}
+	if diameter, err := toNullFloat(r.FormValue("diameter_mm")); err == nil && diameter.Valid && diameter.Float64 >= 0 {
+		input.DiameterMm = diameter.Float64
+	}
+
+	if distance, err := toNullFloat(r.FormValue("distance_km")); err == nil && distance.Valid && distance.Float64 >= 0 {
+		input.DistanceKm = distance.Float64
+		input.DistanceSource = "informada manualmente"
+	} else {
+		input.DistanceKm, input.DistanceSource = c.resolveDistance(service)
+	}
+
+	return input
+}
+
+// resolveDistance busca a distância (só ida) da base até o local: primeiro a
+// distância cadastrada para a cidade, depois a estimativa pelas coordenadas.
+func (c *PricingController) resolveDistance(service *models.ServiceRequest) (float64, string) {
+	if distance, err := c.PricingModel.FindCityDistance(service.Cidade, service.Estado); err == nil {
+		return distance.DistanceKm, fmt.Sprintf("cadastrada para %s/%s", distance.Cidade, distance.Estado)
+	}
+
+	if service.HasCoordinates() {
+		baseLat, baseLng := config.GetCompanyBase()
+		km, _, err := c.Estimator.Estimate(
+			services.GeoPoint{Lat: baseLat, Lng: baseLng},
+			services.GeoPoint{Lat: service.Latitude.Float64, Lng: service.Longitude.Float64},
+		)
+		if err == nil {
+			return math.Round(km*10) / 10, "estimada pelas coordenadas do local (" + c.Estimator.Name() + ")"
+		}
+	}
+
+	return 0, ""
+}
+
+// parsePriceTableForm lê os valores gerais da tabela de preços
+func parsePriceTableForm(r *http.Request) (*models.PriceTable, bool) {
+	table := &models.PriceTable{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		RoundTrip: r.FormValue("round_trip") == "on",
+	}
+	if table.Name == "" {
+		return nil, false
+	}
+
+	for _, field := range []struct {
+		name   string
+		target *float64
+	}{
+		{"base_fee", &table.BaseFee},
+		{"travel_rate_per_km", &table.TravelRatePerKm},
+		{"free_km", &table.FreeKm},
+	} {
+		value, err := toNullFloat(r.FormValue(field.name))
+		if err != nil || value.Float64 < 0 {
+			return nil, false
+		}
+		*field.target = value.Float64
+	}
+
+	return table, true
+}
+
+func (c *PricingController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "created":
+		return "Tabela de preços criada! Cadastre as faixas de profundidade e os multiplicadores."
+	case "updated":
+		return "Tabela de preços atualizada."
+	case "rule_added":
+		return "Regra incluída."
+	case "rule_deleted":
+		return "Regra removida."
+	case "distance_saved":
+		return "Distância salva."
+	case "distance_deleted":
+		return "Distância removida."
+	default:
+		return ""
+	}
+}
+
+func (c *PricingController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "service_type":
+		return "Selecione o tipo de serviço."
+	case "table_values":
+		return "Informe o nome e valores não negativos para taxa fixa, R$/km e franquia."
+	case "table_exists":
+		return "Já existe uma tabela de preços para este tipo de serviço."
+	case "rule":
+		return "Regra inválida: confira a faixa (mínimo menor que o máximo) e o valor (multiplicadores maiores que zero)."
+	case "distance":
+		return "Informe cidade, UF com 2 letras e distância em km."
+	case "no_lines":
+		return "O cálculo não gerou nenhuma linha; confira a tabela de preços e os parâmetros."
+	default:
+		return ""
+	}
+}
+
+func (c *PricingController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"martins-pocos/config"
+	"martins-pocos/models"
+	"martins-pocos/services"
+	"martins-pocos/utils"
+)
+
+// Registros exibidos nas trilhas de privacidade
+const (
+	privacyEventsShown      = 20
+	adminPrivacyEventsShown = 50
+	erasureRequestsShown    = 100
+)
+
+type PrivacyController struct {
+	PrivacyModel      *models.PrivacyModel
+	UserModel         *models.UserModel
+	LoginAttemptModel *models.LoginAttemptModel
+	Mailer            services.Mailer
+}
+
+func NewPrivacyController(privacyModel *models.PrivacyModel, userModel *models.UserModel, loginAttemptModel *models.LoginAttemptModel, mailer services.Mailer) *PrivacyController {
+	return &PrivacyController{
+		PrivacyModel:      privacyModel,
+		UserModel:         userModel,
+		LoginAttemptModel: loginAttemptModel,
+		Mailer:            mailer,
+	}
+}
+
+// ============================================
+// PORTAL DO CLIENTE
+// ============================================
+
+// Page - Exportação dos dados e pedido de exclusão da conta (LGPD)
+func (c *PrivacyController) Page(w http.ResponseWriter, r *http.Request) {
+	c.renderPage(w, r, "")
+}
+
+// Export - Baixa um ZIP com os dados pessoais da conta
+func (c *PrivacyController) Export(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	export, err := c.PrivacyModel.ExportUserData(user.UserID)
+	if err != nil {
+		log.Printf("❌ Erro ao exportar dados do usuário %d: %v", user.UserID, err)
+		http.Error(w, "Erro ao exportar dados", http.StatusInternalServerError)
+		return
+	}
+
+	// Monta o arquivo inteiro antes de responder, para que uma falha no meio
+	// vire uma página de erro e não um ZIP corrompido
+	var buf bytes.Buffer
+	if err := services.WriteDataExportZIP(&buf, export); err != nil {
+		log.Printf("❌ Erro ao gerar ZIP dos dados do usuário %d: %v", user.UserID, err)
+		http.Error(w, "Erro ao exportar dados", http.StatusInternalServerError)
+		return
+	}
+
+	details := fmt.Sprintf("%d solicitação(ões), %d contrato(s)", len(export.ServiceRequests), len(export.Contracts))
+	if err := c.PrivacyModel.RecordEvent(user.UserID, 0, models.PrivacyEventExport, user.UserID, details, utils.ClientIP(r)); err != nil {
+		log.Printf("❌ Erro ao registrar exportação do usuário %d: %v", user.UserID, err)
+	}
+
+	filename := fmt.Sprintf("meus-dados-martins-pocos-%s.zip", export.GeneratedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
+
+// RequestErasure - Pede a exclusão dos dados. Exige a senha atual; a
+// anonimização só acontece depois da aprovação da empresa.
+func (c *PrivacyController) RequestErasure(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	if r.FormValue("confirm") != "1" {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderPage(w, r, "Marque a confirmação de que entende as consequências da exclusão.")
+		return
+	}
+
+	if msg, ok := checkLoginThrottle(c.LoginAttemptModel, w, r, user.Email, models.LoginMethodPassword); !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		c.renderPage(w, r, msg)
+		return
+	}
+
+	valid, err := c.UserModel.CheckPassword(user.UserID, r.FormValue("password"))
+	if err != nil {
+		http.Error(w, "Erro ao conferir senha", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		recordLogin(c.LoginAttemptModel, r, user.Email, models.LoginMethodPassword, &models.User{ID: user.UserID}, models.LoginWrongPassword)
+		w.WriteHeader(http.StatusUnauthorized)
+		c.renderPage(w, r, "A senha não confere.")
+		return
+	}
+
+	_, err = c.PrivacyModel.RequestErasure(user.UserID, r.FormValue("reason"), utils.ClientIP(r))
+	if errors.Is(err, models.ErrErasurePending) || errors.Is(err, models.ErrErasureNotClient) {
+		w.WriteHeader(http.StatusConflict)
+		c.renderPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao registrar pedido", http.StatusInternalServerError)
+		return
+	}
+
+	err = c.Mailer.Send(services.Email{
+		To:      user.Email,
+		Subject: "Pedido de exclusão de dados recebido - Martins Poços",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Recebemos seu pedido de exclusão dos dados da sua conta. Ele será analisado pela nossa equipe e você receberá a resposta por e-mail.\n\n"+
+			"Enquanto o pedido estiver em análise, você pode desistir em %s/conta/privacidade.\n",
+			user.UserName, config.GetAppBaseURL()),
+	})
+	if err != nil {
+		log.Printf("❌ Erro ao enviar confirmação de pedido de exclusão para usuário %d: %v", user.UserID, err)
+	}
+
+	http.Redirect(w, r, "/conta/privacidade?success=requested", http.StatusFound)
+}
+
+// CancelErasure - Desiste do pedido de exclusão em análise
+func (c *PrivacyController) CancelErasure(w http.ResponseWriter, r *http.Request) {
+	err := c.PrivacyModel.CancelErasure(currentUser(r).UserID, utils.ClientIP(r))
+	if err != nil && !errors.Is(err, models.ErrErasureNotFound) {
+		http.Error(w, "Erro ao cancelar pedido", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/conta/privacidade?success=cancelled", http.StatusFound)
+}
+
+func (c *PrivacyController) renderPage(w http.ResponseWriter, r *http.Request, errorMsg string) {
+	user := currentUser(r)
+
+	erasure, err := c.PrivacyModel.GetLatestErasureRequest(user.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao carregar pedido de exclusão", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := c.PrivacyModel.GetEventsByUser(user.UserID, privacyEventsShown)
+	if err != nil {
+		http.Error(w, "Erro ao carregar histórico", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Erasure           *models.ErasureRequest
+		Events            []models.PrivacyEvent
+		CanRequestErasure bool
+		RetentionYears    int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Erasure:           erasure,
+		Events:            events,
+		CanRequestErasure: !user.IsAdmin() && (erasure == nil || !erasure.IsPending()),
+		RetentionYears:    config.GetContractRetentionYears(),
+		UserName:          user.UserName,
+		PageTitle:         "Privacidade e dados",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          errorMsg,
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/conta_privacidade.html",
+	}, data)
+}
+
+// ============================================
+// PAINEL DA EMPRESA
+// ============================================
+
+// AdminErasureRequests - Pedidos de exclusão e trilha de auditoria
+func (c *PrivacyController) AdminErasureRequests(w http.ResponseWriter, r *http.Request) {
+	c.renderAdminPage(w, r, "")
+}
+
+// AdminApproveErasure - Aprova o pedido e anonimiza a conta do cliente
+func (c *PrivacyController) AdminApproveErasure(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	// O e-mail é lido antes, pois a anonimização o apaga
+	before, err := c.PrivacyModel.GetErasureRequest(id)
+	if errors.Is(err, models.ErrErasureNotFound) {
+		http.Error(w, "Pedido não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar pedido", http.StatusInternalServerError)
+		return
+	}
+
+	request, err := c.PrivacyModel.ApproveErasure(id, r.FormValue("note"), config.GetContractRetentionYears(), auditActor(r))
+	if errors.Is(err, models.ErrErasureDecided) {
+		w.WriteHeader(http.StatusConflict)
+		c.renderAdminPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Erro ao anonimizar conta do pedido %d: %v", id, err)
+		http.Error(w, "Erro ao aprovar pedido", http.StatusInternalServerError)
+		return
+	}
+
+	retention := "Não havia contratos assinados, então todos os seus dados pessoais foram removidos."
+	if request.RetainUntil.Valid {
+		retention = fmt.Sprintf("Os contratos assinados e as solicitações ligadas a eles são guardados até %s, "+
+			"por obrigação legal, e depois também serão eliminados.", request.RetainUntil.Time.Format("02/01/2006"))
+	}
+	err = c.Mailer.Send(services.Email{
+		To:      before.UserEmail,
+		Subject: "Seus dados foram excluídos - Martins Poços",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Seu pedido de exclusão de dados foi atendido. Sua conta foi encerrada e os dados pessoais dela foram anonimizados.\n\n"+
+			"%s\n\nEsta é a última mensagem que enviaremos para este endereço.\n",
+			before.UserName, retention),
+	})
+	if err != nil {
+		log.Printf("❌ Erro ao avisar conclusão do pedido de exclusão %d: %v", id, err)
+	}
+
+	http.Redirect(w, r, "/admin/privacidade?success=approved", http.StatusFound)
+}
+
+// AdminRejectErasure - Recusa o pedido, com o motivo enviado ao cliente
+func (c *PrivacyController) AdminRejectErasure(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	request, err := c.PrivacyModel.RejectErasure(id, r.FormValue("note"), auditActor(r))
+	if errors.Is(err, models.ErrErasureNotFound) {
+		http.Error(w, "Pedido não encontrado", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, models.ErrDecisionNoteRequired) || errors.Is(err, models.ErrErasureDecided) {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderAdminPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao recusar pedido", http.StatusInternalServerError)
+		return
+	}
+
+	err = c.Mailer.Send(services.Email{
+		To:      request.UserEmail,
+		Subject: "Pedido de exclusão de dados - Martins Poços",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Seu pedido de exclusão de dados não pôde ser atendido neste momento pelo seguinte motivo:\n\n%s\n\n"+
+			"Você pode fazer um novo pedido em %s/conta/privacidade depois de resolvida a pendência.\n",
+			request.UserName, request.DecisionNote.String, config.GetAppBaseURL()),
+	})
+	if err != nil {
+		log.Printf("❌ Erro ao avisar recusa do pedido de exclusão %d: %v", id, err)
+	}
+
+	http.Redirect(w, r, "/admin/privacidade?success=rejected", http.StatusFound)
+}
+
+func (c *PrivacyController) renderAdminPage(w http.ResponseWriter, r *http.Request, errorMsg string) {
+	user := currentUser(r)
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case models.ErasurePending, models.ErasureApproved, models.ErasureRejected, models.ErasureCancelled:
+	default:
+		status = ""
+	}
+
+	requests, err := c.PrivacyModel.GetErasureRequests(status, erasureRequestsShown)
+	if err != nil {
+		http.Error(w, "Erro ao carregar pedidos", http.StatusInternalServerError)
+		return
+	}
+
+	events, err := c.PrivacyModel.GetRecentEvents(adminPrivacyEventsShown)
+	if err != nil {
+		http.Error(w, "Erro ao carregar trilha de auditoria", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Requests          []models.ErasureRequest
+		Events            []models.PrivacyEvent
+		StatusFilter      string
+		RetentionYears    int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Requests:          requests,
+		Events:            events,
+		StatusFilter:      status,
+		RetentionYears:    config.GetContractRetentionYears(),
+		UserName:          user.UserName,
+		PageTitle:         "Privacidade (LGPD)",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          errorMsg,
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_privacidade.html",
+	}, data)
+}
+
+func (c *PrivacyController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "requested":
+		return "Pedido de exclusão registrado. Você receberá a resposta por e-mail."
+	case "cancelled":
+		return "Pedido de exclusão cancelado."
+	case "approved":
+		return "Pedido aprovado. A conta do cliente foi anonimizada."
+	case "rejected":
+		return "Pedido recusado. O cliente foi avisado por e-mail."
+	default:
+		return ""
+	}
+}
+
+func (c *PrivacyController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,429 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"martins-pocos/config"
+	"martins-pocos/models"
+	"martins-pocos/services"
+	"martins-pocos/utils"
+)
+
+// Quantidade de alterações exibidas no histórico do perfil
+const profileChangesShown = 20
+
+type ProfileController struct {
+	UserModel         *models.UserModel
+	AddressModel      *models.UserAddressModel
+	UserTokenModel    *models.UserTokenModel
+	LoginAttemptModel *models.LoginAttemptModel
+	Mailer            services.Mailer
+}
+
+func NewProfileController(userModel *models.UserModel, addressModel *models.UserAddressModel, userTokenModel *models.UserTokenModel, loginAttemptModel *models.LoginAttemptModel, mailer services.Mailer) *ProfileController {
+	return &ProfileController{
+		UserModel:         userModel,
+		AddressModel:      addressModel,
+		UserTokenModel:    userTokenModel,
+		LoginAttemptModel: loginAttemptModel,
+		Mailer:            mailer,
+	}
+}
+
+// Page - Dados de contato, senha, imóveis, avisos e histórico da conta
+func (c *ProfileController) Page(w http.ResponseWriter, r *http.Request) {
+	c.renderPage(w, r, "")
+}
+
+// UpdateContact - Grava nome, telefone e endereço de contato. Um e-mail
+// diferente do atual só vale depois de confirmado pelo link enviado a ele.
+func (c *ProfileController) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	name, email, phone, err := models.ValidateContact(r.FormValue("name"), r.FormValue("email"), r.FormValue("phone"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+
+	ip := utils.ClientIP(r)
+	if _, err := c.UserModel.UpdateContact(user.UserID, name, phone, r.FormValue("address"), ip); err != nil {
+		http.Error(w, "Erro ao salvar dados", http.StatusInternalServerError)
+		return
+	}
+
+	if strings.EqualFold(email, user.Email) {
+		http.Redirect(w, r, "/conta/perfil?success=contact", http.StatusFound)
+		return
+	}
+
+	err = c.UserModel.RequestEmailChange(user.UserID, email, ip)
+	if errors.Is(err, models.ErrEmailTaken) {
+		w.WriteHeader(http.StatusConflict)
+		c.renderPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao salvar e-mail", http.StatusInternalServerError)
+		return
+	}
+
+	if err := c.sendEmailChange(user.UserID, user.UserName, user.Email, email); err != nil {
+		log.Printf("❌ Erro ao enviar confirmação de troca de e-mail para usuário %d: %v", user.UserID, err)
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=email_pending", http.StatusFound)
+}
+
+// CancelEmailChange - Descarta a troca de e-mail ainda não confirmada
+func (c *ProfileController) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	if err := c.UserModel.CancelEmailChange(currentUser(r).UserID); err != nil {
+		http.Error(w, "Erro ao cancelar troca de e-mail", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/conta/perfil", http.StatusFound)
+}
+
+// ConfirmEmailChange - Link enviado ao novo e-mail. Não exige login, pois
+// pode ser aberto em outro aparelho.
+func (c *ProfileController) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, err := c.UserTokenModel.Consume(r.URL.Query().Get("token"), models.UserTokenEmailChange)
+	if errors.Is(err, models.ErrInvalidUserToken) {
+		http.Redirect(w, r, "/login?error=invalid_link", http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao confirmar e-mail", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = c.UserModel.ConfirmEmailChange(userID, utils.ClientIP(r))
+	if errors.Is(err, models.ErrInvalidUserToken) || errors.Is(err, models.ErrEmailTaken) {
+		http.Redirect(w, r, "/conta/perfil?error=email_change", http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao confirmar e-mail", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=email_changed", http.StatusFound)
+}
+
+// ChangePassword - Troca a senha conferindo a atual. As demais sessões da
+// conta são encerradas; a atual continua com um identificador novo.
+func (c *ProfileController) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	if msg, ok := checkLoginThrottle(c.LoginAttemptModel, w, r, user.Email, models.LoginMethodPassword); !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		c.renderPage(w, r, msg)
+		return
+	}
+
+	valid, err := c.UserModel.CheckPassword(user.UserID, r.FormValue("current_password"))
+	if err != nil {
+		http.Error(w, "Erro ao conferir senha", http.StatusInternalServerError)
+		return
+	}
+	if !valid {
+		recordLogin(c.LoginAttemptModel, r, user.Email, models.LoginMethodPassword, &models.User{ID: user.UserID}, models.LoginWrongPassword)
+		w.WriteHeader(http.StatusUnauthorized)
+		c.renderPage(w, r, "A senha atual não confere.")
+		return
+	}
+
+	password := r.FormValue("password")
+	if len(password) < minPasswordLength {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderPage(w, r, fmt.Sprintf("A nova senha deve ter ao menos %d caracteres.", minPasswordLength))
+		return
+	}
+	if password != r.FormValue("password_confirm") {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderPage(w, r, "As senhas não conferem.")
+		return
+	}
+
+	if err := c.UserModel.UpdatePassword(user.UserID, password); err != nil {
+		http.Error(w, "Erro ao trocar senha", http.StatusInternalServerError)
+		return
+	}
+	if err := c.UserModel.RecordProfileChange(user.UserID, models.ProfileFieldPassword, "", "alterada no perfil", utils.ClientIP(r)); err != nil {
+		log.Printf("❌ Erro ao registrar troca de senha do usuário %d: %v", user.UserID, err)
+	}
+
+	// UpdatePassword encerrou todas as sessões, inclusive esta: regrava com
+	// um identificador novo
+	store := config.GetSessionStore()
+	session, _ := store.Get(r, "session")
+	if err := store.RenewID(session); err == nil {
+		session.Save(r, w)
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=password", http.StatusFound)
+}
+
+// UpdateNotifications - Avisos por WhatsApp que o cliente aceita receber
+func (c *ProfileController) UpdateNotifications(w http.ResponseWriter, r *http.Request) {
+	prefs := models.NotificationPreferences{
+		RequestUpdates: r.FormValue(models.NotifyRequestUpdates) == "1",
+		Quotes:         r.FormValue(models.NotifyQuotes) == "1",
+		Warranty:       r.FormValue(models.NotifyWarranty) == "1",
+		Maintenance:    r.FormValue(models.NotifyMaintenance) == "1",
+	}
+
+	if err := c.UserModel.UpdateNotificationPreferences(currentUser(r).UserID, prefs, utils.ClientIP(r)); err != nil {
+		http.Error(w, "Erro ao salvar avisos", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=notifications#avisos", http.StatusFound)
+}
+
+// CreateAddress - Cadastra um imóvel
+func (c *ProfileController) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	address := addressFromForm(r)
+	address.UserID = currentUser(r).UserID
+
+	if err := address.Normalize(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+
+	err := c.AddressModel.Create(address, utils.ClientIP(r))
+	if errors.Is(err, models.ErrTooManyAddresses) {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao salvar endereço", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=address_saved#imoveis", http.StatusFound)
+}
+
+// UpdateAddress - Altera um imóvel
+func (c *ProfileController) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	address := addressFromForm(r)
+	address.ID, _ = strconv.Atoi(mux.Vars(r)["id"])
+	address.UserID = currentUser(r).UserID
+
+	if err := address.Normalize(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		c.renderPage(w, r, capitalize(err.Error())+".")
+		return
+	}
+
+	err := c.AddressModel.Update(address, utils.ClientIP(r))
+	if errors.Is(err, models.ErrAddressNotFound) {
+		http.Error(w, "Endereço não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao salvar endereço", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=address_saved#imoveis", http.StatusFound)
+}
+
+// DeleteAddress - Remove um imóvel (solicitações já feitas guardam o endereço)
+func (c *ProfileController) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	err := c.AddressModel.Delete(id, currentUser(r).UserID, utils.ClientIP(r))
+	if err != nil && !errors.Is(err, models.ErrAddressNotFound) {
+		http.Error(w, "Erro ao remover endereço", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=address_deleted#imoveis", http.StatusFound)
+}
+
+// SetDefaultAddress - Escolhe o imóvel pré-selecionado nas solicitações
+func (c *ProfileController) SetDefaultAddress(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	err := c.AddressModel.SetDefault(id, currentUser(r).UserID)
+	if errors.Is(err, models.ErrAddressNotFound) {
+		http.Error(w, "Endereço não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao salvar endereço", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/perfil?success=address_saved#imoveis", http.StatusFound)
+}
+
+// addressFromForm lê os campos de endereço (mesmos nomes do formulário de
+// solicitação)
+func addressFromForm(r *http.Request) *models.UserAddress {
+	return &models.UserAddress{
+		Label:      r.FormValue("address_label"),
+		CEP:        r.FormValue("cep"),
+		Logradouro: r.FormValue("logradouro"),
+		Numero:     r.FormValue("numero"),
+		Bairro:     r.FormValue("bairro"),
+		Cidade:     r.FormValue("cidade"),
+		Estado:     r.FormValue("estado"),
+	}
+}
+
+// sendEmailChange envia o link de confirmação ao novo e-mail e avisa o atual
+func (c *ProfileController) sendEmailChange(userID int, name, currentEmail, newEmail string) error {
+	token, err := c.UserTokenModel.Create(userID, models.UserTokenEmailChange, models.EmailChangeTTL)
+	if err != nil {
+		return err
+	}
+
+	link := config.GetAppBaseURL() + "/conta/perfil/confirmar-email?token=" + url.QueryEscape(token)
+	err = c.Mailer.Send(services.Email{
+		To:      newEmail,
+		Subject: "Confirme seu novo e-mail - Martins Poços",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Para passar a usar este endereço na sua conta da Martins Poços, confirme pelo link abaixo:\n\n%s\n\n"+
+			"O link vale por %d horas. Se você não pediu a troca, ignore esta mensagem.\n",
+			name, link, int(models.EmailChangeTTL.Hours())),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Mailer.Send(services.Email{
+		To:      currentEmail,
+		Subject: "Troca de e-mail solicitada - Martins Poços",
+		Body: fmt.Sprintf("Olá, %s!\n\n"+
+			"Foi pedida a troca do e-mail da sua conta para %s. Ela só vale depois de confirmada pelo novo endereço.\n\n"+
+			"Se não foi você, troque sua senha e encerre as outras sessões em %s/conta/sessoes.\n",
+			name, newEmail, config.GetAppBaseURL()),
+	})
+}
+
+func (c *ProfileController) renderPage(w http.ResponseWriter, r *http.Request, errorMsg string) {
+	user := currentUser(r)
+
+	profile, err := c.UserModel.GetProfile(user.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao carregar perfil", http.StatusInternalServerError)
+		return
+	}
+
+	addresses, err := c.AddressModel.GetByUser(user.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao carregar endereços", http.StatusInternalServerError)
+		return
+	}
+
+	changes, err := c.UserModel.GetProfileChanges(user.UserID, profileChangesShown)
+	if err != nil {
+		http.Error(w, "Erro ao carregar histórico", http.StatusInternalServerError)
+		return
+	}
+
+	if errorMsg == "" {
+		errorMsg = c.getErrorMsg(r)
+	}
+
+	data := struct {
+		Profile           *models.UserProfile
+		Addresses         []models.UserAddress
+		MaxAddresses      int
+		States            []models.State
+		Changes           []models.ProfileChange
+		MinPassword       int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Profile:           profile,
+		Addresses:         addresses,
+		MaxAddresses:      models.MaxUserAddresses,
+		States:            models.BrazilianStates,
+		Changes:           changes,
+		MinPassword:       minPasswordLength,
+		UserName:          user.UserName,
+		PageTitle:         "Meu Perfil",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          errorMsg,
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/conta_perfil.html",
+	}, data)
+}
+
+func (c *ProfileController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "contact":
+		return "Dados atualizados."
+	case "email_pending":
+		return "Dados atualizados. Enviamos um link para o novo e-mail; a troca vale depois de confirmada."
+	case "email_changed":
+		return "E-mail alterado com sucesso."
+	case "password":
+		return "Senha alterada. As sessões em outros dispositivos foram encerradas."
+	case "notifications":
+		return "Preferências de avisos salvas."
+	case "address_saved":
+		return "Endereço salvo."
+	case "address_deleted":
+		return "Endereço removido."
+	default:
+		return ""
+	}
+}
+
+func (c *ProfileController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "email_change":
+		return "Não foi possível confirmar o novo e-mail. Ele pode já estar em uso; tente novamente."
+	default:
+		return ""
+	}
+}
+
+func (c *ProfileController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,621 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/constants"
+	"martins-pocos/models"
+	"martins-pocos/services"
+
+	"github.com/gorilla/mux"
+)
+
+// Validade padrão de um orçamento novo
+const quoteValidityDays = 15
+
+type QuoteController struct {
+	QuoteModel      *models.QuoteModel
+	ServiceModel    *models.ServiceModel
+	MaterialModel   *models.MaterialModel
+	WhatsAppService *services.WhatsAppService
+}
+
+func NewQuoteController(quoteModel *models.QuoteModel, serviceModel *models.ServiceModel, materialModel *models.MaterialModel, whatsappService *services.WhatsAppService) *QuoteController {
+	return &QuoteController{
+		QuoteModel:      quoteModel,
+		ServiceModel:    serviceModel,
+		MaterialModel:   materialModel,
+		WhatsAppService: whatsappService,
+	}
+}
+
+// ==================== ADMIN ====================
+
+// CreateQuote - Cria um orçamento em rascunho para a solicitação já vistoriada (admin)
+func (c *QuoteController) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	serviceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	service, err := c.ServiceModel.GetByID(serviceID)
+	if err != nil {
+		http.Error(w, "Solicitação não encontrada", http.StatusNotFound)
+		return
+	}
+	if service.StatusID != constants.StatusRealizada {
+		http.Error(w, "O orçamento só pode ser emitido após a vistoria realizada", http.StatusBadRequest)
+		return
+	}
+
+	adminID := currentUser(r).UserID
+
+	quote := &models.Quote{
+		ServiceRequestID: serviceID,
+		ValidUntil:       time.Now().AddDate(0, 0, quoteValidityDays),
+		CreatedBy:        sql.NullInt64{Int64: int64(adminID), Valid: true},
+	}
+	if err := c.QuoteModel.Create(quote, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao criar orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/orcamentos/%d?success=created", quote.ID), http.StatusFound)
+}
+
+// ListQuotes - Lista os orçamentos (admin)
+func (c *QuoteController) ListQuotes(w http.ResponseWriter, r *http.Request) {
+	statusFilter := r.URL.Query().Get("status")
+	switch statusFilter {
+	case models.QuoteStatusDraft, models.QuoteStatusSent, models.QuoteStatusAccepted,
+		models.QuoteStatusRejected, models.QuoteStatusConverted:
+	default:
+		statusFilter = ""
+	}
+
+	quotes, err := c.QuoteModel.GetAll(statusFilter)
+	if err != nil {
+		http.Error(w, "Erro ao buscar orçamentos", http.StatusInternalServerError)
+		return
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Quotes            []models.Quote
+		StatusFilter      string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Quotes:            quotes,
+		StatusFilter:      statusFilter,
+		UserName:          userName,
+		PageTitle:         "Orçamentos",
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_orcamentos.html",
+	}, data)
+}
+
+// ViewQuote - Detalhes do orçamento com edição das linhas enquanto rascunho (admin)
+func (c *QuoteController) ViewQuote(w http.ResponseWriter, r *http.Request) {
+	quoteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := c.QuoteModel.GetByID(quoteID)
+	if err != nil {
+		http.Error(w, "Orçamento não encontrado", http.StatusNotFound)
+		return
+	}
+
+	var materials []models.Material
+	if quote.IsEditable() {
+		materials, _ = c.MaterialModel.GetAll("", false)
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Quote             *models.Quote
+		ItemTypes         []models.QuoteItemType
+		Materials         []models.Material
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Quote:             quote,
+		ItemTypes:         models.QuoteItemTypes,
+		Materials:         materials,
+		UserName:          userName,
+		PageTitle:         "Orçamento " + quote.QuoteNumber,
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_orcamento.html",
+	}, data)
+}
+
+// UpdateQuote - Atualiza validade, desconto, impostos e observações (admin, apenas rascunho)
+func (c *QuoteController) UpdateQuote(w http.ResponseWriter, r *http.Request) {
+	quoteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/orcamentos/%d", quoteID)
+
+	validUntil, err := time.Parse("2006-01-02", r.FormValue("valid_until"))
+	if err != nil {
+		http.Redirect(w, r, redirectURL+"?error=valid_until", http.StatusFound)
+		return
+	}
+	discount, err := toNullFloat(r.FormValue("discount_value"))
+	if err != nil || discount.Float64 < 0 {
+		http.Redirect(w, r, redirectURL+"?error=discount", http.StatusFound)
+		return
+	}
+	tax, err := toNullFloat(r.FormValue("tax_percent"))
+	if err != nil || tax.Float64 < 0 || tax.Float64 > 100 {
+		http.Redirect(w, r, redirectURL+"?error=tax", http.StatusFound)
+		return
+	}
+
+	quote := &models.Quote{
+		ID:            quoteID,
+		ValidUntil:    validUntil,
+		DiscountValue: discount.Float64,
+		TaxPercent:    tax.Float64,
+		Notes:         toNullString(r.FormValue("notes")),
+	}
+	if err := c.QuoteModel.UpdateHeader(quote, auditActor(r)); err != nil {
+		if err == models.ErrQuoteNotEditable {
+			http.Redirect(w, r, redirectURL+"?error=not_editable", http.StatusFound)
+			return
+		}
+		http.Error(w, "Erro ao atualizar orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=updated", http.StatusFound)
+}
+
+// AddItem - Inclui uma linha no orçamento (admin, apenas rascunho)
+func (c *QuoteController) AddItem(w http.ResponseWriter, r *http.Request) {
+	quoteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/orcamentos/%d", quoteID)
+
+	itemType, ok := models.GetQuoteItemType(r.FormValue("item_type"))
+	if !ok {
+		http.Redirect(w, r, redirectURL+"?error=item_type#itens", http.StatusFound)
+		return
+	}
+	quantity, err := toNullFloat(r.FormValue("quantity"))
+	if err != nil || !quantity.Valid || quantity.Float64 <= 0 {
+		http.Redirect(w, r, redirectURL+"?error=item_quantity#itens", http.StatusFound)
+		return
+	}
+	unitPrice, err := toNullFloat(r.FormValue("unit_price"))
+	if err != nil || unitPrice.Float64 < 0 {
+		http.Redirect(w, r, redirectURL+"?error=item_price#itens", http.StatusFound)
+		return
+	}
+
+	item := &models.QuoteItem{
+		QuoteID:     quoteID,
+		ItemType:    itemType.Code,
+		Description: strings.TrimSpace(r.FormValue("description")),
+		Quantity:    quantity.Float64,
+		Unit:        strings.TrimSpace(r.FormValue("unit")),
+		UnitPrice:   unitPrice.Float64,
+	}
+
+	// Materiais do catálogo herdam nome, unidade e preço de venda quando não informados
+	if itemType.Code == models.QuoteItemMaterial {
+		materialID, err := strconv.Atoi(r.FormValue("material_id"))
+		if err != nil {
+			http.Redirect(w, r, redirectURL+"?error=item_material#itens", http.StatusFound)
+			return
+		}
+		material, err := c.MaterialModel.GetByID(materialID)
+		if err != nil || !material.Active {
+			http.Redirect(w, r, redirectURL+"?error=item_material#itens", http.StatusFound)
+			return
+		}
+		item.MaterialID = sql.NullInt64{Int64: int64(material.ID), Valid: true}
+		if item.Description == "" {
+			item.Description = material.Name
+		}
+		item.Unit = material.Unit
+		if !unitPrice.Valid {
+			item.UnitPrice = material.SalePrice
+		}
+	}
+
+	if item.Description == "" {
+		item.Description = itemType.Name
+	}
+	if item.Unit == "" {
+		item.Unit = itemType.Unit
+	}
+	if !unitPrice.Valid && !item.MaterialID.Valid {
+		http.Redirect(w, r, redirectURL+"?error=item_price#itens", http.StatusFound)
+		return
+	}
+
+	if err := c.QuoteModel.AddItem(item, auditActor(r)); err != nil {
+		if err == models.ErrQuoteNotEditable {
+			http.Redirect(w, r, redirectURL+"?error=not_editable#itens", http.StatusFound)
+			return
+		}
+		http.Error(w, "Erro ao incluir item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=item_added#itens", http.StatusFound)
+}
+
+// DeleteItem - Remove uma linha do orçamento (admin, apenas rascunho)
+func (c *QuoteController) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	quoteID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+	itemID, err := strconv.Atoi(vars["item_id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/orcamentos/%d", quoteID)
+
+	err = c.QuoteModel.DeleteItem(quoteID, itemID, auditActor(r))
+	switch {
+	case err == models.ErrQuoteNotEditable:
+		http.Redirect(w, r, redirectURL+"?error=not_editable#itens", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Item não encontrado", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao remover item: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=item_deleted#itens", http.StatusFound)
+}
+
+// SendQuote - Envia o orçamento ao cliente e avisa pelo WhatsApp (admin)
+func (c *QuoteController) SendQuote(w http.ResponseWriter, r *http.Request) {
+	quoteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/orcamentos/%d", quoteID)
+
+	err = c.QuoteModel.Send(quoteID, auditActor(r))
+	switch {
+	case err == models.ErrQuoteEmpty:
+		http.Redirect(w, r, redirectURL+"?error=empty", http.StatusFound)
+		return
+	case err == models.ErrQuoteNotEditable:
+		http.Redirect(w, r, redirectURL+"?error=not_editable", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Orçamento não encontrado", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao enviar orçamento: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if quote, err := c.QuoteModel.GetByID(quoteID); err == nil {
+		c.notifyClient(quote)
+	}
+
+	http.Redirect(w, r, redirectURL+"?success=sent", http.StatusFound)
+}
+
+// ConvertQuote - Gera o contrato em rascunho a partir do orçamento aceito (admin)
+func (c *QuoteController) ConvertQuote(w http.ResponseWriter, r *http.Request) {
+	quoteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	redirectURL := fmt.Sprintf("/admin/orcamentos/%d", quoteID)
+
+	contractID, err := c.QuoteModel.ConvertToContract(quoteID, auditActor(r))
+	switch {
+	case err == models.ErrQuoteNotAccepted:
+		http.Redirect(w, r, redirectURL+"?error=not_accepted", http.StatusFound)
+		return
+	case err == models.ErrQuoteContractExists:
+		http.Redirect(w, r, redirectURL+"?error=contract_exists", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Orçamento não encontrado", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao gerar contrato: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/contratos/%d?success=created", contractID), http.StatusFound)
+}
+
+// notifyClient avisa o cliente pelo WhatsApp que há um orçamento para analisar
+func (c *QuoteController) notifyClient(quote *models.Quote) {
+	if quote.ClientPhone == "" || !wantsNotification(quote.UserID, models.NotifyQuotes) {
+		return
+	}
+
+	message := fmt.Sprintf("📄 *Novo Orçamento*\n\nOlá %s!\n\nSeu orçamento *%s* (%s) está disponível.\n\n"+
+		"*Valor:* R$ %.2f\n*Válido até:* %s\n\nAcesse o sistema para aceitar ou recusar.\n\n_Martins Poços - Sistema Automatizado_",
+		quote.ClientName, quote.QuoteNumber, quote.ServiceTypeName, quote.Total(), quote.ValidUntil.Format("02/01/2006"))
+
+	if err := c.WhatsAppService.SendMessage(quote.ClientPhone, message); err != nil {
+		log.Printf("⚠️ Erro ao avisar cliente sobre o orçamento %d: %v", quote.ID, err)
+	}
+}
+
+// ==================== CLIENTE ====================
+
+// ClientQuotes - Lista os orçamentos recebidos pelo cliente
+func (c *QuoteController) ClientQuotes(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).UserID
+	userName := currentUser(r).UserName
+
+	quotes, err := c.QuoteModel.GetByUserID(userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar orçamentos", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Quotes            []models.Quote
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Quotes:            quotes,
+		UserName:          userName,
+		PageTitle:         "Meus Orçamentos",
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           false,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/cliente_orcamentos.html",
+	}, data)
+}
+
+// ClientViewQuote - Cliente visualiza o orçamento e pode aceitar ou recusar
+func (c *QuoteController) ClientViewQuote(w http.ResponseWriter, r *http.Request) {
+	quoteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+	userName := currentUser(r).UserName
+
+	quote, err := c.QuoteModel.GetByIDAndUser(quoteID, userID)
+	if err != nil {
+		http.Error(w, "Orçamento não encontrado", http.StatusNotFound)
+		return
+	}
+
+	data := struct {
+		Quote             *models.Quote
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Quote:             quote,
+		UserName:          userName,
+		PageTitle:         "Orçamento " + quote.QuoteNumber,
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           false,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/cliente_orcamento.html",
+	}, data)
+}
+
+// ClientAcceptQuote - Cliente aceita o orçamento
+func (c *QuoteController) ClientAcceptQuote(w http.ResponseWriter, r *http.Request) {
+	c.respond(w, r, true)
+}
+
+// ClientRejectQuote - Cliente recusa o orçamento
+func (c *QuoteController) ClientRejectQuote(w http.ResponseWriter, r *http.Request) {
+	c.respond(w, r, false)
+}
+
+func (c *QuoteController) respond(w http.ResponseWriter, r *http.Request, accept bool) {
+	quoteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+
+	redirectURL := fmt.Sprintf("/orcamentos/%d", quoteID)
+
+	err = c.QuoteModel.Respond(quoteID, userID, accept, strings.TrimSpace(r.FormValue("response")))
+	switch {
+	case err == models.ErrQuoteNotOpen:
+		http.Redirect(w, r, redirectURL+"?error=already_answered", http.StatusFound)
+		return
+	case err == models.ErrQuoteExpired:
+		http.Redirect(w, r, redirectURL+"?error=expired", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Orçamento não encontrado", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao registrar resposta: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if accept {
+		http.Redirect(w, r, redirectURL+"?success=accepted", http.StatusFound)
+	} else {
+		http.Redirect(w, r, redirectURL+"?success=rejected", http.StatusFound)
+	}
+}
+
+func (c *QuoteController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "created":
+		return "Orçamento criado! Inclua os itens e envie ao cliente."
+	case "updated":
+		return "Orçamento atualizado."
+	case "item_added":
+		return "Item incluído no orçamento."
+	case "item_deleted":
+		return "Item removido do orçamento."
+	case "sent":
+		return "Orçamento enviado ao cliente."
+	case "accepted":
+		return "Orçamento aceito! Em breve enviaremos o contrato para assinatura."
+	case "rejected":
+		return "Orçamento recusado. Obrigado pelo retorno."
+	default:
+		return ""
+	}
+}
+
+func (c *QuoteController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "valid_until":
+		return "Informe uma data de validade válida."
+	case "discount":
+		return "Desconto inválido."
+	case "tax":
+		return "Percentual de impostos deve estar entre 0 e 100."
+	case "item_type":
+		return "Selecione o tipo do item."
+	case "item_quantity":
+		return "Informe uma quantidade maior que zero."
+	case "item_price":
+		return "Informe um preço unitário válido."
+	case "item_material":
+		return "Selecione um material ativo do catálogo."
+	case "not_editable":
+		return "O orçamento já foi enviado e não pode mais ser alterado."
+	case "empty":
+		return "Inclua ao menos um item antes de enviar o orçamento."
+	case "not_accepted":
+		return "Apenas orçamentos aceitos pelo cliente podem virar contrato."
+	case "contract_exists":
+		return "Esta solicitação já possui um contrato."
+	case "already_answered":
+		return "Este orçamento já foi respondido."
+	case "expired":
+		return "Este orçamento está vencido. Solicite um novo orçamento."
+	default:
+		return ""
+	}
+}
+
+func (c *QuoteController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
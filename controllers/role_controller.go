@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"martins-pocos/models"
+)
+
+type RoleController struct {
+	RoleModel *models.RoleModel
+}
+
+func NewRoleController(roleModel *models.RoleModel) *RoleController {
+	return &RoleController{RoleModel: roleModel}
+}
+
+// ListUsers - Usuários com seus papéis e a matriz de permissões por papel
+func (c *RoleController) ListUsers(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("q")
+	roleFilter := r.URL.Query().Get("papel")
+
+	users, err := c.RoleModel.GetUsers(search, roleFilter)
+	if err != nil {
+		http.Error(w, "Erro ao buscar usuários", http.StatusInternalServerError)
+		return
+	}
+
+	roles, err := c.RoleModel.GetAllRoles()
+	if err != nil {
+		http.Error(w, "Erro ao buscar papéis", http.StatusInternalServerError)
+		return
+	}
+
+	permissions, err := c.RoleModel.GetAllPermissions()
+	if err != nil {
+		http.Error(w, "Erro ao buscar permissões", http.StatusInternalServerError)
+		return
+	}
+
+	user := currentUser(r)
+
+	data := struct {
+		Users             []models.RoleUser
+		Roles             []models.Role
+		Permissions       []models.Permission
+		Search            string
+		RoleFilter        string
+		CurrentUserID     int
+		CanRevokeSessions bool
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Users:             users,
+		Roles:             roles,
+		Permissions:       permissions,
+		Search:            search,
+		RoleFilter:        roleFilter,
+		CurrentUserID:     user.UserID,
+		CanRevokeSessions: user.HasPermission(models.PermUsersSecurity),
+		UserName:          user.UserName,
+		PageTitle:         "Usuários e Papéis",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_usuarios.html",
+	}, data)
+}
+
+// AssignRole - Troca o papel de um usuário
+func (c *RoleController) AssignRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userID, _ := strconv.Atoi(vars["id"])
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	err := c.RoleModel.AssignRole(userID, r.FormValue("role"), auditActor(r))
+	switch {
+	case errors.Is(err, models.ErrLastOwner):
+		c.redirectBack(w, r, "error", "last_owner")
+		return
+	case errors.Is(err, models.ErrRoleNotFound):
+		c.redirectBack(w, r, "error", "role_not_found")
+		return
+	case err != nil:
+		http.Error(w, "Erro ao atribuir papel", http.StatusInternalServerError)
+		return
+	}
+
+	c.redirectBack(w, r, "success", "role_assigned")
+}
+
+// UpdateRolePermissions - Define as permissões concedidas por um papel
+func (c *RoleController) UpdateRolePermissions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	roleID, _ := strconv.Atoi(vars["id"])
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	err := c.RoleModel.SetRolePermissions(roleID, r.Form["permissions"], auditActor(r))
+	switch {
+	case errors.Is(err, models.ErrOwnerLockedOut):
+		c.redirectBack(w, r, "error", "owner_locked_out")
+		return
+	case errors.Is(err, models.ErrRoleNotFound):
+		c.redirectBack(w, r, "error", "role_not_found")
+		return
+	case err != nil:
+		http.Error(w, "Erro ao salvar permissões", http.StatusInternalServerError)
+		return
+	}
+
+	c.redirectBack(w, r, "success", "permissions_saved")
+}
+
+// redirectBack volta à tela de usuários mantendo a busca e o filtro
+func (c *RoleController) redirectBack(w http.ResponseWriter, r *http.Request, key, value string) {
+	q := url.Values{}
+	if search := r.FormValue("q"); search != "" {
+		q.Set("q", search)
+	}
+	if role := r.FormValue("papel"); role != "" {
+		q.Set("papel", role)
+	}
+	q.Set(key, value)
+	http.Redirect(w, r, "/admin/usuarios?"+q.Encode(), http.StatusFound)
+}
+
+func (c *RoleController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "role_assigned":
+		return "Papel atribuído com sucesso."
+	case "permissions_saved":
+		return "Permissões do papel atualizadas. Valem a partir da próxima requisição de cada usuário."
+	case "sessions_revoked":
+		return "Sessões do usuário encerradas: " + r.URL.Query().Get("n") + ". Os tokens da API foram revogados."
+	default:
+		return ""
+	}
+}
+
+func (c *RoleController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "last_owner":
+		return models.ErrLastOwner.Error() + "."
+	case "owner_locked_out":
+		return models.ErrOwnerLockedOut.Error() + "."
+	case "role_not_found":
+		return models.ErrRoleNotFound.Error() + "."
+	default:
+		return ""
+	}
+}
+
+func (c *RoleController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"martins-pocos/config"
+	"martins-pocos/models"
+	"martins-pocos/services"
+)
+
+type RouteController struct {
+	ServiceModel    *models.ServiceModel
+	UserModel       *models.UserModel
+	WhatsAppService *services.WhatsAppService
+	Planner         *services.RoutePlanner
+}
+
+func NewRouteController(serviceModel *models.ServiceModel, userModel *models.UserModel, whatsappService *services.WhatsAppService) *RouteController {
+	return &RouteController{
+		ServiceModel:    serviceModel,
+		UserModel:       userModel,
+		WhatsAppService: whatsappService,
+		Planner:         services.NewRoutePlanner(services.NewDistanceEstimator()),
+	}
+}
+
+// routeView agrupa o resultado da roteirização para as telas
+type routeView struct {
+	Date          time.Time
+	Technician    *models.User
+	Route         *services.DailyRoute
+	Unlocated     []services.RouteStop
+	TotalDuration string
+}
+
+// DailyRoute - Tela de roteirização das visitas confirmadas do dia (admin)
+func (c *RouteController) DailyRoute(w http.ResponseWriter, r *http.Request) {
+	date, technicianID := c.parseRouteParams(r)
+
+	view, err := c.buildRoute(date, technicianID)
+	if err != nil {
+		http.Error(w, "Erro ao montar rota: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	technicians, err := c.UserModel.GetTechnicians(technicianID)
+	if err != nil {
+		http.Error(w, "Erro ao carregar técnicos", http.StatusInternalServerError)
+		return
+	}
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		View              *routeView
+		Technicians       []models.User
+		TechnicianID      int
+		DateValue         string
+		SuccessMsg        string
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		View:              view,
+		Technicians:       technicians,
+		TechnicianID:      technicianID,
+		DateValue:         date.Format("2006-01-02"),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		UserName:          userName,
+		PageTitle:         "Roteiro de Visitas",
+		CustomCSS:         "/static/css/routes.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_rotas.html",
+	}, data)
+}
+
+// PrintRoute - Folha de rota para impressão
+func (c *RouteController) PrintRoute(w http.ResponseWriter, r *http.Request) {
+	date, technicianID := c.parseRouteParams(r)
+
+	view, err := c.buildRoute(date, technicianID)
+	if err != nil {
+		http.Error(w, "Erro ao montar rota: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		View        *routeView
+		GeneratedAt time.Time
+	}{
+		View:        view,
+		GeneratedAt: time.Now(),
+	}
+
+	c.renderTemplate(w, r, []string{"templates/admin_rota_impressao.html"}, data)
+}
+
+// SendRouteWhatsApp - Envia o resumo do roteiro para o técnico
+func (c *RouteController) SendRouteWhatsApp(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	date, technicianID := c.parseRouteParams(r)
+	redirectURL := fmt.Sprintf("/admin/rotas?date=%s&technician_id=%d", date.Format("2006-01-02"), technicianID)
+
+	if technicianID == 0 {
+		http.Redirect(w, r, redirectURL+"&error=no_technician", http.StatusFound)
+		return
+	}
+
+	view, err := c.buildRoute(date, technicianID)
+	if err != nil {
+		http.Error(w, "Erro ao montar rota: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if view.Technician == nil || view.Technician.Phone == "" {
+		http.Redirect(w, r, redirectURL+"&error=no_phone", http.StatusFound)
+		return
+	}
+
+	if len(view.Route.Legs) == 0 && len(view.Unlocated) == 0 {
+		http.Redirect(w, r, redirectURL+"&error=empty", http.StatusFound)
+		return
+	}
+
+	message := services.BuildRouteSummary(view.Technician.Name, date, view.Route, view.Unlocated)
+	if err := c.WhatsAppService.SendMessage(view.Technician.Phone, message); err != nil {
+		http.Redirect(w, r, redirectURL+"&error=whatsapp", http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, redirectURL+"&success=sent", http.StatusFound)
+}
+
+// buildRoute busca as visitas confirmadas e calcula a ordem ideal
+func (c *RouteController) buildRoute(date time.Time, technicianID int) (*routeView, error) {
+	visits, err := c.ServiceModel.GetScheduledVisits(date, technicianID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &routeView{Date: date}
+
+	if technicianID > 0 {
+		technician, err := c.UserModel.GetByID(technicianID)
+		if err == nil {
+			view.Technician = technician
+		}
+	}
+
+	var stops []services.RouteStop
+	for _, visit := range visits {
+		stop := services.RouteStop{
+			RequestID: visit.ID,
+			Label:     fmt.Sprintf("#%d %s - %s", visit.ID, visit.FullName, visit.ServiceTypeName),
+			Address:   fmt.Sprintf("%s, %s - %s, %s/%s", visit.Logradouro, visit.Numero, visit.Bairro, visit.Cidade, visit.Estado),
+			Time:      visitTime(visit.PreferredTime),
+		}
+
+		if !visit.HasCoordinates() {
+			view.Unlocated = append(view.Unlocated, stop)
+			continue
+		}
+
+		stop.Point = services.GeoPoint{Lat: visit.Latitude.Float64, Lng: visit.Longitude.Float64}
+		stops = append(stops, stop)
+	}
+
+	baseLat, baseLng := config.GetCompanyBase()
+	view.Route = c.Planner.Plan(services.GeoPoint{Lat: baseLat, Lng: baseLng}, stops)
+	view.TotalDuration = services.FormatMinutes(view.Route.TotalMinutes)
+
+	return view, nil
+}
+
+// parseRouteParams lê data (padrão: hoje) e técnico da query ou formulário
+func (c *RouteController) parseRouteParams(r *http.Request) (time.Time, int) {
+	date, err := time.Parse("2006-01-02", r.FormValue("date"))
+	if err != nil {
+		now := time.Now()
+		date = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	technicianID, _ := strconv.Atoi(r.FormValue("technician_id"))
+	return date, technicianID
+}
+
+// visitTime extrai HH:MM do horário retornado pelo banco
+func visitTime(preferredTime string) string {
+	if len(preferredTime) >= 16 {
+		return preferredTime[11:16]
+	}
+	if len(preferredTime) >= 5 {
+		return preferredTime[:5]
+	}
+	return preferredTime
+}
+
+func (c *RouteController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "sent":
+		return "Roteiro enviado ao técnico via WhatsApp!"
+	default:
+		return ""
+	}
+}
+
+func (c *RouteController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "no_technician":
+		return "Selecione um técnico para enviar o roteiro."
+	case "no_phone":
+		return "O técnico selecionado não possui telefone cadastrado."
+	case "empty":
+		return "Não há visitas confirmadas para este dia."
+	case "whatsapp":
+		return "Não foi possível enviar a mensagem pelo WhatsApp."
+	default:
+		return ""
+	}
+}
+
+func (c *RouteController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"martins-pocos/models"
+)
+
+// Quantidade de tentativas exibidas na tela de acessos
+const loginAttemptsPageSize = 200
+
+type SecurityController struct {
+	LoginAttemptModel *models.LoginAttemptModel
+	SessionModel      *models.SessionModel
+}
+
+func NewSecurityController(loginAttemptModel *models.LoginAttemptModel, sessionModel *models.SessionModel) *SecurityController {
+	return &SecurityController{
+		LoginAttemptModel: loginAttemptModel,
+		SessionModel:      sessionModel,
+	}
+}
+
+// LoginAttempts - Contas e IPs bloqueados e histórico de tentativas de login
+func (c *SecurityController) LoginAttempts(w http.ResponseWriter, r *http.Request) {
+	search := r.URL.Query().Get("q")
+	onlyFailures := r.URL.Query().Get("falhas") == "1"
+
+	locked, err := c.LoginAttemptModel.GetLocked()
+	if err != nil {
+		http.Error(w, "Erro ao buscar bloqueios", http.StatusInternalServerError)
+		return
+	}
+
+	lockedIPs, err := c.LoginAttemptModel.GetLockedIPs()
+	if err != nil {
+		http.Error(w, "Erro ao buscar bloqueios", http.StatusInternalServerError)
+		return
+	}
+
+	attempts, err := c.LoginAttemptModel.GetRecent(search, onlyFailures, loginAttemptsPageSize)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tentativas", http.StatusInternalServerError)
+		return
+	}
+
+	user := currentUser(r)
+
+	data := struct {
+		Locked            []models.LockedLogin
+		LockedIPs         []models.LockedLogin
+		Attempts          []models.LoginAttempt
+		Search            string
+		OnlyFailures      bool
+		PageSize          int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Locked:            locked,
+		LockedIPs:         lockedIPs,
+		Attempts:          attempts,
+		Search:            search,
+		OnlyFailures:      onlyFailures,
+		PageSize:          loginAttemptsPageSize,
+		UserName:          user.UserName,
+		PageTitle:         "Acessos",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          "",
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_acessos.html",
+	}, data)
+}
+
+// Unlock - Libera uma conta (e-mail ou telefone) bloqueada
+func (c *SecurityController) Unlock(w http.ResponseWriter, r *http.Request) {
+	identifier := strings.TrimSpace(r.FormValue("identifier"))
+	if identifier == "" {
+		http.Redirect(w, r, "/admin/acessos", http.StatusFound)
+		return
+	}
+
+	if err := c.LoginAttemptModel.Unlock(identifier, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao desbloquear", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/acessos?success=unlocked&item="+url.QueryEscape(identifier), http.StatusFound)
+}
+
+// UnlockIP - Libera um IP bloqueado
+func (c *SecurityController) UnlockIP(w http.ResponseWriter, r *http.Request) {
+	ip := strings.TrimSpace(r.FormValue("ip"))
+	if ip == "" {
+		http.Redirect(w, r, "/admin/acessos", http.StatusFound)
+		return
+	}
+
+	if err := c.LoginAttemptModel.UnlockIP(ip, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao desbloquear", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/acessos?success=unlocked&item="+url.QueryEscape(ip), http.StatusFound)
+}
+
+// RevokeUserSessions - Encerra todas as sessões de um usuário (ex.: aparelho perdido)
+func (c *SecurityController) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	count, err := c.SessionModel.RevokeAll(userID, auditActor(r))
+	if err != nil {
+		http.Error(w, "Erro ao encerrar sessões", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/usuarios?success=sessions_revoked&n="+strconv.FormatInt(count, 10), http.StatusFound)
+}
+
+func (c *SecurityController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "unlocked":
+		return "Acesso liberado: " + r.URL.Query().Get("item") + "."
+	default:
+		return ""
+	}
+}
+
+func (c *SecurityController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"strconv"
 	"time"
 
-	"martins-pocos/config"
 	"martins-pocos/models"
 
 	"github.com/gorilla/mux"
@@ -16,10 +15,14 @@ import (
 
 type ServiceController struct {
 	ServiceModel *models.ServiceModel
+	AddressModel *models.UserAddressModel
 }
 
-func NewServiceController(serviceModel *models.ServiceModel) *ServiceController {
-	return &ServiceController{ServiceModel: serviceModel}
+func NewServiceController(serviceModel *models.ServiceModel, addressModel *models.UserAddressModel) *ServiceController {
+	return &ServiceController{
+		ServiceModel: serviceModel,
+		AddressModel: addressModel,
+	}
 }
 
 // PageData estrutura comum para todas as páginas
@@ -91,8 +94,7 @@ func (c *ServiceController) ClienteDashboard(w http.ResponseWriter, r *http.Requ
 		allRequests = []models.ServiceRequest{}
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	userName := currentUser(r).UserName
 
 	data := struct {
 		UserName          string
@@ -142,7 +144,7 @@ func (c *ServiceController) ClienteDashboard(w http.ResponseWriter, r *http.Requ
 		IsAdmin:           false,
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -206,11 +208,17 @@ func (c *ServiceController) VerSolicitacao(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	customFields, err := c.ServiceModel.CustomFieldAnswers(service)
+	if err != nil {
+		http.Error(w, "Erro ao carregar informações adicionais", http.StatusInternalServerError)
+		return
+	}
+
+	userName := currentUser(r).UserName
 
 	data := struct {
 		Service           *models.ServiceRequest
+		CustomFields      []models.CustomFieldAnswer
 		UserName          string
 		PageTitle         string
 		CustomCSS         string
@@ -220,6 +228,7 @@ func (c *ServiceController) VerSolicitacao(w http.ResponseWriter, r *http.Reques
 		IsAdmin           bool
 	}{
 		Service:           service,
+		CustomFields:      customFields,
 		UserName:          userName,
 		PageTitle:         "Detalhes da Solicitação",
 		CustomCSS:         "../static/css/ver_solicitacao.css",
@@ -229,7 +238,7 @@ func (c *ServiceController) VerSolicitacao(w http.ResponseWriter, r *http.Reques
 		IsAdmin:           false,
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
@@ -261,9 +270,8 @@ func (c *ServiceController) CancelarSolicitacao(w http.ResponseWriter, r *http.R
 
 // Helper methods
 func (c *ServiceController) getUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userID, ok := session.Values["user_id"].(int)
-	if !ok {
+	userID := currentUser(r).UserID
+	if userID == 0 {
 		http.Redirect(w, r, "/login", http.StatusFound)
 		return 0, false
 	}
@@ -298,9 +306,9 @@ func (c *ServiceController) getSuccessMessage(r *http.Request) string {
 	}
 }
 
-func (c *ServiceController) renderTemplate(w http.ResponseWriter, templatePaths []string, data interface{}) {
+func (c *ServiceController) renderTemplate(w http.ResponseWriter, r *http.Request, templatePaths []string, data interface{}) {
 	// Criar template com funções auxiliares
-	tmpl := template.New("").Funcs(GetTemplateFuncs())
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
 	
 	// Parsear todos os templates
 	var err error
@@ -343,11 +351,26 @@ func (c *ServiceController) showServiceRequestForm(w http.ResponseWriter, r *htt
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName := session.Values["user_name"].(string)
+	user := currentUser(r)
+
+	addresses, err := c.AddressModel.GetByUser(user.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao carregar endereços", http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := c.ServiceModel.GetActiveServiceFieldsByType()
+	if err != nil {
+		http.Error(w, "Erro ao carregar campos do formulário", http.StatusInternalServerError)
+		return
+	}
 
 	data := struct {
 		ServiceTypes      []models.ServiceType
+		Fields            map[int][]models.ServiceField
+		Values            models.CustomFieldValues
+		Addresses         []models.UserAddress
+		CanSaveAddress    bool
 		UserName          string
 		PageTitle         string
 		CustomCSS         string
@@ -357,7 +380,11 @@ func (c *ServiceController) showServiceRequestForm(w http.ResponseWriter, r *htt
 		IsAdmin           bool
 	}{
 		ServiceTypes:      serviceTypes,
-		UserName:          userName,
+		Fields:            fields,
+		Values:            models.CustomFieldValues{},
+		Addresses:         addresses,
+		CanSaveAddress:    len(addresses) < models.MaxUserAddresses,
+		UserName:          user.UserName,
 		PageTitle:         "Solicitar Serviço",
 		CustomCSS:         "../static/css/solicitar_servico.css",
 		CustomJS:          "../static/js/solicitar_servico.js",
@@ -366,11 +393,12 @@ func (c *ServiceController) showServiceRequestForm(w http.ResponseWriter, r *htt
 		IsAdmin:           false,
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
 		"templates/components/scripts.html",
+		"templates/components/custom_fields.html",
 		"templates/solicitar_servico.html",
 	}, data)
 }
@@ -393,15 +421,19 @@ func (c *ServiceController) showEditForm(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	session, _ := config.GetSessionStore().Get(r, "session")
-	userName, ok := session.Values["user_name"].(string)
-	if !ok {
-		userName = "Usuário"
+	fields, err := c.ServiceModel.GetActiveServiceFieldsByType()
+	if err != nil {
+		http.Error(w, "Erro ao carregar campos do formulário", http.StatusInternalServerError)
+		return
 	}
 
+	userName := currentUser(r).UserName
+
 	data := struct {
 		Service           *models.ServiceRequest
 		ServiceTypes      []models.ServiceType
+		Fields            map[int][]models.ServiceField
+		Values            models.CustomFieldValues
 		UserName          string
 		PageTitle         string
 		CustomCSS         string
@@ -412,6 +444,8 @@ func (c *ServiceController) showEditForm(w http.ResponseWriter, r *http.Request,
 	}{
 		Service:           service,
 		ServiceTypes:      serviceTypes,
+		Fields:            fields,
+		Values:            service.CustomFields,
 		UserName:          userName,
 		PageTitle:         "Editar Solicitação",
 		CustomCSS:         "/static/css/solicitar_servico.css",
@@ -421,11 +455,12 @@ func (c *ServiceController) showEditForm(w http.ResponseWriter, r *http.Request,
 		IsAdmin:           false,
 	}
 
-	c.renderTemplate(w, []string{
+	c.renderTemplate(w, r, []string{
 		"templates/components/head.html",
 		"templates/components/navbar.html",
 		"templates/components/footer.html",
 		"templates/components/scripts.html",
+		"templates/components/custom_fields.html",
 		"templates/editar_solicitacao.html",
 	}, data)
 }
\ No newline at end of file
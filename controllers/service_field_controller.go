@@ -0,0 +1,358 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"martins-pocos/models"
+
+	"github.com/gorilla/mux"
+)
+
+// ServiceFieldController cadastra os campos adicionais que o formulário de
+// solicitação pede para cada tipo de serviço
+type ServiceFieldController struct {
+	ServiceModel *models.ServiceModel
+}
+
+func NewServiceFieldController(serviceModel *models.ServiceModel) *ServiceFieldController {
+	return &ServiceFieldController{ServiceModel: serviceModel}
+}
+
+func serviceFieldsURL(serviceTypeID int) string {
+	return "/admin/cadastros/tipos-servico/" + strconv.Itoa(serviceTypeID) + "/campos"
+}
+
+// serviceTypeFromRequest resolve o tipo de serviço da URL; responde 404 se não existir
+func (c *ServiceFieldController) serviceTypeFromRequest(w http.ResponseWriter, r *http.Request) (*models.ServiceType, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return nil, false
+	}
+
+	serviceType, err := c.ServiceModel.GetServiceTypeByID(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Tipo de serviço não encontrado", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar tipo de serviço", http.StatusInternalServerError)
+		return nil, false
+	}
+	return serviceType, true
+}
+
+// fieldFromRequest resolve o campo da URL e o seu tipo de serviço
+func (c *ServiceFieldController) fieldFromRequest(w http.ResponseWriter, r *http.Request) (*models.ServiceField, *models.ServiceType, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	field, err := c.ServiceModel.GetServiceField(id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Campo não encontrado", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar campo", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	serviceType, err := c.ServiceModel.GetServiceTypeByID(field.ServiceTypeID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tipo de serviço", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return field, serviceType, true
+}
+
+// List - Campos do tipo de serviço, com reordenação por arrastar e ativação
+func (c *ServiceFieldController) List(w http.ResponseWriter, r *http.Request) {
+	serviceType, ok := c.serviceTypeFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := c.ServiceModel.GetServiceFields(serviceType.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar campos", http.StatusInternalServerError)
+		return
+	}
+
+	user := currentUser(r)
+
+	data := struct {
+		ServiceType       *models.ServiceType
+		Fields            []models.ServiceField
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		ServiceType:       serviceType,
+		Fields:            fields,
+		UserName:          user.UserName,
+		PageTitle:         "Campos do formulário - " + serviceType.Name,
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_campos_servico.html",
+	}, data)
+}
+
+// Create - Novo campo no fim do formulário do tipo de serviço
+func (c *ServiceFieldController) Create(w http.ResponseWriter, r *http.Request) {
+	serviceType, ok := c.serviceTypeFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	field := &models.ServiceField{ServiceTypeID: serviceType.ID, FieldType: models.FieldTypeText, Active: true}
+
+	if r.Method == "GET" {
+		c.showForm(w, r, serviceType, field, "")
+		return
+	}
+
+	if err := c.parseForm(r, field); err != nil {
+		c.showForm(w, r, serviceType, field, err.Error())
+		return
+	}
+
+	err := c.ServiceModel.CreateServiceField(field, auditActor(r))
+	if err == models.ErrFieldKeyTaken {
+		c.showForm(w, r, serviceType, field, "Já existe um campo com esta chave neste tipo de serviço.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao cadastrar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, serviceFieldsURL(serviceType.ID)+"?success=created", http.StatusFound)
+}
+
+// Edit - Editar rótulo, tipo, opções e obrigatoriedade do campo
+func (c *ServiceFieldController) Edit(w http.ResponseWriter, r *http.Request) {
+	field, serviceType, ok := c.fieldFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == "GET" {
+		c.showForm(w, r, serviceType, field, "")
+		return
+	}
+
+	if err := c.parseForm(r, field); err != nil {
+		c.showForm(w, r, serviceType, field, err.Error())
+		return
+	}
+
+	if err := c.ServiceModel.UpdateServiceField(field, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao atualizar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, serviceFieldsURL(serviceType.ID)+"?success=updated", http.StatusFound)
+}
+
+// SetActive - Ativa ou desativa o campo no formulário
+func (c *ServiceFieldController) SetActive(w http.ResponseWriter, r *http.Request) {
+	field, _, ok := c.fieldFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	active := r.FormValue("active") == "1"
+	err := c.ServiceModel.SetServiceFieldActive(field.ID, active, auditActor(r))
+	if err == sql.ErrNoRows {
+		http.Error(w, "Campo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao alterar campo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	success := "activated"
+	if !active {
+		success = "deactivated"
+	}
+	http.Redirect(w, r, serviceFieldsURL(field.ServiceTypeID)+"?success="+success, http.StatusFound)
+}
+
+// Reorder - Grava a ordem definida arrastando as linhas da lista
+func (c *ServiceFieldController) Reorder(w http.ResponseWriter, r *http.Request) {
+	serviceType, ok := c.serviceTypeFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	var ids []int
+	for _, value := range r.Form["ids"] {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	err := c.ServiceModel.ReorderServiceFields(serviceType.ID, ids, auditActor(r))
+	if err == models.ErrFieldInvalidOrder {
+		http.Redirect(w, r, serviceFieldsURL(serviceType.ID)+"?error=order", http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao reordenar: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, serviceFieldsURL(serviceType.ID)+"?success=reordered", http.StatusFound)
+}
+
+func (c *ServiceFieldController) showForm(w http.ResponseWriter, r *http.Request, serviceType *models.ServiceType, field *models.ServiceField, errorMsg string) {
+	user := currentUser(r)
+
+	pageTitle := "Novo campo"
+	if field.ID > 0 {
+		pageTitle = "Editar campo"
+	}
+
+	data := struct {
+		ServiceType       *models.ServiceType
+		Field             *models.ServiceField
+		FieldTypes        []models.ServiceFieldType
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		ServiceType:       serviceType,
+		Field:             field,
+		FieldTypes:        models.ServiceFieldTypes,
+		ErrorMsg:          errorMsg,
+		UserName:          user.UserName,
+		PageTitle:         pageTitle,
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_campo_servico_form.html",
+	}, data)
+}
+
+// parseForm preenche o campo com os dados do formulário. A chave só é
+// lida no cadastro; depois não muda, para não perder respostas gravadas.
+func (c *ServiceFieldController) parseForm(r *http.Request, field *models.ServiceField) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Erro ao processar formulário")
+	}
+
+	if field.ID == 0 {
+		field.Key = r.FormValue("field_key")
+	}
+	field.Label = r.FormValue("label")
+	field.FieldType = r.FormValue("field_type")
+	field.HelpText = r.FormValue("help_text")
+	field.Required = r.FormValue("required") == "on"
+	field.SetOptionsText(r.FormValue("options"))
+
+	switch field.Normalize() {
+	case models.ErrFieldInvalidKey:
+		return fmt.Errorf("Chave inválida: use letras minúsculas, números e _ (até 50 caracteres)")
+	case models.ErrFieldInvalidType:
+		return fmt.Errorf("Escolha um tipo de campo da lista")
+	case models.ErrFieldNoOptions:
+		return fmt.Errorf("Informe ao menos uma opção, uma por linha")
+	}
+	if field.Label == "" || len([]rune(field.Label)) > 100 {
+		return fmt.Errorf("Informe o rótulo (até 100 caracteres)")
+	}
+	if len([]rune(field.HelpText)) > 255 {
+		return fmt.Errorf("O texto de ajuda aceita até 255 caracteres")
+	}
+
+	return nil
+}
+
+func (c *ServiceFieldController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "created":
+		return "Campo cadastrado com sucesso!"
+	case "updated":
+		return "Campo atualizado com sucesso!"
+	case "activated":
+		return "Campo ativado. Ele volta a aparecer no formulário."
+	case "deactivated":
+		return "Campo desativado. As respostas já gravadas continuam nas solicitações."
+	case "reordered":
+		return "Ordem salva."
+	default:
+		return ""
+	}
+}
+
+func (c *ServiceFieldController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "order":
+		return "A lista mudou enquanto você reordenava. Confira a ordem e tente de novo."
+	default:
+		return ""
+	}
+}
+
+func (c *ServiceFieldController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
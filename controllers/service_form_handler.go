@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"log"
 	"net/http"
 	"time"
 
@@ -26,6 +27,17 @@ func (c *ServiceController) createServiceRequest(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// Guarda o local nos imóveis do perfil para as próximas solicitações
+	if r.FormValue("save_address") == "1" {
+		address := addressFromForm(r)
+		address.UserID = userID
+		if err := address.Normalize(); err == nil {
+			if err := c.AddressModel.CreateIfMissing(address, utils.ClientIP(r)); err != nil {
+				log.Printf("⚠️ Erro ao salvar endereço da solicitação %d: %v", service.ID, err)
+			}
+		}
+	}
+
 	http.Redirect(w, r, "/dashboard/cliente?success=created", http.StatusFound)
 }
 
@@ -70,6 +82,20 @@ func (c *ServiceController) parseServiceRequestForm(r *http.Request, userID int)
 		return nil, err
 	}
 
+	// Campos adicionais do tipo escolhido, enviados como cf_<chave>
+	fields, err := c.ServiceModel.GetActiveServiceFields(serviceType.ID)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]string{}
+	for _, field := range fields {
+		raw[field.Key] = r.FormValue("cf_" + field.Key)
+	}
+	customFields, err := models.ParseCustomFields(fields, raw)
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.ServiceRequest{
 		UserID:        userID,
 		FullName:      r.FormValue("full_name"),
@@ -83,5 +109,6 @@ func (c *ServiceController) parseServiceRequestForm(r *http.Request, userID int)
 		Estado:        r.FormValue("estado"),
 		PreferredDate: preferredDate,
 		PreferredTime: preferredTime.Format("15:04"),
+		CustomFields:  customFields,
 	}, nil
 }
\ No newline at end of file
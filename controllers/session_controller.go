@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"martins-pocos/config"
+	"martins-pocos/models"
+)
+
+type SessionController struct {
+	SessionModel *models.SessionModel
+}
+
+func NewSessionController(sessionModel *models.SessionModel) *SessionController {
+	return &SessionController{SessionModel: sessionModel}
+}
+
+// ListSessions - Sessões abertas da conta (dispositivo, IP e último acesso)
+func (c *SessionController) ListSessions(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+
+	sessions, err := c.SessionModel.GetActiveByUser(user.UserID, config.CurrentSessionKey(r))
+	if err != nil {
+		http.Error(w, "Erro ao buscar sessões", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Sessions          []models.UserSession
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Sessions:          sessions,
+		UserName:          user.UserName,
+		PageTitle:         "Sessões",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          "",
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/conta_sessoes.html",
+	}, data)
+}
+
+// RevokeSession - Encerra uma sessão da conta em outro dispositivo
+func (c *SessionController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// A sessão atual é encerrada pelo "Sair"
+	if id == config.CurrentSessionKey(r) {
+		http.Redirect(w, r, "/conta/sessoes", http.StatusFound)
+		return
+	}
+
+	err := c.SessionModel.Revoke(currentUser(r).UserID, id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Erro ao encerrar sessão", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/sessoes?success=revoked", http.StatusFound)
+}
+
+// RevokeOtherSessions - Encerra todas as sessões da conta menos a atual
+func (c *SessionController) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	count, err := c.SessionModel.RevokeOthers(currentUser(r).UserID, config.CurrentSessionKey(r))
+	if err != nil {
+		http.Error(w, "Erro ao encerrar sessões", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/conta/sessoes?success=others_revoked&n="+strconv.FormatInt(count, 10), http.StatusFound)
+}
+
+func (c *SessionController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "revoked":
+		return "Sessão encerrada."
+	case "others_revoked":
+		return "Sessões encerradas nos outros dispositivos: " + r.URL.Query().Get("n") + ". Os tokens da API foram revogados."
+	default:
+		return ""
+	}
+}
+
+func (c *SessionController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,12 +1,27 @@
 package controllers
 
 import (
+	"database/sql"
 	"html/template"
+	"net/http"
+	"strconv"
+
+	"martins-pocos/middleware"
 )
 
-// GetTemplateFuncs retorna funções auxiliares para os templates
-func GetTemplateFuncs() template.FuncMap {
+// GetTemplateFuncs retorna funções auxiliares para os templates. csrfField
+// e csrfToken usam o token CSRF da sessão da requisição.
+func GetTemplateFuncs(r *http.Request) template.FuncMap {
+	csrfToken := middleware.CSRFToken(r)
+
 	return template.FuncMap{
+		"csrfField": func() template.HTML {
+			return template.HTML(`<input type="hidden" name="` + middleware.CSRFFieldName + `" value="` +
+				template.HTMLEscapeString(csrfToken) + `">`)
+		},
+		"csrfToken": func() string {
+			return csrfToken
+		},
 		"add": func(a, b int) int {
 			return a + b
 		},
@@ -53,6 +68,27 @@ func GetTemplateFuncs() template.FuncMap {
 			}
 			return s[start:end]
 		},
+		"nullFloat": func(v sql.NullFloat64, decimals int) string {
+			if !v.Valid {
+				return "—"
+			}
+			return strconv.FormatFloat(v.Float64, 'f', decimals, 64)
+		},
+		"nullFloatInput": func(v sql.NullFloat64) string {
+			if !v.Valid {
+				return ""
+			}
+			return strconv.FormatFloat(v.Float64, 'f', -1, 64)
+		},
+		"qty": func(v float64) string {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		},
+		"nullDate": func(v sql.NullTime, layout string) string {
+			if !v.Valid {
+				return ""
+			}
+			return v.Time.Format(layout)
+		},
 	}
 	
 }
\ No newline at end of file
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"martins-pocos/config"
+	"martins-pocos/middleware"
+	"martins-pocos/models"
+)
+
+// Campo do formulário com o código pedido antes de ações sensíveis
+const stepUpCodeField = "totp_code"
+
+// Biblioteca que desenha o QR code do endereço otpauth:// no navegador
+const qrCodeScript = "https://cdnjs.cloudflare.com/ajax/libs/qrcodejs/1.0.0/qrcode.min.js"
+
+type TwoFactorController struct {
+	TwoFactorModel    *models.TwoFactorModel
+	LoginAttemptModel *models.LoginAttemptModel
+}
+
+func NewTwoFactorController(twoFactorModel *models.TwoFactorModel, loginAttemptModel *models.LoginAttemptModel) *TwoFactorController {
+	return &TwoFactorController{
+		TwoFactorModel:    twoFactorModel,
+		LoginAttemptModel: loginAttemptModel,
+	}
+}
+
+// Page - Configuração da verificação em duas etapas da conta
+func (c *TwoFactorController) Page(w http.ResponseWriter, r *http.Request) {
+	c.renderPage(w, r, nil, "")
+}
+
+// Enable - Confere o primeiro código do aplicativo e ativa a verificação
+func (c *TwoFactorController) Enable(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	if !user.IsAdmin() {
+		http.Error(w, "Acesso negado", http.StatusForbidden)
+		return
+	}
+
+	codes, err := c.TwoFactorModel.Enable(user.UserID, r.FormValue("code"))
+	if errors.Is(err, models.ErrInvalidTwoFactorCode) || errors.Is(err, models.ErrTwoFactorNotPending) {
+		c.renderPage(w, r, nil, capitalize(err.Error())+". Confira o horário do celular e tente novamente.")
+		return
+	}
+	if errors.Is(err, models.ErrTwoFactorAlreadyEnabled) {
+		http.Redirect(w, r, middleware.TwoFactorSetupPath, http.StatusFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao ativar verificação", http.StatusInternalServerError)
+		return
+	}
+
+	middleware.MarkTwoFactorVerified(w, r)
+	user.TwoFactor = true
+	c.renderPage(w, r, codes, "")
+}
+
+// RegenerateCodes - Gera novos códigos de recuperação (os anteriores deixam de valer)
+func (c *TwoFactorController) RegenerateCodes(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	if msg, ok := c.confirmCode(w, r); !ok {
+		c.renderPage(w, r, nil, msg)
+		return
+	}
+
+	codes, err := c.TwoFactorModel.RegenerateRecoveryCodes(user.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao gerar códigos", http.StatusInternalServerError)
+		return
+	}
+
+	c.renderPage(w, r, codes, "")
+}
+
+// Disable - Desativa a verificação em duas etapas (não permitido quando obrigatória)
+func (c *TwoFactorController) Disable(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	if config.RequireAdminTwoFactor() && user.IsAdmin() {
+		c.renderPage(w, r, nil, "A verificação em duas etapas é obrigatória para contas da empresa.")
+		return
+	}
+
+	if msg, ok := c.confirmCode(w, r); !ok {
+		c.renderPage(w, r, nil, msg)
+		return
+	}
+
+	if err := c.TwoFactorModel.Disable(user.UserID); err != nil {
+		http.Error(w, "Erro ao desativar verificação", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, middleware.TwoFactorSetupPath+"?success=disabled", http.StatusFound)
+}
+
+// RequireRecent protege ações sensíveis (assinar pela empresa, excluir
+// solicitações): quem tem a verificação ativa e não confirmou o código nos
+// últimos minutos vê uma tela pedindo o código, que reenvia o formulário
+// original. Deve ser usado dentro de RequireAuth.
+func (c *TwoFactorController) RequireRecent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := currentUser(r)
+		if !user.TwoFactor || middleware.TwoFactorRecent(r) {
+			next(w, r)
+			return
+		}
+
+		errorMsg := ""
+		if r.FormValue(stepUpCodeField) != "" {
+			msg, ok := c.confirmCode(w, r)
+			if ok {
+				next(w, r)
+				return
+			}
+			errorMsg = msg
+		}
+
+		c.renderStepUp(w, r, errorMsg)
+	}
+}
+
+// confirmCode confere o código enviado no formulário (campo totp_code ou
+// code), com os mesmos limites de tentativas do login
+func (c *TwoFactorController) confirmCode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	user := currentUser(r)
+
+	code := r.FormValue(stepUpCodeField)
+	if code == "" {
+		code = r.FormValue("code")
+	}
+
+	if msg, ok := checkLoginThrottle(c.LoginAttemptModel, w, r, user.Email, models.LoginMethodPassword); !ok {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return msg, false
+	}
+
+	_, err := c.TwoFactorModel.Verify(user.UserID, code)
+	if errors.Is(err, models.ErrInvalidTwoFactorCode) {
+		recordLogin(c.LoginAttemptModel, r, user.Email, models.LoginMethodPassword, &models.User{ID: user.UserID}, models.LoginInvalidCode)
+		w.WriteHeader(http.StatusUnauthorized)
+		return capitalize(err.Error()) + ".", false
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return "Erro ao verificar código. Tente novamente.", false
+	}
+
+	middleware.MarkTwoFactorVerified(w, r)
+	return "", true
+}
+
+// formField é um campo do formulário original reenviado após o código
+type formField struct {
+	Name  string
+	Value string
+}
+
+func (c *TwoFactorController) renderStepUp(w http.ResponseWriter, r *http.Request, errorMsg string) {
+	user := currentUser(r)
+
+	var fields []formField
+	for name, values := range r.PostForm {
+		if name == stepUpCodeField || name == middleware.CSRFFieldName {
+			continue
+		}
+		for _, value := range values {
+			fields = append(fields, formField{Name: name, Value: value})
+		}
+	}
+
+	data := struct {
+		Action            string
+		Fields            []formField
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Action:            r.URL.RequestURI(),
+		Fields:            fields,
+		UserName:          user.UserName,
+		PageTitle:         "Confirmar identidade",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        "",
+		ErrorMsg:          errorMsg,
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/confirmar_2fa.html",
+	}, data)
+}
+
+func (c *TwoFactorController) renderPage(w http.ResponseWriter, r *http.Request, recoveryCodes []string, errorMsg string) {
+	user := currentUser(r)
+	if !user.IsAdmin() {
+		http.Error(w, "Disponível apenas para contas da empresa", http.StatusForbidden)
+		return
+	}
+
+	var secret, uri string
+	remaining := 0
+	if user.TwoFactor {
+		var err error
+		remaining, err = c.TwoFactorModel.RemainingRecoveryCodes(user.UserID)
+		if err != nil {
+			http.Error(w, "Erro ao buscar códigos de recuperação", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		var err error
+		secret, err = c.TwoFactorModel.BeginEnrollment(user.UserID)
+		if err != nil {
+			http.Error(w, "Erro ao iniciar configuração", http.StatusInternalServerError)
+			return
+		}
+		uri = models.ProvisioningURI(secret, user.Email)
+	}
+
+	data := struct {
+		Enabled           bool
+		Required          bool
+		MustEnroll        bool
+		Secret            string
+		ProvisioningURI   string
+		RecoveryCodes     []string
+		RemainingCodes    int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Enabled:           user.TwoFactor,
+		Required:          config.RequireAdminTwoFactor(),
+		MustEnroll:        r.URL.Query().Get("obrigatorio") == "1" && !user.TwoFactor,
+		Secret:            secret,
+		ProvisioningURI:   uri,
+		RecoveryCodes:     recoveryCodes,
+		RemainingCodes:    remaining,
+		UserName:          user.UserName,
+		PageTitle:         "Verificação em duas etapas",
+		CustomCSS:         "",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          errorMsg,
+		IsAdmin:           user.IsAdmin(),
+		AdditionalScripts: []string{qrCodeScript},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/conta_2fa.html",
+	}, data)
+}
+
+func (c *TwoFactorController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "disabled":
+		return "Verificação em duas etapas desativada."
+	default:
+		return ""
+	}
+}
+
+func (c *TwoFactorController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,441 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/config"
+	"martins-pocos/models"
+	"martins-pocos/services"
+
+	"github.com/gorilla/mux"
+)
+
+type WarrantyController struct {
+	ClaimModel      *models.WarrantyClaimModel
+	ContractModel   *models.ContractModel
+	ServiceModel    *models.ServiceModel
+	WhatsAppService *services.WhatsAppService
+}
+
+func NewWarrantyController(claimModel *models.WarrantyClaimModel, contractModel *models.ContractModel, serviceModel *models.ServiceModel, whatsappService *services.WhatsAppService) *WarrantyController {
+	return &WarrantyController{
+		ClaimModel:      claimModel,
+		ContractModel:   contractModel,
+		ServiceModel:    serviceModel,
+		WhatsAppService: whatsappService,
+	}
+}
+
+// ==================== CLIENTE ====================
+
+// ClientOpenClaim - Cliente consulta a cobertura e abre uma reclamação de garantia
+func (c *WarrantyController) ClientOpenClaim(w http.ResponseWriter, r *http.Request) {
+	contractID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+
+	contract, err := c.ContractModel.GetByID(contractID)
+	if err != nil {
+		http.Error(w, "Contrato não encontrado", http.StatusNotFound)
+		return
+	}
+	service, err := c.ServiceModel.GetByID(contract.ServiceRequestID)
+	if err != nil || service.UserID != userID {
+		http.Error(w, "Acesso negado", http.StatusForbidden)
+		return
+	}
+	contract.ServiceRequest = service
+
+	coverage, err := c.evaluateCoverage(contract)
+	if err != nil {
+		http.Error(w, "Erro ao verificar garantia", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == "GET" {
+		c.showClaimForm(w, r, contract, coverage, "", "")
+		return
+	}
+
+	description := strings.TrimSpace(r.FormValue("description"))
+	if !coverage.Covered {
+		c.showClaimForm(w, r, contract, coverage, description, coverage.Note)
+		return
+	}
+	if len([]rune(description)) < 10 {
+		c.showClaimForm(w, r, contract, coverage, description, "Descreva o problema com pelo menos 10 caracteres.")
+		return
+	}
+
+	claim := &models.WarrantyClaim{
+		ContractID:   contract.ID,
+		UserID:       userID,
+		Description:  description,
+		CoverageNote: toNullString(coverage.Note),
+	}
+	if err := c.ClaimModel.Create(claim); err != nil {
+		if err == models.ErrClaimAlreadyOpen {
+			c.showClaimForm(w, r, contract, coverage, description, "Já existe uma reclamação em análise para este contrato.")
+			return
+		}
+		http.Error(w, "Erro ao abrir reclamação: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/garantias?success=opened", http.StatusFound)
+}
+
+// ClientClaims - Lista as reclamações de garantia do cliente
+func (c *WarrantyController) ClientClaims(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).UserID
+	userName := currentUser(r).UserName
+
+	claims, err := c.ClaimModel.GetByUserID(userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar reclamações", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Claims            []models.WarrantyClaim
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Claims:            claims,
+		UserName:          userName,
+		PageTitle:         "Minhas Garantias",
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           false,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/cliente_garantias.html",
+	}, data)
+}
+
+// ==================== ADMIN ====================
+
+// AdminListClaims - Lista as reclamações de garantia (admin)
+func (c *WarrantyController) AdminListClaims(w http.ResponseWriter, r *http.Request) {
+	statusFilter := r.URL.Query().Get("status")
+	switch statusFilter {
+	case models.ClaimStatusOpen, models.ClaimStatusApproved, models.ClaimStatusRejected:
+	default:
+		statusFilter = ""
+	}
+
+	claims, err := c.ClaimModel.GetAll(statusFilter)
+	if err != nil {
+		http.Error(w, "Erro ao buscar reclamações", http.StatusInternalServerError)
+		return
+	}
+	openCount, _ := c.ClaimModel.CountOpen()
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Claims            []models.WarrantyClaim
+		StatusFilter      string
+		OpenCount         int
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Claims:            claims,
+		StatusFilter:      statusFilter,
+		OpenCount:         openCount,
+		UserName:          userName,
+		PageTitle:         "Reclamações de Garantia",
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_garantias.html",
+	}, data)
+}
+
+// AdminViewClaim - Detalhes da reclamação com a verificação de cobertura (admin)
+func (c *WarrantyController) AdminViewClaim(w http.ResponseWriter, r *http.Request) {
+	claimID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	claim, err := c.ClaimModel.GetByID(claimID)
+	if err != nil {
+		http.Error(w, "Reclamação não encontrada", http.StatusNotFound)
+		return
+	}
+
+	contract, err := c.ContractModel.GetByID(claim.ContractID)
+	if err != nil {
+		http.Error(w, "Contrato não encontrado", http.StatusNotFound)
+		return
+	}
+	service, _ := c.ServiceModel.GetByID(contract.ServiceRequestID)
+	contract.ServiceRequest = service
+
+	// Cobertura na data de abertura da reclamação, não na data de hoje
+	months, err := c.ClaimModel.WarrantyMonths(contract.GuaranteeTypeID, config.GetWarrantyMonths())
+	if err != nil {
+		http.Error(w, "Erro ao verificar garantia", http.StatusInternalServerError)
+		return
+	}
+	coverage := models.EvaluateWarrantyCoverage(contract, months, claim.CreatedAt)
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Claim             *models.WarrantyClaim
+		Contract          *models.Contract
+		Coverage          models.WarrantyCoverage
+		SpawnsFollowUp    bool
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		ErrorMsg          string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Claim:             claim,
+		Contract:          contract,
+		Coverage:          coverage,
+		SpawnsFollowUp:    claim.GuaranteeCode == models.GuaranteeSecondAttempt,
+		UserName:          userName,
+		PageTitle:         fmt.Sprintf("Reclamação de Garantia #%d", claim.ID),
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		ErrorMsg:          c.getErrorMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_garantia_detalhe.html",
+	}, data)
+}
+
+// ApproveClaim - Aprova a reclamação (admin)
+func (c *WarrantyController) ApproveClaim(w http.ResponseWriter, r *http.Request) {
+	c.decideClaim(w, r, true)
+}
+
+// RejectClaim - Rejeita a reclamação com motivo obrigatório (admin)
+func (c *WarrantyController) RejectClaim(w http.ResponseWriter, r *http.Request) {
+	c.decideClaim(w, r, false)
+}
+
+func (c *WarrantyController) decideClaim(w http.ResponseWriter, r *http.Request, approve bool) {
+	claimID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	redirectURL := fmt.Sprintf("/admin/garantias/%d", claimID)
+
+	var claim *models.WarrantyClaim
+	if approve {
+		claim, err = c.ClaimModel.Approve(claimID, reason, auditActor(r))
+	} else {
+		if reason == "" {
+			http.Redirect(w, r, redirectURL+"?error=reason_required", http.StatusFound)
+			return
+		}
+		claim, err = c.ClaimModel.Reject(claimID, reason, auditActor(r))
+	}
+
+	switch {
+	case err == models.ErrClaimNotOpen:
+		http.Redirect(w, r, redirectURL+"?error=already_decided", http.StatusFound)
+		return
+	case err == sql.ErrNoRows:
+		http.Error(w, "Reclamação não encontrada", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, "Erro ao registrar decisão: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	c.notifyDecision(claim)
+
+	if approve {
+		http.Redirect(w, r, redirectURL+"?success=approved", http.StatusFound)
+	} else {
+		http.Redirect(w, r, redirectURL+"?success=rejected", http.StatusFound)
+	}
+}
+
+// notifyDecision avisa o cliente pelo WhatsApp sobre a decisão da reclamação
+func (c *WarrantyController) notifyDecision(claim *models.WarrantyClaim) {
+	if claim.ClientPhone == "" || !wantsNotification(claim.UserID, models.NotifyWarranty) {
+		return
+	}
+
+	var message string
+	if claim.Status == models.ClaimStatusApproved {
+		message = fmt.Sprintf("✅ *Garantia Aprovada*\n\nOlá %s!\n\nSua reclamação de garantia do contrato *%s* foi aprovada.",
+			claim.ClientName, claim.ContractNumber)
+		if claim.FollowUpRequestID.Valid {
+			message += "\n\nAbrimos uma nova solicitação sem custo e entraremos em contato para agendar a visita."
+		}
+	} else {
+		message = fmt.Sprintf("❌ *Garantia Não Aprovada*\n\nOlá %s!\n\nSua reclamação de garantia do contrato *%s* não foi aprovada.",
+			claim.ClientName, claim.ContractNumber)
+	}
+	if claim.DecisionReason.Valid {
+		message += "\n\n*Motivo:* " + claim.DecisionReason.String
+	}
+	message += "\n\n_Martins Poços - Sistema Automatizado_"
+
+	if err := c.WhatsAppService.SendMessage(claim.ClientPhone, message); err != nil {
+		log.Printf("⚠️ Erro ao avisar cliente sobre a reclamação %d: %v", claim.ID, err)
+	}
+}
+
+// evaluateCoverage verifica a cobertura do contrato na data de hoje
+func (c *WarrantyController) evaluateCoverage(contract *models.Contract) (models.WarrantyCoverage, error) {
+	months, err := c.ClaimModel.WarrantyMonths(contract.GuaranteeTypeID, config.GetWarrantyMonths())
+	if err != nil {
+		return models.WarrantyCoverage{}, err
+	}
+	return models.EvaluateWarrantyCoverage(contract, months, time.Now()), nil
+}
+
+func (c *WarrantyController) showClaimForm(w http.ResponseWriter, r *http.Request, contract *models.Contract, coverage models.WarrantyCoverage, description, errorMsg string) {
+	userName := currentUser(r).UserName
+
+	claims, _ := c.ClaimModel.GetByContractID(contract.ID)
+	hasOpenClaim := false
+	for i := range claims {
+		if claims[i].IsOpen() {
+			hasOpenClaim = true
+		}
+	}
+
+	data := struct {
+		Contract          *models.Contract
+		Coverage          models.WarrantyCoverage
+		Claims            []models.WarrantyClaim
+		HasOpenClaim      bool
+		Description       string
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Contract:          contract,
+		Coverage:          coverage,
+		Claims:            claims,
+		HasOpenClaim:      hasOpenClaim,
+		Description:       description,
+		ErrorMsg:          errorMsg,
+		UserName:          userName,
+		PageTitle:         "Garantia do Contrato " + contract.ContractNumber,
+		CustomCSS:         "/static/css/contracts.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           false,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/cliente_garantia_form.html",
+	}, data)
+}
+
+func (c *WarrantyController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "opened":
+		return "Reclamação de garantia enviada! Você será avisado quando for analisada."
+	case "approved":
+		return "Reclamação aprovada e cliente notificado."
+	case "rejected":
+		return "Reclamação rejeitada e cliente notificado."
+	default:
+		return ""
+	}
+}
+
+func (c *WarrantyController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "reason_required":
+		return "Informe o motivo da rejeição."
+	case "already_decided":
+		return "Esta reclamação já foi analisada."
+	default:
+		return ""
+	}
+}
+
+func (c *WarrantyController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,608 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/constants"
+	"martins-pocos/models"
+	"martins-pocos/services"
+
+	"github.com/gorilla/mux"
+)
+
+// Código do tipo de serviço de análise da água (seed de service_types)
+const serviceTypeAnalise = "analise"
+
+type WaterAnalysisController struct {
+	AnalysisModel *models.WaterAnalysisModel
+	ServiceModel  *models.ServiceModel
+	WellModel     *models.WellModel
+}
+
+func NewWaterAnalysisController(analysisModel *models.WaterAnalysisModel, serviceModel *models.ServiceModel, wellModel *models.WellModel) *WaterAnalysisController {
+	return &WaterAnalysisController{
+		AnalysisModel: analysisModel,
+		ServiceModel:  serviceModel,
+		WellModel:     wellModel,
+	}
+}
+
+// qualityChart é o gráfico de histórico de um parâmetro pronto para o template
+type qualityChart struct {
+	Series services.ParameterSeries
+	SVG    template.HTML
+}
+
+// ============================================
+// ADMIN
+// ============================================
+
+// AdminListAnalyses - Lista todos os laudos (admin)
+func (c *WaterAnalysisController) AdminListAnalyses(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+
+	pageSize := 10
+	offset := (page - 1) * pageSize
+
+	analyses, totalCount, err := c.AnalysisModel.GetAll(pageSize, offset)
+	if err != nil {
+		http.Error(w, "Erro ao buscar análises", http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := (totalCount + pageSize - 1) / pageSize
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Analyses          []models.WaterAnalysis
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		CurrentPage       int
+		TotalPages        int
+		TotalCount        int
+		HasPrevPage       bool
+		HasNextPage       bool
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Analyses:          analyses,
+		UserName:          userName,
+		PageTitle:         "Análises da Água",
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		CurrentPage:       page,
+		TotalPages:        totalPages,
+		TotalCount:        totalCount,
+		HasPrevPage:       page > 1,
+		HasNextPage:       page < totalPages,
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_analises.html",
+	}, data)
+}
+
+// CreateFromRequest - Registrar laudo a partir de uma solicitação de análise
+func (c *WaterAnalysisController) CreateFromRequest(w http.ResponseWriter, r *http.Request) {
+	serviceRequestID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	service, err := c.ServiceModel.GetByID(serviceRequestID)
+	if err != nil {
+		http.Error(w, "Solicitação não encontrada", http.StatusNotFound)
+		return
+	}
+	if service.ServiceTypeCode != serviceTypeAnalise || service.StatusID == constants.StatusCancelada {
+		http.Error(w, "Apenas solicitações de análise da água ativas podem receber laudo", http.StatusBadRequest)
+		return
+	}
+
+	// Cada solicitação gera no máximo um laudo
+	existing, err := c.AnalysisModel.GetByServiceRequestID(serviceRequestID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar laudo", http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		http.Redirect(w, r, fmt.Sprintf("/admin/analises/%d/editar", existing.ID), http.StatusFound)
+		return
+	}
+
+	analysis := &models.WaterAnalysis{
+		UserID:           service.UserID,
+		ServiceRequestID: sql.NullInt64{Int64: int64(service.ID), Valid: true},
+		SampleDate:       service.PreferredDate,
+		OwnerName:        service.UserName,
+	}
+
+	// Sugere o poço registrado para o cliente quando houver apenas um
+	wells, err := c.WellModel.GetByUserID(service.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar poços do cliente", http.StatusInternalServerError)
+		return
+	}
+	if len(wells) == 1 {
+		analysis.WellID = sql.NullInt64{Int64: int64(wells[0].ID), Valid: true}
+	}
+
+	if r.Method == "GET" {
+		c.showAnalysisForm(w, r, analysis, wells, "")
+		return
+	}
+
+	if err := c.parseAnalysisForm(r, analysis, wells); err != nil {
+		c.showAnalysisForm(w, r, analysis, wells, err.Error())
+		return
+	}
+
+	if err := c.AnalysisModel.Create(analysis, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao registrar laudo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/analises/%d?success=created", analysis.ID), http.StatusFound)
+}
+
+// EditAnalysis - Corrigir laudo (admin)
+func (c *WaterAnalysisController) EditAnalysis(w http.ResponseWriter, r *http.Request) {
+	analysisID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	analysis, err := c.AnalysisModel.GetByID(analysisID)
+	if err != nil {
+		http.Error(w, "Laudo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	wells, err := c.WellModel.GetByUserID(analysis.UserID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar poços do cliente", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == "GET" {
+		c.showAnalysisForm(w, r, analysis, wells, "")
+		return
+	}
+
+	if err := c.parseAnalysisForm(r, analysis, wells); err != nil {
+		c.showAnalysisForm(w, r, analysis, wells, err.Error())
+		return
+	}
+
+	if err := c.AnalysisModel.Update(analysis, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao atualizar laudo: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/analises/%d?success=updated", analysis.ID), http.StatusFound)
+}
+
+// AdminViewAnalysis - Visualizar laudo (admin)
+func (c *WaterAnalysisController) AdminViewAnalysis(w http.ResponseWriter, r *http.Request) {
+	analysisID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	analysis, err := c.AnalysisModel.GetByID(analysisID)
+	if err != nil {
+		http.Error(w, "Laudo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.showReport(w, r, analysis, true)
+}
+
+// AdminAnalysisPDF - Baixar laudo em PDF (admin)
+func (c *WaterAnalysisController) AdminAnalysisPDF(w http.ResponseWriter, r *http.Request) {
+	analysisID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	analysis, err := c.AnalysisModel.GetByID(analysisID)
+	if err != nil {
+		http.Error(w, "Laudo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.writeAnalysisPDF(w, analysis)
+}
+
+// AdminWellQuality - Histórico de qualidade da água do poço (admin)
+func (c *WaterAnalysisController) AdminWellQuality(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	well, err := c.WellModel.GetByID(wellID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.showWellQuality(w, r, well, true)
+}
+
+// ============================================
+// CLIENTE
+// ============================================
+
+// ClientAnalyses - Lista os laudos do cliente
+func (c *WaterAnalysisController) ClientAnalyses(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).UserID
+	userName := currentUser(r).UserName
+
+	analyses, err := c.AnalysisModel.GetByUserID(userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar análises", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Analyses          []models.WaterAnalysis
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Analyses:          analyses,
+		UserName:          userName,
+		PageTitle:         "Minhas Análises da Água",
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           false,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/cliente_analises.html",
+	}, data)
+}
+
+// ClientViewAnalysis - Cliente visualiza seu laudo
+func (c *WaterAnalysisController) ClientViewAnalysis(w http.ResponseWriter, r *http.Request) {
+	analysisID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+
+	analysis, err := c.AnalysisModel.GetByIDAndUser(analysisID, userID)
+	if err != nil {
+		http.Error(w, "Laudo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.showReport(w, r, analysis, false)
+}
+
+// ClientAnalysisPDF - Cliente baixa seu laudo em PDF
+func (c *WaterAnalysisController) ClientAnalysisPDF(w http.ResponseWriter, r *http.Request) {
+	analysisID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+
+	analysis, err := c.AnalysisModel.GetByIDAndUser(analysisID, userID)
+	if err != nil {
+		http.Error(w, "Laudo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.writeAnalysisPDF(w, analysis)
+}
+
+// ClientWellQuality - Cliente acompanha o histórico de qualidade do seu poço
+func (c *WaterAnalysisController) ClientWellQuality(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+
+	well, err := c.WellModel.GetByIDAndUser(wellID, userID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.showWellQuality(w, r, well, false)
+}
+
+// ============================================
+// HELPERS
+// ============================================
+
+func (c *WaterAnalysisController) showReport(w http.ResponseWriter, r *http.Request, analysis *models.WaterAnalysis, isAdmin bool) {
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Analysis          *models.WaterAnalysis
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Analysis:          analysis,
+		UserName:          userName,
+		PageTitle:         "Laudo de Análise da Água",
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           isAdmin,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/analise_laudo.html",
+	}, data)
+}
+
+func (c *WaterAnalysisController) showWellQuality(w http.ResponseWriter, r *http.Request, well *models.Well, isAdmin bool) {
+	userName := currentUser(r).UserName
+
+	analyses, err := c.AnalysisModel.GetWellHistory(well.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar histórico de análises", http.StatusInternalServerError)
+		return
+	}
+
+	var charts []qualityChart
+	for _, series := range services.BuildParameterSeries(analyses) {
+		charts = append(charts, qualityChart{
+			Series: series,
+			SVG:    template.HTML(services.RenderParameterChartSVG(series)),
+		})
+	}
+
+	// Lista de laudos do mais recente para o mais antigo
+	recent := make([]models.WaterAnalysis, 0, len(analyses))
+	for i := len(analyses) - 1; i >= 0; i-- {
+		recent = append(recent, analyses[i])
+	}
+
+	data := struct {
+		Well              *models.Well
+		Analyses          []models.WaterAnalysis
+		Charts            []qualityChart
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Well:              well,
+		Analyses:          recent,
+		Charts:            charts,
+		UserName:          userName,
+		PageTitle:         "Qualidade da Água - " + well.Identification,
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           isAdmin,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/poco_qualidade.html",
+	}, data)
+}
+
+func (c *WaterAnalysisController) showAnalysisForm(w http.ResponseWriter, r *http.Request, analysis *models.WaterAnalysis, wells []models.Well, errorMsg string) {
+	userName := currentUser(r).UserName
+
+	pageTitle := "Registrar Laudo"
+	if analysis.ID > 0 {
+		pageTitle = "Editar Laudo"
+	}
+
+	// Valores já informados por parâmetro, para preencher o formulário
+	values := make(map[string]string)
+	for _, result := range analysis.Results {
+		if result.PresenceAbsence {
+			values[result.ParameterCode] = strconv.FormatFloat(result.Value, 'f', 0, 64)
+		} else {
+			values[result.ParameterCode] = strconv.FormatFloat(result.Value, 'f', -1, 64)
+		}
+	}
+
+	wellID := 0
+	if analysis.WellID.Valid {
+		wellID = int(analysis.WellID.Int64)
+	}
+
+	data := struct {
+		Analysis          *models.WaterAnalysis
+		Wells             []models.Well
+		WellID            int
+		Parameters        []models.WaterParameter
+		Values            map[string]string
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Analysis:          analysis,
+		Wells:             wells,
+		WellID:            wellID,
+		Parameters:        models.WaterParameters,
+		Values:            values,
+		ErrorMsg:          errorMsg,
+		UserName:          userName,
+		PageTitle:         pageTitle,
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_analise_form.html",
+	}, data)
+}
+
+// parseAnalysisForm preenche o laudo com os dados do formulário
+func (c *WaterAnalysisController) parseAnalysisForm(r *http.Request, analysis *models.WaterAnalysis, wells []models.Well) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Erro ao processar formulário")
+	}
+
+	analysis.Laboratory = toNullString(strings.TrimSpace(r.FormValue("laboratory")))
+	analysis.ReportNumber = toNullString(strings.TrimSpace(r.FormValue("report_number")))
+	analysis.Notes = toNullString(strings.TrimSpace(r.FormValue("notes")))
+
+	// O poço precisa pertencer ao cliente do laudo
+	analysis.WellID = sql.NullInt64{}
+	if wellStr := r.FormValue("well_id"); wellStr != "" {
+		wellID, err := strconv.Atoi(wellStr)
+		if err != nil {
+			return fmt.Errorf("Poço inválido")
+		}
+		found := false
+		for _, well := range wells {
+			if well.ID == wellID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Poço não pertence ao cliente")
+		}
+		analysis.WellID = sql.NullInt64{Int64: int64(wellID), Valid: true}
+	}
+
+	sampleDate, err := time.Parse("2006-01-02", r.FormValue("sample_date"))
+	if err != nil {
+		return fmt.Errorf("Data da coleta inválida")
+	}
+	if sampleDate.After(time.Now()) {
+		return fmt.Errorf("Data da coleta não pode ser futura")
+	}
+	analysis.SampleDate = sampleDate
+
+	analysis.Results = nil
+	for _, parameter := range models.WaterParameters {
+		value, err := toNullFloat(r.FormValue("param_" + parameter.Code))
+		if err != nil || (value.Valid && value.Float64 < 0) {
+			return fmt.Errorf("Valor inválido para %s", parameter.Name)
+		}
+		if !value.Valid {
+			continue
+		}
+		if parameter.PresenceAbsence && value.Float64 != 0 && value.Float64 != 1 {
+			return fmt.Errorf("Valor inválido para %s", parameter.Name)
+		}
+		analysis.Results = append(analysis.Results, models.NewWaterAnalysisResult(parameter, value.Float64))
+	}
+
+	if len(analysis.Results) == 0 {
+		return fmt.Errorf("Informe o resultado de pelo menos um parâmetro")
+	}
+
+	return nil
+}
+
+func (c *WaterAnalysisController) writeAnalysisPDF(w http.ResponseWriter, analysis *models.WaterAnalysis) {
+	pdf := services.BuildWaterAnalysisPDF(analysis)
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"laudo-agua-%d.pdf\"", analysis.ID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.Write(pdf)
+}
+
+func (c *WaterAnalysisController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "created":
+		return "Laudo registrado com sucesso!"
+	case "updated":
+		return "Laudo atualizado com sucesso!"
+	default:
+		return ""
+	}
+}
+
+func (c *WaterAnalysisController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,614 @@
+package controllers
+
+import (
+	"database/sql"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/constants"
+	"martins-pocos/models"
+	"martins-pocos/services"
+
+	"github.com/gorilla/mux"
+)
+
+type WellController struct {
+	WellModel     *models.WellModel
+	ServiceModel  *models.ServiceModel
+	ContractModel *models.ContractModel
+	PlanModel     *models.MaintenancePlanModel
+}
+
+func NewWellController(wellModel *models.WellModel, serviceModel *models.ServiceModel, contractModel *models.ContractModel, planModel *models.MaintenancePlanModel) *WellController {
+	return &WellController{
+		WellModel:     wellModel,
+		ServiceModel:  serviceModel,
+		ContractModel: contractModel,
+		PlanModel:     planModel,
+	}
+}
+
+// AdminListWells - Lista todos os poços cadastrados (admin)
+func (c *WellController) AdminListWells(w http.ResponseWriter, r *http.Request) {
+	page := 1
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+
+	pageSize := 10
+	offset := (page - 1) * pageSize
+
+	wells, totalCount, err := c.WellModel.GetAll(pageSize, offset)
+	if err != nil {
+		http.Error(w, "Erro ao buscar poços", http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := (totalCount + pageSize - 1) / pageSize
+
+	userName := currentUser(r).UserName
+
+	data := struct {
+		Wells             []models.Well
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		CurrentPage       int
+		TotalPages        int
+		TotalCount        int
+		HasPrevPage       bool
+		HasNextPage       bool
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Wells:             wells,
+		UserName:          userName,
+		PageTitle:         "Poços Cadastrados",
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		CurrentPage:       page,
+		TotalPages:        totalPages,
+		TotalCount:        totalCount,
+		HasPrevPage:       page > 1,
+		HasNextPage:       page < totalPages,
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_pocos.html",
+	}, data)
+}
+
+// CreateWellFromRequest - Registrar poço a partir de uma solicitação realizada
+func (c *WellController) CreateWellFromRequest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	serviceRequestID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	service, err := c.ServiceModel.GetByID(serviceRequestID)
+	if err != nil || service.StatusID != constants.StatusRealizada {
+		http.Error(w, "Solicitação não encontrada ou não está realizada", http.StatusBadRequest)
+		return
+	}
+
+	// Cada solicitação gera no máximo um poço
+	existing, err := c.WellModel.GetByServiceRequestID(serviceRequestID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar poço", http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d/editar", existing.ID), http.StatusFound)
+		return
+	}
+
+	well := &models.Well{
+		UserID:           service.UserID,
+		ServiceRequestID: sql.NullInt64{Int64: int64(service.ID), Valid: true},
+		Identification:   fmt.Sprintf("Poço %s - %s", service.FullName, service.Cidade),
+		Latitude:         service.Latitude,
+		Longitude:        service.Longitude,
+	}
+	well.ContractID = c.contractIDForRequest(service.ID)
+
+	if r.Method == "GET" {
+		c.showWellForm(w, r, well, service)
+		return
+	}
+
+	if err := c.parseWellForm(r, well); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.WellModel.Create(well, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao registrar poço: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?success=created", well.ID), http.StatusFound)
+}
+
+// EditWell - Editar ficha técnica do poço (admin)
+func (c *WellController) EditWell(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	well, err := c.WellModel.GetByID(wellID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	var service *models.ServiceRequest
+	if well.ServiceRequestID.Valid {
+		service, _ = c.ServiceModel.GetByID(int(well.ServiceRequestID.Int64))
+		if !well.ContractID.Valid {
+			well.ContractID = c.contractIDForRequest(int(well.ServiceRequestID.Int64))
+		}
+	}
+
+	if r.Method == "GET" {
+		c.showWellForm(w, r, well, service)
+		return
+	}
+
+	if err := c.parseWellForm(r, well); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := c.WellModel.Update(well, auditActor(r)); err != nil {
+		http.Error(w, "Erro ao atualizar poço: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?success=updated", well.ID), http.StatusFound)
+}
+
+// AdminViewWell - Ficha técnica do poço (admin)
+func (c *WellController) AdminViewWell(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	well, err := c.WellModel.GetByID(wellID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.showWellSheet(w, r, well, true)
+}
+
+// ClientWells - Lista os poços do cliente
+func (c *WellController) ClientWells(w http.ResponseWriter, r *http.Request) {
+	userID := currentUser(r).UserID
+	userName := currentUser(r).UserName
+
+	wells, err := c.WellModel.GetByUserID(userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar poços", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Wells             []models.Well
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Wells:             wells,
+		UserName:          userName,
+		PageTitle:         "Meus Poços",
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           false,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/cliente_pocos.html",
+	}, data)
+}
+
+// ClientViewWell - Cliente visualiza a ficha técnica do seu poço
+func (c *WellController) ClientViewWell(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+
+	well, err := c.WellModel.GetByIDAndUser(wellID, userID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.showWellSheet(w, r, well, false)
+}
+
+// ============================================
+// PERFIL LITOLÓGICO
+// ============================================
+
+// AddDrillingInterval - Registrar camada no perfil litológico (admin)
+func (c *WellController) AddDrillingInterval(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Erro ao processar formulário", http.StatusBadRequest)
+		return
+	}
+
+	from, errFrom := toNullFloat(r.FormValue("depth_from_m"))
+	to, errTo := toNullFloat(r.FormValue("depth_to_m"))
+	if errFrom != nil || errTo != nil || !from.Valid || !to.Valid {
+		http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?error=interval_invalid#perfil", wellID), http.StatusFound)
+		return
+	}
+
+	interval := &models.DrillingInterval{
+		WellID:       wellID,
+		DepthFromM:   from.Float64,
+		DepthToM:     to.Float64,
+		Lithology:    strings.TrimSpace(r.FormValue("lithology")),
+		WaterEntry:   r.FormValue("water_entry") == "on",
+		Observations: toNullString(strings.TrimSpace(r.FormValue("observations"))),
+	}
+
+	err = c.WellModel.AddDrillingInterval(interval, auditActor(r))
+	switch err {
+	case nil:
+		http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?success=interval_added#perfil", wellID), http.StatusFound)
+	case sql.ErrNoRows:
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+	case models.ErrIntervalInvalid:
+		http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?error=interval_invalid#perfil", wellID), http.StatusFound)
+	case models.ErrIntervalOverlap:
+		http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?error=interval_overlap#perfil", wellID), http.StatusFound)
+	case models.ErrIntervalBeyondDepth:
+		http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?error=interval_depth#perfil", wellID), http.StatusFound)
+	case models.ErrIntervalNoLithology:
+		http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?error=interval_lithology#perfil", wellID), http.StatusFound)
+	default:
+		http.Error(w, "Erro ao registrar camada: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DeleteDrillingInterval - Remover camada do perfil litológico (admin)
+func (c *WellController) DeleteDrillingInterval(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	wellID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+	intervalID, err := strconv.Atoi(vars["interval_id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.WellModel.DeleteDrillingInterval(wellID, intervalID, auditActor(r)); err != nil {
+		http.Error(w, "Camada não encontrada", http.StatusNotFound)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/pocos/%d?success=interval_deleted#perfil", wellID), http.StatusFound)
+}
+
+// AdminWellPDF - Baixar ficha técnica em PDF (admin)
+func (c *WellController) AdminWellPDF(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	well, err := c.WellModel.GetByID(wellID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.writeWellPDF(w, well)
+}
+
+// ClientWellPDF - Cliente baixa a ficha técnica do seu poço em PDF
+func (c *WellController) ClientWellPDF(w http.ResponseWriter, r *http.Request) {
+	wellID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	userID := currentUser(r).UserID
+
+	well, err := c.WellModel.GetByIDAndUser(wellID, userID)
+	if err != nil {
+		http.Error(w, "Poço não encontrado", http.StatusNotFound)
+		return
+	}
+
+	c.writeWellPDF(w, well)
+}
+
+func (c *WellController) writeWellPDF(w http.ResponseWriter, well *models.Well) {
+	intervals, err := c.WellModel.GetDrillingLog(well.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar perfil litológico", http.StatusInternalServerError)
+		return
+	}
+
+	pdf := services.BuildWellSheetPDF(well, intervals)
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=\"ficha-poco-%d.pdf\"", well.ID))
+	w.Header().Set("Content-Length", strconv.Itoa(len(pdf)))
+	w.Write(pdf)
+}
+
+func (c *WellController) showWellSheet(w http.ResponseWriter, r *http.Request, well *models.Well, isAdmin bool) {
+	userName := currentUser(r).UserName
+
+	drillingLog, err := c.WellModel.GetDrillingLog(well.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar perfil litológico", http.StatusInternalServerError)
+		return
+	}
+
+	plans, err := c.PlanModel.GetByWellID(well.ID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar planos de manutenção", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Well              *models.Well
+		DrillingLog       []models.DrillingInterval
+		MaintenancePlans  []models.MaintenancePlan
+		ProfileSVG        template.HTML
+		Lithologies       []services.Lithology
+		ErrorMsg          string
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		SuccessMsg        string
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Well:              well,
+		DrillingLog:       drillingLog,
+		MaintenancePlans:  plans,
+		ProfileSVG:        template.HTML(services.RenderDrillingProfileSVG(drillingLog, well.DepthM, well.StaticLevelM)),
+		Lithologies:       services.Lithologies,
+		ErrorMsg:          c.getErrorMsg(r),
+		UserName:          userName,
+		PageTitle:         "Ficha Técnica - " + well.Identification,
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		SuccessMsg:        c.getSuccessMsg(r),
+		IsAdmin:           isAdmin,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/poco_ficha.html",
+	}, data)
+}
+
+func (c *WellController) showWellForm(w http.ResponseWriter, r *http.Request, well *models.Well, service *models.ServiceRequest) {
+	userName := currentUser(r).UserName
+
+	pageTitle := "Registrar Poço"
+	if well.ID > 0 {
+		pageTitle = "Editar Poço"
+	}
+
+	data := struct {
+		Well              *models.Well
+		Service           *models.ServiceRequest
+		UserName          string
+		PageTitle         string
+		CustomCSS         string
+		CustomJS          string
+		CurrentYear       int
+		IsAdmin           bool
+		AdditionalScripts []string
+	}{
+		Well:              well,
+		Service:           service,
+		UserName:          userName,
+		PageTitle:         pageTitle,
+		CustomCSS:         "/static/css/wells.css",
+		CustomJS:          "",
+		CurrentYear:       time.Now().Year(),
+		IsAdmin:           true,
+		AdditionalScripts: []string{},
+	}
+
+	c.renderTemplate(w, r, []string{
+		"templates/components/head.html",
+		"templates/components/navbar.html",
+		"templates/components/footer.html",
+		"templates/components/scripts.html",
+		"templates/admin_poco_form.html",
+	}, data)
+}
+
+// parseWellForm preenche o poço com os dados do formulário
+func (c *WellController) parseWellForm(r *http.Request, well *models.Well) error {
+	if err := r.ParseForm(); err != nil {
+		return fmt.Errorf("Erro ao processar formulário")
+	}
+
+	well.Identification = strings.TrimSpace(r.FormValue("identification"))
+	if well.Identification == "" {
+		return fmt.Errorf("Identificação do poço é obrigatória")
+	}
+
+	fields := []struct {
+		name   string
+		label  string
+		target *sql.NullFloat64
+	}{
+		{"depth_m", "Profundidade", &well.DepthM},
+		{"diameter_mm", "Diâmetro", &well.DiameterMm},
+		{"static_level_m", "Nível estático", &well.StaticLevelM},
+		{"dynamic_level_m", "Nível dinâmico", &well.DynamicLevelM},
+		{"flow_rate_m3h", "Vazão", &well.FlowRateM3h},
+	}
+
+	for _, field := range fields {
+		value, err := toNullFloat(r.FormValue(field.name))
+		if err != nil || (value.Valid && value.Float64 < 0) {
+			return fmt.Errorf("%s inválido", field.label)
+		}
+		*field.target = value
+	}
+
+	if well.DepthM.Valid && well.DynamicLevelM.Valid && well.DynamicLevelM.Float64 > well.DepthM.Float64 {
+		return fmt.Errorf("Nível dinâmico não pode ser maior que a profundidade")
+	}
+	if well.StaticLevelM.Valid && well.DynamicLevelM.Valid && well.StaticLevelM.Float64 > well.DynamicLevelM.Float64 {
+		return fmt.Errorf("Nível estático não pode ser maior que o nível dinâmico")
+	}
+
+	latitude, longitude, err := parseCoordinates(r.FormValue("latitude"), r.FormValue("longitude"))
+	if err != nil {
+		return err
+	}
+	well.Latitude = latitude
+	well.Longitude = longitude
+
+	well.DrillingDate = sql.NullTime{}
+	if dateStr := r.FormValue("drilling_date"); dateStr != "" {
+		date, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			return fmt.Errorf("Data de perfuração inválida")
+		}
+		well.DrillingDate = sql.NullTime{Time: date, Valid: true}
+	}
+
+	well.CasingMaterial = toNullString(strings.TrimSpace(r.FormValue("casing_material")))
+	well.PumpModel = toNullString(strings.TrimSpace(r.FormValue("pump_model")))
+	well.Notes = toNullString(strings.TrimSpace(r.FormValue("notes")))
+
+	return nil
+}
+
+// contractIDForRequest retorna o contrato vinculado à solicitação, se houver
+func (c *WellController) contractIDForRequest(serviceRequestID int) sql.NullInt64 {
+	contract, err := c.ContractModel.GetByServiceRequestID(serviceRequestID)
+	if err != nil || contract == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(contract.ID), Valid: true}
+}
+
+// toNullFloat converte um campo numérico opcional (aceita vírgula decimal)
+func toNullFloat(s string) (sql.NullFloat64, error) {
+	s = strings.TrimSpace(strings.Replace(s, ",", ".", 1))
+	if s == "" {
+		return sql.NullFloat64{}, nil
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return sql.NullFloat64{}, err
+	}
+	return sql.NullFloat64{Float64: value, Valid: true}, nil
+}
+
+func (c *WellController) getSuccessMsg(r *http.Request) string {
+	switch r.URL.Query().Get("success") {
+	case "created":
+		return "Poço registrado com sucesso!"
+	case "updated":
+		return "Ficha técnica atualizada com sucesso!"
+	case "interval_added":
+		return "Camada registrada no perfil litológico!"
+	case "interval_deleted":
+		return "Camada removida do perfil litológico!"
+	case "plan_created":
+		return "Plano de manutenção criado com sucesso!"
+	case "plan_updated":
+		return "Plano de manutenção atualizado com sucesso!"
+	default:
+		return ""
+	}
+}
+
+func (c *WellController) getErrorMsg(r *http.Request) string {
+	switch r.URL.Query().Get("error") {
+	case "interval_invalid":
+		return "Intervalo inválido: a profundidade final deve ser maior que a inicial."
+	case "interval_overlap":
+		return "O intervalo informado sobrepõe uma camada já registrada."
+	case "interval_depth":
+		return "O intervalo ultrapassa a profundidade total do poço."
+	case "interval_lithology":
+		return "Informe a litologia da camada."
+	default:
+		return ""
+	}
+}
+
+func (c *WellController) renderTemplate(w http.ResponseWriter, r *http.Request, paths []string, data interface{}) {
+	tmpl := template.New("").Funcs(GetTemplateFuncs(r))
+	tmpl, err := tmpl.ParseFiles(paths...)
+	if err != nil {
+		http.Error(w, "Erro ao carregar template: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	baseName := filepath.Base(paths[len(paths)-1])
+	if err := tmpl.ExecuteTemplate(w, baseName, data); err != nil {
+		http.Error(w, "Erro ao renderizar: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"martins-pocos/config"
+	"martins-pocos/models"
+	"martins-pocos/utils"
+)
+
+// APIRequireAuth autentica as rotas /api pela sessão do site ou por um
+// token no cabeçalho "Authorization: Bearer <token>". Em ambos os casos o
+// usuário fica disponível em CurrentPrincipal. Falhas respondem 401 em JSON.
+func APIRequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var principal *Principal
+
+		if header := r.Header.Get("Authorization"); header != "" {
+			plain, ok := bearerToken(header)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+				utils.SendErrorResponse(w, "Cabeçalho Authorization inválido", http.StatusUnauthorized)
+				return
+			}
+
+			token, err := models.NewAPITokenModel(config.GetDB()).Authenticate(plain)
+			if errors.Is(err, models.ErrInvalidToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				utils.SendErrorResponse(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				utils.SendErrorResponse(w, "Erro ao validar token", http.StatusInternalServerError)
+				return
+			}
+
+			principal = &Principal{
+				UserID:   token.UserID,
+				UserName: token.UserName,
+				UserType: token.UserType,
+				TokenID:  token.ID,
+				Scopes:   token.Scopes,
+			}
+			if err := loadPermissions(principal); err != nil {
+				utils.SendErrorResponse(w, "Erro ao carregar permissões", http.StatusInternalServerError)
+				return
+			}
+		} else {
+			var err error
+			principal, err = sessionPrincipal(r)
+			if errors.Is(err, errNoSession) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				utils.SendErrorResponse(w, "Autenticação necessária", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				utils.SendErrorResponse(w, "Erro ao carregar usuário", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		next(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// APIRequirePermission exige que o papel do usuário conceda a permissão,
+// respondendo 403 em JSON. Deve ser usado dentro de APIRequireAuth.
+func APIRequirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := CurrentPrincipal(r)
+		if principal == nil || !principal.HasPermission(permission) {
+			utils.SendErrorResponse(w, "Acesso negado", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// APIRequireScope exige que o token da requisição conceda o escopo.
+// Deve ser usado dentro de APIRequireAuth.
+func APIRequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := CurrentPrincipal(r)
+		if principal == nil || !principal.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+			utils.SendErrorResponse(w, "Token sem permissão: "+scope, http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// bearerToken extrai o token de um cabeçalho "Bearer <token>"
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -1,49 +1,102 @@
 package middleware
 
 import (
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 
 	"martins-pocos/config"
+	"martins-pocos/models"
 )
 
+// errNoSession indica que a requisição não tem usuário na sessão
+var errNoSession = errors.New("sessão sem usuário")
+
+// sessionPrincipal carrega o usuário da sessão e confirma que ele ainda
+// existe. Nome e tipo vêm do banco, não dos valores gravados no login.
+func sessionPrincipal(r *http.Request) (*Principal, error) {
+	session, _ := config.GetSessionStore().Get(r, "session")
+
+	userID, ok := session.Values["user_id"].(int)
+	if !ok {
+		return nil, errNoSession
+	}
+
+	user, err := models.NewUserModel(config.GetDB()).GetByID(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errNoSession
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	principal := &Principal{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserType:  user.UserType,
+		Email:     user.Email,
+		TwoFactor: user.HasTwoFactor(),
+	}
+	if err := loadPermissions(principal); err != nil {
+		return nil, err
+	}
+
+	return principal, nil
+}
+
+// loadPermissions preenche o papel e as permissões do usuário
+func loadPermissions(p *Principal) error {
+	role, permissions, err := models.NewRoleModel(config.GetDB()).PermissionsForUser(p.UserID)
+	if err != nil {
+		return err
+	}
+	p.Role = role
+	p.Permissions = permissions
+	return nil
+}
+
+// RequireAuth carrega o usuário da sessão uma única vez e o guarda no
+// contexto da requisição (ver CurrentPrincipal). Sessões de usuários que
+// não existem mais são encerradas. Com REQUIRE_ADMIN_2FA=true, gestores sem
+// verificação em duas etapas só acessam a tela de configuração.
 func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := config.GetSessionStore().Get(r, "session")
-		
-		userID, ok := session.Values["user_id"]
-		if !ok || userID == nil {
+		principal, err := sessionPrincipal(r)
+		if errors.Is(err, errNoSession) {
+			session, _ := config.GetSessionStore().Get(r, "session")
+			if _, ok := session.Values["user_id"]; ok {
+				session.Options.MaxAge = -1
+				session.Save(r, w)
+			}
 			http.Redirect(w, r, "/login", http.StatusFound)
 			return
 		}
-		
-		next(w, r)
-	}
-}
+		if err != nil {
+			log.Printf("❌ Erro ao carregar usuário da sessão: %v", err)
+			http.Error(w, "Erro interno", http.StatusInternalServerError)
+			return
+		}
 
-func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := config.GetSessionStore().Get(r, "session")
-		
-		userType, ok := session.Values["user_type"].(string)
-		if !ok || userType != "gestor" {
-			http.Error(w, "Acesso negado", http.StatusForbidden)
+		if needsTwoFactorSetup(principal, r) {
+			http.Redirect(w, r, TwoFactorSetupPath+"?obrigatorio=1", http.StatusFound)
 			return
 		}
-		
-		next(w, r)
+
+		next(w, r.WithContext(WithPrincipal(r.Context(), principal)))
 	}
 }
 
-func RequireClient(next http.HandlerFunc) http.HandlerFunc {
+// RequirePermission restringe a rota a usuários cujo papel concede a
+// permissão (ex.: "contracts.sign_company"). Deve ser usado dentro de RequireAuth.
+func RequirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := config.GetSessionStore().Get(r, "session")
-		
-		userType, ok := session.Values["user_type"].(string)
-		if !ok || userType != "cliente" {
+		principal := CurrentPrincipal(r)
+		if principal == nil || !principal.HasPermission(permission) {
 			http.Error(w, "Acesso negado", http.StatusForbidden)
 			return
 		}
-		
+
 		next(w, r)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const principalKey contextKey = "principal"
+
+// Principal é o usuário autenticado da requisição, venha ele da sessão do
+// site ou de um token Bearer
+type Principal struct {
+	UserID   int
+	UserName string
+	UserType string
+	TokenID  int      // 0 quando autenticado pela sessão
+	Scopes   []string // escopos do token; a sessão não tem restrição
+
+	Email     string // preenchido apenas pela sessão
+	TwoFactor bool   // verificação em duas etapas ativa (apenas pela sessão)
+
+	Role        string   // código do papel (ex.: PROPRIETARIO)
+	Permissions []string // permissões concedidas pelo papel
+}
+
+// IsAdmin indica se o usuário é da empresa (gestor), e não cliente.
+// O que ele pode fazer no painel é decidido por HasPermission.
+func (p *Principal) IsAdmin() bool {
+	return p.UserType == "gestor"
+}
+
+// ViaToken indica se a requisição foi autenticada por token
+func (p *Principal) ViaToken() bool {
+	return p.TokenID != 0
+}
+
+// HasScope indica se a requisição pode usar o escopo. Requisições pela
+// sessão do site têm acesso completo.
+func (p *Principal) HasScope(scope string) bool {
+	if !p.ViaToken() {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission indica se o papel do usuário concede a permissão
+func (p *Principal) HasPermission(permission string) bool {
+	for _, perm := range p.Permissions {
+		if perm == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPrincipal guarda o usuário autenticado no contexto
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// CurrentPrincipal retorna o usuário autenticado da requisição, ou nil
+func CurrentPrincipal(r *http.Request) *Principal {
+	p, _ := r.Context().Value(principalKey).(*Principal)
+	return p
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"martins-pocos/config"
+	"martins-pocos/utils"
+)
+
+// Nome do campo dos formulários e do cabeçalho que levam o token CSRF
+const (
+	CSRFFieldName  = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// Chave da sessão com o token CSRF
+const sessionCSRFToken = "csrf_token"
+
+// Cookie assinado com o token CSRF de quem ainda não tem sessão gravada
+const csrfCookieName = "csrf"
+
+const csrfTokenKey contextKey = "csrf_token"
+
+// csrfSessionStore retorna o armazenamento das sessões que guardam o token
+var csrfSessionStore = func() sessions.Store { return config.GetSessionStore() }
+
+// csrfCodecs assinam o cookie do token dos visitantes (as chaves das sessões)
+var csrfCodecs = func() []securecookie.Codec { return config.GetSessionStore().Codecs }
+
+// CSRF garante um token por sessão e o exige em toda requisição que altera
+// dados (POST, PUT, DELETE...), no campo csrf_token do formulário ou no
+// cabeçalho X-CSRF-Token. Chamadas à API sem o cookie da sessão não
+// dependem do navegador e ficam de fora. O token fica disponível em
+// CSRFToken para os templates.
+//
+// Visitantes sem sessão gravada recebem o token num cookie assinado
+// (double-submit), sem criar linhas em sessions; o token passa para a
+// sessão no banco quando ela existe (após o login).
+func CSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/static/") || csrfExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, err := csrfLoadToken(w, r)
+		if err != nil {
+			log.Printf("❌ Erro ao gerar token CSRF: %v", err)
+			http.Error(w, "Erro interno", http.StatusInternalServerError)
+			return
+		}
+
+		if !csrfSafeMethod(r.Method) {
+			sent := r.Header.Get(CSRFHeaderName)
+			if sent == "" {
+				sent = r.PostFormValue(CSRFFieldName)
+			}
+			if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				log.Printf("⚠️ Requisição recusada por token CSRF inválido: %s %s", r.Method, r.URL.Path)
+				if strings.HasPrefix(r.URL.Path, "/api/") {
+					utils.SendErrorResponse(w, "Token CSRF ausente ou inválido", http.StatusForbidden)
+				} else {
+					http.Error(w, "Formulário expirado ou inválido. Volte, recarregue a página e tente novamente.", http.StatusForbidden)
+				}
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey, token)))
+	})
+}
+
+// csrfLoadToken retorna o token da requisição. Com sessão gravada, o token
+// fica nela (herdando o do cookie do visitante, para que os formulários
+// abertos antes do login continuem válidos); sem sessão, fica só no cookie.
+func csrfLoadToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	session, _ := csrfSessionStore().Get(r, "session")
+	if !session.IsNew {
+		if token, _ := session.Values[sessionCSRFToken].(string); token != "" {
+			return token, nil
+		}
+	}
+
+	token := csrfCookieToken(r)
+	fromCookie := token != ""
+	if !fromCookie {
+		var err error
+		if token, err = newCSRFToken(); err != nil {
+			return "", err
+		}
+	}
+
+	if !session.IsNew {
+		session.Values[sessionCSRFToken] = token
+		if err := session.Save(r, w); err != nil {
+			log.Printf("❌ Erro ao gravar token CSRF na sessão: %v", err)
+		}
+		return token, nil
+	}
+
+	if !fromCookie {
+		encoded, err := securecookie.EncodeMulti(csrfCookieName, token, csrfCodecs()...)
+		if err != nil {
+			return "", err
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     csrfCookieName,
+			Value:    encoded,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	return token, nil
+}
+
+// csrfCookieToken lê o token do cookie do visitante, ou "" se ausente ou
+// com assinatura inválida
+func csrfCookieToken(r *http.Request) string {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil {
+		return ""
+	}
+	var token string
+	if err := securecookie.DecodeMulti(csrfCookieName, cookie.Value, &token, csrfCodecs()...); err != nil {
+		return ""
+	}
+	return token
+}
+
+// CSRFToken retorna o token CSRF da sessão, para ser incluído nos formulários
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfTokenKey).(string)
+	return token
+}
+
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// csrfExempt indica chamadas à API que não levam o cookie da sessão:
+// autenticadas por token Bearer (que o navegador nunca envia sozinho) ou
+// pelas credenciais no corpo (emissão e renovação de tokens). Com o cookie,
+// o token CSRF é exigido mesmo que haja cabeçalho Authorization.
+func csrfExempt(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		return false
+	}
+	_, err := r.Cookie("session")
+	return err != nil
+}
+
+func newCSRFToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
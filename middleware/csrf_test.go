@@ -0,0 +1,315 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// withCookieStore troca o armazenamento das sessões (PostgreSQL) por cookies
+// assinados durante o teste
+func withCookieStore(t *testing.T) *sessions.CookieStore {
+	t.Helper()
+	store := sessions.NewCookieStore([]byte("csrf-test-secret-0123456789abcdef"))
+	previousStore, previousCodecs := csrfSessionStore, csrfCodecs
+	csrfSessionStore = func() sessions.Store { return store }
+	csrfCodecs = func() []securecookie.Codec { return store.Codecs }
+	t.Cleanup(func() { csrfSessionStore, csrfCodecs = previousStore, previousCodecs })
+	return store
+}
+
+func responseCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// csrfHandler responde 200 com o token visto pelo handler
+func csrfHandler() http.Handler {
+	return CSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r)))
+	}))
+}
+
+// csrfVisitor faz um GET sem sessão e devolve o cookie e o token do visitante
+func csrfVisitor(t *testing.T, handler http.Handler) (*http.Cookie, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET inicial = %d, esperado 200", rec.Code)
+	}
+	token := rec.Body.String()
+	if token == "" {
+		t.Fatal("GET inicial não gerou token CSRF")
+	}
+	cookie := responseCookie(rec, csrfCookieName)
+	if cookie == nil {
+		t.Fatal("GET inicial não gravou o cookie do token")
+	}
+	return cookie, token
+}
+
+// csrfSession grava uma sessão de usuário logado, como faz o login, e
+// devolve o cookie dela já com o token CSRF
+func csrfSession(t *testing.T, store sessions.Store, handler http.Handler, extra ...*http.Cookie) (*http.Cookie, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	session, _ := store.New(req, "session")
+	session.Values["user_id"] = 1
+	rec := httptest.NewRecorder()
+	if err := session.Save(req, rec); err != nil {
+		t.Fatalf("erro ao gravar a sessão: %v", err)
+	}
+	cookie := responseCookie(rec, "session")
+
+	req = httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req.AddCookie(cookie)
+	for _, c := range extra {
+		req.AddCookie(c)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if updated := responseCookie(rec, "session"); updated != nil {
+		cookie = updated
+	}
+	return cookie, rec.Body.String()
+}
+
+func formRequest(method, path string, form url.Values) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func TestCSRFRejectsForgedRequests(t *testing.T) {
+	store := withCookieStore(t)
+	handler := csrfHandler()
+	cookie, token := csrfSession(t, store, handler)
+
+	tests := []struct {
+		name   string
+		path   string
+		form   url.Values
+		header string
+	}{
+		{"POST sem token", "/solicitar-servico", url.Values{"full_name": {"Maria"}}, ""},
+		{"POST com token errado no formulário", "/solicitar-servico", url.Values{CSRFFieldName: {token + "x"}}, ""},
+		{"POST com token errado no cabeçalho", "/solicitar-servico", nil, "invalido"},
+		{"POST com token de outra sessão", "/solicitar-servico", url.Values{CSRFFieldName: {"dG9rZW4tZGUtb3V0cmEtc2Vzc2Fv"}}, ""},
+		{"POST na API com o cookie e sem token", "/api/v1/service-requests", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := formRequest(http.MethodPost, tt.path, tt.form)
+			req.AddCookie(cookie)
+			if tt.header != "" {
+				req.Header.Set(CSRFHeaderName, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, esperado 403", rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRFRejectsOtherUnsafeMethods(t *testing.T) {
+	store := withCookieStore(t)
+	handler := csrfHandler()
+	cookie, _ := csrfSession(t, store, handler)
+
+	for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/admin/solicitacao/1", nil)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, esperado 403", rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRFAcceptsValidToken(t *testing.T) {
+	store := withCookieStore(t)
+	handler := csrfHandler()
+	cookie, token := csrfSession(t, store, handler)
+
+	t.Run("campo do formulário", func(t *testing.T) {
+		req := formRequest(http.MethodPost, "/solicitar-servico", url.Values{CSRFFieldName: {token}})
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, esperado 200", rec.Code)
+		}
+		if rec.Body.String() != token {
+			t.Errorf("token no contexto = %q, esperado o da sessão", rec.Body.String())
+		}
+	})
+
+	t.Run("cabeçalho X-CSRF-Token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/service-requests", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(CSRFHeaderName, token)
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, esperado 200", rec.Code)
+		}
+	})
+}
+
+func TestCSRFSafeMethodsPassThrough(t *testing.T) {
+	store := withCookieStore(t)
+	handler := csrfHandler()
+	cookie, _ := csrfSession(t, store, handler)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		t.Run(method, func(t *testing.T) {
+			req := httptest.NewRequest(method, "/dashboard", nil)
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, esperado 200", rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRFAPIExemption(t *testing.T) {
+	store := withCookieStore(t)
+	handler := csrfHandler()
+	cookie, _ := csrfSession(t, store, handler)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		withCookie    bool
+		want          int
+	}{
+		{"Bearer sem cookie", "/api/v1/service-requests", "Bearer abc", false, http.StatusOK},
+		{"emissão de token sem cookie nem Bearer", "/api/v1/auth/token", "", false, http.StatusOK},
+		{"Bearer com o cookie da sessão", "/api/v1/service-requests", "Bearer abc", true, http.StatusForbidden},
+		{"cookie da sessão sem Bearer", "/api/v1/service-requests", "", true, http.StatusForbidden},
+		{"Bearer fora da API", "/solicitar-servico", "Bearer abc", false, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.withCookie {
+				req.AddCookie(cookie)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, esperado %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFStaticFilesPassThrough(t *testing.T) {
+	withCookieStore(t)
+	rec := httptest.NewRecorder()
+	csrfHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/static/css/global.css", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, esperado 200", rec.Code)
+	}
+}
+
+func TestCSRFVisitorDoesNotCreateSession(t *testing.T) {
+	withCookieStore(t)
+	handler := csrfHandler()
+	cookie, token := csrfVisitor(t, handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/solicitar-servico", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if responseCookie(rec, "session") != nil {
+		t.Error("visitante recebeu cookie de sessão")
+	}
+	if responseCookie(rec, csrfCookieName) != nil {
+		t.Error("cookie do token reenviado a quem já o tinha")
+	}
+	if rec.Body.String() != token {
+		t.Errorf("token = %q, esperado o do cookie", rec.Body.String())
+	}
+}
+
+func TestCSRFVisitorToken(t *testing.T) {
+	withCookieStore(t)
+	handler := csrfHandler()
+	cookie, token := csrfVisitor(t, handler)
+	_, otherToken := csrfVisitor(t, handler)
+
+	forged := &http.Cookie{Name: csrfCookieName, Value: token}
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		token  string
+		want   int
+	}{
+		{"cookie e token do visitante", cookie, token, http.StatusOK},
+		{"token sem o cookie", nil, token, http.StatusForbidden},
+		{"token de outro visitante", cookie, otherToken, http.StatusForbidden},
+		{"cookie sem assinatura", forged, token, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := formRequest(http.MethodPost, "/login", url.Values{CSRFFieldName: {tt.token}})
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, esperado %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFVisitorTokenCarriedIntoSession(t *testing.T) {
+	store := withCookieStore(t)
+	handler := csrfHandler()
+	visitor, token := csrfVisitor(t, handler)
+
+	cookie, sessionToken := csrfSession(t, store, handler, visitor)
+	if sessionToken != token {
+		t.Fatalf("token da sessão = %q, esperado o do visitante", sessionToken)
+	}
+
+	// Sem o cookie do visitante, o token continua valendo pela sessão
+	req := formRequest(http.MethodPost, "/solicitar-servico", url.Values{CSRFFieldName: {token}})
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, esperado 200", rec.Code)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"martins-pocos/utils"
+)
+
+// Página mostrada quando um handler entra em pânico. É estática para não
+// depender de templates nem de dados da requisição que falhou.
+const internalErrorPage = `<!DOCTYPE html>
+<html lang="pt-BR">
+<head>
+  <meta charset="UTF-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1.0" />
+  <title>Erro interno - Martins Poços</title>
+  <link href="https://cdnjs.cloudflare.com/ajax/libs/bootstrap/5.3.2/css/bootstrap.min.css" rel="stylesheet" />
+  <link rel="stylesheet" href="/static/css/global.css" />
+</head>
+<body>
+  <div class="container py-5 text-center">
+    <h1 class="display-5 mb-3">Algo deu errado</h1>
+    <p class="text-muted mb-4">Ocorreu um erro inesperado ao processar sua solicitação. A equipe já foi notificada pelo registro do servidor.</p>
+    <a href="/" class="btn btn-primary">Voltar ao início</a>
+  </div>
+</body>
+</html>`
+
+// Recover captura pânicos dos handlers, registra a pilha no log e responde
+// 500: página HTML para o site e JSON para as rotas /api.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// Conexão abortada de propósito: o servidor HTTP já trata
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			log.Printf("🔥 PANIC em %s %s: %v\n%s", r.Method, r.URL.Path, rec, debug.Stack())
+
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				utils.SendErrorResponse(w, "Erro interno", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(internalErrorPage))
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"martins-pocos/config"
+	"martins-pocos/utils"
+)
+
+// TwoFactorSetupPath é a tela onde o usuário configura a verificação em
+// duas etapas
+const TwoFactorSetupPath = "/conta/2fa"
+
+// Por quanto tempo uma confirmação do código dispensa nova confirmação em
+// ações sensíveis (assinar pela empresa, excluir solicitações)
+const TwoFactorStepUpWindow = 5 * time.Minute
+
+// Chave da sessão com o horário (Unix) da última confirmação do código
+const sessionTwoFactorAt = "mfa_verified_at"
+
+// needsTwoFactorSetup indica se o gestor deve ser levado à configuração da
+// verificação em duas etapas antes de usar o sistema
+func needsTwoFactorSetup(p *Principal, r *http.Request) bool {
+	if !config.RequireAdminTwoFactor() || !p.IsAdmin() || p.TwoFactor {
+		return false
+	}
+	return r.URL.Path != "/logout" && !strings.HasPrefix(r.URL.Path, TwoFactorSetupPath)
+}
+
+// TwoFactorRecent indica se a sessão confirmou o código há menos de
+// TwoFactorStepUpWindow
+func TwoFactorRecent(r *http.Request) bool {
+	session, _ := config.GetSessionStore().Get(r, "session")
+	verifiedAt, ok := session.Values[sessionTwoFactorAt].(int64)
+	return ok && time.Since(time.Unix(verifiedAt, 0)) < TwoFactorStepUpWindow
+}
+
+// MarkTwoFactorVerified registra na sessão que o código acabou de ser confirmado
+func MarkTwoFactorVerified(w http.ResponseWriter, r *http.Request) error {
+	session, _ := config.GetSessionStore().Get(r, "session")
+	session.Values[sessionTwoFactorAt] = time.Now().Unix()
+	return session.Save(r, w)
+}
+
+// APIRequireRecentTwoFactor protege ações sensíveis da API chamadas com a
+// sessão do site: quem tem a verificação ativa precisa tê-la confirmado há
+// pouco no site. Tokens já exigem o código na emissão. Deve ser usado
+// dentro de APIRequireAuth.
+func APIRequireRecentTwoFactor(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := CurrentPrincipal(r)
+		if principal != nil && !principal.ViaToken() && principal.TwoFactor && !TwoFactorRecent(r) {
+			utils.SendErrorResponse(w, "Confirme o código de verificação em duas etapas no site antes desta ação", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,385 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Tipos de token da API
+const (
+	APITokenPersonal = "PESSOAL" // criado na página da conta, longa duração
+	APITokenAccess   = "ACESSO"  // curta duração, emitido no login da API
+	APITokenRefresh  = "REFRESH" // troca por um novo par acesso/refresh
+)
+
+// Escopos de permissão dos tokens
+const (
+	ScopeRequestsRead   = "requests:read"
+	ScopeRequestsWrite  = "requests:write"
+	ScopeContractsRead  = "contracts:read"
+	ScopeContractsWrite = "contracts:write"
+)
+
+// Validade dos tokens emitidos no login da API
+const (
+	AccessTokenTTL  = time.Hour
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidToken = errors.New("token inválido, expirado ou revogado")
+	ErrInvalidScope = errors.New("escopo de permissão inválido")
+)
+
+// APIScope descreve um escopo para exibição
+type APIScope struct {
+	Code string
+	Name string
+}
+
+// APIScopes é o catálogo de escopos, na ordem de exibição
+var APIScopes = []APIScope{
+	{ScopeRequestsRead, "Ler solicitações"},
+	{ScopeRequestsWrite, "Criar, editar e cancelar solicitações"},
+	{ScopeContractsRead, "Ler contratos e observações"},
+	{ScopeContractsWrite, "Gerenciar contratos (observações e assinatura)"},
+}
+
+// ParseScopes valida a lista de escopos, removendo repetições e mantendo a
+// ordem do catálogo. Ao menos um escopo é exigido.
+func ParseScopes(values []string) ([]string, error) {
+	requested := make(map[string]bool, len(values))
+	for _, v := range values {
+		requested[strings.TrimSpace(v)] = true
+	}
+
+	var scopes []string
+	for _, s := range APIScopes {
+		if requested[s.Code] {
+			scopes = append(scopes, s.Code)
+			delete(requested, s.Code)
+		}
+	}
+	if len(scopes) == 0 || len(requested) > 0 {
+		return nil, ErrInvalidScope
+	}
+	return scopes, nil
+}
+
+// APIToken é um token de acesso à API. O valor em claro só existe no momento
+// da emissão; o banco guarda apenas o hash.
+type APIToken struct {
+	ID         int           `json:"id"`
+	UserID     int           `json:"user_id"`
+	ParentID   sql.NullInt64 `json:"parent_id"`
+	Name       string        `json:"name"`
+	Kind       string        `json:"kind"`
+	Prefix     string        `json:"prefix"`
+	Scopes     []string      `json:"scopes"`
+	ExpiresAt  sql.NullTime  `json:"expires_at"`
+	LastUsedAt sql.NullTime  `json:"last_used_at"`
+	RevokedAt  sql.NullTime  `json:"revoked_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+
+	// Campos relacionados expandidos
+	UserName string `json:"user_name,omitempty"`
+	UserType string `json:"user_type,omitempty"`
+}
+
+// HasScope indica se o token concede o escopo
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// KindLabel retorna o tipo do token para exibição
+func (t *APIToken) KindLabel() string {
+	switch t.Kind {
+	case APITokenPersonal:
+		return "Token pessoal"
+	case APITokenRefresh:
+		return "Sessão de aplicativo"
+	default:
+		return "Acesso"
+	}
+}
+
+// TokenPair é o resultado do login ou da renovação pela API
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	Scopes           []string
+}
+
+type APITokenModel struct {
+	DB *sql.DB
+}
+
+func NewAPITokenModel(db *sql.DB) *APITokenModel {
+	return &APITokenModel{DB: db}
+}
+
+// Prefixos dos tokens em claro, para identificar o tipo à primeira vista
+var apiTokenPrefixes = map[string]string{
+	APITokenPersonal: "mpp_",
+	APITokenAccess:   "mpa_",
+	APITokenRefresh:  "mpr_",
+}
+
+// generateAPIToken gera um token aleatório de 256 bits com o prefixo do tipo
+func generateAPIToken(kind string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiTokenPrefixes[kind] + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashAPIToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// insertToken grava um novo token e retorna o valor em claro
+func insertToken(q interface {
+	QueryRow(string, ...interface{}) *sql.Row
+}, token *APIToken) (string, error) {
+	plain, err := generateAPIToken(token.Kind)
+	if err != nil {
+		return "", err
+	}
+	token.Prefix = plain[:12]
+
+	err = q.QueryRow(`
+		INSERT INTO api_tokens (user_id, parent_id, name, kind, token_hash, token_prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		token.UserID, token.ParentID, token.Name, token.Kind, hashAPIToken(plain), token.Prefix,
+		strings.Join(token.Scopes, " "), token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// CreatePersonal cria um token pessoal. Retorna o valor em claro, que deve
+// ser mostrado uma única vez ao usuário.
+func (m *APITokenModel) CreatePersonal(userID int, name string, scopes []string, expiresAt sql.NullTime) (string, *APIToken, error) {
+	token := &APIToken{
+		UserID:    userID,
+		Name:      name,
+		Kind:      APITokenPersonal,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	plain, err := insertToken(m.DB, token)
+	if err != nil {
+		return "", nil, err
+	}
+	return plain, token, nil
+}
+
+// IssuePair emite um par acesso/refresh para um aplicativo (login pela API)
+func (m *APITokenModel) IssuePair(userID int, clientName string, scopes []string) (*TokenPair, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	pair, err := issuePair(tx, userID, clientName, scopes)
+	if err != nil {
+		return nil, err
+	}
+	return pair, tx.Commit()
+}
+
+// Refresh troca um token de refresh válido por um novo par. O refresh usado e
+// o acesso emitido junto com ele são revogados (rotação).
+func (m *APITokenModel) Refresh(refreshToken string) (*TokenPair, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		id, userID int
+		name       string
+		scopes     string
+	)
+	err = tx.QueryRow(`
+		SELECT id, user_id, name, scopes FROM api_tokens
+		WHERE token_hash = $1 AND kind = $2 AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		FOR UPDATE`,
+		hashAPIToken(refreshToken), APITokenRefresh,
+	).Scan(&id, &userID, &name, &scopes)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE (id = $1 OR parent_id = $1) AND revoked_at IS NULL`, id); err != nil {
+		return nil, err
+	}
+
+	pair, err := issuePair(tx, userID, name, strings.Fields(scopes))
+	if err != nil {
+		return nil, err
+	}
+	return pair, tx.Commit()
+}
+
+func issuePair(tx *sql.Tx, userID int, clientName string, scopes []string) (*TokenPair, error) {
+	now := time.Now()
+	refresh := &APIToken{
+		UserID:    userID,
+		Name:      clientName,
+		Kind:      APITokenRefresh,
+		Scopes:    scopes,
+		ExpiresAt: sql.NullTime{Time: now.Add(RefreshTokenTTL), Valid: true},
+	}
+	refreshPlain, err := insertToken(tx, refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &APIToken{
+		UserID:    userID,
+		ParentID:  sql.NullInt64{Int64: int64(refresh.ID), Valid: true},
+		Name:      clientName,
+		Kind:      APITokenAccess,
+		Scopes:    scopes,
+		ExpiresAt: sql.NullTime{Time: now.Add(AccessTokenTTL), Valid: true},
+	}
+	accessPlain, err := insertToken(tx, access)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessPlain,
+		AccessExpiresAt:  access.ExpiresAt.Time,
+		RefreshToken:     refreshPlain,
+		RefreshExpiresAt: refresh.ExpiresAt.Time,
+		Scopes:           scopes,
+	}, nil
+}
+
+// Authenticate valida um token de acesso ou pessoal e registra o uso.
+// Tokens de refresh não autenticam requisições.
+func (m *APITokenModel) Authenticate(plain string) (*APIToken, error) {
+	token := &APIToken{}
+	var scopes string
+	err := m.DB.QueryRow(`
+		SELECT t.id, t.user_id, t.parent_id, t.name, t.kind, t.token_prefix, t.scopes,
+		       t.expires_at, t.last_used_at, t.revoked_at, t.created_at, u.name, ut.type_name
+		FROM api_tokens t
+		JOIN users u ON t.user_id = u.id
+		JOIN user_types ut ON u.user_type_id = ut.id
+		WHERE t.token_hash = $1 AND t.kind IN ($2, $3) AND t.revoked_at IS NULL
+		  AND (t.expires_at IS NULL OR t.expires_at > CURRENT_TIMESTAMP)`,
+		hashAPIToken(plain), APITokenPersonal, APITokenAccess,
+	).Scan(
+		&token.ID, &token.UserID, &token.ParentID, &token.Name, &token.Kind, &token.Prefix, &scopes,
+		&token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt, &token.UserName, &token.UserType,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = strings.Fields(scopes)
+
+	m.DB.Exec("UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1 OR id = $2",
+		token.ID, token.ParentID)
+	return token, nil
+}
+
+// Revoke revoga um token do usuário (e o acesso vinculado, se for refresh)
+func (m *APITokenModel) Revoke(id, userID int) error {
+	result, err := m.DB.Exec(`UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE (id = $1 OR parent_id = $1) AND user_id = $2 AND revoked_at IS NULL`, id, userID)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// revokeUserAPITokens revoga todos os tokens pessoais e de aplicativo do
+// usuário. Acompanha o encerramento das sessões do site, para que quem
+// perdeu o acesso pelo navegador não o mantenha pela API.
+func revokeUserAPITokens(tx *sql.Tx, userID int) (int64, error) {
+	result, err := tx.Exec(`UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RevokeByValue revoga o token informado em claro, de qualquer tipo.
+// Revogar um acesso também revoga o refresh que o originou.
+func (m *APITokenModel) RevokeByValue(plain string) error {
+	_, err := m.DB.Exec(`
+		WITH target AS (
+			SELECT COALESCE(parent_id, id) AS root FROM api_tokens WHERE token_hash = $1
+		)
+		UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE (id IN (SELECT root FROM target) OR parent_id IN (SELECT root FROM target))
+		  AND revoked_at IS NULL`, hashAPIToken(plain))
+	return err
+}
+
+// GetActiveByUserID lista os tokens pessoais e as sessões de aplicativo
+// ainda válidas do usuário
+func (m *APITokenModel) GetActiveByUserID(userID int) ([]APIToken, error) {
+	rows, err := m.DB.Query(`
+		SELECT t.id, t.user_id, t.parent_id, t.name, t.kind, t.token_prefix, t.scopes,
+		       t.expires_at, t.last_used_at, t.revoked_at, t.created_at
+		FROM api_tokens t
+		WHERE t.user_id = $1 AND t.kind IN ($2, $3) AND t.revoked_at IS NULL
+		  AND (t.expires_at IS NULL OR t.expires_at > CURRENT_TIMESTAMP)
+		ORDER BY t.created_at DESC`, userID, APITokenPersonal, APITokenRefresh)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		var token APIToken
+		var scopes string
+		if err := rows.Scan(
+			&token.ID, &token.UserID, &token.ParentID, &token.Name, &token.Kind, &token.Prefix, &scopes,
+			&token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt, &token.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		token.Scopes = strings.Fields(scopes)
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
@@ -0,0 +1,587 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actor identifica quem executa uma alteração (usuário e IP) para o
+// registro de auditoria. UserID 0 indica o próprio sistema.
+type Actor struct {
+	UserID int
+	IP     string
+}
+
+// Ações registradas em audit_log
+const (
+	AuditCreate         = "CRIAR"
+	AuditUpdate         = "ALTERAR"
+	AuditDelete         = "EXCLUIR"
+	AuditStatus         = "ALTERAR_STATUS"
+	AuditSend           = "ENVIAR"
+	AuditSign           = "ASSINAR"
+	AuditResolve        = "RESOLVER"
+	AuditConvert        = "CONVERTER"
+	AuditStockMovement  = "MOVIMENTAR_ESTOQUE"
+	AuditApprove        = "APROVAR"
+	AuditReject         = "RECUSAR"
+	AuditAssignRole     = "ATRIBUIR_PAPEL"
+	AuditPermissions    = "ALTERAR_PERMISSOES"
+	AuditUnlock         = "DESBLOQUEAR"
+	AuditRevokeSessions = "ENCERRAR_SESSOES"
+	AuditRestore        = "RESTAURAR"
+	AuditPurge          = "EXPURGAR"
+)
+
+// Tipos de registro auditados
+const (
+	AuditEntityRequest          = "solicitacao"
+	AuditEntityContract         = "contrato"
+	AuditEntityContractItem     = "item_contrato"
+	AuditEntityObservation      = "observacao"
+	AuditEntityQuote            = "orcamento"
+	AuditEntityQuoteItem        = "item_orcamento"
+	AuditEntityMaterial         = "material"
+	AuditEntityPriceTable       = "tabela_preco"
+	AuditEntityPriceRule        = "regra_preco"
+	AuditEntityCityDistance     = "distancia"
+	AuditEntityWell             = "poco"
+	AuditEntityDrillingInterval = "perfil_poco"
+	AuditEntityAnalysis         = "analise"
+	AuditEntityMaintenancePlan  = "manutencao"
+	AuditEntityWarrantyClaim    = "garantia"
+	AuditEntityUser             = "usuario"
+	AuditEntityRole             = "papel"
+	AuditEntityErasure          = "pedido_exclusao"
+	AuditEntityLogin            = "acesso"
+	AuditEntityServiceType      = "tipo_servico"
+	AuditEntityRequestStatus    = "status_solicitacao"
+	AuditEntityGuaranteeType    = "tipo_garantia"
+	AuditEntityContractStatus   = "status_contrato"
+	AuditEntityServiceField     = "campo_servico"
+)
+
+// Tabela de onde vem o retrato (antes/depois) de cada tipo de registro.
+// Tipos sem tabela gravam apenas os dados informados pela ação.
+var auditTables = map[string]string{
+	AuditEntityRequest:          "service_requests",
+	AuditEntityContract:         "contracts",
+	AuditEntityContractItem:     "contract_items",
+	AuditEntityObservation:      "contract_client_observations",
+	AuditEntityQuote:            "quotes",
+	AuditEntityQuoteItem:        "quote_items",
+	AuditEntityMaterial:         "materials",
+	AuditEntityPriceTable:       "price_tables",
+	AuditEntityPriceRule:        "price_rules",
+	AuditEntityCityDistance:     "price_city_distances",
+	AuditEntityWell:             "wells",
+	AuditEntityDrillingInterval: "well_drilling_log",
+	AuditEntityAnalysis:         "water_analyses",
+	AuditEntityMaintenancePlan:  "maintenance_plans",
+	AuditEntityWarrantyClaim:    "warranty_claims",
+	AuditEntityUser:             "users",
+	AuditEntityRole:             "roles",
+	AuditEntityErasure:          "data_erasure_requests",
+	AuditEntityServiceType:      "service_types",
+	AuditEntityRequestStatus:    "request_status",
+	AuditEntityGuaranteeType:    "guarantee_types",
+	AuditEntityContractStatus:   "contract_status",
+	AuditEntityServiceField:     "service_type_fields",
+}
+
+// Colunas que nunca vão para o registro (segredos e imagens de assinatura)
+var auditRedacted = []string{"password", "totp_secret", "client_signature", "company_signature"}
+
+// Campos pessoais retirados dos retratos quando o titular é anonimizado
+// (LGPD), por tipo de registro. Acompanham o que anonymizeAccount e
+// anonymizeRequests apagam nas próprias tabelas.
+var auditPersonalFields = map[string][]string{
+	AuditEntityUser:          {"name", "email", "phone", "address", "pending_email"},
+	AuditEntityRequest:       {"full_name", "description", "cep", "logradouro", "numero", "bairro", "latitude", "longitude", "custom_fields"},
+	AuditEntityContract:      {"client_requirements"},
+	AuditEntityQuote:         {"client_response"},
+	AuditEntityWarrantyClaim: {"description"},
+	AuditEntityWell:          {"latitude", "longitude"},
+}
+
+// Campos que mudam em toda alteração e só poluiriam a comparação
+var auditIgnoredChanges = map[string]bool{"updated_at": true}
+
+// AuditOption é um item das listas de filtro do registro de auditoria
+type AuditOption struct {
+	Code  string
+	Label string
+}
+
+// AuditActions lista as ações na ordem de exibição dos filtros
+var AuditActions = []AuditOption{
+	{AuditCreate, "Criação"},
+	{AuditUpdate, "Alteração"},
+	{AuditDelete, "Exclusão"},
+	{AuditStatus, "Mudança de status"},
+	{AuditSend, "Envio ao cliente"},
+	{AuditSign, "Assinatura"},
+	{AuditResolve, "Observação resolvida"},
+	{AuditConvert, "Conversão em contrato"},
+	{AuditStockMovement, "Movimentação de estoque"},
+	{AuditApprove, "Aprovação"},
+	{AuditReject, "Recusa"},
+	{AuditAssignRole, "Atribuição de papel"},
+	{AuditPermissions, "Permissões do papel"},
+	{AuditUnlock, "Desbloqueio de acesso"},
+	{AuditRevokeSessions, "Sessões encerradas"},
+	{AuditRestore, "Restauração da lixeira"},
+	{AuditPurge, "Expurgo da lixeira"},
+}
+
+// AuditEntityTypes lista os tipos de registro na ordem de exibição dos filtros
+var AuditEntityTypes = []AuditOption{
+	{AuditEntityRequest, "Solicitação"},
+	{AuditEntityQuote, "Orçamento"},
+	{AuditEntityQuoteItem, "Item de orçamento"},
+	{AuditEntityContract, "Contrato"},
+	{AuditEntityContractItem, "Item de contrato"},
+	{AuditEntityObservation, "Observação de contrato"},
+	{AuditEntityWarrantyClaim, "Garantia"},
+	{AuditEntityWell, "Poço"},
+	{AuditEntityDrillingInterval, "Perfil do poço"},
+	{AuditEntityAnalysis, "Análise da água"},
+	{AuditEntityMaintenancePlan, "Plano de manutenção"},
+	{AuditEntityMaterial, "Material"},
+	{AuditEntityPriceTable, "Tabela de preço"},
+	{AuditEntityPriceRule, "Regra de preço"},
+	{AuditEntityCityDistance, "Distância de cidade"},
+	{AuditEntityUser, "Usuário"},
+	{AuditEntityRole, "Papel"},
+	{AuditEntityErasure, "Pedido de exclusão (LGPD)"},
+	{AuditEntityLogin, "Acesso"},
+	{AuditEntityServiceType, "Tipo de serviço"},
+	{AuditEntityRequestStatus, "Status de solicitação"},
+	{AuditEntityGuaranteeType, "Tipo de garantia"},
+	{AuditEntityContractStatus, "Status de contrato"},
+	{AuditEntityServiceField, "Campo de formulário"},
+}
+
+func auditLabel(options []AuditOption, code string) string {
+	for _, o := range options {
+		if o.Code == code {
+			return o.Label
+		}
+	}
+	return code
+}
+
+// ValidAuditAction indica se o código é uma ação conhecida (filtros)
+func ValidAuditAction(code string) bool {
+	return auditLabel(AuditActions, code) != code
+}
+
+// ValidAuditEntityType indica se o código é um tipo de registro conhecido (filtros)
+func ValidAuditEntityType(code string) bool {
+	return auditLabel(AuditEntityTypes, code) != code
+}
+
+// AuditEntry é uma linha do registro de auditoria
+type AuditEntry struct {
+	ID         int64          `json:"id"`
+	ActorID    sql.NullInt64  `json:"actor_id"`
+	Action     string         `json:"action"`
+	EntityType string         `json:"entity_type"`
+	EntityID   sql.NullInt64  `json:"entity_id"`
+	Before     sql.NullString `json:"before"`
+	After      sql.NullString `json:"after"`
+	IP         sql.NullString `json:"ip"`
+	CreatedAt  time.Time      `json:"created_at"`
+
+	// Campos relacionados expandidos
+	ActorName string `json:"actor_name,omitempty"`
+}
+
+// ActionLabel retorna o nome da ação para exibição
+func (e *AuditEntry) ActionLabel() string {
+	return auditLabel(AuditActions, e.Action)
+}
+
+// EntityLabel retorna o nome do tipo de registro para exibição
+func (e *AuditEntry) EntityLabel() string {
+	return auditLabel(AuditEntityTypes, e.EntityType)
+}
+
+// EntityURL retorna a página do registro no painel (vazio se não houver)
+func (e *AuditEntry) EntityURL() string {
+	if !e.EntityID.Valid || e.Action == AuditDelete || e.Action == AuditPurge {
+		return ""
+	}
+	id := strconv.FormatInt(e.EntityID.Int64, 10)
+	switch e.EntityType {
+	case AuditEntityRequest:
+		return "/admin/solicitacao/" + id
+	case AuditEntityContract:
+		return "/admin/contratos/" + id
+	case AuditEntityQuote:
+		return "/admin/orcamentos/" + id
+	case AuditEntityMaterial:
+		return "/admin/materiais/" + id
+	case AuditEntityPriceTable:
+		return "/admin/precos/" + id
+	case AuditEntityWell:
+		return "/admin/pocos/" + id
+	case AuditEntityAnalysis:
+		return "/admin/analises/" + id
+	case AuditEntityWarrantyClaim:
+		return "/admin/garantias/" + id
+	case AuditEntityMaintenancePlan:
+		return "/admin/manutencoes/" + id + "/editar"
+	case AuditEntityServiceType, AuditEntityRequestStatus, AuditEntityGuaranteeType, AuditEntityContractStatus:
+		for _, catalog := range Catalogs {
+			if catalog.EntityType == e.EntityType {
+				return "/admin/cadastros/" + catalog.Slug + "/" + id + "/editar"
+			}
+		}
+		return ""
+	case AuditEntityServiceField:
+		return "/admin/cadastros/campos/" + id + "/editar"
+	default:
+		return ""
+	}
+}
+
+// AuditChange é um campo alterado, com os valores antes e depois
+type AuditChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// Changes compara os retratos antes e depois e retorna os campos que
+// mudaram. Na criação aparecem os campos preenchidos; na exclusão, os que
+// existiam.
+func (e *AuditEntry) Changes() []AuditChange {
+	before := decodeAuditData(e.Before)
+	after := decodeAuditData(e.After)
+
+	fields := map[string]bool{}
+	for k := range before {
+		fields[k] = true
+	}
+	for k := range after {
+		fields[k] = true
+	}
+
+	var names []string
+	for k := range fields {
+		if !auditIgnoredChanges[k] {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []AuditChange
+	for _, name := range names {
+		b, a := formatAuditValue(before[name]), formatAuditValue(after[name])
+		if b != a {
+			changes = append(changes, AuditChange{Field: name, Before: b, After: a})
+		}
+	}
+	return changes
+}
+
+// ChangesSummary descreve as alterações em uma linha (exportação CSV)
+func (e *AuditEntry) ChangesSummary() string {
+	var parts []string
+	for _, c := range e.Changes() {
+		parts = append(parts, fmt.Sprintf("%s: %q → %q", c.Field, c.Before, c.After))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func decodeAuditData(data sql.NullString) map[string]interface{} {
+	values := map[string]interface{}{}
+	if data.Valid {
+		json.Unmarshal([]byte(data.String), &values)
+	}
+	return values
+}
+
+func formatAuditValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case bool:
+		if value {
+			return "sim"
+		}
+		return "não"
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(value)
+		return string(data)
+	}
+}
+
+// ============================================
+// GRAVAÇÃO
+// ============================================
+
+// auditDB é o que os auxiliares de auditoria usam do banco: *sql.DB ou a
+// *sql.Tx da alteração, para que o registro entre na mesma transação
+type auditDB interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// auditSnapshot retorna a linha atual do registro como JSON, sem as colunas
+// sigilosas. Registro inexistente (ou tipo sem tabela) retorna NULL.
+func auditSnapshot(db auditDB, entityType string, id int) (sql.NullString, error) {
+	table, ok := auditTables[entityType]
+	if !ok || id == 0 {
+		return sql.NullString{}, nil
+	}
+
+	var data string
+	err := db.QueryRow(`SELECT to_jsonb(t)::text FROM `+table+` t WHERE t.id = $1`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, nil
+	}
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	values := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return sql.NullString{}, err
+	}
+	for _, key := range auditRedacted {
+		if v, ok := values[key]; ok && v != nil && v != "" {
+			values[key] = "(omitido)"
+		}
+	}
+	return auditJSON(values), nil
+}
+
+// anonymizeAuditData retira os campos pessoais dos retratos antes/depois
+// dos registros do tipo informado. ids é uma subconsulta com os IDs
+// afetados, que recebe args. Deve rodar na transação da anonimização.
+func anonymizeAuditData(tx *sql.Tx, entityType, ids string, args ...interface{}) error {
+	keys := auditPersonalFields[entityType]
+	if len(keys) == 0 {
+		return nil
+	}
+	array := "ARRAY['" + strings.Join(keys, "','") + "']"
+
+	_, err := tx.Exec(`
+		UPDATE audit_log
+		SET before_data = before_data - `+array+`, after_data = after_data - `+array+`
+		WHERE entity_type = '`+entityType+`' AND entity_id IN (`+ids+`)`, args...)
+	return err
+}
+
+// auditJSON converte dados informados pela própria ação (ex.: lista de
+// permissões) para gravar como antes/depois
+func auditJSON(v interface{}) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// insertAudit grava uma linha do registro de auditoria
+func insertAudit(db auditDB, actor Actor, action, entityType string, id int, before, after sql.NullString) error {
+	_, err := db.Exec(`
+		INSERT INTO audit_log (actor_id, action, entity_type, entity_id, before_data, after_data, ip)
+		VALUES (NULLIF($1, 0), $2, $3, NULLIF($4, 0), $5::jsonb, $6::jsonb, NULLIF($7, ''))`,
+		actor.UserID, action, entityType, id, before, after, actor.IP)
+	return err
+}
+
+// recordAudit grava a ação com o retrato anterior informado e o atual lido
+// da tabela. Deve ser chamado na transação da alteração, depois dela.
+func recordAudit(db auditDB, actor Actor, action, entityType string, id int, before sql.NullString) error {
+	after, err := auditSnapshot(db, entityType, id)
+	if err != nil {
+		return err
+	}
+	return insertAudit(db, actor, action, entityType, id, before, after)
+}
+
+// withAudit executa uma alteração simples em uma transação, registrando o
+// retrato do registro antes e depois. write retorna o ID do registro
+// criado (ou 0 para manter id).
+func withAudit(db *sql.DB, actor Actor, action, entityType string, id int, write func(tx *sql.Tx) (int, error)) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, entityType, id)
+	if err != nil {
+		return err
+	}
+
+	newID, err := write(tx)
+	if err != nil {
+		return err
+	}
+	if newID != 0 {
+		id = newID
+	}
+
+	if err := recordAudit(tx, actor, action, entityType, id, before); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// requireAffected converte uma alteração que não atingiu nenhuma linha em
+// sql.ErrNoRows, para uso dentro de withAudit
+func requireAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ============================================
+// CONSULTA
+// ============================================
+
+// AuditFilter são os filtros do visualizador. Campos zerados não filtram.
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	EntityType string
+	EntityID   int
+	From       time.Time
+	To         time.Time
+}
+
+// AuditActorOption é um usuário que aparece como autor no registro
+type AuditActorOption struct {
+	ID   int
+	Name string
+}
+
+type AuditModel struct {
+	DB *sql.DB
+}
+
+func NewAuditModel(db *sql.DB) *AuditModel {
+	return &AuditModel{DB: db}
+}
+
+func (f AuditFilter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if f.ActorID > 0 {
+		add("a.actor_id = $%d", f.ActorID)
+	}
+	if f.Action != "" {
+		add("a.action = $%d", f.Action)
+	}
+	if f.EntityType != "" {
+		add("a.entity_type = $%d", f.EntityType)
+	}
+	if f.EntityID > 0 {
+		add("a.entity_id = $%d", f.EntityID)
+	}
+	if !f.From.IsZero() {
+		add("a.created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		// Até o fim do dia informado
+		add("a.created_at < $%d", f.To.AddDate(0, 0, 1))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Search lista o registro conforme os filtros, do mais recente para o mais
+// antigo, com o total para a paginação
+func (m *AuditModel) Search(filter AuditFilter, limit, offset int) ([]AuditEntry, int, error) {
+	where, args := filter.where()
+
+	var total int
+	if err := m.DB.QueryRow(`SELECT COUNT(*) FROM audit_log a`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT a.id, a.actor_id, a.action, a.entity_type, a.entity_id, a.before_data::text, a.after_data::text,
+		       a.ip, a.created_at, COALESCE(u.name, '')
+		FROM audit_log a
+		LEFT JOIN users u ON a.actor_id = u.id
+		%s
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+
+	rows, err := m.DB.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.EntityType, &e.EntityID, &e.Before, &e.After,
+			&e.IP, &e.CreatedAt, &e.ActorName)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// GetActors lista os usuários que já aparecem como autores no registro
+func (m *AuditModel) GetActors() ([]AuditActorOption, error) {
+	rows, err := m.DB.Query(`
+		SELECT u.id, u.name FROM users u
+		WHERE EXISTS (SELECT 1 FROM audit_log a WHERE a.actor_id = u.id)
+		ORDER BY u.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actors []AuditActorOption
+	for rows.Next() {
+		var a AuditActorOption
+		if err := rows.Scan(&a.ID, &a.Name); err != nil {
+			return nil, err
+		}
+		actors = append(actors, a)
+	}
+	return actors, rows.Err()
+}
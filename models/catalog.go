@@ -0,0 +1,444 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCatalogCodeTaken    = errors.New("já existe um item com este código")
+	ErrCatalogInvalidCode  = errors.New("código inválido: use letras, números e _ (até 50 caracteres)")
+	ErrCatalogSystemValue  = errors.New("este item é usado pelo sistema e não pode ser desativado")
+	ErrCatalogInUse        = errors.New("há registros em aberto usando este item; conclua-os ou altere-os antes de desativar")
+	ErrCatalogInvalidOrder = errors.New("a nova ordem não corresponde aos itens do cadastro")
+)
+
+var catalogCodePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,50}$`)
+
+// CatalogColor é uma opção do seletor de cor dos status
+type CatalogColor struct {
+	Value      string
+	Label      string
+	ColorClass string
+	BadgeClass string
+	// Classe usada para mostrar a amostra no seletor e nas listagens
+	Preview string
+}
+
+// Catalog descreve uma das tabelas de valores do sistema (tipos de serviço,
+// status, tipos de garantia) e as colunas que cada uma tem
+type Catalog struct {
+	Slug       string
+	Name       string
+	ItemLabel  string
+	EntityType string
+	Table      string
+
+	HasIcon      bool
+	Colors       []CatalogColor
+	HasGuarantee bool
+
+	// Códigos referenciados no código da aplicação: não podem ser desativados
+	SystemCodes []string
+
+	// inUse conta os registros em aberto que usam o item ($1 = id)
+	inUse string
+}
+
+// HasColor indica se o cadastro tem seletor de cor
+func (c *Catalog) HasColor() bool {
+	return len(c.Colors) > 0
+}
+
+// color retorna a opção de cor pelo valor do formulário
+func (c *Catalog) color(value string) (CatalogColor, bool) {
+	for _, color := range c.Colors {
+		if color.Value == value {
+			return color, true
+		}
+	}
+	return CatalogColor{}, false
+}
+
+// ApplyColor aplica ao item a cor escolhida no formulário
+func (c *Catalog) ApplyColor(item *CatalogItem, value string) bool {
+	color, ok := c.color(value)
+	if !ok {
+		return false
+	}
+	item.ColorClass, item.BadgeClass = color.ColorClass, color.BadgeClass
+	return true
+}
+
+func (c *Catalog) isSystemCode(code string) bool {
+	for _, system := range c.SystemCodes {
+		if system == code {
+			return true
+		}
+	}
+	return false
+}
+
+// CatalogIcons são os ícones (Bootstrap Icons) oferecidos para os tipos de serviço
+var CatalogIcons = []string{
+	"search", "arrow-down-circle", "tools", "wrench", "gear", "droplet", "droplet-half",
+	"moisture", "water", "bucket", "funnel", "thermometer-half", "lightning", "hammer",
+	"clipboard-check", "shield-check", "truck", "house", "geo-alt", "recycle",
+}
+
+// Cores dos status de solicitação (classes definidas em global.css)
+var requestStatusColors = []CatalogColor{
+	{"status-solicitada", "Amarelo", "status-solicitada", "", "status-badge status-solicitada"},
+	{"status-confirmada", "Azul", "status-confirmada", "", "status-badge status-confirmada"},
+	{"status-andamento", "Roxo", "status-andamento", "", "status-badge status-andamento"},
+	{"status-realizada", "Verde", "status-realizada", "", "status-badge status-realizada"},
+	{"status-cancelada", "Vermelho", "status-cancelada", "", "status-badge status-cancelada"},
+	{"status-neutra", "Cinza", "status-neutra", "", "status-badge status-neutra"},
+}
+
+// Cores dos status de contrato (cores contextuais do Bootstrap)
+var contractStatusColors = []CatalogColor{
+	{"secondary", "Cinza", "text-secondary", "bg-secondary", "badge bg-secondary"},
+	{"primary", "Azul", "text-primary", "bg-primary", "badge bg-primary"},
+	{"info", "Ciano", "text-info", "bg-info text-dark", "badge bg-info text-dark"},
+	{"warning", "Amarelo", "text-warning", "bg-warning text-dark", "badge bg-warning text-dark"},
+	{"success", "Verde", "text-success", "bg-success", "badge bg-success"},
+	{"danger", "Vermelho", "text-danger", "bg-danger", "badge bg-danger"},
+	{"dark", "Preto", "text-dark", "bg-dark", "badge bg-dark"},
+}
+
+// Catalogs lista os cadastros administráveis, na ordem das abas
+var Catalogs = []*Catalog{
+	{
+		Slug:        "tipos-servico",
+		Name:        "Tipos de serviço",
+		ItemLabel:   "tipo de serviço",
+		EntityType:  AuditEntityServiceType,
+		Table:       "service_types",
+		HasIcon:     true,
+		SystemCodes: []string{"analise", "manutencao"},
+		inUse: `
+			SELECT COUNT(*) FROM service_requests sr
+			JOIN request_status rs ON sr.status_id = rs.id
+			WHERE sr.service_type_id = $1 AND sr.deleted_at IS NULL
+			  AND rs.code NOT IN ('REALIZADA', 'CANCELADA')`,
+	},
+	{
+		Slug:        "status-solicitacao",
+		Name:        "Status de solicitação",
+		ItemLabel:   "status de solicitação",
+		EntityType:  AuditEntityRequestStatus,
+		Table:       "request_status",
+		Colors:      requestStatusColors,
+		SystemCodes: []string{"SOLICITADA", "CONFIRMADA", "REALIZADA", "CANCELADA"},
+		inUse:       `SELECT COUNT(*) FROM service_requests WHERE status_id = $1 AND deleted_at IS NULL`,
+	},
+	{
+		Slug:         "tipos-garantia",
+		Name:         "Tipos de garantia",
+		ItemLabel:    "tipo de garantia",
+		EntityType:   AuditEntityGuaranteeType,
+		Table:        "guarantee_types",
+		HasGuarantee: true,
+		SystemCodes:  []string{GuaranteeSecondAttempt, "SEM_GARANTIA", "PERSONALIZADA"},
+		inUse: `
+			SELECT COUNT(*) FROM contracts c
+			JOIN contract_status cs ON c.status_id = cs.id
+			WHERE c.guarantee_type_id = $1 AND cs.code IN ('RASCUNHO', 'AGUARDANDO_ASSINATURAS')`,
+	},
+	{
+		Slug:        "status-contrato",
+		Name:        "Status de contrato",
+		ItemLabel:   "status de contrato",
+		EntityType:  AuditEntityContractStatus,
+		Table:       "contract_status",
+		Colors:      contractStatusColors,
+		SystemCodes: []string{"RASCUNHO", "AGUARDANDO_ASSINATURAS", "ASSINADO", "CANCELADO"},
+		inUse:       `SELECT COUNT(*) FROM contracts WHERE status_id = $1`,
+	},
+}
+
+// CatalogBySlug retorna o cadastro pelo trecho da URL
+func CatalogBySlug(slug string) (*Catalog, bool) {
+	for _, catalog := range Catalogs {
+		if catalog.Slug == slug {
+			return catalog, true
+		}
+	}
+	return nil, false
+}
+
+// CatalogItem é um valor de um dos cadastros. Os campos que a tabela não
+// tem ficam vazios.
+type CatalogItem struct {
+	ID                 int
+	Code               string
+	Name               string
+	Description        string
+	Icon               string
+	ColorClass         string
+	BadgeClass         string
+	RequiresCustomText bool
+	WarrantyMonths     sql.NullInt64
+	DisplayOrder       int
+	Active             bool
+
+	// Preenchidos na listagem
+	System bool
+	InUse  int
+}
+
+// Normalize limpa os campos do formulário e valida o código
+func (i *CatalogItem) Normalize() error {
+	i.Code = strings.TrimSpace(i.Code)
+	i.Name = strings.TrimSpace(i.Name)
+	i.Description = strings.TrimSpace(i.Description)
+	if !catalogCodePattern.MatchString(i.Code) {
+		return ErrCatalogInvalidCode
+	}
+	return nil
+}
+
+// ColorValue retorna o valor do seletor de cor correspondente às classes do item
+func (i *CatalogItem) ColorValue(catalog *Catalog) string {
+	for _, color := range catalog.Colors {
+		if color.ColorClass == i.ColorClass {
+			return color.Value
+		}
+	}
+	return ""
+}
+
+// CatalogModel administra os cadastros de valores (ver Catalogs)
+type CatalogModel struct {
+	DB *sql.DB
+}
+
+func NewCatalogModel(db *sql.DB) *CatalogModel {
+	return &CatalogModel{DB: db}
+}
+
+// selectColumns monta a lista de colunas do cadastro, com valores vazios
+// para as colunas que a tabela não tem
+func (c *Catalog) selectColumns() string {
+	icon, color, badge, custom, months := "''", "''", "''", "false", "NULL::integer"
+	if c.HasIcon {
+		icon = "COALESCE(icon, '')"
+	}
+	if c.HasColor() {
+		color = "COALESCE(color_class, '')"
+	}
+	if c.Table == "contract_status" {
+		badge = "COALESCE(badge_class, '')"
+	}
+	if c.HasGuarantee {
+		custom, months = "COALESCE(requires_custom_text, false)", "warranty_months"
+	}
+	return "id, code, name, COALESCE(description, ''), " + icon + ", " + color + ", " + badge + ", " +
+		custom + ", " + months + ", COALESCE(display_order, 0), COALESCE(active, true)"
+}
+
+func scanCatalogItem(row interface{ Scan(...interface{}) error }, item *CatalogItem) error {
+	return row.Scan(&item.ID, &item.Code, &item.Name, &item.Description, &item.Icon, &item.ColorClass,
+		&item.BadgeClass, &item.RequiresCustomText, &item.WarrantyMonths, &item.DisplayOrder, &item.Active)
+}
+
+// List retorna todos os itens do cadastro, inclusive os inativos, com a
+// contagem de registros em aberto que usam cada um
+func (m *CatalogModel) List(catalog *Catalog) ([]CatalogItem, error) {
+	rows, err := m.DB.Query(`SELECT ` + catalog.selectColumns() + `, (` +
+		strings.Replace(catalog.inUse, "$1", catalog.Table+".id", 1) + `)
+		FROM ` + catalog.Table + ` ORDER BY display_order NULLS LAST, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []CatalogItem
+	for rows.Next() {
+		var item CatalogItem
+		err := rows.Scan(&item.ID, &item.Code, &item.Name, &item.Description, &item.Icon, &item.ColorClass,
+			&item.BadgeClass, &item.RequiresCustomText, &item.WarrantyMonths, &item.DisplayOrder, &item.Active,
+			&item.InUse)
+		if err != nil {
+			return nil, err
+		}
+		item.System = catalog.isSystemCode(item.Code)
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetByID busca um item do cadastro
+func (m *CatalogModel) GetByID(catalog *Catalog, id int) (*CatalogItem, error) {
+	item := &CatalogItem{}
+	err := scanCatalogItem(m.DB.QueryRow(`SELECT `+catalog.selectColumns()+` FROM `+catalog.Table+` WHERE id = $1`, id), item)
+	if err != nil {
+		return nil, err
+	}
+	item.System = catalog.isSystemCode(item.Code)
+	return item, nil
+}
+
+// itemColumns retorna as colunas editáveis do cadastro e os valores do item.
+// Textos vazios são gravados como '' (as listagens antigas não aceitam NULL).
+func (c *Catalog) itemColumns(item *CatalogItem) ([]string, []interface{}) {
+	columns := []string{"name", "description"}
+	values := []interface{}{item.Name, item.Description}
+	if c.HasIcon {
+		columns = append(columns, "icon")
+		values = append(values, item.Icon)
+	}
+	if c.HasColor() {
+		columns = append(columns, "color_class")
+		values = append(values, item.ColorClass)
+	}
+	if c.Table == "contract_status" {
+		columns = append(columns, "badge_class")
+		values = append(values, item.BadgeClass)
+	}
+	if c.HasGuarantee {
+		columns = append(columns, "requires_custom_text", "warranty_months")
+		values = append(values, item.RequiresCustomText, item.WarrantyMonths)
+	}
+	return columns, values
+}
+
+func (m *CatalogModel) codeExists(catalog *Catalog, code string) (bool, error) {
+	var exists bool
+	err := m.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+catalog.Table+` WHERE LOWER(code) = LOWER($1))`, code).Scan(&exists)
+	return exists, err
+}
+
+// Create cadastra um item ativo no fim da lista
+func (m *CatalogModel) Create(catalog *Catalog, item *CatalogItem, actor Actor) error {
+	exists, err := m.codeExists(catalog, item.Code)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrCatalogCodeTaken
+	}
+
+	columns, values := catalog.itemColumns(item)
+	columns = append(columns, "code", "display_order", "active")
+	values = append(values, item.Code)
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+	}
+
+	return withAudit(m.DB, actor, AuditCreate, catalog.EntityType, 0, func(tx *sql.Tx) (int, error) {
+		query := `INSERT INTO ` + catalog.Table + ` (` + strings.Join(columns, ", ") + `)
+			VALUES (` + strings.Join(placeholders, ", ") + `,
+			        (SELECT COALESCE(MAX(display_order), 0) + 1 FROM ` + catalog.Table + `), true)
+			RETURNING id, display_order`
+		err := tx.QueryRow(query, values...).Scan(&item.ID, &item.DisplayOrder)
+		item.Active = true
+		return item.ID, err
+	})
+}
+
+// Update altera nome, descrição e aparência do item. O código não muda:
+// ele pode estar referenciado no sistema e em filtros salvos.
+func (m *CatalogModel) Update(catalog *Catalog, item *CatalogItem, actor Actor) error {
+	columns, values := catalog.itemColumns(item)
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = column + " = $" + strconv.Itoa(i+1)
+	}
+	values = append(values, item.ID)
+
+	return withAudit(m.DB, actor, AuditUpdate, catalog.EntityType, item.ID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(`UPDATE `+catalog.Table+` SET `+strings.Join(sets, ", ")+
+			` WHERE id = $`+strconv.Itoa(len(values)), values...))
+	})
+}
+
+// SetActive ativa ou desativa o item. Itens usados pelo sistema ou por
+// registros em aberto não podem ser desativados.
+func (m *CatalogModel) SetActive(catalog *Catalog, id int, active bool, actor Actor) error {
+	return withAudit(m.DB, actor, AuditStatus, catalog.EntityType, id, func(tx *sql.Tx) (int, error) {
+		var code string
+		err := tx.QueryRow(`SELECT code FROM `+catalog.Table+` WHERE id = $1 FOR UPDATE`, id).Scan(&code)
+		if err != nil {
+			return 0, err
+		}
+
+		if !active {
+			if catalog.isSystemCode(code) {
+				return 0, ErrCatalogSystemValue
+			}
+			var inUse int
+			if err := tx.QueryRow(catalog.inUse, id).Scan(&inUse); err != nil {
+				return 0, err
+			}
+			if inUse > 0 {
+				return 0, ErrCatalogInUse
+			}
+		}
+
+		_, err = tx.Exec(`UPDATE `+catalog.Table+` SET active = $1 WHERE id = $2`, active, id)
+		return 0, err
+	})
+}
+
+// Reorder grava a ordem de exibição. ids deve conter todos os itens do
+// cadastro, na nova ordem; só os que mudaram de posição vão para a auditoria.
+func (m *CatalogModel) Reorder(catalog *Catalog, ids []int, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := map[int]int{}
+	rows, err := tx.Query(`SELECT id, COALESCE(display_order, 0) FROM ` + catalog.Table + ` FOR UPDATE`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id, order int
+		if err := rows.Scan(&id, &order); err != nil {
+			rows.Close()
+			return err
+		}
+		current[id] = order
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(ids) != len(current) {
+		return ErrCatalogInvalidOrder
+	}
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if _, ok := current[id]; !ok || seen[id] {
+			return ErrCatalogInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	for i, id := range ids {
+		if current[id] == i+1 {
+			continue
+		}
+		before, err := auditSnapshot(tx, catalog.EntityType, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE `+catalog.Table+` SET display_order = $1 WHERE id = $2`, i+1, id); err != nil {
+			return err
+		}
+		if err := recordAudit(tx, actor, AuditUpdate, catalog.EntityType, id, before); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
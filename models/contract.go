@@ -152,12 +152,14 @@ func (m *ContractModel) GetPendingObservationsCount(contractID int) (int, error)
 }
 
 // ResolveObservation marca uma observação como resolvida
-func (m *ContractModel) ResolveObservation(observationID, adminID int) error {
+func (m *ContractModel) ResolveObservation(observationID int, actor Actor) error {
 	query := `UPDATE contract_client_observations 
 	          SET resolved = true, resolved_at = CURRENT_TIMESTAMP, resolved_by = $1 
 	          WHERE id = $2`
-	_, err := m.DB.Exec(query, adminID, observationID)
-	return err
+	return withAudit(m.DB, actor, AuditResolve, AuditEntityObservation, observationID, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(query, actor.UserID, observationID)
+		return 0, err
+	})
 }
 
 // DeleteObservation remove uma observação (apenas se não resolvida)
@@ -261,14 +263,26 @@ func (m *ContractModel) GetGuaranteeTypeIDByCode(code string) (int, error) {
 
 // GenerateContractNumber gera um número único para o contrato
 func (m *ContractModel) GenerateContractNumber() string {
+	return nextContractNumber(m.DB)
+}
+
+// nextContractNumber calcula o próximo número do ano; aceita *sql.DB ou *sql.Tx
+func nextContractNumber(q interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}) string {
 	year := time.Now().Year()
 	var count int
-	m.DB.QueryRow("SELECT COUNT(*) FROM contracts WHERE EXTRACT(YEAR FROM created_at) = $1", year).Scan(&count)
+	q.QueryRow("SELECT COUNT(*) FROM contracts WHERE EXTRACT(YEAR FROM created_at) = $1", year).Scan(&count)
 	return fmt.Sprintf("MP-%d-%04d", year, count+1)
 }
 
+// Condição das alterações de contrato: a solicitação não pode estar na
+// lixeira (solicitação excluída conta como inexistente)
+const contractRequestActive = `EXISTS (SELECT 1 FROM service_requests sr
+	WHERE sr.id = contracts.service_request_id AND sr.deleted_at IS NULL)`
+
 // Create cria um novo contrato
-func (m *ContractModel) Create(contract *Contract) error {
+func (m *ContractModel) Create(contract *Contract, actor Actor) error {
 	contract.ContractNumber = m.GenerateContractNumber()
 	
 	// Obter ID do status "RASCUNHO"
@@ -286,43 +300,49 @@ func (m *ContractModel) Create(contract *Contract) error {
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 		RETURNING id, created_at, updated_at`
 
-	return m.DB.QueryRow(
-		query,
-		contract.ServiceRequestID, contract.ContractNumber, contract.TotalValue,
-		contract.PaymentConditions, contract.GuaranteeTypeID, contract.GuaranteeCustom,
-		contract.ClientRequirements, contract.MaterialsUsed, contract.AdditionalNotes,
-		contract.StatusID,
-	).Scan(&contract.ID, &contract.CreatedAt, &contract.UpdatedAt)
+	return withAudit(m.DB, actor, AuditCreate, AuditEntityContract, 0, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRow(
+			query,
+			contract.ServiceRequestID, contract.ContractNumber, contract.TotalValue,
+			contract.PaymentConditions, contract.GuaranteeTypeID, contract.GuaranteeCustom,
+			contract.ClientRequirements, contract.MaterialsUsed, contract.AdditionalNotes,
+			contract.StatusID,
+		).Scan(&contract.ID, &contract.CreatedAt, &contract.UpdatedAt)
+		return contract.ID, err
+	})
 }
 
 // Update atualiza um contrato (apenas se não estiver assinado)
-func (m *ContractModel) Update(contract *Contract) error {
+func (m *ContractModel) Update(contract *Contract, actor Actor) error {
 	query := `
 		UPDATE contracts SET
 			total_value = $1, payment_conditions = $2, guarantee_type_id = $3,
 			guarantee_custom = $4, client_requirements = $5, materials_used = $6,
 			additional_notes = $7, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $8 AND client_signed = false AND company_signed = false`
-
-	result, err := m.DB.Exec(
-		query,
-		contract.TotalValue, contract.PaymentConditions, contract.GuaranteeTypeID,
-		contract.GuaranteeCustom, contract.ClientRequirements, contract.MaterialsUsed,
-		contract.AdditionalNotes, contract.ID,
-	)
-	if err != nil {
-		return err
-	}
+		WHERE id = $8 AND client_signed = false AND company_signed = false
+		  AND ` + contractRequestActive
+
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityContract, contract.ID, func(tx *sql.Tx) (int, error) {
+		result, err := tx.Exec(
+			query,
+			contract.TotalValue, contract.PaymentConditions, contract.GuaranteeTypeID,
+			contract.GuaranteeCustom, contract.ClientRequirements, contract.MaterialsUsed,
+			contract.AdditionalNotes, contract.ID,
+		)
+		if err != nil {
+			return 0, err
+		}
 
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		return fmt.Errorf("contrato não pode ser editado (já assinado ou não encontrado)")
-	}
-	return nil
+		rows, _ := result.RowsAffected()
+		if rows == 0 {
+			return 0, fmt.Errorf("contrato não pode ser editado (já assinado ou não encontrado)")
+		}
+		return 0, nil
+	})
 }
 
 // SendForSignature envia o contrato para assinatura
-func (m *ContractModel) SendForSignature(contractID int) error {
+func (m *ContractModel) SendForSignature(contractID int, actor Actor) error {
 	log.Printf("🔵 [MODEL] SendForSignature iniciado para contrato ID: %d", contractID)
 	
 	// Obter ID do novo status
@@ -339,7 +359,9 @@ func (m *ContractModel) SendForSignature(contractID int) error {
 	
 	// Verificar se o contrato existe e está em rascunho
 	var currentStatusID int
-	checkQuery := `SELECT status_id FROM contracts WHERE id = $1`
+	checkQuery := `SELECT c.status_id FROM contracts c
+	               JOIN service_requests sr ON c.service_request_id = sr.id
+	               WHERE c.id = $1 AND sr.deleted_at IS NULL`
 	
 	err = m.DB.QueryRow(checkQuery, contractID).Scan(&currentStatusID)
 	
@@ -362,18 +384,27 @@ func (m *ContractModel) SendForSignature(contractID int) error {
 	// Atualizar status
 	updateQuery := `UPDATE contracts 
 	                SET status_id = $1, updated_at = CURRENT_TIMESTAMP 
-	                WHERE id = $2`
+	                WHERE id = $2 AND ` + contractRequestActive
 	
 	log.Printf("📝 [MODEL] Atualizando status de %d para %d (AGUARDANDO_ASSINATURAS)", currentStatusID, newStatusID)
 	
-	result, err := m.DB.Exec(updateQuery, newStatusID, contractID)
+	err = withAudit(m.DB, actor, AuditSend, AuditEntityContract, contractID, func(tx *sql.Tx) (int, error) {
+		result, err := tx.Exec(updateQuery, newStatusID, contractID)
+		if err != nil {
+			return 0, err
+		}
+		rowsAffected, _ := result.RowsAffected()
+		log.Printf("✅ [MODEL] Linhas afetadas: %d", rowsAffected)
+		if rowsAffected == 0 {
+			return 0, fmt.Errorf("contrato #%d não encontrado", contractID)
+		}
+		return 0, nil
+	})
 	if err != nil {
 		log.Printf("❌ [MODEL] ERRO ao executar UPDATE: %v", err)
 		return fmt.Errorf("erro ao atualizar status: %w", err)
 	}
 	
-	rowsAffected, _ := result.RowsAffected()
-	log.Printf("✅ [MODEL] Linhas afetadas: %d", rowsAffected)
 	log.Printf("✅ [MODEL] SendForSignature concluído com sucesso!")
 	
 	return nil
@@ -390,7 +421,10 @@ func (m *ContractModel) SignByClient(contractID int, signature string) error {
 	// Verificar se o contrato existe e está aguardando assinaturas
 	var statusID int
 	var clientSigned bool
-	checkQuery := `SELECT status_id, client_signed FROM contracts WHERE id = $1`
+	// (solicitação na lixeira conta como inexistente)
+	checkQuery := `SELECT c.status_id, c.client_signed FROM contracts c
+	               JOIN service_requests sr ON c.service_request_id = sr.id
+	               WHERE c.id = $1 AND sr.deleted_at IS NULL`
 	err = m.DB.QueryRow(checkQuery, contractID).Scan(&statusID, &clientSigned)
 	
 	if err == sql.ErrNoRows {
@@ -421,21 +455,26 @@ func (m *ContractModel) SignByClient(contractID int, signature string) error {
 	              client_signed_at = CURRENT_TIMESTAMP,
 	              client_signature = $1, 
 	              updated_at = CURRENT_TIMESTAMP
-	          WHERE id = $2`
+	          WHERE id = $2 AND ` + contractRequestActive
 
-	_, err = tx.Exec(query, signature, contractID)
+	result, err := tx.Exec(query, signature, contractID)
 	if err != nil {
 		return fmt.Errorf("erro ao assinar: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("contrato não encontrado")
+	}
 
 	// Verificar se ambos assinaram e finalizar contrato
-	m.checkAndFinalizeContract(tx, contractID)
+	if err := m.checkAndFinalizeContract(tx, contractID); err != nil {
+		return err
+	}
 	
 	return tx.Commit()
 }
 
 // SignByCompany assina o contrato pela empresa
-func (m *ContractModel) SignByCompany(contractID int, signature string) error {
+func (m *ContractModel) SignByCompany(contractID int, signature string, actor Actor) error {
 	// Obter ID do status "AGUARDANDO_ASSINATURAS"
 	waitingStatusID, err := m.GetStatusIDByCode("AGUARDANDO_ASSINATURAS")
 	if err != nil {
@@ -445,7 +484,10 @@ func (m *ContractModel) SignByCompany(contractID int, signature string) error {
 	// Verificar se o contrato existe e está aguardando assinaturas
 	var statusID int
 	var companySigned bool
-	checkQuery := `SELECT status_id, company_signed FROM contracts WHERE id = $1`
+	// (solicitação na lixeira conta como inexistente)
+	checkQuery := `SELECT c.status_id, c.company_signed FROM contracts c
+	               JOIN service_requests sr ON c.service_request_id = sr.id
+	               WHERE c.id = $1 AND sr.deleted_at IS NULL`
 	err = m.DB.QueryRow(checkQuery, contractID).Scan(&statusID, &companySigned)
 	
 	if err == sql.ErrNoRows {
@@ -470,27 +512,41 @@ func (m *ContractModel) SignByCompany(contractID int, signature string) error {
 	}
 	defer tx.Rollback()
 
+	before, err := auditSnapshot(tx, AuditEntityContract, contractID)
+	if err != nil {
+		return err
+	}
+
 	// Assinar
 	query := `UPDATE contracts 
 	          SET company_signed = true, 
 	              company_signed_at = CURRENT_TIMESTAMP,
 	              company_signature = $1, 
 	              updated_at = CURRENT_TIMESTAMP
-	          WHERE id = $2`
+	          WHERE id = $2 AND ` + contractRequestActive
 
-	_, err = tx.Exec(query, signature, contractID)
+	result, err := tx.Exec(query, signature, contractID)
 	if err != nil {
 		return fmt.Errorf("erro ao assinar: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("contrato não encontrado")
+	}
 
 	// Verificar se ambos assinaram e finalizar
-	m.checkAndFinalizeContract(tx, contractID)
+	if err := m.checkAndFinalizeContract(tx, contractID); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, actor, AuditSign, AuditEntityContract, contractID, before); err != nil {
+		return err
+	}
 	
 	return tx.Commit()
 }
 
-// checkAndFinalizeContract verifica se ambos assinaram e finaliza
-func (m *ContractModel) checkAndFinalizeContract(tx *sql.Tx, contractID int) {
+// checkAndFinalizeContract verifica se ambos assinaram, finaliza e dá baixa no estoque
+func (m *ContractModel) checkAndFinalizeContract(tx *sql.Tx, contractID int) error {
 	var clientSigned, companySigned bool
 	tx.QueryRow("SELECT client_signed, company_signed FROM contracts WHERE id = $1", contractID).
 		Scan(&clientSigned, &companySigned)
@@ -503,7 +559,12 @@ func (m *ContractModel) checkAndFinalizeContract(tx *sql.Tx, contractID int) {
 			tx.Exec("UPDATE contracts SET status_id = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", 
 				signedStatusID, contractID)
 		}
+
+		if err := deductContractStock(tx, contractID); err != nil {
+			return fmt.Errorf("erro ao dar baixa no estoque: %w", err)
+		}
 	}
+	return nil
 }
 
 // GetByID busca um contrato pelo ID com dados relacionados
@@ -518,9 +579,10 @@ func (m *ContractModel) GetByID(id int) (*Contract, error) {
 			gt.id, gt.code, gt.name, gt.description, gt.requires_custom_text,
 			cs.id, cs.code, cs.name, cs.description, cs.color_class, cs.badge_class
 		FROM contracts c
+		JOIN service_requests sr ON c.service_request_id = sr.id
 		LEFT JOIN guarantee_types gt ON c.guarantee_type_id = gt.id
 		LEFT JOIN contract_status cs ON c.status_id = cs.id
-		WHERE c.id = $1`
+		WHERE c.id = $1 AND sr.deleted_at IS NULL`
 
 	guaranteeType := &GuaranteeType{}
 	status := &ContractStatus{}
@@ -591,9 +653,10 @@ func (m *ContractModel) GetByServiceRequestID(serviceRequestID int) (*Contract,
 // GetAllWithDetails busca todos os contratos com detalhes
 func (m *ContractModel) GetAllWithDetails(statusCode string, limit, offset int) ([]Contract, int, error) {
 	baseQuery := ` FROM contracts c 
+	               JOIN service_requests sr ON c.service_request_id = sr.id
 	               LEFT JOIN contract_status cs ON c.status_id = cs.id
 	               LEFT JOIN guarantee_types gt ON c.guarantee_type_id = gt.id
-	               WHERE 1=1`
+	               WHERE sr.deleted_at IS NULL`
 	args := []interface{}{}
 	argPos := 1
 
@@ -656,7 +719,7 @@ func (m *ContractModel) GetAllByUserID(userID int, statusCode string, limit, off
 	               JOIN service_requests sr ON c.service_request_id = sr.id 
 	               LEFT JOIN contract_status cs ON c.status_id = cs.id
 	               LEFT JOIN guarantee_types gt ON c.guarantee_type_id = gt.id
-	               WHERE sr.user_id = $1`
+	               WHERE sr.user_id = $1 AND sr.deleted_at IS NULL`
 	args := []interface{}{userID}
 	argPos := 2
 
@@ -729,7 +792,8 @@ func (m *ContractModel) GetPendingForClient(userID int) ([]Contract, error) {
 		SELECT c.id, c.service_request_id, c.contract_number, c.total_value, c.status_id, c.created_at
 		FROM contracts c
 		JOIN service_requests sr ON c.service_request_id = sr.id
-		WHERE sr.user_id = $1 AND c.status_id = $2 AND c.client_signed = false`
+		WHERE sr.user_id = $1 AND c.status_id = $2 AND c.client_signed = false
+		  AND sr.deleted_at IS NULL`
 
 	rows, err := m.DB.Query(query, userID, waitingStatusID)
 	if err != nil {
@@ -0,0 +1,313 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Meios de login registrados
+const (
+	LoginMethodPassword = "SENHA"
+	LoginMethodWhatsApp = "WHATSAPP"
+	LoginMethodAPI      = "API"
+	LoginMethodAdmin    = "ADMIN" // desbloqueio feito por um gestor
+)
+
+// Resultados de uma tentativa de login
+const (
+	LoginOK               = "OK"
+	LoginUnknownUser      = "USUARIO_INEXISTENTE"
+	LoginWrongPassword    = "SENHA_INCORRETA"
+	LoginInvalidCode      = "CODIGO_INVALIDO"
+	LoginEmailNotVerified = "EMAIL_NAO_VERIFICADO"
+	LoginBlocked          = "BLOQUEADO"
+	LoginUnlocked         = "DESBLOQUEIO"
+)
+
+// Regras do bloqueio: a partir de loginDelayAfter falhas na janela cada nova
+// tentativa espera um intervalo que dobra; com LoginLockoutAfter falhas o
+// identificador fica bloqueado por LoginLockoutDuration. O IP é bloqueado
+// com loginIPLockoutAfter falhas, somando todos os identificadores.
+const (
+	LoginLockoutAfter    = 5
+	LoginLockoutDuration = 15 * time.Minute
+
+	loginWindow         = 15 * time.Minute
+	loginDelayAfter     = 3
+	loginBaseDelay      = 5 * time.Second
+	loginIPLockoutAfter = 20
+)
+
+// Só estes resultados contam como falha para o bloqueio. Tentativas
+// recusadas por bloqueio não o prolongam.
+const loginFailureReasons = `('USUARIO_INEXISTENTE', 'SENHA_INCORRETA', 'CODIGO_INVALIDO')`
+
+// LoginAttempt é o registro de uma tentativa de login ou de um desbloqueio
+type LoginAttempt struct {
+	ID         int            `json:"id"`
+	Identifier string         `json:"identifier"`
+	UserID     sql.NullInt64  `json:"user_id"`
+	IP         string         `json:"ip"`
+	UserAgent  sql.NullString `json:"user_agent"`
+	Method     string         `json:"method"`
+	Success    bool           `json:"success"`
+	Reason     string         `json:"reason"`
+	ActorID    sql.NullInt64  `json:"actor_id"`
+	CreatedAt  time.Time      `json:"created_at"`
+
+	// Campos relacionados expandidos
+	UserName  string `json:"user_name,omitempty"`
+	ActorName string `json:"actor_name,omitempty"`
+}
+
+// ReasonLabel retorna o resultado para exibição
+func (a *LoginAttempt) ReasonLabel() string {
+	switch a.Reason {
+	case LoginOK:
+		return "Sucesso"
+	case LoginUnknownUser:
+		return "Usuário inexistente"
+	case LoginWrongPassword:
+		return "Senha incorreta"
+	case LoginInvalidCode:
+		return "Código inválido"
+	case LoginEmailNotVerified:
+		return "E-mail não verificado"
+	case LoginBlocked:
+		return "Recusado (bloqueio)"
+	case LoginUnlocked:
+		return "Desbloqueio"
+	default:
+		return a.Reason
+	}
+}
+
+// LoginThrottle é a situação de um identificador/IP antes de tentar o login
+type LoginThrottle struct {
+	Locked     bool          // bloqueio temporário
+	RetryAfter time.Duration // espera até a próxima tentativa (0 = liberado)
+}
+
+// Allowed indica se a tentativa pode prosseguir
+func (t LoginThrottle) Allowed() bool {
+	return t.RetryAfter <= 0
+}
+
+// Message descreve a espera para o usuário
+func (t LoginThrottle) Message() string {
+	if t.Locked {
+		minutes := int(math.Ceil(t.RetryAfter.Minutes()))
+		return fmt.Sprintf("Muitas tentativas sem sucesso. O acesso foi bloqueado temporariamente; tente novamente em %d minuto(s).", minutes)
+	}
+	seconds := int(math.Ceil(t.RetryAfter.Seconds()))
+	return fmt.Sprintf("Muitas tentativas sem sucesso. Aguarde %d segundos para tentar novamente.", seconds)
+}
+
+// LockedLogin é um identificador ou IP bloqueado, para a tela de acessos
+type LockedLogin struct {
+	Identifier  string    `json:"identifier"`
+	UserName    string    `json:"user_name"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+type LoginAttemptModel struct {
+	DB *sql.DB
+}
+
+func NewLoginAttemptModel(db *sql.DB) *LoginAttemptModel {
+	return &LoginAttemptModel{DB: db}
+}
+
+// NormalizeLoginIdentifier padroniza e-mails para contagem e busca
+func NormalizeLoginIdentifier(identifier string) string {
+	return strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// Record grava uma tentativa
+func (m *LoginAttemptModel) Record(a *LoginAttempt) error {
+	return m.DB.QueryRow(`
+		INSERT INTO login_attempts (identifier, user_id, ip, user_agent, method, success, reason, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		NormalizeLoginIdentifier(a.Identifier), a.UserID, a.IP, a.UserAgent, a.Method, a.Success, a.Reason, a.ActorID,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+// Check calcula a espera exigida antes de uma nova tentativa do
+// identificador a partir do IP. Falhas anteriores ao último login bem
+// sucedido (ou desbloqueio) do identificador não contam.
+func (m *LoginAttemptModel) Check(identifier, ip string) (LoginThrottle, error) {
+	window := fmt.Sprintf("%d seconds", int(loginWindow.Seconds()))
+
+	var failures int
+	var sinceLast sql.NullFloat64
+	err := m.DB.QueryRow(`
+		SELECT COUNT(*), EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - MAX(created_at))
+		FROM login_attempts
+		WHERE identifier = $1 AND NOT success AND reason IN `+loginFailureReasons+`
+		  AND created_at > CURRENT_TIMESTAMP - $2::interval
+		  AND created_at > COALESCE((SELECT MAX(created_at) FROM login_attempts
+		                             WHERE identifier = $1 AND success), '-infinity'::timestamp)`,
+		NormalizeLoginIdentifier(identifier), window).Scan(&failures, &sinceLast)
+	if err != nil {
+		return LoginThrottle{}, err
+	}
+	status := throttleFor(failures, sinceLast, loginDelayAfter, LoginLockoutAfter)
+
+	var ipFailures int
+	var ipSinceLast sql.NullFloat64
+	err = m.DB.QueryRow(`
+		SELECT COUNT(*), EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - MAX(created_at))
+		FROM login_attempts
+		WHERE ip = $1 AND NOT success AND reason IN `+loginFailureReasons+`
+		  AND created_at > CURRENT_TIMESTAMP - $2::interval
+		  AND created_at > COALESCE((SELECT MAX(created_at) FROM login_attempts
+		                             WHERE ip = $1 AND reason = 'DESBLOQUEIO'), '-infinity'::timestamp)`,
+		ip, window).Scan(&ipFailures, &ipSinceLast)
+	if err != nil {
+		return LoginThrottle{}, err
+	}
+	ipStatus := throttleFor(ipFailures, ipSinceLast, loginIPLockoutAfter, loginIPLockoutAfter)
+
+	if ipStatus.RetryAfter > status.RetryAfter {
+		return ipStatus, nil
+	}
+	return status, nil
+}
+
+// throttleFor aplica as regras de espera a uma contagem de falhas.
+// sinceLast é o tempo, em segundos, desde a última falha.
+func throttleFor(failures int, sinceLast sql.NullFloat64, delayAfter, lockAfter int) LoginThrottle {
+	if failures < delayAfter || !sinceLast.Valid {
+		return LoginThrottle{}
+	}
+	elapsed := time.Duration(sinceLast.Float64 * float64(time.Second))
+
+	if failures >= lockAfter {
+		if remaining := LoginLockoutDuration - elapsed; remaining > 0 {
+			return LoginThrottle{Locked: true, RetryAfter: remaining}
+		}
+		return LoginThrottle{}
+	}
+
+	delay := loginBaseDelay << (failures - delayAfter)
+	if remaining := delay - elapsed; remaining > 0 {
+		return LoginThrottle{RetryAfter: remaining}
+	}
+	return LoginThrottle{}
+}
+
+// GetLocked retorna os identificadores bloqueados no momento
+func (m *LoginAttemptModel) GetLocked() ([]LockedLogin, error) {
+	return m.getLocked(`identifier`, `s.identifier = a.identifier AND s.success`, LoginLockoutAfter)
+}
+
+// GetLockedIPs retorna os IPs bloqueados no momento
+func (m *LoginAttemptModel) GetLockedIPs() ([]LockedLogin, error) {
+	return m.getLocked(`ip`, `s.ip = a.ip AND s.reason = 'DESBLOQUEIO'`, loginIPLockoutAfter)
+}
+
+func (m *LoginAttemptModel) getLocked(column, resetCondition string, threshold int) ([]LockedLogin, error) {
+	query := `
+		SELECT a.` + column + `, COALESCE(MAX(u.name), ''), COUNT(*), MAX(a.created_at),
+		       MAX(a.created_at) + $2::interval
+		FROM login_attempts a
+		LEFT JOIN users u ON a.user_id = u.id
+		WHERE NOT a.success AND a.reason IN ` + loginFailureReasons + `
+		  AND a.created_at > CURRENT_TIMESTAMP - $3::interval
+		  AND a.created_at > COALESCE((SELECT MAX(s.created_at) FROM login_attempts s
+		                               WHERE ` + resetCondition + `), '-infinity'::timestamp)
+		GROUP BY a.` + column + `
+		HAVING COUNT(*) >= $1 AND MAX(a.created_at) + $2::interval > CURRENT_TIMESTAMP
+		ORDER BY MAX(a.created_at) DESC`
+
+	lockout := fmt.Sprintf("%d seconds", int(LoginLockoutDuration.Seconds()))
+	window := fmt.Sprintf("%d seconds", int(loginWindow.Seconds()))
+	rows, err := m.DB.Query(query, threshold, lockout, window)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locked []LockedLogin
+	for rows.Next() {
+		var l LockedLogin
+		var identifier sql.NullString
+		if err := rows.Scan(&identifier, &l.UserName, &l.Failures, &l.LastFailure, &l.LockedUntil); err != nil {
+			return nil, err
+		}
+		l.Identifier = identifier.String
+		locked = append(locked, l)
+	}
+
+	return locked, nil
+}
+
+// Unlock libera um identificador bloqueado, registrando o gestor responsável
+func (m *LoginAttemptModel) Unlock(identifier string, actor Actor) error {
+	if err := m.unlock(identifier, "", actor.UserID); err != nil {
+		return err
+	}
+	return insertAudit(m.DB, actor, AuditUnlock, AuditEntityLogin, 0,
+		sql.NullString{}, auditJSON(map[string]string{"identificador": identifier}))
+}
+
+// UnlockIP libera um IP bloqueado. O registro usa o próprio IP como
+// identificador, para não liberar nenhuma conta junto.
+func (m *LoginAttemptModel) UnlockIP(ip string, actor Actor) error {
+	if err := m.unlock("ip:"+ip, ip, actor.UserID); err != nil {
+		return err
+	}
+	return insertAudit(m.DB, actor, AuditUnlock, AuditEntityLogin, 0,
+		sql.NullString{}, auditJSON(map[string]string{"ip": ip}))
+}
+
+func (m *LoginAttemptModel) unlock(identifier, ip string, actorID int) error {
+	return m.Record(&LoginAttempt{
+		Identifier: identifier,
+		IP:         ip,
+		Method:     LoginMethodAdmin,
+		Success:    true,
+		Reason:     LoginUnlocked,
+		ActorID:    sql.NullInt64{Int64: int64(actorID), Valid: true},
+	})
+}
+
+// GetRecent lista as tentativas mais recentes, com filtro opcional por
+// identificador/IP (busca parcial) e apenas falhas
+func (m *LoginAttemptModel) GetRecent(search string, onlyFailures bool, limit int) ([]LoginAttempt, error) {
+	query := `
+		SELECT a.id, a.identifier, a.user_id, COALESCE(a.ip, ''), a.user_agent, a.method, a.success,
+		       a.reason, a.actor_id, a.created_at, COALESCE(u.name, ''), COALESCE(act.name, '')
+		FROM login_attempts a
+		LEFT JOIN users u ON a.user_id = u.id
+		LEFT JOIN users act ON a.actor_id = act.id
+		WHERE ($1 = '' OR a.identifier ILIKE '%' || $1 || '%' OR a.ip ILIKE '%' || $1 || '%')
+		  AND (NOT $2 OR NOT a.success)
+		ORDER BY a.created_at DESC
+		LIMIT $3`
+
+	rows, err := m.DB.Query(query, strings.TrimSpace(search), onlyFailures, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []LoginAttempt
+	for rows.Next() {
+		var a LoginAttempt
+		err := rows.Scan(&a.ID, &a.Identifier, &a.UserID, &a.IP, &a.UserAgent, &a.Method, &a.Success,
+			&a.Reason, &a.ActorID, &a.CreatedAt, &a.UserName, &a.ActorName)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"martins-pocos/constants"
+)
+
+// MaintenancePlan é um plano de manutenção preventiva recorrente de um poço
+type MaintenancePlan struct {
+	ID              int            `json:"id"`
+	WellID          int            `json:"well_id"`
+	Title           string         `json:"title"`
+	IntervalMonths  int            `json:"interval_months"`
+	Checklist       sql.NullString `json:"checklist"`
+	NextDueDate     time.Time      `json:"next_due_date"`
+	ReminderDays    int            `json:"reminder_days"`
+	LastReminderFor sql.NullTime   `json:"last_reminder_for"`
+	LastRequestID   sql.NullInt64  `json:"last_request_id"`
+	Active          bool           `json:"active"`
+	Notes           sql.NullString `json:"notes"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+
+	// Campos relacionados expandidos
+	WellIdentification string `json:"well_identification,omitempty"`
+	UserID             int    `json:"user_id,omitempty"`
+	OwnerName          string `json:"owner_name,omitempty"`
+	OwnerPhone         string `json:"owner_phone,omitempty"`
+	// O cliente aceita lembretes de manutenção (preferências do perfil)
+	OwnerWantsReminders bool `json:"-"`
+}
+
+// ChecklistItems retorna os itens do checklist (um por linha)
+func (p *MaintenancePlan) ChecklistItems() []string {
+	if !p.Checklist.Valid {
+		return nil
+	}
+	var items []string
+	for _, line := range strings.Split(p.Checklist.String, "\n") {
+		if item := strings.TrimSpace(line); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// DaysUntilDue retorna quantos dias faltam para a próxima manutenção (negativo se atrasada)
+func (p *MaintenancePlan) DaysUntilDue() int {
+	today := truncateDay(time.Now())
+	due := truncateDay(p.NextDueDate)
+	return int(due.Sub(today).Hours() / 24)
+}
+
+// IsOverdue indica se a manutenção já venceu
+func (p *MaintenancePlan) IsOverdue() bool {
+	return p.DaysUntilDue() < 0
+}
+
+// AddMonths soma meses mantendo o dia, limitado ao último dia do mês (31/01 + 1 = 28/02)
+func AddMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location()).AddDate(0, months, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, date.Location())
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ErrPlanNoAddress indica que o poço não tem solicitação de origem para copiar o endereço
+var ErrPlanNoAddress = errors.New("poço sem endereço de referência para gerar a solicitação")
+
+type MaintenancePlanModel struct {
+	DB *sql.DB
+}
+
+func NewMaintenancePlanModel(db *sql.DB) *MaintenancePlanModel {
+	return &MaintenancePlanModel{DB: db}
+}
+
+const maintenancePlanSelectColumns = `
+		SELECT p.id, p.well_id, p.title, p.interval_months, p.checklist, p.next_due_date,
+		       p.reminder_days, p.last_reminder_for, p.last_request_id, p.active, p.notes,
+		       p.created_at, p.updated_at,
+		       w.identification, u.id, u.name, COALESCE(u.phone, ''), u.notify_maintenance
+		FROM maintenance_plans p
+		JOIN wells w ON p.well_id = w.id
+		JOIN users u ON w.user_id = u.id`
+
+func scanMaintenancePlan(scanner interface{ Scan(...interface{}) error }, p *MaintenancePlan) error {
+	return scanner.Scan(
+		&p.ID, &p.WellID, &p.Title, &p.IntervalMonths, &p.Checklist, &p.NextDueDate,
+		&p.ReminderDays, &p.LastReminderFor, &p.LastRequestID, &p.Active, &p.Notes,
+		&p.CreatedAt, &p.UpdatedAt,
+		&p.WellIdentification, &p.UserID, &p.OwnerName, &p.OwnerPhone, &p.OwnerWantsReminders,
+	)
+}
+
+// Create registra um novo plano de manutenção
+func (m *MaintenancePlanModel) Create(plan *MaintenancePlan, actor Actor) error {
+	query := `
+		INSERT INTO maintenance_plans (well_id, title, interval_months, checklist, next_due_date, reminder_days, active, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`
+
+	return withAudit(m.DB, actor, AuditCreate, AuditEntityMaintenancePlan, 0, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRow(
+			query,
+			plan.WellID, plan.Title, plan.IntervalMonths, plan.Checklist,
+			plan.NextDueDate, plan.ReminderDays, plan.Active, plan.Notes,
+		).Scan(&plan.ID, &plan.CreatedAt, &plan.UpdatedAt)
+		return plan.ID, err
+	})
+}
+
+// Update atualiza o plano de manutenção
+func (m *MaintenancePlanModel) Update(plan *MaintenancePlan, actor Actor) error {
+	query := `
+		UPDATE maintenance_plans SET
+			title = $1, interval_months = $2, checklist = $3, next_due_date = $4,
+			reminder_days = $5, active = $6, notes = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8`
+
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityMaintenancePlan, plan.ID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(
+			query,
+			plan.Title, plan.IntervalMonths, plan.Checklist, plan.NextDueDate,
+			plan.ReminderDays, plan.Active, plan.Notes, plan.ID,
+		))
+	})
+}
+
+// GetByID busca um plano pelo ID
+func (m *MaintenancePlanModel) GetByID(id int) (*MaintenancePlan, error) {
+	plan := &MaintenancePlan{}
+	row := m.DB.QueryRow(maintenancePlanSelectColumns+` WHERE p.id = $1`, id)
+	if err := scanMaintenancePlan(row, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// GetByWellID retorna os planos de um poço
+func (m *MaintenancePlanModel) GetByWellID(wellID int) ([]MaintenancePlan, error) {
+	return m.query(maintenancePlanSelectColumns+` WHERE p.well_id = $1 ORDER BY p.active DESC, p.next_due_date`, wellID)
+}
+
+// GetUpcoming retorna os planos ativos com vencimento até a data informada (inclui atrasados)
+func (m *MaintenancePlanModel) GetUpcoming(until time.Time) ([]MaintenancePlan, error) {
+	return m.query(maintenancePlanSelectColumns+` WHERE p.active = true AND p.next_due_date <= $1 ORDER BY p.next_due_date, u.name`, until)
+}
+
+// GetPendingReminders retorna os planos que entraram na janela de lembrete
+// e ainda não tiveram o cliente avisado para o vencimento atual
+func (m *MaintenancePlanModel) GetPendingReminders(today time.Time) ([]MaintenancePlan, error) {
+	query := maintenancePlanSelectColumns + `
+		WHERE p.active = true
+		  AND p.next_due_date >= $1
+		  AND p.next_due_date - p.reminder_days <= $1
+		  AND (p.last_reminder_for IS NULL OR p.last_reminder_for <> p.next_due_date)
+		ORDER BY p.next_due_date`
+	return m.query(query, today)
+}
+
+// GetDue retorna os planos ativos vencidos até a data informada
+func (m *MaintenancePlanModel) GetDue(today time.Time) ([]MaintenancePlan, error) {
+	return m.query(maintenancePlanSelectColumns+` WHERE p.active = true AND p.next_due_date <= $1 ORDER BY p.next_due_date`, today)
+}
+
+// MarkReminderSent registra que o cliente foi avisado do vencimento
+func (m *MaintenancePlanModel) MarkReminderSent(planID int, dueDate time.Time) error {
+	_, err := m.DB.Exec(`UPDATE maintenance_plans SET last_reminder_for = $1 WHERE id = $2`, dueDate, planID)
+	return err
+}
+
+// GenerateRequest cria a solicitação de manutenção (status SOLICITADA) de um plano vencido
+// e avança o próximo vencimento. Retorna o ID da solicitação, ou 0 se o plano não estava
+// mais vencido (ex: processado por outra instância).
+func (m *MaintenancePlanModel) GenerateRequest(planID, serviceTypeID int, today time.Time) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED evita que duas execuções simultâneas gerem solicitações duplicadas
+	var (
+		plan     MaintenancePlan
+		userID   int
+		fullName string
+		originID sql.NullInt64
+		lat, lng sql.NullFloat64
+		cep      sql.NullString
+		street   sql.NullString
+		number   sql.NullString
+		district sql.NullString
+		city     sql.NullString
+		state    sql.NullString
+	)
+	err = tx.QueryRow(`
+		SELECT p.id, p.title, p.interval_months, p.checklist, p.next_due_date, p.notes,
+		       w.identification, w.user_id, u.name, w.service_request_id,
+		       COALESCE(w.latitude, sr.latitude), COALESCE(w.longitude, sr.longitude),
+		       sr.cep, sr.logradouro, sr.numero, sr.bairro, sr.cidade, sr.estado
+		FROM maintenance_plans p
+		JOIN wells w ON p.well_id = w.id
+		JOIN users u ON w.user_id = u.id
+		LEFT JOIN service_requests sr ON w.service_request_id = sr.id
+		WHERE p.id = $1 AND p.active = true AND p.next_due_date <= $2
+		FOR UPDATE OF p SKIP LOCKED`, planID, today).Scan(
+		&plan.ID, &plan.Title, &plan.IntervalMonths, &plan.Checklist, &plan.NextDueDate, &plan.Notes,
+		&plan.WellIdentification, &userID, &fullName, &originID,
+		&lat, &lng, &cep, &street, &number, &district, &city, &state,
+	)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if !originID.Valid || !cep.Valid {
+		return 0, ErrPlanNoAddress
+	}
+
+	// Vencimentos perdidos não geram várias solicitações: uma só, com a data de hoje
+	preferredDate := plan.NextDueDate
+	if preferredDate.Before(today) {
+		preferredDate = today
+	}
+
+	var description strings.Builder
+	fmt.Fprintf(&description, "Manutenção preventiva programada: %s\nPoço: %s\n", plan.Title, plan.WellIdentification)
+	if items := plan.ChecklistItems(); len(items) > 0 {
+		description.WriteString("\nChecklist:\n")
+		for _, item := range items {
+			fmt.Fprintf(&description, "- %s\n", item)
+		}
+	}
+	if plan.Notes.Valid {
+		fmt.Fprintf(&description, "\nObservações: %s\n", plan.Notes.String)
+	}
+
+	var requestID int
+	err = tx.QueryRow(`
+		INSERT INTO service_requests (
+			user_id, full_name, service_type_id, description, cep, logradouro,
+			numero, bairro, cidade, estado, preferred_date, preferred_time, status_id,
+			latitude, longitude, maintenance_plan_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING id`,
+		userID, fullName, serviceTypeID, strings.TrimSpace(description.String()),
+		cep.String, street.String, number.String, district.String, city.String, state.String,
+		preferredDate, "08:00", constants.StatusSolicitada,
+		lat, lng, plan.ID,
+	).Scan(&requestID)
+	if err != nil {
+		return 0, err
+	}
+
+	next := plan.NextDueDate
+	for !next.After(today) {
+		next = AddMonths(next, plan.IntervalMonths)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE maintenance_plans
+		SET next_due_date = $1, last_request_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`, next, requestID, plan.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return requestID, nil
+}
+
+func (m *MaintenancePlanModel) query(query string, args ...interface{}) ([]MaintenancePlan, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plans []MaintenancePlan
+	for rows.Next() {
+		var plan MaintenancePlan
+		if err := scanMaintenancePlan(rows, &plan); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
@@ -0,0 +1,568 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tipos de movimentação de estoque
+const (
+	MovementIn       = "ENTRADA"
+	MovementOut      = "SAIDA"
+	MovementAdjust   = "AJUSTE"
+	MovementContract = "CONTRATO"
+)
+
+// Unidades de medida aceitas no catálogo
+var MaterialUnits = []string{"un", "m", "kg", "l", "cx", "rl"}
+
+var (
+	ErrDuplicateSKU        = errors.New("já existe um material com este código (SKU)")
+	ErrInvalidMovement     = errors.New("movimentação inválida")
+	ErrContractNotEditable = errors.New("contrato já assinado não pode ter itens alterados")
+)
+
+// Material é um item do catálogo com saldo em estoque
+type Material struct {
+	ID            int       `json:"id"`
+	SKU           string    `json:"sku"`
+	Name          string    `json:"name"`
+	Unit          string    `json:"unit"`
+	UnitCost      float64   `json:"unit_cost"`
+	SalePrice     float64   `json:"sale_price"`
+	StockQuantity float64   `json:"stock_quantity"`
+	MinStock      float64   `json:"min_stock"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// IsLowStock indica se o saldo está no mínimo configurado ou negativo
+func (m *Material) IsLowStock() bool {
+	if m.StockQuantity < 0 {
+		return true
+	}
+	return m.MinStock > 0 && m.StockQuantity <= m.MinStock
+}
+
+// StockValue retorna o valor do saldo em estoque pelo custo unitário
+func (m *Material) StockValue() float64 {
+	if m.StockQuantity <= 0 {
+		return 0
+	}
+	return m.StockQuantity * m.UnitCost
+}
+
+// StockMovement é uma entrada ou saída de estoque
+type StockMovement struct {
+	ID           int            `json:"id"`
+	MaterialID   int            `json:"material_id"`
+	MovementType string         `json:"movement_type"`
+	Quantity     float64        `json:"quantity"`
+	BalanceAfter float64        `json:"balance_after"`
+	ContractID   sql.NullInt64  `json:"contract_id"`
+	Notes        sql.NullString `json:"notes"`
+	CreatedBy    sql.NullInt64  `json:"created_by"`
+	CreatedAt    time.Time      `json:"created_at"`
+
+	// Campos relacionados expandidos
+	ContractNumber string `json:"contract_number,omitempty"`
+	CreatedByName  string `json:"created_by_name,omitempty"`
+}
+
+// TypeLabel retorna o nome do tipo de movimentação para exibição
+func (s *StockMovement) TypeLabel() string {
+	switch s.MovementType {
+	case MovementIn:
+		return "Entrada"
+	case MovementOut:
+		return "Saída"
+	case MovementAdjust:
+		return "Ajuste de inventário"
+	case MovementContract:
+		return "Baixa por contrato"
+	default:
+		return s.MovementType
+	}
+}
+
+// IsInbound indica se a movimentação aumentou o saldo
+func (s *StockMovement) IsInbound() bool {
+	return s.Quantity > 0
+}
+
+// ContractItem é um material incluído no contrato
+type ContractItem struct {
+	ID         int       `json:"id"`
+	ContractID int       `json:"contract_id"`
+	MaterialID int       `json:"material_id"`
+	Quantity   float64   `json:"quantity"`
+	UnitPrice  float64   `json:"unit_price"`
+	UnitCost   float64   `json:"unit_cost"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Campos relacionados expandidos
+	SKU          string  `json:"sku,omitempty"`
+	MaterialName string  `json:"material_name,omitempty"`
+	Unit         string  `json:"unit,omitempty"`
+	InStock      float64 `json:"in_stock,omitempty"`
+}
+
+// Total retorna o valor de venda do item
+func (i *ContractItem) Total() float64 {
+	return i.Quantity * i.UnitPrice
+}
+
+// CostTotal retorna o custo do item
+func (i *ContractItem) CostTotal() float64 {
+	return i.Quantity * i.UnitCost
+}
+
+// ContractItemsSummary totaliza os itens de um contrato (custo do serviço)
+type ContractItemsSummary struct {
+	SaleTotal float64
+	CostTotal float64
+}
+
+// Margin retorna a diferença entre o valor de venda e o custo dos materiais
+func (s ContractItemsSummary) Margin() float64 {
+	return s.SaleTotal - s.CostTotal
+}
+
+// SummarizeContractItems soma valores de venda e custo dos itens
+func SummarizeContractItems(items []ContractItem) ContractItemsSummary {
+	var summary ContractItemsSummary
+	for i := range items {
+		summary.SaleTotal += items[i].Total()
+		summary.CostTotal += items[i].CostTotal()
+	}
+	return summary
+}
+
+type MaterialModel struct {
+	DB *sql.DB
+}
+
+func NewMaterialModel(db *sql.DB) *MaterialModel {
+	return &MaterialModel{DB: db}
+}
+
+// ==================== Catálogo ====================
+
+// SKUExists verifica se o código já está em uso por outro material
+func (m *MaterialModel) SKUExists(sku string, excludeID int) (bool, error) {
+	var exists bool
+	err := m.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM materials WHERE UPPER(sku) = UPPER($1) AND id <> $2)",
+		sku, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// Create cadastra um material. O saldo inicial é registrado como movimentação de entrada.
+func (m *MaterialModel) Create(material *Material, actor Actor) error {
+	exists, err := m.SKUExists(material.SKU, 0)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateSKU
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO materials (sku, name, unit, unit_cost, sale_price, stock_quantity, min_stock, active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		material.SKU, material.Name, material.Unit, material.UnitCost, material.SalePrice,
+		material.StockQuantity, material.MinStock, material.Active,
+	).Scan(&material.ID, &material.CreatedAt, &material.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if material.StockQuantity != 0 {
+		_, err = tx.Exec(`
+			INSERT INTO stock_movements (material_id, movement_type, quantity, balance_after, notes, created_by)
+			VALUES ($1, $2, $3, $3, 'Saldo inicial', $4)`,
+			material.ID, MovementIn, material.StockQuantity, actor.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := recordAudit(tx, actor, AuditCreate, AuditEntityMaterial, material.ID, sql.NullString{}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update atualiza os dados do catálogo. O saldo só muda por movimentações.
+func (m *MaterialModel) Update(material *Material, actor Actor) error {
+	exists, err := m.SKUExists(material.SKU, material.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrDuplicateSKU
+	}
+
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityMaterial, material.ID, func(tx *sql.Tx) (int, error) {
+		return 0, tx.QueryRow(`
+			UPDATE materials
+			SET sku = $1, name = $2, unit = $3, unit_cost = $4, sale_price = $5, min_stock = $6,
+			    active = $7, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $8
+			RETURNING updated_at`,
+			material.SKU, material.Name, material.Unit, material.UnitCost, material.SalePrice,
+			material.MinStock, material.Active, material.ID,
+		).Scan(&material.UpdatedAt)
+	})
+}
+
+const materialSelectColumns = `
+	SELECT id, sku, name, unit, unit_cost, sale_price, stock_quantity, min_stock, active, created_at, updated_at
+	FROM materials`
+
+func scanMaterial(scanner interface{ Scan(...interface{}) error }, material *Material) error {
+	return scanner.Scan(
+		&material.ID, &material.SKU, &material.Name, &material.Unit, &material.UnitCost, &material.SalePrice,
+		&material.StockQuantity, &material.MinStock, &material.Active, &material.CreatedAt, &material.UpdatedAt,
+	)
+}
+
+// GetByID busca um material pelo ID
+func (m *MaterialModel) GetByID(id int) (*Material, error) {
+	material := &Material{}
+	if err := scanMaterial(m.DB.QueryRow(materialSelectColumns+" WHERE id = $1", id), material); err != nil {
+		return nil, err
+	}
+	return material, nil
+}
+
+// GetAll lista o catálogo, opcionalmente filtrando por nome/SKU
+func (m *MaterialModel) GetAll(search string, includeInactive bool) ([]Material, error) {
+	query := materialSelectColumns + `
+		WHERE ($1 = '' OR name ILIKE '%' || $1 || '%' OR sku ILIKE '%' || $1 || '%')
+		  AND ($2 OR active = true)
+		ORDER BY name`
+	return m.query(query, strings.TrimSpace(search), includeInactive)
+}
+
+// GetLowStock lista os materiais ativos com saldo no mínimo ou negativo
+func (m *MaterialModel) GetLowStock() ([]Material, error) {
+	query := materialSelectColumns + `
+		WHERE active = true AND (stock_quantity < 0 OR (min_stock > 0 AND stock_quantity <= min_stock))
+		ORDER BY (stock_quantity - min_stock), name`
+	return m.query(query)
+}
+
+func (m *MaterialModel) query(query string, args ...interface{}) ([]Material, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var materials []Material
+	for rows.Next() {
+		var material Material
+		if err := scanMaterial(rows, &material); err != nil {
+			return nil, err
+		}
+		materials = append(materials, material)
+	}
+	return materials, rows.Err()
+}
+
+// ==================== Movimentações ====================
+
+// AddMovement registra uma movimentação manual. Em ENTRADA e SAIDA a quantidade é
+// o volume movimentado; em AJUSTE é o saldo contado no inventário.
+func (m *MaterialModel) AddMovement(materialID int, movementType string, quantity float64, notes string, actor Actor) (*StockMovement, error) {
+	if quantity < 0 || (quantity == 0 && movementType != MovementAdjust) {
+		return nil, ErrInvalidMovement
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current float64
+	err = tx.QueryRow("SELECT stock_quantity FROM materials WHERE id = $1 FOR UPDATE", materialID).Scan(&current)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityMaterial, materialID)
+	if err != nil {
+		return nil, err
+	}
+
+	var delta float64
+	switch movementType {
+	case MovementIn:
+		delta = quantity
+	case MovementOut:
+		delta = -quantity
+	case MovementAdjust:
+		delta = quantity - current
+	default:
+		return nil, ErrInvalidMovement
+	}
+
+	movement := &StockMovement{
+		MaterialID:   materialID,
+		MovementType: movementType,
+		Quantity:     delta,
+		BalanceAfter: current + delta,
+		Notes:        nullableString(notes),
+		CreatedBy:    sql.NullInt64{Int64: int64(actor.UserID), Valid: true},
+	}
+	if err := insertStockMovement(tx, movement); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, actor, AuditStockMovement, AuditEntityMaterial, materialID, before); err != nil {
+		return nil, err
+	}
+
+	return movement, tx.Commit()
+}
+
+// insertStockMovement grava a movimentação e atualiza o saldo do material na transação
+func insertStockMovement(tx *sql.Tx, movement *StockMovement) error {
+	err := tx.QueryRow(`
+		UPDATE materials SET stock_quantity = stock_quantity + $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		RETURNING stock_quantity`, movement.Quantity, movement.MaterialID).Scan(&movement.BalanceAfter)
+	if err != nil {
+		return err
+	}
+
+	return tx.QueryRow(`
+		INSERT INTO stock_movements (material_id, movement_type, quantity, balance_after, contract_id, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		movement.MaterialID, movement.MovementType, movement.Quantity, movement.BalanceAfter,
+		movement.ContractID, movement.Notes, movement.CreatedBy,
+	).Scan(&movement.ID, &movement.CreatedAt)
+}
+
+// GetMovements lista as últimas movimentações de um material
+func (m *MaterialModel) GetMovements(materialID, limit int) ([]StockMovement, error) {
+	rows, err := m.DB.Query(`
+		SELECT sm.id, sm.material_id, sm.movement_type, sm.quantity, sm.balance_after, sm.contract_id,
+		       sm.notes, sm.created_by, sm.created_at,
+		       COALESCE(c.contract_number, ''), COALESCE(u.name, '')
+		FROM stock_movements sm
+		LEFT JOIN contracts c ON sm.contract_id = c.id
+		LEFT JOIN users u ON sm.created_by = u.id
+		WHERE sm.material_id = $1
+		ORDER BY sm.created_at DESC, sm.id DESC
+		LIMIT $2`, materialID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []StockMovement
+	for rows.Next() {
+		var mv StockMovement
+		err := rows.Scan(
+			&mv.ID, &mv.MaterialID, &mv.MovementType, &mv.Quantity, &mv.BalanceAfter, &mv.ContractID,
+			&mv.Notes, &mv.CreatedBy, &mv.CreatedAt, &mv.ContractNumber, &mv.CreatedByName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, mv)
+	}
+	return movements, rows.Err()
+}
+
+// ==================== Itens do contrato ====================
+
+// GetContractItems lista os materiais de um contrato
+func (m *MaterialModel) GetContractItems(contractID int) ([]ContractItem, error) {
+	rows, err := m.DB.Query(`
+		SELECT ci.id, ci.contract_id, ci.material_id, ci.quantity, ci.unit_price, ci.unit_cost, ci.created_at,
+		       mt.sku, mt.name, mt.unit, mt.stock_quantity
+		FROM contract_items ci
+		JOIN materials mt ON ci.material_id = mt.id
+		WHERE ci.contract_id = $1
+		ORDER BY mt.name`, contractID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ContractItem
+	for rows.Next() {
+		var item ContractItem
+		err := rows.Scan(
+			&item.ID, &item.ContractID, &item.MaterialID, &item.Quantity, &item.UnitPrice, &item.UnitCost,
+			&item.CreatedAt, &item.SKU, &item.MaterialName, &item.Unit, &item.InStock,
+		)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// AddContractItem inclui um material no contrato com o preço e custo atuais do catálogo.
+// Se o material já estiver no contrato, a quantidade é somada.
+func (m *MaterialModel) AddContractItem(contractID, materialID int, quantity float64, actor Actor) error {
+	if quantity <= 0 {
+		return ErrInvalidMovement
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockEditableContract(tx, contractID); err != nil {
+		return err
+	}
+
+	// Material já no contrato: a linha existente é alterada
+	action := AuditCreate
+	var itemID int
+	err = tx.QueryRow("SELECT id FROM contract_items WHERE contract_id = $1 AND material_id = $2", contractID, materialID).
+		Scan(&itemID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	before, err := auditSnapshot(tx, AuditEntityContractItem, itemID)
+	if err != nil {
+		return err
+	}
+	if itemID != 0 {
+		action = AuditUpdate
+	}
+
+	// Sem linha retornada: material inexistente ou inativo
+	err = tx.QueryRow(`
+		INSERT INTO contract_items (contract_id, material_id, quantity, unit_price, unit_cost)
+		SELECT $1, id, $3, sale_price, unit_cost FROM materials WHERE id = $2 AND active = true
+		ON CONFLICT (contract_id, material_id)
+		DO UPDATE SET quantity = contract_items.quantity + EXCLUDED.quantity
+		RETURNING id`,
+		contractID, materialID, quantity).Scan(&itemID)
+	if err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, actor, action, AuditEntityContractItem, itemID, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteContractItem remove um material do contrato
+func (m *MaterialModel) DeleteContractItem(contractID, itemID int, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockEditableContract(tx, contractID); err != nil {
+		return err
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityContractItem, itemID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM contract_items WHERE id = $1 AND contract_id = $2", itemID, contractID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := recordAudit(tx, actor, AuditDelete, AuditEntityContractItem, itemID, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockEditableContract trava o contrato e garante que nenhuma parte assinou ainda
+func lockEditableContract(tx *sql.Tx, contractID int) error {
+	var clientSigned, companySigned bool
+	err := tx.QueryRow("SELECT client_signed, company_signed FROM contracts WHERE id = $1 FOR UPDATE", contractID).
+		Scan(&clientSigned, &companySigned)
+	if err != nil {
+		return err
+	}
+	if clientSigned || companySigned {
+		return ErrContractNotEditable
+	}
+	return nil
+}
+
+// deductContractStock dá baixa no estoque dos itens do contrato. É idempotente:
+// stock_deducted_at garante uma única baixa mesmo se chamado mais de uma vez.
+// O saldo pode ficar negativo — o contrato assinado não é bloqueado por falta de estoque.
+func deductContractStock(tx *sql.Tx, contractID int) error {
+	var contractNumber string
+	err := tx.QueryRow(`
+		UPDATE contracts SET stock_deducted_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND stock_deducted_at IS NULL
+		RETURNING contract_number`, contractID).Scan(&contractNumber)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT material_id, quantity FROM contract_items WHERE contract_id = $1 ORDER BY material_id`, contractID)
+	if err != nil {
+		return err
+	}
+
+	var movements []StockMovement
+	for rows.Next() {
+		var materialID int
+		var quantity float64
+		if err := rows.Scan(&materialID, &quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		movements = append(movements, StockMovement{
+			MaterialID:   materialID,
+			MovementType: MovementContract,
+			Quantity:     -quantity,
+			ContractID:   sql.NullInt64{Int64: int64(contractID), Valid: true},
+			Notes:        sql.NullString{String: fmt.Sprintf("Contrato %s assinado", contractNumber), Valid: true},
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range movements {
+		if err := insertStockMovement(tx, &movements[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Finalidades dos códigos enviados por WhatsApp
+const (
+	PhoneCodeLogin  = "LOGIN"
+	PhoneCodeVerify = "VERIFICAR_TELEFONE"
+)
+
+// Regras dos códigos: validade, tentativas por código e limites de envio
+const (
+	PhoneCodeTTL         = 5 * time.Minute
+	PhoneCodeMaxAttempts = 5
+
+	phoneCodeResendInterval = time.Minute
+	phoneCodeWindow         = 15 * time.Minute
+	phoneCodeMaxPerPhone    = 3
+	phoneCodeIPWindow       = time.Hour
+	phoneCodeMaxPerIP       = 10
+)
+
+var (
+	ErrInvalidPhone     = errors.New("telefone inválido")
+	ErrInvalidPhoneCode = errors.New("código inválido ou expirado")
+	ErrPhoneRateLimited = errors.New("muitos códigos solicitados; aguarde alguns minutos e tente novamente")
+)
+
+// NormalizePhone reduz o telefone a DDD + número, só dígitos (10 ou 11),
+// aceitando máscara, o código do país 55 e o 0 de longa distância
+func NormalizePhone(phone string) (string, error) {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if len(digits) > 11 && strings.HasPrefix(digits, "55") {
+		digits = digits[2:]
+	}
+	// Prefixo de longa distância (0 + DDD)
+	if len(digits) > 10 && strings.HasPrefix(digits, "0") {
+		digits = digits[1:]
+	}
+	if len(digits) != 10 && len(digits) != 11 {
+		return "", ErrInvalidPhone
+	}
+	return digits, nil
+}
+
+type PhoneCodeModel struct {
+	DB *sql.DB
+}
+
+func NewPhoneCodeModel(db *sql.DB) *PhoneCodeModel {
+	return &PhoneCodeModel{DB: db}
+}
+
+func hashPhoneCode(phone, code string) string {
+	return hashAPIToken(phone + ":" + code)
+}
+
+// Create gera um código de 6 dígitos para o telefone (já normalizado),
+// respeitando os limites por telefone e por IP. Códigos anteriores da
+// mesma finalidade deixam de valer. Retorna o código em claro para envio.
+func (m *PhoneCodeModel) Create(phone, purpose string, userID sql.NullInt64, ip string) (string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var perPhone int
+	var lastSent sql.NullTime
+	err = tx.QueryRow(`
+		SELECT COUNT(*), MAX(created_at) FROM phone_codes
+		WHERE phone = $1 AND created_at > $2`,
+		phone, time.Now().Add(-phoneCodeWindow)).Scan(&perPhone, &lastSent)
+	if err != nil {
+		return "", err
+	}
+	if perPhone >= phoneCodeMaxPerPhone || (lastSent.Valid && time.Since(lastSent.Time) < phoneCodeResendInterval) {
+		return "", ErrPhoneRateLimited
+	}
+
+	var perIP int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM phone_codes WHERE ip = $1 AND created_at > $2`,
+		ip, time.Now().Add(-phoneCodeIPWindow)).Scan(&perIP)
+	if err != nil {
+		return "", err
+	}
+	if perIP >= phoneCodeMaxPerIP {
+		return "", ErrPhoneRateLimited
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	_, err = tx.Exec(`
+		UPDATE phone_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE phone = $1 AND purpose = $2 AND used_at IS NULL`, phone, purpose)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO phone_codes (phone, purpose, user_id, code_hash, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		phone, purpose, userID, hashPhoneCode(phone, code), ip, time.Now().Add(PhoneCodeTTL))
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Verify confere o código mais recente do telefone e o consome. Cada erro
+// conta uma tentativa; após PhoneCodeMaxAttempts o código é descartado.
+// Retorna o usuário associado ao código, quando houver.
+func (m *PhoneCodeModel) Verify(phone, purpose, code string) (sql.NullInt64, error) {
+	var userID sql.NullInt64
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return userID, err
+	}
+	defer tx.Rollback()
+
+	var id, attempts int
+	var codeHash string
+	err = tx.QueryRow(`
+		SELECT id, user_id, code_hash, attempts FROM phone_codes
+		WHERE phone = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE`, phone, purpose).Scan(&id, &userID, &codeHash, &attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return userID, ErrInvalidPhoneCode
+	}
+	if err != nil {
+		return userID, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashPhoneCode(phone, strings.TrimSpace(code)))) != 1 {
+		attempts++
+		query := `UPDATE phone_codes SET attempts = $1 WHERE id = $2`
+		if attempts >= PhoneCodeMaxAttempts {
+			query = `UPDATE phone_codes SET attempts = $1, used_at = CURRENT_TIMESTAMP WHERE id = $2`
+		}
+		if _, err := tx.Exec(query, attempts, id); err != nil {
+			return userID, err
+		}
+		if err := tx.Commit(); err != nil {
+			return userID, err
+		}
+		return sql.NullInt64{}, ErrInvalidPhoneCode
+	}
+
+	if _, err := tx.Exec(`UPDATE phone_codes SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, id); err != nil {
+		return userID, err
+	}
+	if err := tx.Commit(); err != nil {
+		return userID, err
+	}
+	return userID, nil
+}
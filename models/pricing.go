@@ -0,0 +1,413 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Tipos de regra da tabela de preços
+const (
+	PriceRuleDepth    = "PROFUNDIDADE" // faixa de profundidade, valor em R$/m
+	PriceRuleDiameter = "DIAMETRO"     // faixa de diâmetro (mm), valor multiplicador
+	PriceRuleTerrain  = "TERRENO"      // tipo de terreno, valor multiplicador
+)
+
+// Terrain descreve um tipo de terreno aceito nas regras de preço
+type Terrain struct {
+	Code string
+	Name string
+}
+
+// Terrains são os tipos de terreno considerados na perfuração
+var Terrains = []Terrain{
+	{"SEDIMENTAR", "Sedimentar"},
+	{"MISTO", "Misto"},
+	{"CRISTALINO", "Cristalino (rocha)"},
+}
+
+func isTerrain(code string) bool {
+	for _, t := range Terrains {
+		if t.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// TerrainName retorna o nome do terreno pelo código
+func TerrainName(code string) string {
+	for _, t := range Terrains {
+		if t.Code == code {
+			return t.Name
+		}
+	}
+	return code
+}
+
+var (
+	ErrPriceTableExists = errors.New("já existe tabela de preços para este tipo de serviço")
+	ErrInvalidPriceRule = errors.New("regra de preço inválida")
+)
+
+// PriceTable é a tabela de preços de um tipo de serviço
+type PriceTable struct {
+	ID              int       `json:"id"`
+	ServiceTypeID   int       `json:"service_type_id"`
+	Name            string    `json:"name"`
+	BaseFee         float64   `json:"base_fee"`
+	TravelRatePerKm float64   `json:"travel_rate_per_km"`
+	FreeKm          float64   `json:"free_km"`
+	RoundTrip       bool      `json:"round_trip"`
+	Active          bool      `json:"active"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+
+	// Campos relacionados expandidos
+	ServiceTypeName string      `json:"service_type_name,omitempty"`
+	Rules           []PriceRule `json:"rules,omitempty"`
+}
+
+// RulesOfType filtra as regras da tabela pelo tipo
+func (t *PriceTable) RulesOfType(ruleType string) []PriceRule {
+	var rules []PriceRule
+	for _, rule := range t.Rules {
+		if rule.RuleType == ruleType {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// PriceRule é uma regra da tabela de preços. Faixas usam MinValue/MaxValue
+// (MaxValue nulo = sem limite); regras de terreno usam Terrain.
+type PriceRule struct {
+	ID           int             `json:"id"`
+	PriceTableID int             `json:"price_table_id"`
+	RuleType     string          `json:"rule_type"`
+	MinValue     sql.NullFloat64 `json:"min_value"`
+	MaxValue     sql.NullFloat64 `json:"max_value"`
+	Terrain      sql.NullString  `json:"terrain"`
+	Value        float64         `json:"value"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// Validate confere os campos exigidos por cada tipo de regra
+func (r *PriceRule) Validate() error {
+	switch r.RuleType {
+	case PriceRuleDepth, PriceRuleDiameter:
+		if !r.MinValue.Valid || r.MinValue.Float64 < 0 {
+			return ErrInvalidPriceRule
+		}
+		if r.MaxValue.Valid && r.MaxValue.Float64 <= r.MinValue.Float64 {
+			return ErrInvalidPriceRule
+		}
+	case PriceRuleTerrain:
+		if !r.Terrain.Valid || !isTerrain(r.Terrain.String) {
+			return ErrInvalidPriceRule
+		}
+	default:
+		return ErrInvalidPriceRule
+	}
+
+	// Preço por metro pode ser zero; multiplicadores não
+	if r.Value < 0 || (r.RuleType != PriceRuleDepth && r.Value == 0) {
+		return ErrInvalidPriceRule
+	}
+	return nil
+}
+
+// TerrainName retorna o nome do terreno da regra
+func (r *PriceRule) TerrainName() string {
+	return TerrainName(r.Terrain.String)
+}
+
+// Contains indica se o valor está dentro da faixa [min, max) da regra
+func (r *PriceRule) Contains(v float64) bool {
+	if !r.MinValue.Valid || v < r.MinValue.Float64 {
+		return false
+	}
+	return !r.MaxValue.Valid || v < r.MaxValue.Float64
+}
+
+// CityDistance é a distância rodoviária da base até uma cidade atendida
+type CityDistance struct {
+	ID         int       `json:"id"`
+	Cidade     string    `json:"cidade"`
+	Estado     string    `json:"estado"`
+	DistanceKm float64   `json:"distance_km"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type PricingModel struct {
+	DB *sql.DB
+}
+
+func NewPricingModel(db *sql.DB) *PricingModel {
+	return &PricingModel{DB: db}
+}
+
+// ============================================
+// TABELAS DE PREÇO
+// ============================================
+
+// CreateTable cria a tabela de preços de um tipo de serviço (uma por tipo)
+func (m *PricingModel) CreateTable(table *PriceTable, actor Actor) error {
+	return withAudit(m.DB, actor, AuditCreate, AuditEntityPriceTable, 0, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRow(`
+			INSERT INTO price_tables (service_type_id, name, base_fee, travel_rate_per_km, free_km, round_trip, active)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (service_type_id) DO NOTHING
+			RETURNING id, created_at, updated_at`,
+			table.ServiceTypeID, table.Name, table.BaseFee, table.TravelRatePerKm, table.FreeKm, table.RoundTrip, table.Active,
+		).Scan(&table.ID, &table.CreatedAt, &table.UpdatedAt)
+		if err == sql.ErrNoRows {
+			return 0, ErrPriceTableExists
+		}
+		return table.ID, err
+	})
+}
+
+// UpdateTable atualiza os valores gerais da tabela
+func (m *PricingModel) UpdateTable(table *PriceTable, actor Actor) error {
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityPriceTable, table.ID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(`
+			UPDATE price_tables
+			SET name = $1, base_fee = $2, travel_rate_per_km = $3, free_km = $4, round_trip = $5, active = $6,
+			    updated_at = CURRENT_TIMESTAMP
+			WHERE id = $7`,
+			table.Name, table.BaseFee, table.TravelRatePerKm, table.FreeKm, table.RoundTrip, table.Active, table.ID))
+	})
+}
+
+const priceTableSelectColumns = `
+	SELECT pt.id, pt.service_type_id, pt.name, pt.base_fee, pt.travel_rate_per_km, pt.free_km,
+	       pt.round_trip, pt.active, pt.created_at, pt.updated_at, st.name
+	FROM price_tables pt
+	JOIN service_types st ON pt.service_type_id = st.id`
+
+func scanPriceTable(scanner interface{ Scan(...interface{}) error }, table *PriceTable) error {
+	return scanner.Scan(
+		&table.ID, &table.ServiceTypeID, &table.Name, &table.BaseFee, &table.TravelRatePerKm, &table.FreeKm,
+		&table.RoundTrip, &table.Active, &table.CreatedAt, &table.UpdatedAt, &table.ServiceTypeName,
+	)
+}
+
+// GetTableByID busca a tabela com suas regras
+func (m *PricingModel) GetTableByID(id int) (*PriceTable, error) {
+	table := &PriceTable{}
+	if err := scanPriceTable(m.DB.QueryRow(priceTableSelectColumns+" WHERE pt.id = $1", id), table); err != nil {
+		return nil, err
+	}
+	return table, m.loadRules(table)
+}
+
+// GetActiveTableByServiceType busca a tabela ativa do tipo de serviço com suas regras
+func (m *PricingModel) GetActiveTableByServiceType(serviceTypeID int) (*PriceTable, error) {
+	table := &PriceTable{}
+	query := priceTableSelectColumns + " WHERE pt.service_type_id = $1 AND pt.active = true"
+	if err := scanPriceTable(m.DB.QueryRow(query, serviceTypeID), table); err != nil {
+		return nil, err
+	}
+	return table, m.loadRules(table)
+}
+
+// GetAllTables lista as tabelas de preço (sem regras)
+func (m *PricingModel) GetAllTables() ([]PriceTable, error) {
+	rows, err := m.DB.Query(priceTableSelectColumns + " ORDER BY st.name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []PriceTable
+	for rows.Next() {
+		var table PriceTable
+		if err := scanPriceTable(rows, &table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// ============================================
+// REGRAS
+// ============================================
+
+// AddRule inclui uma regra na tabela. Regras de terreno substituem a existente
+// para o mesmo terreno.
+func (m *PricingModel) AddRule(rule *PriceRule, actor Actor) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if rule.RuleType == PriceRuleTerrain {
+		var replacedID int
+		err = tx.QueryRow("SELECT id FROM price_rules WHERE price_table_id = $1 AND rule_type = $2 AND terrain = $3",
+			rule.PriceTableID, PriceRuleTerrain, rule.Terrain).Scan(&replacedID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if replacedID != 0 {
+			before, err := auditSnapshot(tx, AuditEntityPriceRule, replacedID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec("DELETE FROM price_rules WHERE id = $1", replacedID); err != nil {
+				return err
+			}
+			if err := recordAudit(tx, actor, AuditDelete, AuditEntityPriceRule, replacedID, before); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO price_rules (price_table_id, rule_type, min_value, max_value, terrain, value)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		rule.PriceTableID, rule.RuleType, rule.MinValue, rule.MaxValue, rule.Terrain, rule.Value,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE price_tables SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", rule.PriceTableID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actor, AuditCreate, AuditEntityPriceRule, rule.ID, sql.NullString{}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteRule remove uma regra da tabela
+func (m *PricingModel) DeleteRule(tableID, ruleID int, actor Actor) error {
+	return withAudit(m.DB, actor, AuditDelete, AuditEntityPriceRule, ruleID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec("DELETE FROM price_rules WHERE id = $1 AND price_table_id = $2", ruleID, tableID))
+	})
+}
+
+func (m *PricingModel) loadRules(table *PriceTable) error {
+	rows, err := m.DB.Query(`
+		SELECT id, price_table_id, rule_type, min_value, max_value, terrain, value, created_at
+		FROM price_rules
+		WHERE price_table_id = $1
+		ORDER BY rule_type, min_value NULLS LAST, terrain`, table.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	table.Rules = nil
+	for rows.Next() {
+		var rule PriceRule
+		err := rows.Scan(&rule.ID, &rule.PriceTableID, &rule.RuleType, &rule.MinValue, &rule.MaxValue,
+			&rule.Terrain, &rule.Value, &rule.CreatedAt)
+		if err != nil {
+			return err
+		}
+		table.Rules = append(table.Rules, rule)
+	}
+	return rows.Err()
+}
+
+// ============================================
+// DISTÂNCIAS POR CIDADE
+// ============================================
+
+// SaveCityDistance cadastra ou atualiza a distância até a cidade
+func (m *PricingModel) SaveCityDistance(distance *CityDistance, actor Actor) error {
+	distance.Cidade = strings.TrimSpace(distance.Cidade)
+	distance.Estado = strings.ToUpper(strings.TrimSpace(distance.Estado))
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Cidade já cadastrada: a distância existente é alterada
+	action := AuditCreate
+	var existingID int
+	err = tx.QueryRow("SELECT id FROM price_city_distances WHERE cidade = $1 AND estado = $2",
+		distance.Cidade, distance.Estado).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	before, err := auditSnapshot(tx, AuditEntityCityDistance, existingID)
+	if err != nil {
+		return err
+	}
+	if existingID != 0 {
+		action = AuditUpdate
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO price_city_distances (cidade, estado, distance_km)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (cidade, estado) DO UPDATE SET distance_km = EXCLUDED.distance_km
+		RETURNING id, created_at`,
+		distance.Cidade, distance.Estado, distance.DistanceKm,
+	).Scan(&distance.ID, &distance.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, actor, action, AuditEntityCityDistance, distance.ID, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteCityDistance remove a distância cadastrada
+func (m *PricingModel) DeleteCityDistance(id int, actor Actor) error {
+	return withAudit(m.DB, actor, AuditDelete, AuditEntityCityDistance, id, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec("DELETE FROM price_city_distances WHERE id = $1", id))
+	})
+}
+
+// FindCityDistance busca a distância até a cidade, sem diferenciar maiúsculas
+func (m *PricingModel) FindCityDistance(cidade, estado string) (*CityDistance, error) {
+	distance := &CityDistance{}
+	err := m.DB.QueryRow(`
+		SELECT id, cidade, estado, distance_km, created_at
+		FROM price_city_distances
+		WHERE LOWER(cidade) = LOWER($1) AND estado = UPPER($2)`,
+		strings.TrimSpace(cidade), strings.TrimSpace(estado),
+	).Scan(&distance.ID, &distance.Cidade, &distance.Estado, &distance.DistanceKm, &distance.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return distance, nil
+}
+
+// GetAllCityDistances lista as distâncias cadastradas
+func (m *PricingModel) GetAllCityDistances() ([]CityDistance, error) {
+	rows, err := m.DB.Query(`
+		SELECT id, cidade, estado, distance_km, created_at
+		FROM price_city_distances
+		ORDER BY estado, cidade`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var distances []CityDistance
+	for rows.Next() {
+		var distance CityDistance
+		if err := rows.Scan(&distance.ID, &distance.Cidade, &distance.Estado, &distance.DistanceKm, &distance.CreatedAt); err != nil {
+			return nil, err
+		}
+		distances = append(distances, distance)
+	}
+	return distances, rows.Err()
+}
@@ -0,0 +1,1021 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Situações de um pedido de exclusão de dados
+const (
+	ErasurePending   = "PENDENTE"
+	ErasureApproved  = "APROVADA"
+	ErasureRejected  = "RECUSADA"
+	ErasureCancelled = "CANCELADA"
+)
+
+// Ações registradas na trilha de auditoria de privacidade (privacy_events)
+const (
+	PrivacyEventExport     = "EXPORTACAO"
+	PrivacyEventRequested  = "PEDIDO_EXCLUSAO"
+	PrivacyEventCancelled  = "PEDIDO_CANCELADO"
+	PrivacyEventRejected   = "PEDIDO_RECUSADO"
+	PrivacyEventAnonymized = "ANONIMIZACAO"
+	PrivacyEventPurged     = "EXPURGO"
+)
+
+// Textos gravados no lugar dos dados pessoais apagados
+const (
+	anonymizedName  = "Titular removido"
+	anonymizedField = "Removido (LGPD)"
+)
+
+var (
+	ErrErasurePending       = errors.New("já existe um pedido de exclusão em análise")
+	ErrErasureNotFound      = errors.New("pedido de exclusão não encontrado")
+	ErrErasureDecided       = errors.New("o pedido já foi decidido")
+	ErrErasureNotClient     = errors.New("apenas contas de cliente podem pedir a exclusão dos dados pelo portal")
+	ErrDecisionNoteRequired = errors.New("informe o motivo da recusa")
+)
+
+// ErasureRequest é um pedido do cliente para apagar os dados da conta
+type ErasureRequest struct {
+	ID           int            `json:"id"`
+	UserID       int            `json:"user_id"`
+	Status       string         `json:"status"`
+	Reason       sql.NullString `json:"reason"`
+	DecisionNote sql.NullString `json:"decision_note"`
+	DecidedBy    sql.NullInt64  `json:"decided_by"`
+	DecidedAt    sql.NullTime   `json:"decided_at"`
+	RetainUntil  sql.NullTime   `json:"retain_until"`
+	PurgedAt     sql.NullTime   `json:"purged_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+
+	// Campos relacionados expandidos
+	UserName        string `json:"user_name,omitempty"`
+	UserEmail       string `json:"user_email,omitempty"`
+	DeciderName     string `json:"decider_name,omitempty"`
+	RequestCount    int    `json:"request_count"`
+	OpenRequests    int    `json:"open_requests"`
+	SignedContracts int    `json:"signed_contracts"`
+}
+
+// StatusLabel retorna o nome da situação para exibição
+func (e *ErasureRequest) StatusLabel() string {
+	switch e.Status {
+	case ErasureApproved:
+		return "Concluído"
+	case ErasureRejected:
+		return "Recusado"
+	case ErasureCancelled:
+		return "Cancelado"
+	default:
+		return "Em análise"
+	}
+}
+
+// StatusBadge retorna a classe do badge Bootstrap da situação
+func (e *ErasureRequest) StatusBadge() string {
+	switch e.Status {
+	case ErasureApproved:
+		return "bg-success"
+	case ErasureRejected:
+		return "bg-danger"
+	case ErasureCancelled:
+		return "bg-secondary"
+	default:
+		return "bg-warning text-dark"
+	}
+}
+
+// IsPending indica se o pedido ainda aguarda decisão
+func (e *ErasureRequest) IsPending() bool {
+	return e.Status == ErasurePending
+}
+
+// PrivacyEvent é um registro da trilha de auditoria de privacidade
+type PrivacyEvent struct {
+	ID               int            `json:"id"`
+	UserID           sql.NullInt64  `json:"user_id"`
+	ErasureRequestID sql.NullInt64  `json:"erasure_request_id"`
+	Action           string         `json:"action"`
+	ActorID          sql.NullInt64  `json:"actor_id"`
+	Details          sql.NullString `json:"details"`
+	IP               sql.NullString `json:"ip"`
+	CreatedAt        time.Time      `json:"created_at"`
+
+	// Campos relacionados expandidos
+	UserName  string `json:"user_name,omitempty"`
+	ActorName string `json:"actor_name,omitempty"`
+}
+
+// ActionLabel retorna a descrição da ação para exibição
+func (e *PrivacyEvent) ActionLabel() string {
+	switch e.Action {
+	case PrivacyEventExport:
+		return "Exportação dos dados"
+	case PrivacyEventRequested:
+		return "Pedido de exclusão"
+	case PrivacyEventCancelled:
+		return "Pedido cancelado pelo titular"
+	case PrivacyEventRejected:
+		return "Pedido recusado"
+	case PrivacyEventAnonymized:
+		return "Conta anonimizada"
+	case PrivacyEventPurged:
+		return "Dados guardados eliminados"
+	default:
+		return e.Action
+	}
+}
+
+// ============================================
+// EXPORTAÇÃO DOS DADOS DO TITULAR
+// ============================================
+
+// DataExport reúne os dados pessoais do cliente entregues na exportação.
+// Os tipos usam ponteiros no lugar de sql.Null* para que o JSON fique legível.
+type DataExport struct {
+	GeneratedAt     time.Time               `json:"gerado_em"`
+	User            ExportedUser            `json:"usuario"`
+	Addresses       []ExportedAddress       `json:"imoveis"`
+	ServiceRequests []ExportedRequest       `json:"solicitacoes"`
+	Contracts       []ExportedContract      `json:"contratos"`
+	Observations    []ExportedObservation   `json:"observacoes"`
+	WarrantyClaims  []ExportedWarrantyClaim `json:"garantias"`
+	ProfileChanges  []ExportedProfileChange `json:"historico_perfil"`
+	PrivacyEvents   []ExportedPrivacyEvent  `json:"historico_privacidade"`
+}
+
+type ExportedUser struct {
+	ID              int                     `json:"id"`
+	Name            string                  `json:"nome"`
+	Email           string                  `json:"email"`
+	Phone           string                  `json:"telefone"`
+	Address         string                  `json:"endereco"`
+	EmailVerifiedAt *time.Time              `json:"email_confirmado_em"`
+	PhoneVerifiedAt *time.Time              `json:"telefone_confirmado_em"`
+	Notifications   NotificationPreferences `json:"avisos"`
+	CreatedAt       time.Time               `json:"criado_em"`
+}
+
+type ExportedAddress struct {
+	Label      string    `json:"nome"`
+	CEP        string    `json:"cep"`
+	Logradouro string    `json:"logradouro"`
+	Numero     string    `json:"numero"`
+	Bairro     string    `json:"bairro"`
+	Cidade     string    `json:"cidade"`
+	Estado     string    `json:"estado"`
+	IsDefault  bool      `json:"padrao"`
+	CreatedAt  time.Time `json:"criado_em"`
+}
+
+type ExportedRequest struct {
+	ID            int       `json:"id"`
+	ServiceType   string    `json:"tipo_servico"`
+	Status        string    `json:"situacao"`
+	FullName      string    `json:"nome"`
+	Description   string    `json:"descricao"`
+	CEP           string    `json:"cep"`
+	Logradouro    string    `json:"logradouro"`
+	Numero        string    `json:"numero"`
+	Bairro        string    `json:"bairro"`
+	Cidade        string    `json:"cidade"`
+	Estado        string    `json:"estado"`
+	PreferredDate string    `json:"data_preferencial"`
+	PreferredTime string    `json:"horario_preferencial"`
+	Latitude      *float64  `json:"latitude"`
+	Longitude     *float64  `json:"longitude"`
+	CreatedAt     time.Time `json:"criada_em"`
+
+	CustomFields CustomFieldValues `json:"campos_adicionais"`
+}
+
+type ExportedContract struct {
+	ID                 int        `json:"id"`
+	ServiceRequestID   int        `json:"solicitacao_id"`
+	Number             string     `json:"numero"`
+	TotalValue         float64    `json:"valor_total"`
+	PaymentConditions  string     `json:"condicoes_pagamento"`
+	Guarantee          string     `json:"garantia"`
+	ClientRequirements string     `json:"exigencias_cliente"`
+	Status             string     `json:"situacao"`
+	ClientSigned       bool       `json:"assinado_cliente"`
+	ClientSignedAt     *time.Time `json:"assinado_cliente_em"`
+	CompanySigned      bool       `json:"assinado_empresa"`
+	CompanySignedAt    *time.Time `json:"assinado_empresa_em"`
+	SignatureFile      string     `json:"arquivo_assinatura,omitempty"`
+	CreatedAt          time.Time  `json:"criado_em"`
+
+	// Assinatura do cliente (data URL PNG), gravada como arquivo à parte
+	ClientSignature string `json:"-"`
+}
+
+type ExportedObservation struct {
+	ContractNumber string    `json:"contrato"`
+	Observation    string    `json:"observacao"`
+	Resolved       bool      `json:"resolvida"`
+	CreatedAt      time.Time `json:"criada_em"`
+}
+
+type ExportedWarrantyClaim struct {
+	ContractNumber string    `json:"contrato"`
+	Description    string    `json:"descricao"`
+	Status         string    `json:"situacao"`
+	DecisionReason string    `json:"motivo_decisao"`
+	CreatedAt      time.Time `json:"aberta_em"`
+}
+
+type ExportedProfileChange struct {
+	Field     string    `json:"campo"`
+	OldValue  string    `json:"valor_anterior"`
+	NewValue  string    `json:"valor_novo"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"alterado_em"`
+}
+
+type ExportedPrivacyEvent struct {
+	Action    string    `json:"acao"`
+	Details   string    `json:"detalhes"`
+	CreatedAt time.Time `json:"registrado_em"`
+}
+
+// ============================================
+// MODELO
+// ============================================
+
+type PrivacyModel struct {
+	DB *sql.DB
+}
+
+func NewPrivacyModel(db *sql.DB) *PrivacyModel {
+	return &PrivacyModel{DB: db}
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullFloatPtr(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+// ExportUserData reúne os dados pessoais do usuário para a exportação
+func (m *PrivacyModel) ExportUserData(userID int) (*DataExport, error) {
+	export := &DataExport{GeneratedAt: time.Now()}
+
+	var emailVerifiedAt, phoneVerifiedAt sql.NullTime
+	u := &export.User
+	err := m.DB.QueryRow(`
+		SELECT id, name, email, COALESCE(phone, ''), COALESCE(address, ''), email_verified_at, phone_verified_at,
+		       notify_request_updates, notify_quotes, notify_warranty, notify_maintenance, created_at
+		FROM users WHERE id = $1`, userID).
+		Scan(&u.ID, &u.Name, &u.Email, &u.Phone, &u.Address, &emailVerifiedAt, &phoneVerifiedAt,
+			&u.Notifications.RequestUpdates, &u.Notifications.Quotes, &u.Notifications.Warranty,
+			&u.Notifications.Maintenance, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	u.EmailVerifiedAt = nullTimePtr(emailVerifiedAt)
+	u.PhoneVerifiedAt = nullTimePtr(phoneVerifiedAt)
+
+	steps := []func(int, *DataExport) error{
+		m.exportAddresses,
+		m.exportRequests,
+		m.exportContracts,
+		m.exportObservations,
+		m.exportWarrantyClaims,
+		m.exportProfileChanges,
+		m.exportPrivacyEvents,
+	}
+	for _, step := range steps {
+		if err := step(userID, export); err != nil {
+			return nil, err
+		}
+	}
+
+	return export, nil
+}
+
+func (m *PrivacyModel) exportAddresses(userID int, export *DataExport) error {
+	rows, err := m.DB.Query(`
+		SELECT label, cep, logradouro, numero, bairro, cidade, estado, is_default, created_at
+		FROM user_addresses WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a ExportedAddress
+		if err := rows.Scan(&a.Label, &a.CEP, &a.Logradouro, &a.Numero, &a.Bairro, &a.Cidade, &a.Estado, &a.IsDefault, &a.CreatedAt); err != nil {
+			return err
+		}
+		export.Addresses = append(export.Addresses, a)
+	}
+	return rows.Err()
+}
+
+func (m *PrivacyModel) exportRequests(userID int, export *DataExport) error {
+	rows, err := m.DB.Query(`
+		SELECT sr.id, COALESCE(st.name, ''), COALESCE(rs.name, ''), sr.full_name, COALESCE(sr.description, ''),
+		       sr.cep, sr.logradouro, sr.numero, sr.bairro, sr.cidade, sr.estado,
+		       TO_CHAR(sr.preferred_date, 'YYYY-MM-DD'), TO_CHAR(sr.preferred_time, 'HH24:MI'),
+		       sr.latitude, sr.longitude, sr.created_at, sr.custom_fields
+		FROM service_requests sr
+		LEFT JOIN service_types st ON sr.service_type_id = st.id
+		LEFT JOIN request_status rs ON sr.status_id = rs.id
+		WHERE sr.user_id = $1
+		ORDER BY sr.created_at`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r ExportedRequest
+		var lat, lng sql.NullFloat64
+		err := rows.Scan(&r.ID, &r.ServiceType, &r.Status, &r.FullName, &r.Description,
+			&r.CEP, &r.Logradouro, &r.Numero, &r.Bairro, &r.Cidade, &r.Estado,
+			&r.PreferredDate, &r.PreferredTime, &lat, &lng, &r.CreatedAt, &r.CustomFields)
+		if err != nil {
+			return err
+		}
+		r.Latitude = nullFloatPtr(lat)
+		r.Longitude = nullFloatPtr(lng)
+		export.ServiceRequests = append(export.ServiceRequests, r)
+	}
+	return rows.Err()
+}
+
+func (m *PrivacyModel) exportContracts(userID int, export *DataExport) error {
+	rows, err := m.DB.Query(`
+		SELECT c.id, c.service_request_id, c.contract_number, c.total_value, c.payment_conditions,
+		       COALESCE(gt.name, ''), COALESCE(c.client_requirements, ''), COALESCE(cs.name, ''),
+		       c.client_signed, c.client_signed_at, COALESCE(c.client_signature, ''),
+		       c.company_signed, c.company_signed_at, c.created_at
+		FROM contracts c
+		JOIN service_requests sr ON c.service_request_id = sr.id
+		LEFT JOIN guarantee_types gt ON c.guarantee_type_id = gt.id
+		LEFT JOIN contract_status cs ON c.status_id = cs.id
+		WHERE sr.user_id = $1
+		ORDER BY c.created_at`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c ExportedContract
+		var clientSignedAt, companySignedAt sql.NullTime
+		err := rows.Scan(&c.ID, &c.ServiceRequestID, &c.Number, &c.TotalValue, &c.PaymentConditions,
+			&c.Guarantee, &c.ClientRequirements, &c.Status,
+			&c.ClientSigned, &clientSignedAt, &c.ClientSignature,
+			&c.CompanySigned, &companySignedAt, &c.CreatedAt)
+		if err != nil {
+			return err
+		}
+		c.ClientSignedAt = nullTimePtr(clientSignedAt)
+		c.CompanySignedAt = nullTimePtr(companySignedAt)
+		export.Contracts = append(export.Contracts, c)
+	}
+	return rows.Err()
+}
+
+func (m *PrivacyModel) exportObservations(userID int, export *DataExport) error {
+	rows, err := m.DB.Query(`
+		SELECT c.contract_number, co.observation, COALESCE(co.resolved, false), co.created_at
+		FROM contract_client_observations co
+		JOIN contracts c ON co.contract_id = c.id
+		WHERE co.user_id = $1
+		ORDER BY co.created_at`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var o ExportedObservation
+		if err := rows.Scan(&o.ContractNumber, &o.Observation, &o.Resolved, &o.CreatedAt); err != nil {
+			return err
+		}
+		export.Observations = append(export.Observations, o)
+	}
+	return rows.Err()
+}
+
+func (m *PrivacyModel) exportWarrantyClaims(userID int, export *DataExport) error {
+	rows, err := m.DB.Query(`
+		SELECT c.contract_number, wc.description, wc.status, COALESCE(wc.decision_reason, ''), wc.created_at
+		FROM warranty_claims wc
+		JOIN contracts c ON wc.contract_id = c.id
+		WHERE wc.user_id = $1
+		ORDER BY wc.created_at`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w ExportedWarrantyClaim
+		if err := rows.Scan(&w.ContractNumber, &w.Description, &w.Status, &w.DecisionReason, &w.CreatedAt); err != nil {
+			return err
+		}
+		export.WarrantyClaims = append(export.WarrantyClaims, w)
+	}
+	return rows.Err()
+}
+
+func (m *PrivacyModel) exportProfileChanges(userID int, export *DataExport) error {
+	rows, err := m.DB.Query(`
+		SELECT field, COALESCE(old_value, ''), COALESCE(new_value, ''), COALESCE(ip, ''), created_at
+		FROM user_profile_changes WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c ProfileChange
+		var p ExportedProfileChange
+		if err := rows.Scan(&c.Field, &p.OldValue, &p.NewValue, &p.IP, &p.CreatedAt); err != nil {
+			return err
+		}
+		p.Field = c.FieldLabel()
+		export.ProfileChanges = append(export.ProfileChanges, p)
+	}
+	return rows.Err()
+}
+
+func (m *PrivacyModel) exportPrivacyEvents(userID int, export *DataExport) error {
+	events, err := m.GetEventsByUser(userID, 0)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		export.PrivacyEvents = append(export.PrivacyEvents, ExportedPrivacyEvent{
+			Action:    e.ActionLabel(),
+			Details:   e.Details.String,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return nil
+}
+
+// ============================================
+// TRILHA DE AUDITORIA
+// ============================================
+
+// RecordEvent registra uma ação na trilha de auditoria de privacidade.
+// requestID e actorID são opcionais (0).
+func (m *PrivacyModel) RecordEvent(userID, requestID int, action string, actorID int, details, ip string) error {
+	return recordPrivacyEvent(m.DB, userID, requestID, action, actorID, details, ip)
+}
+
+func recordPrivacyEvent(db interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}, userID, requestID int, action string, actorID int, details, ip string) error {
+	_, err := db.Exec(`
+		INSERT INTO privacy_events (user_id, erasure_request_id, action, actor_id, details, ip)
+		VALUES ($1, NULLIF($2, 0), $3, NULLIF($4, 0), NULLIF($5, ''), NULLIF($6, ''))`,
+		userID, requestID, action, actorID, details, ip)
+	return err
+}
+
+const privacyEventColumns = `
+		SELECT pe.id, pe.user_id, pe.erasure_request_id, pe.action, pe.actor_id, pe.details, pe.ip, pe.created_at,
+		       COALESCE(u.name, ''), COALESCE(a.name, '')
+		FROM privacy_events pe
+		LEFT JOIN users u ON pe.user_id = u.id
+		LEFT JOIN users a ON pe.actor_id = a.id`
+
+func (m *PrivacyModel) queryEvents(query string, args ...interface{}) ([]PrivacyEvent, error) {
+	rows, err := m.DB.Query(privacyEventColumns+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []PrivacyEvent
+	for rows.Next() {
+		var e PrivacyEvent
+		err := rows.Scan(&e.ID, &e.UserID, &e.ErasureRequestID, &e.Action, &e.ActorID, &e.Details, &e.IP, &e.CreatedAt,
+			&e.UserName, &e.ActorName)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// GetEventsByUser lista a trilha de um usuário, da mais recente para a
+// mais antiga. limit 0 traz tudo.
+func (m *PrivacyModel) GetEventsByUser(userID, limit int) ([]PrivacyEvent, error) {
+	if limit <= 0 {
+		return m.queryEvents(` WHERE pe.user_id = $1 ORDER BY pe.created_at DESC, pe.id DESC`, userID)
+	}
+	return m.queryEvents(` WHERE pe.user_id = $1 ORDER BY pe.created_at DESC, pe.id DESC LIMIT $2`, userID, limit)
+}
+
+// GetRecentEvents lista os últimos registros da trilha, de todos os usuários
+func (m *PrivacyModel) GetRecentEvents(limit int) ([]PrivacyEvent, error) {
+	return m.queryEvents(` ORDER BY pe.created_at DESC, pe.id DESC LIMIT $1`, limit)
+}
+
+// ============================================
+// PEDIDOS DE EXCLUSÃO
+// ============================================
+
+const erasureRequestColumns = `
+		SELECT er.id, er.user_id, er.status, er.reason, er.decision_note, er.decided_by, er.decided_at,
+		       er.retain_until, er.purged_at, er.created_at,
+		       u.name, u.email, COALESCE(d.name, ''),
+		       (SELECT COUNT(*) FROM service_requests sr WHERE sr.user_id = er.user_id),
+		       (SELECT COUNT(*) FROM service_requests sr
+		        JOIN request_status rs ON sr.status_id = rs.id
+		        WHERE sr.user_id = er.user_id AND rs.code IN ('SOLICITADA', 'CONFIRMADA')),
+		       (SELECT COUNT(*) FROM contracts c
+		        JOIN service_requests sr ON c.service_request_id = sr.id
+		        WHERE sr.user_id = er.user_id AND (c.client_signed OR c.company_signed))
+		FROM data_erasure_requests er
+		JOIN users u ON er.user_id = u.id
+		LEFT JOIN users d ON er.decided_by = d.id`
+
+func scanErasureRequest(scanner interface{ Scan(...interface{}) error }, e *ErasureRequest) error {
+	return scanner.Scan(&e.ID, &e.UserID, &e.Status, &e.Reason, &e.DecisionNote, &e.DecidedBy, &e.DecidedAt,
+		&e.RetainUntil, &e.PurgedAt, &e.CreatedAt,
+		&e.UserName, &e.UserEmail, &e.DeciderName,
+		&e.RequestCount, &e.OpenRequests, &e.SignedContracts)
+}
+
+func (m *PrivacyModel) queryErasureRequests(query string, args ...interface{}) ([]ErasureRequest, error) {
+	rows, err := m.DB.Query(erasureRequestColumns+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []ErasureRequest
+	for rows.Next() {
+		var e ErasureRequest
+		if err := scanErasureRequest(rows, &e); err != nil {
+			return nil, err
+		}
+		requests = append(requests, e)
+	}
+	return requests, rows.Err()
+}
+
+// GetErasureRequests lista os pedidos, os pendentes primeiro. status vazio traz todos.
+func (m *PrivacyModel) GetErasureRequests(status string, limit int) ([]ErasureRequest, error) {
+	order := ` ORDER BY (er.status = 'PENDENTE') DESC, er.created_at DESC LIMIT $1`
+	if status == "" {
+		return m.queryErasureRequests(order, limit)
+	}
+	return m.queryErasureRequests(` WHERE er.status = $2`+order, limit, status)
+}
+
+// GetLatestErasureRequest retorna o pedido mais recente do usuário (nil se não houver)
+func (m *PrivacyModel) GetLatestErasureRequest(userID int) (*ErasureRequest, error) {
+	e := &ErasureRequest{}
+	err := scanErasureRequest(m.DB.QueryRow(erasureRequestColumns+`
+		WHERE er.user_id = $1 ORDER BY er.created_at DESC, er.id DESC LIMIT 1`, userID), e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// GetErasureRequest busca um pedido pelo ID
+func (m *PrivacyModel) GetErasureRequest(id int) (*ErasureRequest, error) {
+	e := &ErasureRequest{}
+	err := scanErasureRequest(m.DB.QueryRow(erasureRequestColumns+` WHERE er.id = $1`, id), e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrErasureNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// CountPendingErasureRequests conta os pedidos aguardando decisão
+func (m *PrivacyModel) CountPendingErasureRequests() (int, error) {
+	var count int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM data_erasure_requests WHERE status = 'PENDENTE'`).Scan(&count)
+	return count, err
+}
+
+// RequestErasure registra o pedido de exclusão do cliente. Só um pedido
+// pode estar em análise por vez.
+func (m *PrivacyModel) RequestErasure(userID int, reason, ip string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userType string
+	err = tx.QueryRow(`
+		SELECT ut.type_name FROM users u
+		JOIN user_types ut ON u.user_type_id = ut.id
+		WHERE u.id = $1 AND u.anonymized_at IS NULL
+		FOR UPDATE OF u`, userID).Scan(&userType)
+	if err != nil {
+		return 0, err
+	}
+	if userType != "cliente" {
+		return 0, ErrErasureNotClient
+	}
+
+	var pending bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM data_erasure_requests WHERE user_id = $1 AND status = 'PENDENTE')`, userID).Scan(&pending); err != nil {
+		return 0, err
+	}
+	if pending {
+		return 0, ErrErasurePending
+	}
+
+	var id int
+	reason = strings.TrimSpace(reason)
+	err = tx.QueryRow(`
+		INSERT INTO data_erasure_requests (user_id, reason) VALUES ($1, NULLIF($2, ''))
+		RETURNING id`, userID, reason).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := recordPrivacyEvent(tx, userID, id, PrivacyEventRequested, userID, reason, ip); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// CancelErasure desiste do pedido em análise
+func (m *PrivacyModel) CancelErasure(userID int, ip string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		UPDATE data_erasure_requests SET status = 'CANCELADA'
+		WHERE user_id = $1 AND status = 'PENDENTE'
+		RETURNING id`, userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrErasureNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := recordPrivacyEvent(tx, userID, id, PrivacyEventCancelled, userID, "", ip); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RejectErasure recusa o pedido (ex.: serviço em andamento ou débito em
+// aberto). O motivo é obrigatório e fica visível para o cliente.
+func (m *PrivacyModel) RejectErasure(id int, note string, actor Actor) (*ErasureRequest, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, ErrDecisionNoteRequired
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var status string
+	err = tx.QueryRow(`SELECT user_id, status FROM data_erasure_requests WHERE id = $1 FOR UPDATE`, id).Scan(&userID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrErasureNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != ErasurePending {
+		return nil, ErrErasureDecided
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityErasure, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE data_erasure_requests
+		SET status = 'RECUSADA', decision_note = $1, decided_by = $2, decided_at = CURRENT_TIMESTAMP
+		WHERE id = $3`, note, actor.UserID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordPrivacyEvent(tx, userID, id, PrivacyEventRejected, actor.UserID, note, actor.IP); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(tx, actor, AuditReject, AuditEntityErasure, id, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return m.GetErasureRequest(id)
+}
+
+// ApproveErasure aprova o pedido e anonimiza a conta na mesma transação.
+// Solicitações com contrato assinado (e os contratos, observações e
+// garantias delas) ficam guardadas por retentionYears a partir da última
+// assinatura; retain_until marca quando o expurgo pode ser feito.
+func (m *PrivacyModel) ApproveErasure(id int, note string, retentionYears int, actor Actor) (*ErasureRequest, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var status string
+	err = tx.QueryRow(`SELECT user_id, status FROM data_erasure_requests WHERE id = $1 FOR UPDATE`, id).Scan(&userID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrErasureNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != ErasurePending {
+		return nil, ErrErasureDecided
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityErasure, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastSigned sql.NullTime
+	err = tx.QueryRow(`
+		SELECT MAX(GREATEST(COALESCE(c.client_signed_at, c.created_at), COALESCE(c.company_signed_at, c.created_at)))
+		FROM contracts c
+		JOIN service_requests sr ON c.service_request_id = sr.id
+		WHERE sr.user_id = $1 AND (c.client_signed OR c.company_signed)`, userID).Scan(&lastSigned)
+	if err != nil {
+		return nil, err
+	}
+
+	var retainUntil sql.NullTime
+	if lastSigned.Valid {
+		retainUntil = sql.NullTime{Time: truncateDay(lastSigned.Time).AddDate(retentionYears, 0, 0), Valid: true}
+	}
+
+	if err := anonymizeAccount(tx, userID); err != nil {
+		return nil, err
+	}
+	if err := anonymizeRequests(tx, userID, !retainUntil.Valid); err != nil {
+		return nil, err
+	}
+
+	var purgedAt interface{}
+	if !retainUntil.Valid {
+		purgedAt = time.Now()
+	}
+	_, err = tx.Exec(`
+		UPDATE data_erasure_requests
+		SET status = 'APROVADA', decision_note = NULLIF($1, ''), decided_by = $2, decided_at = CURRENT_TIMESTAMP,
+		    retain_until = $3, purged_at = $4
+		WHERE id = $5`, strings.TrimSpace(note), actor.UserID, retainUntil, purgedAt, id)
+	if err != nil {
+		return nil, err
+	}
+
+	details := "Nenhum contrato assinado; todos os dados pessoais foram removidos."
+	if retainUntil.Valid {
+		details = fmt.Sprintf("Contratos assinados guardados até %s (prazo legal).", retainUntil.Time.Format("02/01/2006"))
+	}
+	if err := recordPrivacyEvent(tx, userID, id, PrivacyEventAnonymized, actor.UserID, details, actor.IP); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(tx, actor, AuditApprove, AuditEntityErasure, id, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return m.GetErasureRequest(id)
+}
+
+// PurgeExpired elimina os dados guardados das contas anonimizadas cujo
+// prazo de guarda terminou até a data informada. Retorna quantas contas
+// foram expurgadas.
+func (m *PrivacyModel) PurgeExpired(today time.Time) (int, error) {
+	rows, err := m.DB.Query(`
+		SELECT id, user_id FROM data_erasure_requests
+		WHERE status = 'APROVADA' AND purged_at IS NULL AND retain_until <= $1`, truncateDay(today))
+	if err != nil {
+		return 0, err
+	}
+
+	type due struct{ id, userID int }
+	var pending []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, d := range pending {
+		if err := m.purge(d.id, d.userID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (m *PrivacyModel) purge(requestID, userID int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE data_erasure_requests SET purged_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND purged_at IS NULL`, requestID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil
+	}
+
+	if err := anonymizeRequests(tx, userID, true); err != nil {
+		return err
+	}
+
+	if err := recordPrivacyEvent(tx, userID, requestID, PrivacyEventPurged, 0, "Prazo de guarda encerrado.", ""); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// anonymizeAccount apaga os dados pessoais do cadastro e tudo que permite
+// entrar na conta (senha, sessões, tokens e códigos)
+func anonymizeAccount(tx *sql.Tx, userID int) error {
+	var email, phone string
+	err := tx.QueryRow(`SELECT email, COALESCE(phone, '') FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&email, &phone)
+	if err != nil {
+		return err
+	}
+	// O cadastro guarda o telefone como digitado (com máscara); os códigos,
+	// as tentativas de entrada e os desbloqueios usam só os dígitos
+	normalizedPhone, _ := NormalizePhone(phone)
+
+	// A senha "!" não é um hash bcrypt válido, então nenhuma senha confere
+	_, err = tx.Exec(`
+		UPDATE users
+		SET name = $1, email = $2, password = '!', phone = NULL, address = NULL, pending_email = NULL,
+		    email_verified_at = NULL, phone_verified_at = NULL,
+		    totp_secret = NULL, totp_enabled_at = NULL,
+		    notify_request_updates = false, notify_quotes = false, notify_warranty = false, notify_maintenance = false,
+		    anonymized_at = CURRENT_TIMESTAMP
+		WHERE id = $3`,
+		fmt.Sprintf("%s #%d", anonymizedName, userID), fmt.Sprintf("removido-%d@anonimizado.invalid", userID), userID)
+	if err != nil {
+		return err
+	}
+
+	cleanup := []string{
+		`DELETE FROM sessions WHERE user_id = $1`,
+		`DELETE FROM api_tokens WHERE user_id = $1`,
+		`DELETE FROM user_tokens WHERE user_id = $1`,
+		`DELETE FROM phone_codes WHERE user_id = $1`,
+		`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM user_addresses WHERE user_id = $1`,
+		`DELETE FROM user_profile_changes WHERE user_id = $1`,
+		`UPDATE privacy_events SET ip = NULL WHERE user_id = $1`,
+		// Lembretes de manutenção dos poços do cliente deixam de ser gerados
+		`UPDATE maintenance_plans SET active = false, updated_at = CURRENT_TIMESTAMP
+		 WHERE well_id IN (SELECT id FROM wells WHERE user_id = $1)`,
+	}
+	for _, query := range cleanup {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+
+	// Códigos pedidos antes do cadastro do telefone ficam sem user_id
+	if normalizedPhone != "" {
+		if _, err := tx.Exec(`DELETE FROM phone_codes WHERE phone = $1`, normalizedPhone); err != nil {
+			return err
+		}
+	}
+
+	// Tentativas que falharam ficam sem user_id; a busca é pelo identificador
+	_, err = tx.Exec(`
+		UPDATE login_attempts SET identifier = 'anonimizado', ip = NULL, user_agent = NULL
+		WHERE user_id = $1 OR LOWER(identifier) = LOWER($2)
+		   OR ($3 <> '' AND identifier = $3) OR ($4 <> '' AND identifier = $4)`,
+		userID, email, phone, normalizedPhone)
+	if err != nil {
+		return err
+	}
+
+	// O registro de auditoria guarda retratos do cadastro, o IP das ações
+	// do próprio titular e o identificador dos desbloqueios de acesso
+	if err := anonymizeAuditData(tx, AuditEntityUser, `SELECT $1::int`, userID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE audit_log SET ip = NULL WHERE actor_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE audit_log SET after_data = jsonb_set(after_data, '{identificador}', '"anonimizado"')
+		WHERE entity_type = $1 AND (LOWER(after_data->>'identificador') = LOWER($2)
+		                            OR ($3 <> '' AND after_data->>'identificador' = $3)
+		                            OR ($4 <> '' AND after_data->>'identificador' = $4))`,
+		AuditEntityLogin, email, phone, normalizedPhone)
+	return err
+}
+
+// anonymizeRequests apaga nome, descrição e endereço das solicitações do
+// usuário, além das observações e respostas dadas por ele. Sem
+// includeSigned, as solicitações com contrato assinado ficam intactas
+// (guarda legal).
+func anonymizeRequests(tx *sql.Tx, userID int, includeSigned bool) error {
+	const scope = `
+		SELECT sr.id FROM service_requests sr
+		WHERE sr.user_id = $1
+		  AND ($2 OR NOT EXISTS (SELECT 1 FROM contracts c
+		                         WHERE c.service_request_id = sr.id AND (c.client_signed OR c.company_signed)))`
+
+	queries := []string{
+		`UPDATE service_requests
+		 SET full_name = '` + anonymizedName + `', description = NULL, cep = '00000-000',
+		     logradouro = '` + anonymizedField + `', numero = 's/n', bairro = '` + anonymizedField + `',
+		     latitude = NULL, longitude = NULL, custom_fields = '{}', updated_at = CURRENT_TIMESTAMP
+		 WHERE id IN (` + scope + `)`,
+		`UPDATE contracts SET client_signature = NULL, client_requirements = NULL
+		 WHERE service_request_id IN (` + scope + `)`,
+		`DELETE FROM contract_client_observations
+		 WHERE contract_id IN (SELECT id FROM contracts WHERE service_request_id IN (` + scope + `))`,
+		`UPDATE quotes SET client_response = NULL
+		 WHERE service_request_id IN (` + scope + `)`,
+		`UPDATE warranty_claims SET description = '` + anonymizedField + `'
+		 WHERE contract_id IN (SELECT id FROM contracts WHERE service_request_id IN (` + scope + `))`,
+		`UPDATE wells SET latitude = NULL, longitude = NULL, updated_at = CURRENT_TIMESTAMP
+		 WHERE user_id = $1 AND ($2 OR service_request_id IS NULL OR service_request_id IN (` + scope + `))`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, userID, includeSigned); err != nil {
+			return err
+		}
+	}
+
+	// Os retratos dos mesmos registros no registro de auditoria
+	audited := map[string]string{
+		AuditEntityRequest:       scope,
+		AuditEntityContract:      `SELECT id FROM contracts WHERE service_request_id IN (` + scope + `)`,
+		AuditEntityQuote:         `SELECT id FROM quotes WHERE service_request_id IN (` + scope + `)`,
+		AuditEntityWarrantyClaim: `SELECT id FROM warranty_claims WHERE contract_id IN (SELECT id FROM contracts WHERE service_request_id IN (` + scope + `))`,
+		AuditEntityWell:          `SELECT id FROM wells WHERE user_id = $1 AND ($2 OR service_request_id IS NULL OR service_request_id IN (` + scope + `))`,
+	}
+	for entityType, ids := range audited {
+		if err := anonymizeAuditData(tx, entityType, ids, userID, includeSigned); err != nil {
+			return err
+		}
+	}
+	return nil
+}
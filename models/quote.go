@@ -0,0 +1,627 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Situações do orçamento
+const (
+	QuoteStatusDraft     = "RASCUNHO"
+	QuoteStatusSent      = "ENVIADO"
+	QuoteStatusAccepted  = "ACEITO"
+	QuoteStatusRejected  = "RECUSADO"
+	QuoteStatusConverted = "CONVERTIDO"
+)
+
+// Tipos de linha do orçamento usados pelo sistema. Materiais do catálogo viram
+// itens do contrato na conversão.
+const (
+	QuoteItemDrilling = "PERFURACAO"
+	QuoteItemTravel   = "DESLOCAMENTO"
+	QuoteItemMaterial = "MATERIAL"
+	QuoteItemOther    = "OUTRO"
+)
+
+// QuoteItemType descreve um tipo de linha do orçamento e sua unidade padrão
+type QuoteItemType struct {
+	Code string
+	Name string
+	Unit string
+}
+
+// QuoteItemTypes são os tipos de linha oferecidos no formulário do orçamento
+var QuoteItemTypes = []QuoteItemType{
+	{QuoteItemDrilling, "Perfuração (por metro)", "m"},
+	{"REVESTIMENTO", "Revestimento (por metro)", "m"},
+	{"BOMBA", "Bomba", "un"},
+	{"INSTALACAO", "Instalação", "un"},
+	{QuoteItemTravel, "Deslocamento", "km"},
+	{QuoteItemMaterial, "Material do catálogo", "un"},
+	{QuoteItemOther, "Outro", "un"},
+}
+
+// GetQuoteItemType busca o tipo de linha pelo código
+func GetQuoteItemType(code string) (QuoteItemType, bool) {
+	for _, t := range QuoteItemTypes {
+		if t.Code == code {
+			return t, true
+		}
+	}
+	return QuoteItemType{}, false
+}
+
+var (
+	ErrQuoteNotEditable    = errors.New("orçamento já enviado não pode ser alterado")
+	ErrQuoteEmpty          = errors.New("orçamento sem itens")
+	ErrQuoteNotOpen        = errors.New("orçamento não está aguardando resposta")
+	ErrQuoteExpired        = errors.New("orçamento vencido")
+	ErrQuoteNotAccepted    = errors.New("apenas orçamentos aceitos podem virar contrato")
+	ErrQuoteContractExists = errors.New("a solicitação já possui contrato")
+)
+
+// Quote é o orçamento detalhado de um serviço, emitido após a vistoria
+type Quote struct {
+	ID               int            `json:"id"`
+	ServiceRequestID int            `json:"service_request_id"`
+	QuoteNumber      string         `json:"quote_number"`
+	Status           string         `json:"status"`
+	ValidUntil       time.Time      `json:"valid_until"`
+	DiscountValue    float64        `json:"discount_value"`
+	TaxPercent       float64        `json:"tax_percent"`
+	Notes            sql.NullString `json:"notes"`
+	ClientResponse   sql.NullString `json:"client_response"`
+	SentAt           sql.NullTime   `json:"sent_at"`
+	RespondedAt      sql.NullTime   `json:"responded_at"`
+	ContractID       sql.NullInt64  `json:"contract_id"`
+	CreatedBy        sql.NullInt64  `json:"created_by"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+
+	// Soma das linhas (calculada na consulta)
+	Subtotal float64 `json:"subtotal"`
+
+	// Campos relacionados expandidos
+	UserID          int         `json:"user_id,omitempty"`
+	ClientName      string      `json:"client_name,omitempty"`
+	ClientPhone     string      `json:"client_phone,omitempty"`
+	ServiceTypeName string      `json:"service_type_name,omitempty"`
+	ContractNumber  string      `json:"contract_number,omitempty"`
+	Items           []QuoteItem `json:"items,omitempty"`
+}
+
+// QuoteItem é uma linha do orçamento
+type QuoteItem struct {
+	ID          int           `json:"id"`
+	QuoteID     int           `json:"quote_id"`
+	ItemType    string        `json:"item_type"`
+	Description string        `json:"description"`
+	Quantity    float64       `json:"quantity"`
+	Unit        string        `json:"unit"`
+	UnitPrice   float64       `json:"unit_price"`
+	MaterialID  sql.NullInt64 `json:"material_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// Total retorna o valor da linha
+func (i *QuoteItem) Total() float64 {
+	return i.Quantity * i.UnitPrice
+}
+
+// TypeName retorna o nome do tipo de linha
+func (i *QuoteItem) TypeName() string {
+	if t, ok := GetQuoteItemType(i.ItemType); ok {
+		return t.Name
+	}
+	return i.ItemType
+}
+
+// roundCents arredonda para centavos
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// TaxableBase retorna o subtotal após o desconto (nunca negativo)
+func (q *Quote) TaxableBase() float64 {
+	return math.Max(q.Subtotal-q.DiscountValue, 0)
+}
+
+// TaxValue retorna o valor dos impostos sobre a base após desconto
+func (q *Quote) TaxValue() float64 {
+	return roundCents(q.TaxableBase() * q.TaxPercent / 100)
+}
+
+// Total retorna o valor final do orçamento
+func (q *Quote) Total() float64 {
+	return roundCents(q.TaxableBase() + q.TaxValue())
+}
+
+// IsExpired indica se o orçamento enviado passou da validade sem resposta
+func (q *Quote) IsExpired() bool {
+	return q.Status == QuoteStatusSent && truncateDay(time.Now()).After(truncateDay(q.ValidUntil))
+}
+
+// IsEditable indica se o orçamento ainda pode ser alterado (rascunho)
+func (q *Quote) IsEditable() bool {
+	return q.Status == QuoteStatusDraft
+}
+
+// CanRespond indica se o cliente ainda pode aceitar ou recusar
+func (q *Quote) CanRespond() bool {
+	return q.Status == QuoteStatusSent && !q.IsExpired()
+}
+
+// CanConvert indica se o orçamento aceito pode virar contrato
+func (q *Quote) CanConvert() bool {
+	return q.Status == QuoteStatusAccepted
+}
+
+// StatusLabel retorna o nome da situação para exibição
+func (q *Quote) StatusLabel() string {
+	switch {
+	case q.IsExpired():
+		return "Vencido"
+	case q.Status == QuoteStatusDraft:
+		return "Rascunho"
+	case q.Status == QuoteStatusSent:
+		return "Aguardando resposta"
+	case q.Status == QuoteStatusAccepted:
+		return "Aceito"
+	case q.Status == QuoteStatusRejected:
+		return "Recusado"
+	case q.Status == QuoteStatusConverted:
+		return "Convertido em contrato"
+	default:
+		return q.Status
+	}
+}
+
+// StatusBadge retorna a classe do badge Bootstrap da situação
+func (q *Quote) StatusBadge() string {
+	switch {
+	case q.IsExpired():
+		return "bg-secondary"
+	case q.Status == QuoteStatusSent:
+		return "bg-warning text-dark"
+	case q.Status == QuoteStatusAccepted:
+		return "bg-success"
+	case q.Status == QuoteStatusRejected:
+		return "bg-danger"
+	case q.Status == QuoteStatusConverted:
+		return "bg-primary"
+	default:
+		return "bg-light text-dark"
+	}
+}
+
+type QuoteModel struct {
+	DB *sql.DB
+}
+
+func NewQuoteModel(db *sql.DB) *QuoteModel {
+	return &QuoteModel{DB: db}
+}
+
+// Create cria um orçamento em rascunho para a solicitação, já com as linhas de Items (se houver)
+func (m *QuoteModel) Create(quote *Quote, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	year := time.Now().Year()
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM quotes WHERE EXTRACT(YEAR FROM created_at) = $1", year).Scan(&count); err != nil {
+		return err
+	}
+	quote.QuoteNumber = fmt.Sprintf("ORC-%d-%04d", year, count+1)
+	quote.Status = QuoteStatusDraft
+
+	err = tx.QueryRow(`
+		INSERT INTO quotes (service_request_id, quote_number, status, valid_until, discount_value, tax_percent, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at`,
+		quote.ServiceRequestID, quote.QuoteNumber, quote.Status, quote.ValidUntil,
+		quote.DiscountValue, quote.TaxPercent, quote.Notes, quote.CreatedBy,
+	).Scan(&quote.ID, &quote.CreatedAt, &quote.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	for i := range quote.Items {
+		item := &quote.Items[i]
+		item.QuoteID = quote.ID
+		err = tx.QueryRow(`
+			INSERT INTO quote_items (quote_id, item_type, description, quantity, unit, unit_price, material_id)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at`,
+			item.QuoteID, item.ItemType, item.Description, item.Quantity, item.Unit, item.UnitPrice, item.MaterialID,
+		).Scan(&item.ID, &item.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := recordAudit(tx, actor, AuditCreate, AuditEntityQuote, quote.ID, sql.NullString{}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateHeader atualiza validade, desconto, impostos e observações (apenas rascunho)
+func (m *QuoteModel) UpdateHeader(quote *Quote, actor Actor) error {
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityQuote, quote.ID, func(tx *sql.Tx) (int, error) {
+		result, err := tx.Exec(`
+			UPDATE quotes
+			SET valid_until = $1, discount_value = $2, tax_percent = $3, notes = $4, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $5 AND status = $6`,
+			quote.ValidUntil, quote.DiscountValue, quote.TaxPercent, quote.Notes, quote.ID, QuoteStatusDraft)
+		if err != nil {
+			return 0, err
+		}
+		if rows, _ := result.RowsAffected(); rows == 0 {
+			return 0, ErrQuoteNotEditable
+		}
+		return 0, nil
+	})
+}
+
+// lockDraftQuote trava o orçamento e garante que ainda é rascunho
+func lockDraftQuote(tx *sql.Tx, quoteID int) error {
+	var status string
+	if err := tx.QueryRow("SELECT status FROM quotes WHERE id = $1 FOR UPDATE", quoteID).Scan(&status); err != nil {
+		return err
+	}
+	if status != QuoteStatusDraft {
+		return ErrQuoteNotEditable
+	}
+	return nil
+}
+
+// AddItem inclui uma linha no orçamento (apenas rascunho)
+func (m *QuoteModel) AddItem(item *QuoteItem, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDraftQuote(tx, item.QuoteID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO quote_items (quote_id, item_type, description, quantity, unit, unit_price, material_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at`,
+		item.QuoteID, item.ItemType, item.Description, item.Quantity, item.Unit, item.UnitPrice, item.MaterialID,
+	).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE quotes SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", item.QuoteID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actor, AuditCreate, AuditEntityQuoteItem, item.ID, sql.NullString{}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteItem remove uma linha do orçamento (apenas rascunho)
+func (m *QuoteModel) DeleteItem(quoteID, itemID int, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDraftQuote(tx, quoteID); err != nil {
+		return err
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityQuoteItem, itemID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("DELETE FROM quote_items WHERE id = $1 AND quote_id = $2", itemID, quoteID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec("UPDATE quotes SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", quoteID); err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actor, AuditDelete, AuditEntityQuoteItem, itemID, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Send envia o orçamento ao cliente. Precisa ter ao menos uma linha.
+func (m *QuoteModel) Send(quoteID int, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockDraftQuote(tx, quoteID); err != nil {
+		return err
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityQuote, quoteID)
+	if err != nil {
+		return err
+	}
+
+	var items int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM quote_items WHERE quote_id = $1", quoteID).Scan(&items); err != nil {
+		return err
+	}
+	if items == 0 {
+		return ErrQuoteEmpty
+	}
+
+	_, err = tx.Exec(`
+		UPDATE quotes SET status = $1, sent_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2`, QuoteStatusSent, quoteID)
+	if err != nil {
+		return err
+	}
+	if err := recordAudit(tx, actor, AuditSend, AuditEntityQuote, quoteID, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Respond registra o aceite ou a recusa do cliente dono da solicitação
+func (m *QuoteModel) Respond(quoteID, userID int, accept bool, response string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var validUntil time.Time
+	err = tx.QueryRow(`
+		SELECT q.status, q.valid_until
+		FROM quotes q
+		JOIN service_requests sr ON q.service_request_id = sr.id
+		WHERE q.id = $1 AND sr.user_id = $2 AND sr.deleted_at IS NULL
+		FOR UPDATE OF q`, quoteID, userID).Scan(&status, &validUntil)
+	if err != nil {
+		return err
+	}
+	if status != QuoteStatusSent {
+		return ErrQuoteNotOpen
+	}
+	if truncateDay(time.Now()).After(truncateDay(validUntil)) {
+		return ErrQuoteExpired
+	}
+
+	newStatus := QuoteStatusRejected
+	if accept {
+		newStatus = QuoteStatusAccepted
+	}
+
+	_, err = tx.Exec(`
+		UPDATE quotes
+		SET status = $1, client_response = $2, responded_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3`, newStatus, nullableString(response), quoteID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ConvertToContract cria um contrato em RASCUNHO a partir do orçamento aceito. O valor
+// total vem do orçamento e as linhas de material do catálogo viram itens do contrato.
+func (m *QuoteModel) ConvertToContract(quoteID int, actor Actor) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Solicitação na lixeira conta como inexistente; o bloqueio da linha da
+	// solicitação impede que ela vá para a lixeira durante a conversão
+	quote := &Quote{}
+	err = tx.QueryRow(`
+		SELECT q.id, q.service_request_id, q.quote_number, q.status, q.discount_value, q.tax_percent, q.notes
+		FROM quotes q
+		JOIN service_requests sr ON q.service_request_id = sr.id
+		WHERE q.id = $1 AND sr.deleted_at IS NULL
+		FOR UPDATE OF q, sr`, quoteID).Scan(
+		&quote.ID, &quote.ServiceRequestID, &quote.QuoteNumber, &quote.Status,
+		&quote.DiscountValue, &quote.TaxPercent, &quote.Notes,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if quote.Status != QuoteStatusAccepted {
+		return 0, ErrQuoteNotAccepted
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityQuote, quoteID)
+	if err != nil {
+		return 0, err
+	}
+
+	var hasContract bool
+	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM contracts WHERE service_request_id = $1)", quote.ServiceRequestID).Scan(&hasContract)
+	if err != nil {
+		return 0, err
+	}
+	if hasContract {
+		return 0, ErrQuoteContractExists
+	}
+
+	err = tx.QueryRow("SELECT COALESCE(SUM(quantity * unit_price), 0) FROM quote_items WHERE quote_id = $1", quoteID).
+		Scan(&quote.Subtotal)
+	if err != nil {
+		return 0, err
+	}
+
+	// Garantia padrão: a primeira do catálogo; o admin ajusta na edição do rascunho
+	var contractID int
+	err = tx.QueryRow(`
+		INSERT INTO contracts (
+			service_request_id, contract_number, total_value, payment_conditions,
+			guarantee_type_id, additional_notes, status_id
+		)
+		SELECT $1, $2, $3, $4,
+			(SELECT id FROM guarantee_types WHERE active = true ORDER BY display_order, id LIMIT 1),
+			$5,
+			(SELECT id FROM contract_status WHERE code = 'RASCUNHO')
+		RETURNING id`,
+		quote.ServiceRequestID, nextContractNumber(tx), quote.Total(),
+		fmt.Sprintf("Conforme orçamento %s aceito pelo cliente.", quote.QuoteNumber),
+		quote.Notes,
+	).Scan(&contractID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Linhas de material somadas por item do catálogo; custo atual do catálogo
+	_, err = tx.Exec(`
+		INSERT INTO contract_items (contract_id, material_id, quantity, unit_price, unit_cost)
+		SELECT $1, qi.material_id, SUM(qi.quantity),
+		       ROUND(SUM(qi.quantity * qi.unit_price) / SUM(qi.quantity), 2), mt.unit_cost
+		FROM quote_items qi
+		JOIN materials mt ON qi.material_id = mt.id
+		WHERE qi.quote_id = $2 AND qi.material_id IS NOT NULL
+		GROUP BY qi.material_id, mt.unit_cost`, contractID, quoteID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE quotes SET status = $1, contract_id = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`,
+		QuoteStatusConverted, contractID, quoteID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO contract_history (contract_id, action, changed_by, changed_fields) VALUES ($1, $2, $3, $4)`,
+		contractID, "CRIADO", actor.UserID, "Contrato criado a partir do orçamento "+quote.QuoteNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := recordAudit(tx, actor, AuditConvert, AuditEntityQuote, quoteID, before); err != nil {
+		return 0, err
+	}
+	if err := recordAudit(tx, actor, AuditCreate, AuditEntityContract, contractID, sql.NullString{}); err != nil {
+		return 0, err
+	}
+
+	return contractID, tx.Commit()
+}
+
+const quoteSelectColumns = `
+	SELECT q.id, q.service_request_id, q.quote_number, q.status, q.valid_until, q.discount_value,
+	       q.tax_percent, q.notes, q.client_response, q.sent_at, q.responded_at, q.contract_id,
+	       q.created_by, q.created_at, q.updated_at,
+	       COALESCE((SELECT SUM(qi.quantity * qi.unit_price) FROM quote_items qi WHERE qi.quote_id = q.id), 0),
+	       sr.user_id, u.name, COALESCE(u.phone, ''), st.name, COALESCE(c.contract_number, '')
+	FROM quotes q
+	JOIN service_requests sr ON q.service_request_id = sr.id
+	JOIN users u ON sr.user_id = u.id
+	JOIN service_types st ON sr.service_type_id = st.id
+	LEFT JOIN contracts c ON q.contract_id = c.id`
+
+func scanQuote(scanner interface{ Scan(...interface{}) error }, quote *Quote) error {
+	return scanner.Scan(
+		&quote.ID, &quote.ServiceRequestID, &quote.QuoteNumber, &quote.Status, &quote.ValidUntil, &quote.DiscountValue,
+		&quote.TaxPercent, &quote.Notes, &quote.ClientResponse, &quote.SentAt, &quote.RespondedAt, &quote.ContractID,
+		&quote.CreatedBy, &quote.CreatedAt, &quote.UpdatedAt,
+		&quote.Subtotal,
+		&quote.UserID, &quote.ClientName, &quote.ClientPhone, &quote.ServiceTypeName, &quote.ContractNumber,
+	)
+}
+
+// GetByID busca o orçamento com suas linhas
+func (m *QuoteModel) GetByID(id int) (*Quote, error) {
+	quote := &Quote{}
+	if err := scanQuote(m.DB.QueryRow(quoteSelectColumns+" WHERE q.id = $1", id), quote); err != nil {
+		return nil, err
+	}
+	return quote, m.loadItems(quote)
+}
+
+// GetByIDAndUser busca um orçamento já enviado ao cliente (rascunhos não aparecem)
+func (m *QuoteModel) GetByIDAndUser(id, userID int) (*Quote, error) {
+	quote := &Quote{}
+	query := quoteSelectColumns + " WHERE q.id = $1 AND sr.user_id = $2 AND q.status <> $3 AND sr.deleted_at IS NULL"
+	if err := scanQuote(m.DB.QueryRow(query, id, userID, QuoteStatusDraft), quote); err != nil {
+		return nil, err
+	}
+	return quote, m.loadItems(quote)
+}
+
+// GetByServiceRequestID lista os orçamentos de uma solicitação
+func (m *QuoteModel) GetByServiceRequestID(serviceRequestID int) ([]Quote, error) {
+	return m.query(quoteSelectColumns+" WHERE q.service_request_id = $1 ORDER BY q.created_at DESC", serviceRequestID)
+}
+
+// GetByUserID lista os orçamentos enviados ao cliente
+func (m *QuoteModel) GetByUserID(userID int) ([]Quote, error) {
+	return m.query(quoteSelectColumns+" WHERE sr.user_id = $1 AND q.status <> $2 AND sr.deleted_at IS NULL ORDER BY q.created_at DESC",
+		userID, QuoteStatusDraft)
+}
+
+// GetAll lista os orçamentos, opcionalmente filtrados pela situação
+func (m *QuoteModel) GetAll(status string) ([]Quote, error) {
+	return m.query(quoteSelectColumns+" WHERE sr.deleted_at IS NULL AND ($1 = '' OR q.status = $1) ORDER BY q.created_at DESC", status)
+}
+
+func (m *QuoteModel) query(query string, args ...interface{}) ([]Quote, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []Quote
+	for rows.Next() {
+		var quote Quote
+		if err := scanQuote(rows, &quote); err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes, rows.Err()
+}
+
+func (m *QuoteModel) loadItems(quote *Quote) error {
+	rows, err := m.DB.Query(`
+		SELECT id, quote_id, item_type, description, quantity, unit, unit_price, material_id, created_at
+		FROM quote_items WHERE quote_id = $1 ORDER BY id`, quote.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	quote.Items = nil
+	for rows.Next() {
+		var item QuoteItem
+		err := rows.Scan(&item.ID, &item.QuoteID, &item.ItemType, &item.Description, &item.Quantity,
+			&item.Unit, &item.UnitPrice, &item.MaterialID, &item.CreatedAt)
+		if err != nil {
+			return err
+		}
+		quote.Items = append(quote.Items, item)
+	}
+	return rows.Err()
+}
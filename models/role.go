@@ -0,0 +1,354 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Códigos de permissão verificados pelas rotas (tabela permissions)
+const (
+	PermRequestsView         = "requests.view"
+	PermRequestsManage       = "requests.manage"
+	PermRequestsDelete       = "requests.delete"
+	PermRoutesManage         = "routes.manage"
+	PermContractsView        = "contracts.view"
+	PermContractsManage      = "contracts.manage"
+	PermContractsSignCompany = "contracts.sign_company"
+	PermQuotesManage         = "quotes.manage"
+	PermPricingManage        = "pricing.manage"
+	PermWellsManage          = "wells.manage"
+	PermAnalysesManage       = "analyses.manage"
+	PermMaintenanceManage    = "maintenance.manage"
+	PermMaterialsManage      = "materials.manage"
+	PermWarrantyManage       = "warranty.manage"
+	PermUsersManageRoles     = "users.manage_roles"
+	PermUsersSecurity        = "users.security"
+	PermPrivacyManage        = "privacy.manage"
+	PermAuditView            = "audit.view"
+	PermCatalogsManage       = "catalogs.manage"
+	PermClientPortal         = "client.portal"
+)
+
+// Papéis padrão (tabela roles)
+const (
+	RoleOwner   = "PROPRIETARIO"
+	RoleOffice  = "ESCRITORIO"
+	RoleTech    = "TECNICO"
+	RoleFinance = "FINANCEIRO"
+	RoleClient  = "CLIENTE"
+)
+
+var (
+	ErrRoleNotFound   = errors.New("papel não encontrado")
+	ErrLastOwner      = errors.New("a empresa precisa manter ao menos um proprietário")
+	ErrOwnerLockedOut = errors.New("o papel de proprietário não pode perder a atribuição de papéis")
+)
+
+// Permission é uma ação nomeada que pode ser concedida a papéis
+type Permission struct {
+	ID           int    `json:"id"`
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+	DisplayOrder int    `json:"display_order"`
+}
+
+// Role agrupa permissões. UserType define se quem tem o papel usa o
+// painel da empresa (gestor) ou o portal do cliente (cliente).
+type Role struct {
+	ID           int            `json:"id"`
+	Code         string         `json:"code"`
+	Name         string         `json:"name"`
+	Description  sql.NullString `json:"description"`
+	UserType     string         `json:"user_type"`
+	DisplayOrder int            `json:"display_order"`
+	CreatedAt    time.Time      `json:"created_at"`
+
+	Permissions []string `json:"permissions"`
+	UserCount   int      `json:"user_count"`
+}
+
+// HasPermission indica se o papel concede a permissão
+func (r *Role) HasPermission(code string) bool {
+	for _, p := range r.Permissions {
+		if p == code {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleUser é um usuário com seu papel, para a tela de atribuição
+type RoleUser struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	UserType  string    `json:"user_type"`
+	RoleID    int       `json:"role_id"`
+	RoleCode  string    `json:"role_code"`
+	RoleName  string    `json:"role_name"`
+	CreatedAt time.Time `json:"created_at"`
+
+	ActiveSessions int `json:"active_sessions"`
+}
+
+type RoleModel struct {
+	DB *sql.DB
+}
+
+func NewRoleModel(db *sql.DB) *RoleModel {
+	return &RoleModel{DB: db}
+}
+
+// GetAllRoles retorna os papéis com suas permissões e o número de usuários
+func (m *RoleModel) GetAllRoles() ([]Role, error) {
+	query := `
+		SELECT r.id, r.code, r.name, r.description, r.user_type, r.display_order, r.created_at,
+		       COALESCE((SELECT string_agg(p.code, ' ' ORDER BY p.display_order)
+		                 FROM role_permissions rp JOIN permissions p ON rp.permission_id = p.id
+		                 WHERE rp.role_id = r.id), ''),
+		       (SELECT COUNT(*) FROM users u WHERE u.role_id = r.id)
+		FROM roles r
+		ORDER BY r.display_order, r.name`
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []Role
+	for rows.Next() {
+		var role Role
+		var permissions string
+		err := rows.Scan(&role.ID, &role.Code, &role.Name, &role.Description, &role.UserType,
+			&role.DisplayOrder, &role.CreatedAt, &permissions, &role.UserCount)
+		if err != nil {
+			return nil, err
+		}
+		role.Permissions = strings.Fields(permissions)
+		roles = append(roles, role)
+	}
+
+	return roles, nil
+}
+
+// GetAllPermissions retorna o catálogo de permissões
+func (m *RoleModel) GetAllPermissions() ([]Permission, error) {
+	rows, err := m.DB.Query(`SELECT id, code, name, display_order FROM permissions ORDER BY display_order, code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []Permission
+	for rows.Next() {
+		var p Permission
+		if err := rows.Scan(&p.ID, &p.Code, &p.Name, &p.DisplayOrder); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, nil
+}
+
+// GetUsers lista os usuários com seus papéis, com busca por nome/e-mail e
+// filtro opcional pelo código do papel
+func (m *RoleModel) GetUsers(search, roleCode string) ([]RoleUser, error) {
+	query := `
+		SELECT u.id, u.name, u.email, ut.type_name, COALESCE(r.id, 0), COALESCE(r.code, ''),
+		       COALESCE(r.name, ''), u.created_at,
+		       (SELECT COUNT(*) FROM sessions s WHERE s.user_id = u.id AND s.expires_at > CURRENT_TIMESTAMP)
+		FROM users u
+		INNER JOIN user_types ut ON u.user_type_id = ut.id
+		LEFT JOIN roles r ON u.role_id = r.id
+		WHERE ($1 = '' OR u.name ILIKE '%' || $1 || '%' OR u.email ILIKE '%' || $1 || '%')
+		  AND ($2 = '' OR r.code = $2)
+		ORDER BY COALESCE(r.display_order, 999), u.name`
+
+	rows, err := m.DB.Query(query, strings.TrimSpace(search), roleCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []RoleUser
+	for rows.Next() {
+		var u RoleUser
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.UserType, &u.RoleID, &u.RoleCode,
+			&u.RoleName, &u.CreatedAt, &u.ActiveSessions)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, nil
+}
+
+// AssignRole troca o papel do usuário. O tipo do usuário acompanha o papel,
+// e o último proprietário não pode ser rebaixado.
+func (m *RoleModel) AssignRole(userID int, roleCode string, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var roleID int
+	var userType string
+	err = tx.QueryRow(`SELECT id, user_type FROM roles WHERE code = $1`, roleCode).Scan(&roleID, &userType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// Trava os proprietários antes de contar: duas trocas simultâneas não
+	// podem rebaixar, cada uma, um dos dois últimos proprietários
+	var otherOwners int
+	if roleCode != RoleOwner {
+		rows, err := tx.Query(`
+			SELECT u.id FROM users u JOIN roles r ON u.role_id = r.id
+			WHERE r.code = $1 ORDER BY u.id FOR UPDATE OF u`, RoleOwner)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var ownerID int
+			if err := rows.Scan(&ownerID); err != nil {
+				rows.Close()
+				return err
+			}
+			if ownerID != userID {
+				otherOwners++
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	var currentCode string
+	err = tx.QueryRow(`
+		SELECT COALESCE(r.code, '') FROM users u LEFT JOIN roles r ON u.role_id = r.id
+		WHERE u.id = $1 FOR UPDATE OF u`, userID).Scan(&currentCode)
+	if err != nil {
+		return err
+	}
+
+	if currentCode == RoleOwner && roleCode != RoleOwner && otherOwners == 0 {
+		return ErrLastOwner
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET role_id = $1,
+		       user_type_id = (SELECT id FROM user_types WHERE type_name = $2)
+		WHERE id = $3`, roleID, userType, userID)
+	if err != nil {
+		return err
+	}
+
+	err = insertAudit(tx, actor, AuditAssignRole, AuditEntityUser, userID,
+		auditJSON(map[string]string{"papel": currentCode}), auditJSON(map[string]string{"papel": roleCode}))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetRolePermissions substitui as permissões do papel. Códigos
+// desconhecidos são ignorados.
+func (m *RoleModel) SetRolePermissions(roleID int, permissions []string, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var code string
+	err = tx.QueryRow(`SELECT code FROM roles WHERE id = $1`, roleID).Scan(&code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if code == RoleOwner {
+		keeps := false
+		for _, p := range permissions {
+			if p == PermUsersManageRoles {
+				keeps = true
+				break
+			}
+		}
+		if !keeps {
+			return ErrOwnerLockedOut
+		}
+	}
+
+	var previous string
+	err = tx.QueryRow(`
+		SELECT COALESCE(string_agg(p.code, ' ' ORDER BY p.code), '')
+		FROM role_permissions rp JOIN permissions p ON rp.permission_id = p.id
+		WHERE rp.role_id = $1`, roleID).Scan(&previous)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role_id = $1`, roleID); err != nil {
+		return err
+	}
+
+	for _, p := range permissions {
+		_, err := tx.Exec(`
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT $1, id FROM permissions WHERE code = $2
+			ON CONFLICT DO NOTHING`, roleID, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	var current string
+	err = tx.QueryRow(`
+		SELECT COALESCE(string_agg(p.code, ' ' ORDER BY p.code), '')
+		FROM role_permissions rp JOIN permissions p ON rp.permission_id = p.id
+		WHERE rp.role_id = $1`, roleID).Scan(&current)
+	if err != nil {
+		return err
+	}
+
+	err = insertAudit(tx, actor, AuditPermissions, AuditEntityRole, roleID,
+		auditJSON(map[string]string{"papel": code, "permissoes": previous}),
+		auditJSON(map[string]string{"papel": code, "permissoes": current}))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PermissionsForUser retorna o papel do usuário e as permissões que ele concede
+func (m *RoleModel) PermissionsForUser(userID int) (string, []string, error) {
+	var roleCode, permissions string
+	err := m.DB.QueryRow(`
+		SELECT COALESCE(r.code, ''),
+		       COALESCE((SELECT string_agg(p.code, ' ')
+		                 FROM role_permissions rp JOIN permissions p ON rp.permission_id = p.id
+		                 WHERE rp.role_id = r.id), '')
+		FROM users u
+		LEFT JOIN roles r ON u.role_id = r.id
+		WHERE u.id = $1`, userID).Scan(&roleCode, &permissions)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return roleCode, strings.Fields(permissions), nil
+}
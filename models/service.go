@@ -54,6 +54,24 @@ type ServiceRequest struct {
 	UpdatedAt       time.Time      `json:"updated_at"`
 	UserName        string         `json:"user_name,omitempty"`
 	UserEmail       string         `json:"user_email,omitempty"`
+
+	// Coordenadas do local e técnico responsável pela visita
+	Latitude        sql.NullFloat64 `json:"latitude"`
+	Longitude       sql.NullFloat64 `json:"longitude"`
+	TechnicianID    sql.NullInt64   `json:"technician_id"`
+	TechnicianName  string          `json:"technician_name,omitempty"`
+
+	// Lixeira: preenchidos quando a solicitação foi excluída
+	DeletedAt       sql.NullTime    `json:"deleted_at"`
+	DeletedByName   string          `json:"deleted_by_name,omitempty"`
+
+	// Respostas aos campos adicionais do tipo de serviço (ver ServiceField)
+	CustomFields    CustomFieldValues `json:"custom_fields,omitempty"`
+}
+
+// HasCoordinates indica se o local da solicitação possui coordenadas
+func (s *ServiceRequest) HasCoordinates() bool {
+	return s.Latitude.Valid && s.Longitude.Valid
 }
 
 type ServiceModel struct {
@@ -68,7 +86,7 @@ func NewServiceModel(db *sql.DB) *ServiceModel {
 
 func (m *ServiceModel) GetAllServiceTypes() ([]ServiceType, error) {
 	query := `SELECT id, code, name, description, icon, active, created_at 
-	          FROM service_types WHERE active = true ORDER BY name`
+	          FROM service_types WHERE active = true ORDER BY display_order NULLS LAST, name`
 	
 	rows, err := m.DB.Query(query)
 	if err != nil {
@@ -142,9 +160,9 @@ func (m *ServiceModel) Create(service *ServiceRequest) error {
 	query := `
 		INSERT INTO service_requests (
 			user_id, full_name, service_type_id, description, cep, logradouro, 
-			numero, bairro, cidade, estado, preferred_date, preferred_time, status_id
+			numero, bairro, cidade, estado, preferred_date, preferred_time, status_id, custom_fields
 		) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id, status_id, created_at, updated_at`
 
 	return m.DB.QueryRow(
@@ -152,7 +170,7 @@ func (m *ServiceModel) Create(service *ServiceRequest) error {
 		service.UserID, service.FullName, service.ServiceTypeID, service.Description,
 		service.CEP, service.Logradouro, service.Numero, service.Bairro,
 		service.Cidade, service.Estado, service.PreferredDate, service.PreferredTime, 
-		constants.StatusSolicitada,
+		constants.StatusSolicitada, service.CustomFields,
 	).Scan(&service.ID, &service.StatusID, &service.CreatedAt, &service.UpdatedAt)
 }
 
@@ -161,15 +179,15 @@ func (m *ServiceModel) Update(service *ServiceRequest) error {
 		UPDATE service_requests 
 		SET full_name = $1, service_type_id = $2, description = $3, cep = $4, 
 		    logradouro = $5, numero = $6, bairro = $7, cidade = $8, estado = $9, 
-		    preferred_date = $10, preferred_time = $11, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $12 AND user_id = $13 AND status_id = $14`
+		    preferred_date = $10, preferred_time = $11, custom_fields = $15, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $12 AND user_id = $13 AND status_id = $14 AND deleted_at IS NULL`
 
 	result, err := m.DB.Exec(
 		query, 
 		service.FullName, service.ServiceTypeID, service.Description, service.CEP,
 		service.Logradouro, service.Numero, service.Bairro, service.Cidade,
 		service.Estado, service.PreferredDate, service.PreferredTime,
-		service.ID, service.UserID, constants.StatusSolicitada,
+		service.ID, service.UserID, constants.StatusSolicitada, service.CustomFields,
 	)
 
 	if err != nil {
@@ -192,7 +210,7 @@ func (m *ServiceModel) Cancel(id, userID int) error {
 	query := `
 		UPDATE service_requests 
 		SET status_id = $1, updated_at = CURRENT_TIMESTAMP 
-		WHERE id = $2 AND user_id = $3 AND status_id = $4`
+		WHERE id = $2 AND user_id = $3 AND status_id = $4 AND deleted_at IS NULL`
 
 	result, err := m.DB.Exec(query, constants.StatusCancelada, id, userID, constants.StatusSolicitada)
 	if err != nil {
@@ -211,12 +229,14 @@ func (m *ServiceModel) Cancel(id, userID int) error {
 	return nil
 }
 
-func (m *ServiceModel) UpdateStatusByID(requestID, statusID int) error {
+func (m *ServiceModel) UpdateStatusByID(requestID, statusID int, actor Actor) error {
 	query := `UPDATE service_requests 
 	          SET status_id = $1, updated_at = CURRENT_TIMESTAMP 
-	          WHERE id = $2`
-	_, err := m.DB.Exec(query, statusID, requestID)
-	return err
+	          WHERE id = $2 AND deleted_at IS NULL`
+	return withAudit(m.DB, actor, AuditStatus, AuditEntityRequest, requestID, func(tx *sql.Tx) (int, error) {
+		_, err := tx.Exec(query, statusID, requestID)
+		return 0, err
+	})
 }
 
 // ==================== Query Methods ====================
@@ -231,7 +251,7 @@ func (m *ServiceModel) GetByUserID(userID int) ([]ServiceRequest, error) {
 		FROM service_requests sr
 		JOIN service_types st ON sr.service_type_id = st.id
 		JOIN request_status rs ON sr.status_id = rs.id
-		WHERE sr.user_id = $1 
+		WHERE sr.user_id = $1 AND sr.deleted_at IS NULL
 		ORDER BY sr.created_at DESC`
 
 	rows, err := m.DB.Query(query, userID)
@@ -277,6 +297,7 @@ func (m *ServiceModel) GetAll() ([]ServiceRequest, error) {
 		JOIN service_types st ON sr.service_type_id = st.id
 		JOIN request_status rs ON sr.status_id = rs.id
 		JOIN users u ON sr.user_id = u.id
+		WHERE sr.deleted_at IS NULL
 		ORDER BY sr.created_at DESC`
 
 	rows, err := m.DB.Query(query)
@@ -324,12 +345,14 @@ func (m *ServiceModel) GetByID(id int) (*ServiceRequest, error) {
 		SELECT sr.id, sr.user_id, sr.full_name, sr.service_type_id, st.code, st.name, st.icon,
 		       sr.description, sr.cep, sr.logradouro, sr.numero, sr.bairro, sr.cidade, sr.estado,
 		       sr.preferred_date, sr.preferred_time, sr.status_id, rs.code, rs.name, rs.color_class,
-		       sr.created_at, sr.updated_at, u.name, u.email
+		       sr.created_at, sr.updated_at, u.name, u.email,
+		       sr.latitude, sr.longitude, sr.technician_id, COALESCE(t.name, ''), sr.custom_fields
 		FROM service_requests sr
 		JOIN service_types st ON sr.service_type_id = st.id
 		JOIN request_status rs ON sr.status_id = rs.id
 		JOIN users u ON sr.user_id = u.id
-		WHERE sr.id = $1`
+		LEFT JOIN users t ON sr.technician_id = t.id
+		WHERE sr.id = $1 AND sr.deleted_at IS NULL`
 	
 	err := m.DB.QueryRow(query, id).Scan(
 		&service.ID, &service.UserID, &service.FullName, &service.ServiceTypeID,
@@ -338,7 +361,9 @@ func (m *ServiceModel) GetByID(id int) (*ServiceRequest, error) {
 		&service.Bairro, &service.Cidade, &service.Estado, &service.PreferredDate,
 		&preferredTime, &service.StatusID, &service.StatusCode, &service.StatusName,
 		&service.StatusColor, &service.CreatedAt, &service.UpdatedAt,
-		&service.UserName, &service.UserEmail)
+		&service.UserName, &service.UserEmail,
+		&service.Latitude, &service.Longitude, &service.TechnicianID, &service.TechnicianName,
+		&service.CustomFields)
 	
 	if err != nil {
 		return nil, err
@@ -359,11 +384,11 @@ func (m *ServiceModel) GetByIDAndUser(id, userID int) (*ServiceRequest, error) {
 		SELECT sr.id, sr.user_id, sr.full_name, sr.service_type_id, st.code, st.name, st.icon,
 		       sr.description, sr.cep, sr.logradouro, sr.numero, sr.bairro, sr.cidade, sr.estado,
 		       sr.preferred_date, sr.preferred_time, sr.status_id, rs.code, rs.name, rs.color_class,
-		       sr.created_at, sr.updated_at
+		       sr.created_at, sr.updated_at, sr.custom_fields
 		FROM service_requests sr
 		JOIN service_types st ON sr.service_type_id = st.id
 		JOIN request_status rs ON sr.status_id = rs.id
-		WHERE sr.id = $1 AND sr.user_id = $2`
+		WHERE sr.id = $1 AND sr.user_id = $2 AND sr.deleted_at IS NULL`
 	
 	err := m.DB.QueryRow(query, id, userID).Scan(
 		&service.ID, &service.UserID, &service.FullName, &service.ServiceTypeID,
@@ -371,7 +396,7 @@ func (m *ServiceModel) GetByIDAndUser(id, userID int) (*ServiceRequest, error) {
 		&service.Description, &service.CEP, &service.Logradouro, &service.Numero,
 		&service.Bairro, &service.Cidade, &service.Estado, &service.PreferredDate,
 		&preferredTime, &service.StatusID, &service.StatusCode, &service.StatusName,
-		&service.StatusColor, &service.CreatedAt, &service.UpdatedAt)
+		&service.StatusColor, &service.CreatedAt, &service.UpdatedAt, &service.CustomFields)
 	
 	if err != nil {
 		return nil, err
@@ -393,7 +418,7 @@ func (m *ServiceModel) GetByUserIDWithFilters(userID int, statusFilter, serviceT
 		FROM service_requests sr
 		JOIN service_types st ON sr.service_type_id = st.id
 		JOIN request_status rs ON sr.status_id = rs.id
-		WHERE sr.user_id = $1`
+		WHERE sr.user_id = $1 AND sr.deleted_at IS NULL`
 	
 	// Construir condições de filtro
 	args := []interface{}{userID}
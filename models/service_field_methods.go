@@ -0,0 +1,461 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tipos de campo adicional do formulário de solicitação
+const (
+	FieldTypeText     = "text"
+	FieldTypeNumber   = "number"
+	FieldTypeSelect   = "select"
+	FieldTypeCheckbox = "checkbox"
+)
+
+// ServiceFieldType é um tipo de campo oferecido no cadastro
+type ServiceFieldType struct {
+	Code  string
+	Label string
+}
+
+// ServiceFieldTypes lista os tipos de campo na ordem do formulário de cadastro
+var ServiceFieldTypes = []ServiceFieldType{
+	{FieldTypeText, "Texto"},
+	{FieldTypeNumber, "Número"},
+	{FieldTypeSelect, "Lista de opções"},
+	{FieldTypeCheckbox, "Caixa de seleção"},
+}
+
+// Tamanho máximo das respostas de texto
+const maxCustomFieldText = 500
+
+var (
+	ErrFieldKeyTaken     = errors.New("já existe um campo com esta chave neste tipo de serviço")
+	ErrFieldInvalidKey   = errors.New("chave inválida: use letras minúsculas, números e _ (até 50 caracteres)")
+	ErrFieldInvalidType  = errors.New("tipo de campo inválido")
+	ErrFieldNoOptions    = errors.New("informe ao menos uma opção, uma por linha")
+	ErrFieldInvalidOrder = errors.New("a nova ordem não corresponde aos campos do tipo de serviço")
+)
+
+var fieldKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,50}$`)
+
+// ServiceField é um campo adicional do formulário de solicitação, próprio
+// de um tipo de serviço (ex.: tipo de terreno na perfuração)
+type ServiceField struct {
+	ID            int
+	ServiceTypeID int
+	Key           string
+	Label         string
+	FieldType     string
+	Options       []string
+	HelpText      string
+	Required      bool
+	DisplayOrder  int
+	Active        bool
+}
+
+// TypeLabel retorna o nome do tipo de campo
+func (f *ServiceField) TypeLabel() string {
+	for _, t := range ServiceFieldTypes {
+		if t.Code == f.FieldType {
+			return t.Label
+		}
+	}
+	return f.FieldType
+}
+
+// OptionsText retorna as opções uma por linha, para o formulário de cadastro
+func (f *ServiceField) OptionsText() string {
+	return strings.Join(f.Options, "\n")
+}
+
+// SetOptionsText lê as opções digitadas uma por linha, sem vazias ou repetidas
+func (f *ServiceField) SetOptionsText(text string) {
+	f.Options = nil
+	seen := map[string]bool{}
+	for _, line := range strings.Split(text, "\n") {
+		option := strings.TrimSpace(line)
+		if option != "" && !seen[option] {
+			seen[option] = true
+			f.Options = append(f.Options, option)
+		}
+	}
+}
+
+// Normalize limpa e valida a definição do campo
+func (f *ServiceField) Normalize() error {
+	f.Key = strings.TrimSpace(f.Key)
+	f.Label = strings.TrimSpace(f.Label)
+	f.HelpText = strings.TrimSpace(f.HelpText)
+	if !fieldKeyPattern.MatchString(f.Key) {
+		return ErrFieldInvalidKey
+	}
+	if f.TypeLabel() == f.FieldType {
+		return ErrFieldInvalidType
+	}
+	if f.FieldType != FieldTypeSelect {
+		f.Options = nil
+	} else if len(f.Options) == 0 {
+		return ErrFieldNoOptions
+	}
+	return nil
+}
+
+// FormValue retorna a resposta já gravada no formato do campo do formulário
+func (f *ServiceField) FormValue(values CustomFieldValues) string {
+	switch v := values[f.Key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// IsChecked indica se a caixa de seleção foi marcada na resposta gravada
+func (f *ServiceField) IsChecked(values CustomFieldValues) bool {
+	checked, _ := values[f.Key].(bool)
+	return checked
+}
+
+// CustomFieldValues são as respostas aos campos adicionais, pela chave do
+// campo. Gravadas como JSONB em service_requests.custom_fields.
+type CustomFieldValues map[string]interface{}
+
+// Value grava as respostas como JSON
+func (v CustomFieldValues) Value() (driver.Value, error) {
+	if v == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan lê as respostas do JSONB
+func (v *CustomFieldValues) Scan(src interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case nil:
+		*v = CustomFieldValues{}
+		return nil
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	default:
+		return fmt.Errorf("custom_fields: tipo inesperado %T", src)
+	}
+	values := CustomFieldValues{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*v = values
+	return nil
+}
+
+// CustomFieldAnswer é uma resposta pronta para exibição
+type CustomFieldAnswer struct {
+	Label string
+	Value string
+}
+
+// ParseCustomFields valida as respostas brutas (do formulário ou da API) com
+// os campos ativos do tipo de serviço e converte cada uma para o seu tipo.
+// Chaves que não pertencem ao tipo são descartadas.
+func ParseCustomFields(fields []ServiceField, raw map[string]string) (CustomFieldValues, error) {
+	values := CustomFieldValues{}
+	for _, field := range fields {
+		value := strings.TrimSpace(raw[field.Key])
+
+		switch field.FieldType {
+		case FieldTypeCheckbox:
+			checked := value == "on" || value == "true" || value == "1"
+			if field.Required && !checked {
+				return nil, fmt.Errorf("marque \"%s\"", field.Label)
+			}
+			values[field.Key] = checked
+			continue
+		}
+
+		if value == "" {
+			if field.Required {
+				return nil, fmt.Errorf("preencha o campo \"%s\"", field.Label)
+			}
+			continue
+		}
+
+		switch field.FieldType {
+		case FieldTypeNumber:
+			number, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				return nil, fmt.Errorf("o campo \"%s\" deve ser um número", field.Label)
+			}
+			values[field.Key] = number
+		case FieldTypeSelect:
+			valid := false
+			for _, option := range field.Options {
+				if option == value {
+					valid = true
+				}
+			}
+			if !valid {
+				return nil, fmt.Errorf("escolha uma opção da lista em \"%s\"", field.Label)
+			}
+			values[field.Key] = value
+		default:
+			if len([]rune(value)) > maxCustomFieldText {
+				return nil, fmt.Errorf("o campo \"%s\" aceita até %d caracteres", field.Label, maxCustomFieldText)
+			}
+			values[field.Key] = value
+		}
+	}
+	return values, nil
+}
+
+const serviceFieldColumns = `id, service_type_id, field_key, label, field_type, options, help_text, required, display_order, active`
+
+func scanServiceField(row interface{ Scan(...interface{}) error }, f *ServiceField) error {
+	var options string
+	err := row.Scan(&f.ID, &f.ServiceTypeID, &f.Key, &f.Label, &f.FieldType, &options,
+		&f.HelpText, &f.Required, &f.DisplayOrder, &f.Active)
+	if err != nil {
+		return err
+	}
+	f.SetOptionsText(options)
+	return nil
+}
+
+func (m *ServiceModel) queryServiceFields(query string, args ...interface{}) ([]ServiceField, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fields []ServiceField
+	for rows.Next() {
+		var f ServiceField
+		if err := scanServiceField(rows, &f); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+// GetServiceTypeByID busca um tipo de serviço, ativo ou não
+func (m *ServiceModel) GetServiceTypeByID(id int) (*ServiceType, error) {
+	var t ServiceType
+	err := m.DB.QueryRow(`SELECT id, code, name, COALESCE(description, ''), COALESCE(icon, ''), active, created_at
+		FROM service_types WHERE id = $1`, id).
+		Scan(&t.ID, &t.Code, &t.Name, &t.Description, &t.Icon, &t.Active, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetServiceFields lista todos os campos do tipo de serviço, inclusive os inativos
+func (m *ServiceModel) GetServiceFields(serviceTypeID int) ([]ServiceField, error) {
+	return m.queryServiceFields(`SELECT `+serviceFieldColumns+` FROM service_type_fields
+		WHERE service_type_id = $1 ORDER BY display_order, id`, serviceTypeID)
+}
+
+// GetActiveServiceFields lista os campos que o formulário pede para o tipo de serviço
+func (m *ServiceModel) GetActiveServiceFields(serviceTypeID int) ([]ServiceField, error) {
+	return m.queryServiceFields(`SELECT `+serviceFieldColumns+` FROM service_type_fields
+		WHERE service_type_id = $1 AND active = true ORDER BY display_order, id`, serviceTypeID)
+}
+
+// GetActiveServiceFieldsByType agrupa os campos ativos de todos os tipos,
+// para o formulário mostrar os do tipo escolhido
+func (m *ServiceModel) GetActiveServiceFieldsByType() (map[int][]ServiceField, error) {
+	fields, err := m.queryServiceFields(`SELECT ` + serviceFieldColumns + ` FROM service_type_fields
+		WHERE active = true ORDER BY display_order, id`)
+	if err != nil {
+		return nil, err
+	}
+	byType := map[int][]ServiceField{}
+	for _, f := range fields {
+		byType[f.ServiceTypeID] = append(byType[f.ServiceTypeID], f)
+	}
+	return byType, nil
+}
+
+// GetServiceField busca um campo adicional
+func (m *ServiceModel) GetServiceField(id int) (*ServiceField, error) {
+	f := &ServiceField{}
+	err := scanServiceField(m.DB.QueryRow(`SELECT `+serviceFieldColumns+` FROM service_type_fields WHERE id = $1`, id), f)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// CustomFieldAnswers monta as respostas da solicitação para exibição, na
+// ordem dos campos do tipo de serviço. Respostas a campos que não são do
+// tipo atual (o tipo foi trocado depois) aparecem no fim, pela chave.
+func (m *ServiceModel) CustomFieldAnswers(service *ServiceRequest) ([]CustomFieldAnswer, error) {
+	if len(service.CustomFields) == 0 {
+		return nil, nil
+	}
+
+	fields, err := m.GetServiceFields(service.ServiceTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var answers []CustomFieldAnswer
+	shown := map[string]bool{}
+	for _, field := range fields {
+		value, ok := service.CustomFields[field.Key]
+		if !ok {
+			continue
+		}
+		shown[field.Key] = true
+		answers = append(answers, CustomFieldAnswer{Label: field.Label, Value: formatCustomFieldValue(value)})
+	}
+
+	var extra []string
+	for key := range service.CustomFields {
+		if !shown[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		answers = append(answers, CustomFieldAnswer{Label: key, Value: formatCustomFieldValue(service.CustomFields[key])})
+	}
+	return answers, nil
+}
+
+func formatCustomFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "Sim"
+		}
+		return "Não"
+	case float64:
+		return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", ",", 1)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// CreateServiceField cadastra um campo ativo no fim do formulário do tipo
+func (m *ServiceModel) CreateServiceField(field *ServiceField, actor Actor) error {
+	var exists bool
+	err := m.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM service_type_fields WHERE service_type_id = $1 AND field_key = $2)`,
+		field.ServiceTypeID, field.Key).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrFieldKeyTaken
+	}
+
+	return withAudit(m.DB, actor, AuditCreate, AuditEntityServiceField, 0, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRow(`
+			INSERT INTO service_type_fields
+				(service_type_id, field_key, label, field_type, options, help_text, required, display_order)
+			VALUES ($1, $2, $3, $4, $5, $6, $7,
+			        (SELECT COALESCE(MAX(display_order), 0) + 1 FROM service_type_fields WHERE service_type_id = $1))
+			RETURNING id, display_order, active`,
+			field.ServiceTypeID, field.Key, field.Label, field.FieldType, field.OptionsText(), field.HelpText, field.Required,
+		).Scan(&field.ID, &field.DisplayOrder, &field.Active)
+		return field.ID, err
+	})
+}
+
+// UpdateServiceField altera o campo. A chave não muda, para não perder as
+// respostas já gravadas.
+func (m *ServiceModel) UpdateServiceField(field *ServiceField, actor Actor) error {
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityServiceField, field.ID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(`
+			UPDATE service_type_fields
+			SET label = $1, field_type = $2, options = $3, help_text = $4, required = $5
+			WHERE id = $6`,
+			field.Label, field.FieldType, field.OptionsText(), field.HelpText, field.Required, field.ID))
+	})
+}
+
+// SetServiceFieldActive ativa ou desativa o campo. Inativo, ele some do
+// formulário, mas as respostas já gravadas continuam visíveis.
+func (m *ServiceModel) SetServiceFieldActive(id int, active bool, actor Actor) error {
+	return withAudit(m.DB, actor, AuditStatus, AuditEntityServiceField, id, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(`UPDATE service_type_fields SET active = $1 WHERE id = $2`, active, id))
+	})
+}
+
+// ReorderServiceFields grava a ordem dos campos do tipo. ids deve conter
+// todos os campos do tipo, na nova ordem.
+func (m *ServiceModel) ReorderServiceFields(serviceTypeID int, ids []int, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current := map[int]int{}
+	rows, err := tx.Query(`SELECT id, display_order FROM service_type_fields WHERE service_type_id = $1 FOR UPDATE`, serviceTypeID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id, order int
+		if err := rows.Scan(&id, &order); err != nil {
+			rows.Close()
+			return err
+		}
+		current[id] = order
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(ids) != len(current) {
+		return ErrFieldInvalidOrder
+	}
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if _, ok := current[id]; !ok || seen[id] {
+			return ErrFieldInvalidOrder
+		}
+		seen[id] = true
+	}
+
+	for i, id := range ids {
+		if current[id] == i+1 {
+			continue
+		}
+		before, err := auditSnapshot(tx, AuditEntityServiceField, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE service_type_fields SET display_order = $1 WHERE id = $2`, i+1, id); err != nil {
+			return err
+		}
+		if err := recordAudit(tx, actor, AuditUpdate, AuditEntityServiceField, id, before); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -12,7 +12,7 @@ func (m *ServiceModel) GetAllWithFilters(statusFilter, serviceTypeFilter, search
 		JOIN service_types st ON sr.service_type_id = st.id
 		JOIN request_status rs ON sr.status_id = rs.id
 		JOIN users u ON sr.user_id = u.id
-		WHERE 1=1`
+		WHERE sr.deleted_at IS NULL`
 	
 	args := []interface{}{}
 	argPos := 1
@@ -94,57 +94,26 @@ func (m *ServiceModel) GetAllWithFilters(statusFilter, serviceTypeFilter, search
 }
 
 // AdminUpdate atualiza uma solicitação (admin pode atualizar status também)
-func (m *ServiceModel) AdminUpdate(service *ServiceRequest) error {
+func (m *ServiceModel) AdminUpdate(service *ServiceRequest, actor Actor) error {
 	query := `
 		UPDATE service_requests 
 		SET full_name = $1, service_type_id = $2, description = $3, cep = $4, 
 		    logradouro = $5, numero = $6, bairro = $7, cidade = $8, estado = $9, 
-		    preferred_date = $10, preferred_time = $11, status_id = $12, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $13`
-
-	result, err := m.DB.Exec(
-		query, 
-		service.FullName, service.ServiceTypeID, service.Description, service.CEP,
-		service.Logradouro, service.Numero, service.Bairro, service.Cidade,
-		service.Estado, service.PreferredDate, service.PreferredTime, service.StatusID,
-		service.ID,
-	)
-
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
-
-	return nil
-}
-
-// Delete deleta uma solicitação (apenas admin)
-func (m *ServiceModel) Delete(requestID int) error {
-	query := `DELETE FROM service_requests WHERE id = $1`
-	
-	result, err := m.DB.Exec(query, requestID)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
-	}
-
-	return nil
+		    preferred_date = $10, preferred_time = $11, status_id = $12,
+		    latitude = $13, longitude = $14, technician_id = $15, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $16 AND deleted_at IS NULL`
+
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityRequest, service.ID, func(tx *sql.Tx) (int, error) {
+		result, err := tx.Exec(
+			query, 
+			service.FullName, service.ServiceTypeID, service.Description, service.CEP,
+			service.Logradouro, service.Numero, service.Bairro, service.Cidade,
+			service.Estado, service.PreferredDate, service.PreferredTime, service.StatusID,
+			service.Latitude, service.Longitude, service.TechnicianID,
+			service.ID,
+		)
+		return 0, requireAffected(result, err)
+	})
 }
 
 // GetStatusStats retorna estatísticas por status
@@ -152,7 +121,7 @@ func (m *ServiceModel) GetStatusStats() (map[string]int, error) {
 	query := `
 		SELECT rs.code, COUNT(sr.id) as count
 		FROM request_status rs
-		LEFT JOIN service_requests sr ON sr.status_id = rs.id
+		LEFT JOIN service_requests sr ON sr.status_id = rs.id AND sr.deleted_at IS NULL
 		GROUP BY rs.code, rs.display_order
 		ORDER BY rs.display_order`
 	
@@ -186,6 +155,7 @@ func (m *ServiceModel) GetRecentRequests(limit int) ([]ServiceRequest, error) {
 		JOIN service_types st ON sr.service_type_id = st.id
 		JOIN request_status rs ON sr.status_id = rs.id
 		JOIN users u ON sr.user_id = u.id
+		WHERE sr.deleted_at IS NULL
 		ORDER BY sr.created_at DESC
 		LIMIT $1`
 
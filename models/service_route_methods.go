@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"martins-pocos/constants"
+)
+
+// GetScheduledVisits retorna as vistorias confirmadas para uma data.
+// Se technicianID for 0, retorna as visitas de todos os técnicos.
+func (m *ServiceModel) GetScheduledVisits(date time.Time, technicianID int) ([]ServiceRequest, error) {
+	query := `
+		SELECT sr.id, sr.user_id, sr.full_name, sr.service_type_id, st.code, st.name, st.icon,
+		       sr.description, sr.cep, sr.logradouro, sr.numero, sr.bairro, sr.cidade, sr.estado,
+		       sr.preferred_date, sr.preferred_time, sr.status_id, rs.code, rs.name, rs.color_class,
+		       sr.created_at, sr.updated_at, u.name, u.email,
+		       sr.latitude, sr.longitude, sr.technician_id, COALESCE(t.name, '')
+		FROM service_requests sr
+		JOIN service_types st ON sr.service_type_id = st.id
+		JOIN request_status rs ON sr.status_id = rs.id
+		JOIN users u ON sr.user_id = u.id
+		LEFT JOIN users t ON sr.technician_id = t.id
+		WHERE sr.status_id = $1 AND sr.preferred_date = $2 AND sr.deleted_at IS NULL`
+
+	args := []interface{}{constants.StatusConfirmada, date.Format("2006-01-02")}
+
+	if technicianID > 0 {
+		query += " AND sr.technician_id = $" + strconv.Itoa(len(args)+1)
+		args = append(args, technicianID)
+	}
+
+	query += " ORDER BY sr.preferred_time"
+
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []ServiceRequest
+	for rows.Next() {
+		var req ServiceRequest
+		var preferredTime sql.NullString
+
+		err := rows.Scan(
+			&req.ID, &req.UserID, &req.FullName, &req.ServiceTypeID, &req.ServiceTypeCode,
+			&req.ServiceTypeName, &req.ServiceTypeIcon, &req.Description,
+			&req.CEP, &req.Logradouro, &req.Numero, &req.Bairro, &req.Cidade, &req.Estado,
+			&req.PreferredDate, &preferredTime, &req.StatusID, &req.StatusCode,
+			&req.StatusName, &req.StatusColor, &req.CreatedAt, &req.UpdatedAt,
+			&req.UserName, &req.UserEmail,
+			&req.Latitude, &req.Longitude, &req.TechnicianID, &req.TechnicianName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if preferredTime.Valid {
+			req.PreferredTime = preferredTime.String
+		}
+
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
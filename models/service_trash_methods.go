@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrRequestHasSignedContract impede excluir solicitação com contrato assinado
+var ErrRequestHasSignedContract = errors.New("a solicitação tem contrato assinado e não pode ser excluída")
+
+// Delete move a solicitação para a lixeira (apenas admin). Ela some de todas
+// as listagens, mas pode ser restaurada até o expurgo. Solicitações com
+// contrato assinado por qualquer das partes não podem ser excluídas.
+func (m *ServiceModel) Delete(requestID int, actor Actor) error {
+	return withAudit(m.DB, actor, AuditDelete, AuditEntityRequest, requestID, func(tx *sql.Tx) (int, error) {
+		// Trava o contrato para que não seja assinado durante a exclusão
+		var signed bool
+		err := tx.QueryRow(`
+			SELECT client_signed OR company_signed FROM contracts
+			WHERE service_request_id = $1 FOR UPDATE`, requestID).Scan(&signed)
+		if err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+		if signed {
+			return 0, ErrRequestHasSignedContract
+		}
+
+		return 0, requireAffected(tx.Exec(`
+			UPDATE service_requests SET deleted_at = CURRENT_TIMESTAMP, deleted_by = NULLIF($1, 0)
+			WHERE id = $2 AND deleted_at IS NULL`, actor.UserID, requestID))
+	})
+}
+
+// Restore tira a solicitação da lixeira
+func (m *ServiceModel) Restore(requestID int, actor Actor) error {
+	return withAudit(m.DB, actor, AuditRestore, AuditEntityRequest, requestID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(`
+			UPDATE service_requests SET deleted_at = NULL, deleted_by = NULL
+			WHERE id = $1 AND deleted_at IS NOT NULL`, requestID))
+	})
+}
+
+// GetDeleted lista a lixeira, da exclusão mais recente para a mais antiga
+func (m *ServiceModel) GetDeleted() ([]ServiceRequest, error) {
+	query := `
+		SELECT sr.id, sr.user_id, sr.full_name, sr.service_type_id, st.code, st.name, st.icon,
+		       sr.description, sr.cidade, sr.estado, sr.preferred_date, sr.status_id, rs.code, rs.name, rs.color_class,
+		       sr.created_at, sr.updated_at, u.name, u.email, sr.deleted_at, COALESCE(d.name, '')
+		FROM service_requests sr
+		JOIN service_types st ON sr.service_type_id = st.id
+		JOIN request_status rs ON sr.status_id = rs.id
+		JOIN users u ON sr.user_id = u.id
+		LEFT JOIN users d ON sr.deleted_by = d.id
+		WHERE sr.deleted_at IS NOT NULL
+		ORDER BY sr.deleted_at DESC`
+
+	rows, err := m.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []ServiceRequest
+	for rows.Next() {
+		var req ServiceRequest
+		err := rows.Scan(
+			&req.ID, &req.UserID, &req.FullName, &req.ServiceTypeID, &req.ServiceTypeCode,
+			&req.ServiceTypeName, &req.ServiceTypeIcon, &req.Description, &req.Cidade, &req.Estado,
+			&req.PreferredDate, &req.StatusID, &req.StatusCode, &req.StatusName, &req.StatusColor,
+			&req.CreatedAt, &req.UpdatedAt, &req.UserName, &req.UserEmail, &req.DeletedAt, &req.DeletedByName,
+		)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// PurgeDeleted elimina de vez as solicitações que estão na lixeira desde
+// antes de cutoff (contratos em rascunho vão junto). Retorna quantas foram
+// eliminadas; a falha em uma solicitação não interrompe as demais, e os
+// erros voltam juntos, cada um com o ID da solicitação.
+func (m *ServiceModel) PurgeDeleted(cutoff time.Time) (int, error) {
+	rows, err := m.DB.Query(`SELECT id FROM service_requests WHERE deleted_at < $1`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var failures []error
+	for _, id := range ids {
+		err := withAudit(m.DB, Actor{}, AuditPurge, AuditEntityRequest, id, func(tx *sql.Tx) (int, error) {
+			// Restaurada ou assinada depois da listagem: fica
+			return 0, requireAffected(tx.Exec(`
+				DELETE FROM service_requests sr
+				WHERE sr.id = $1 AND sr.deleted_at < $2
+				  AND NOT EXISTS (SELECT 1 FROM contracts c
+				                  WHERE c.service_request_id = sr.id AND (c.client_signed OR c.company_signed))`,
+				id, cutoff))
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("solicitação #%d: %w", id, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(failures...)
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// UserSession é uma sessão aberta do usuário (um navegador/dispositivo)
+type UserSession struct {
+	ID         string         `json:"id"`
+	IP         sql.NullString `json:"ip"`
+	UserAgent  sql.NullString `json:"user_agent"`
+	CreatedAt  time.Time      `json:"created_at"`
+	LastSeenAt time.Time      `json:"last_seen_at"`
+
+	// Current indica a sessão da própria requisição
+	Current bool `json:"current"`
+}
+
+// Device descreve o navegador e o sistema a partir do User-Agent
+func (s *UserSession) Device() string {
+	ua := s.UserAgent.String
+	if ua == "" {
+		return "Dispositivo desconhecido"
+	}
+
+	browser := "Navegador"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"), strings.Contains(ua, "Opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "SamsungBrowser"):
+		browser = "Samsung Internet"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+
+	system := ""
+	switch {
+	case strings.Contains(ua, "Android"):
+		system = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		system = "iOS"
+	case strings.Contains(ua, "Windows"):
+		system = "Windows"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		system = "macOS"
+	case strings.Contains(ua, "Linux"):
+		system = "Linux"
+	}
+
+	if system == "" {
+		return browser
+	}
+	return browser + " no " + system
+}
+
+type SessionModel struct {
+	DB *sql.DB
+}
+
+func NewSessionModel(db *sql.DB) *SessionModel {
+	return &SessionModel{DB: db}
+}
+
+// GetActiveByUser lista as sessões não expiradas do usuário, da mais recente
+// para a mais antiga. currentID (chave da sessão da requisição) é marcada.
+func (m *SessionModel) GetActiveByUser(userID int, currentID string) ([]UserSession, error) {
+	rows, err := m.DB.Query(`
+		SELECT id, ip, user_agent, created_at, last_seen_at
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []UserSession
+	for rows.Next() {
+		var s UserSession
+		if err := rows.Scan(&s.ID, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, err
+		}
+		s.Current = s.ID == currentID
+		sessions = append(sessions, s)
+	}
+
+	return sessions, nil
+}
+
+// Revoke encerra uma sessão do usuário
+func (m *SessionModel) Revoke(userID int, id string) error {
+	result, err := m.DB.Exec(`DELETE FROM sessions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeOthers encerra todas as sessões do usuário exceto keepID, junto
+// com os tokens da API, e retorna quantas sessões foram encerradas
+func (m *SessionModel) RevokeOthers(userID int, keepID string) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM sessions WHERE user_id = $1 AND id <> $2`, userID, keepID)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := revokeUserAPITokens(tx, userID); err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// RevokeAll encerra todas as sessões do usuário e revoga os tokens da API
+// (ação do gestor) e retorna quantas sessões foram encerradas
+func (m *SessionModel) RevokeAll(userID int, actor Actor) (int64, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM sessions WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	tokens, err := revokeUserAPITokens(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = insertAudit(tx, actor, AuditRevokeSessions, AuditEntityUser, userID,
+		sql.NullString{}, auditJSON(map[string]int64{"sessoes_encerradas": count, "tokens_revogados": tokens}))
+	if err != nil {
+		return 0, err
+	}
+	return count, tx.Commit()
+}
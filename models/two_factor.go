@@ -0,0 +1,312 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Parâmetros do TOTP (RFC 6238), os padrões aceitos pelos aplicativos
+// autenticadores (Google Authenticator, Authy, Microsoft Authenticator)
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+	// Passos aceitos antes e depois do atual, para relógios dessincronizados
+	totpSkew = 1
+)
+
+// Quantidade de códigos de recuperação gerados a cada ativação
+const RecoveryCodeCount = 10
+
+// Nome exibido no aplicativo autenticador
+const TwoFactorIssuer = "Martins Poços"
+
+var (
+	ErrInvalidTwoFactorCode    = errors.New("código de verificação inválido")
+	ErrTwoFactorAlreadyEnabled = errors.New("a verificação em duas etapas já está ativa")
+	ErrTwoFactorNotPending     = errors.New("inicie a configuração da verificação em duas etapas novamente")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type TwoFactorModel struct {
+	DB *sql.DB
+}
+
+func NewTwoFactorModel(db *sql.DB) *TwoFactorModel {
+	return &TwoFactorModel{DB: db}
+}
+
+// BeginEnrollment retorna o segredo pendente do usuário, gerando um novo se
+// ainda não houver. O segredo só passa a valer depois de Enable.
+func (m *TwoFactorModel) BeginEnrollment(userID int) (string, error) {
+	var secret sql.NullString
+	var enabledAt sql.NullTime
+	err := m.DB.QueryRow(`SELECT totp_secret, totp_enabled_at FROM users WHERE id = $1`, userID).
+		Scan(&secret, &enabledAt)
+	if err != nil {
+		return "", err
+	}
+	if enabledAt.Valid {
+		return "", ErrTwoFactorAlreadyEnabled
+	}
+	if secret.Valid && secret.String != "" {
+		return secret.String, nil
+	}
+
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	newSecret := totpEncoding.EncodeToString(buf)
+
+	_, err = m.DB.Exec(`UPDATE users SET totp_secret = $1 WHERE id = $2 AND totp_enabled_at IS NULL`, newSecret, userID)
+	if err != nil {
+		return "", err
+	}
+	return newSecret, nil
+}
+
+// Enable confere o primeiro código gerado pelo aplicativo, ativa a
+// verificação e retorna os códigos de recuperação em claro (exibidos uma vez)
+func (m *TwoFactorModel) Enable(userID int, code string) ([]string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var secret sql.NullString
+	var enabledAt sql.NullTime
+	err = tx.QueryRow(`SELECT totp_secret, totp_enabled_at FROM users WHERE id = $1 FOR UPDATE`, userID).
+		Scan(&secret, &enabledAt)
+	if err != nil {
+		return nil, err
+	}
+	if enabledAt.Valid {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if !secret.Valid || secret.String == "" {
+		return nil, ErrTwoFactorNotPending
+	}
+
+	counter, ok := matchTOTP(secret.String, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_counter = $1
+		WHERE id = $2`, counter, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify confere um código do aplicativo ou um código de recuperação (que é
+// consumido). Cada código do aplicativo vale uma única vez. Retorna se foi
+// usado um código de recuperação.
+func (m *TwoFactorModel) Verify(userID int, code string) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return true, m.consumeRecoveryCode(userID, code)
+	}
+
+	var secret sql.NullString
+	err := m.DB.QueryRow(`
+		SELECT totp_secret FROM users WHERE id = $1 AND totp_enabled_at IS NOT NULL`, userID).Scan(&secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return false, err
+	}
+
+	counter, ok := matchTOTP(secret.String, code, time.Now())
+	if !ok {
+		return false, ErrInvalidTwoFactorCode
+	}
+
+	// Só aceita passos posteriores ao último usado (evita reaproveitar o código)
+	result, err := m.DB.Exec(`
+		UPDATE users SET totp_last_counter = $1
+		WHERE id = $2 AND totp_last_counter < $1`, counter, userID)
+	if err != nil {
+		return false, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, ErrInvalidTwoFactorCode
+	}
+	return false, nil
+}
+
+func (m *TwoFactorModel) consumeRecoveryCode(userID int, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	result, err := m.DB.Exec(`
+		UPDATE two_factor_recovery_codes SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+		  AND EXISTS (SELECT 1 FROM users WHERE id = $1 AND totp_enabled_at IS NOT NULL)`,
+		userID, hashAPIToken(normalized))
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// Disable desativa a verificação e descarta o segredo e os códigos de recuperação
+func (m *TwoFactorModel) Disable(userID int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_counter = 0
+		WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RegenerateRecoveryCodes invalida os códigos de recuperação anteriores e
+// retorna os novos em claro
+func (m *TwoFactorModel) RegenerateRecoveryCodes(userID int) ([]string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RemainingRecoveryCodes conta os códigos de recuperação ainda não usados
+func (m *TwoFactorModel) RemainingRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := m.DB.QueryRow(`
+		SELECT COUNT(*) FROM two_factor_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// ProvisioningURI monta o endereço otpauth:// lido pelo QR code do aplicativo
+func ProvisioningURI(secret, accountName string) string {
+	label := url.PathEscape(TwoFactorIssuer + ":" + accountName)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", TwoFactorIssuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod.Seconds())))
+	// Alguns aplicativos exibem o "+" literalmente; espaços vão como %20
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(params.Encode(), "+", "%20")
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int) ([]string, error) {
+	if _, err := tx.Exec(`DELETE FROM two_factor_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, RecoveryCodeCount)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(totpEncoding.EncodeToString(buf)) // 8 caracteres
+		code := raw[:4] + "-" + raw[4:]
+
+		_, err := tx.Exec(`
+			INSERT INTO two_factor_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, hashAPIToken(raw))
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode aceita o código com ou sem hífen e em maiúsculas
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// matchTOTP confere o código contra a janela de passos em torno de now e
+// retorna o passo correspondente
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(TOTPPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, uint64(step))), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode calcula o código HOTP (RFC 4226) do passo
+func totpCode(key []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
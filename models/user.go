@@ -2,6 +2,8 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -24,8 +26,30 @@ type User struct {
 	Phone      string    `json:"phone"`
 	Address    string    `json:"address"`
 	CreatedAt  time.Time `json:"created_at"`
+
+	EmailVerifiedAt    sql.NullTime `json:"email_verified_at"`
+	TwoFactorEnabledAt sql.NullTime `json:"two_factor_enabled_at"`
+}
+
+// IsEmailVerified indica se o usuário confirmou o e-mail pelo link enviado
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt.Valid
 }
 
+// HasTwoFactor indica se o usuário ativou a verificação em duas etapas
+func (u *User) HasTwoFactor() bool {
+	return u.TwoFactorEnabledAt.Valid
+}
+
+var ErrInvalidCredentials = errors.New("e-mail ou senha inválidos")
+
+// Hash usado para comparar senhas de e-mails inexistentes, para que o tempo
+// de resposta não revele se a conta existe
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
 type UserModel struct {
 	DB *sql.DB
 }
@@ -48,25 +72,25 @@ func (m *UserModel) Create(user *User) error {
 	}
 
 	query := `
-		INSERT INTO users (name, email, password, user_type_id, phone, address) 
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (name, email, password, user_type_id, phone, address, role_id) 
+		VALUES ($1, $2, $3, $4, $5, $6, (SELECT id FROM roles WHERE code = $7))
 		RETURNING id, created_at`
 
-	return m.DB.QueryRow(query, user.Name, user.Email, string(hashedPassword), clienteTypeID, user.Phone, user.Address).
+	return m.DB.QueryRow(query, user.Name, user.Email, string(hashedPassword), clienteTypeID, user.Phone, user.Address, RoleClient).
 		Scan(&user.ID, &user.CreatedAt)
 }
 
 func (m *UserModel) GetByEmail(email string) (*User, error) {
 	user := &User{}
 	query := `
-		SELECT u.id, u.name, u.email, u.password, u.user_type_id, ut.type_name, u.phone, u.address, u.created_at 
+		SELECT u.id, u.name, u.email, u.password, u.user_type_id, ut.type_name, COALESCE(u.phone, ''), COALESCE(u.address, ''), u.created_at, u.email_verified_at, u.totp_enabled_at 
 		FROM users u
 		INNER JOIN user_types ut ON u.user_type_id = ut.id
 		WHERE u.email = $1`
 	
 	err := m.DB.QueryRow(query, email).Scan(
 		&user.ID, &user.Name, &user.Email, &user.Password, &user.UserTypeID, 
-		&user.UserType, &user.Phone, &user.Address, &user.CreatedAt)
+		&user.UserType, &user.Phone, &user.Address, &user.CreatedAt, &user.EmailVerifiedAt, &user.TwoFactorEnabledAt)
 	
 	if err != nil {
 		return nil, err
@@ -78,14 +102,14 @@ func (m *UserModel) GetByEmail(email string) (*User, error) {
 func (m *UserModel) GetByID(id int) (*User, error) {
 	user := &User{}
 	query := `
-		SELECT u.id, u.name, u.email, u.user_type_id, ut.type_name, u.phone, u.address, u.created_at 
+		SELECT u.id, u.name, u.email, u.user_type_id, ut.type_name, COALESCE(u.phone, ''), COALESCE(u.address, ''), u.created_at, u.email_verified_at, u.totp_enabled_at 
 		FROM users u
 		INNER JOIN user_types ut ON u.user_type_id = ut.id
 		WHERE u.id = $1`
 	
 	err := m.DB.QueryRow(query, id).Scan(
 		&user.ID, &user.Name, &user.Email, &user.UserTypeID, 
-		&user.UserType, &user.Phone, &user.Address, &user.CreatedAt)
+		&user.UserType, &user.Phone, &user.Address, &user.CreatedAt, &user.EmailVerifiedAt, &user.TwoFactorEnabledAt)
 	
 	if err != nil {
 		return nil, err
@@ -94,6 +118,113 @@ func (m *UserModel) GetByID(id int) (*User, error) {
 	return user, nil
 }
 
+// UpdatePassword grava uma nova senha para o usuário, encerra todas as
+// sessões abertas dele e revoga os tokens da API (quem tinha a senha antiga
+// perde o acesso)
+func (m *UserModel) UpdatePassword(userID int, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE users SET password = $1 WHERE id = $2`, string(hashedPassword), userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := revokeUserAPITokens(tx, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkEmailVerified registra que o usuário confirmou o e-mail
+func (m *UserModel) MarkEmailVerified(userID int) error {
+	_, err := m.DB.Exec(`
+		UPDATE users SET email_verified_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND email_verified_at IS NULL`, userID)
+	return err
+}
+
+// GetClientByPhone busca o cliente pelo telefone normalizado (ver
+// NormalizePhone), ignorando a máscara gravada no cadastro. Telefones
+// compartilhados por mais de uma conta não identificam ninguém.
+func (m *UserModel) GetClientByPhone(phone string) (*User, error) {
+	query := `
+		SELECT u.id, u.name, u.email, u.user_type_id, ut.type_name, COALESCE(u.phone, ''), COALESCE(u.address, ''), u.created_at, u.email_verified_at, u.totp_enabled_at
+		FROM users u
+		INNER JOIN user_types ut ON u.user_type_id = ut.id
+		WHERE ut.type_name = 'cliente'
+		  AND regexp_replace(COALESCE(u.phone, ''), '[^0-9]', '', 'g') IN ($1, '55' || $1)
+		LIMIT 2`
+
+	rows, err := m.DB.Query(query, phone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.UserTypeID,
+			&user.UserType, &user.Phone, &user.Address, &user.CreatedAt, &user.EmailVerifiedAt, &user.TwoFactorEnabledAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(users) != 1 {
+		return nil, sql.ErrNoRows
+	}
+	return &users[0], nil
+}
+
+// MarkPhoneVerified registra que o usuário confirmou o telefone por código
+func (m *UserModel) MarkPhoneVerified(userID int) error {
+	_, err := m.DB.Exec(`UPDATE users SET phone_verified_at = CURRENT_TIMESTAMP WHERE id = $1`, userID)
+	return err
+}
+
+// Authenticate confere e-mail e senha. Com a senha errada o usuário é
+// retornado junto com ErrInvalidCredentials (para o registro da tentativa);
+// com e-mail inexistente, apenas o erro.
+func (m *UserModel) Authenticate(email, password string) (*User, error) {
+	user, err := m.GetByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		dummyPasswordHashOnce.Do(func() {
+			dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("senha-inexistente"), bcrypt.DefaultCost)
+		})
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !m.ValidatePassword(password, user.Password) {
+		return user, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
 func (m *UserModel) ValidatePassword(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
@@ -121,8 +252,20 @@ func (m *UserModel) GetUserTypes() ([]UserType, error) {
 	return userTypes, nil
 }
 
+// defaultRoleByType é o papel dado a quem é criado sem papel escolhido: o de
+// menos privilégios do tipo. Proprietários só surgem pela atribuição de papéis.
+var defaultRoleByType = map[string]string{
+	"gestor":  RoleTech,
+	"cliente": RoleClient,
+}
+
 // Método para criar usuário com tipo específico (útil para admin criar outros admins)
 func (m *UserModel) CreateWithType(user *User, userTypeName string) error {
+	roleCode, ok := defaultRoleByType[userTypeName]
+	if !ok {
+		return ErrRoleNotFound
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -136,10 +279,42 @@ func (m *UserModel) CreateWithType(user *User, userTypeName string) error {
 	}
 
 	query := `
-		INSERT INTO users (name, email, password, user_type_id, phone, address) 
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO users (name, email, password, user_type_id, phone, address, role_id) 
+		VALUES ($1, $2, $3, $4, $5, $6, (SELECT id FROM roles WHERE code = $7))
 		RETURNING id, created_at`
 
-	return m.DB.QueryRow(query, user.Name, user.Email, string(hashedPassword), userTypeID, user.Phone, user.Address).
+	return m.DB.QueryRow(query, user.Name, user.Email, string(hashedPassword), userTypeID, user.Phone, user.Address, roleCode).
 		Scan(&user.ID, &user.CreatedAt)
-}
\ No newline at end of file
+}
+
+// GetTechnicians retorna os usuários com papel de técnico. includeID entra
+// na lista mesmo sem o papel, para não sumir o técnico já designado a uma
+// visita (0 para nenhum).
+func (m *UserModel) GetTechnicians(includeID int) ([]User, error) {
+	query := `
+		SELECT u.id, u.name, u.email, u.user_type_id, ut.type_name, COALESCE(u.phone, ''), COALESCE(u.address, ''), u.created_at
+		FROM users u
+		INNER JOIN user_types ut ON u.user_type_id = ut.id
+		LEFT JOIN roles r ON u.role_id = r.id
+		WHERE r.code = $1 OR u.id = $2
+		ORDER BY u.name`
+
+	rows, err := m.DB.Query(query, RoleTech, includeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.UserTypeID,
+			&user.UserType, &user.Phone, &user.Address, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,317 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limite de imóveis cadastrados por usuário
+const MaxUserAddresses = 10
+
+var (
+	ErrAddressNotFound    = errors.New("endereço não encontrado")
+	ErrTooManyAddresses   = fmt.Errorf("limite de %d endereços atingido", MaxUserAddresses)
+	ErrInvalidCEP         = errors.New("CEP inválido")
+	ErrInvalidState       = errors.New("estado inválido")
+	ErrIncompleteAddress  = errors.New("preencha logradouro, número, bairro e cidade")
+	ErrAddressLabelLength = errors.New("o nome do imóvel deve ter até 60 caracteres")
+)
+
+// State é uma unidade da federação, para os campos de estado dos formulários
+type State struct {
+	Code string
+	Name string
+}
+
+var BrazilianStates = []State{
+	{"AC", "Acre"}, {"AL", "Alagoas"}, {"AP", "Amapá"}, {"AM", "Amazonas"},
+	{"BA", "Bahia"}, {"CE", "Ceará"}, {"DF", "Distrito Federal"}, {"ES", "Espírito Santo"},
+	{"GO", "Goiás"}, {"MA", "Maranhão"}, {"MT", "Mato Grosso"}, {"MS", "Mato Grosso do Sul"},
+	{"MG", "Minas Gerais"}, {"PA", "Pará"}, {"PB", "Paraíba"}, {"PR", "Paraná"},
+	{"PE", "Pernambuco"}, {"PI", "Piauí"}, {"RJ", "Rio de Janeiro"}, {"RN", "Rio Grande do Norte"},
+	{"RS", "Rio Grande do Sul"}, {"RO", "Rondônia"}, {"RR", "Roraima"}, {"SC", "Santa Catarina"},
+	{"SP", "São Paulo"}, {"SE", "Sergipe"}, {"TO", "Tocantins"},
+}
+
+// UserAddress é o endereço de um imóvel do cliente (sítio, casa, chácara),
+// oferecido para preencher o local das novas solicitações
+type UserAddress struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	Label      string    `json:"label"`
+	CEP        string    `json:"cep"`
+	Logradouro string    `json:"logradouro"`
+	Numero     string    `json:"numero"`
+	Bairro     string    `json:"bairro"`
+	Cidade     string    `json:"cidade"`
+	Estado     string    `json:"estado"`
+	IsDefault  bool      `json:"is_default"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Normalize limpa os campos e confere se o endereço está completo. Sem
+// nome, o imóvel recebe o logradouro como nome.
+func (a *UserAddress) Normalize() error {
+	clean := func(s string) string { return strings.Join(strings.Fields(s), " ") }
+	a.Label = clean(a.Label)
+	a.Logradouro = clean(a.Logradouro)
+	a.Numero = clean(a.Numero)
+	a.Bairro = clean(a.Bairro)
+	a.Cidade = clean(a.Cidade)
+	a.Estado = strings.ToUpper(strings.TrimSpace(a.Estado))
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, a.CEP)
+	if len(digits) != 8 {
+		return ErrInvalidCEP
+	}
+	a.CEP = digits[:5] + "-" + digits[5:]
+
+	if a.Logradouro == "" || a.Numero == "" || a.Bairro == "" || a.Cidade == "" {
+		return ErrIncompleteAddress
+	}
+
+	valid := false
+	for _, s := range BrazilianStates {
+		if s.Code == a.Estado {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return ErrInvalidState
+	}
+
+	if a.Label == "" {
+		a.Label = a.Logradouro + ", " + a.Numero
+	}
+	if utf8.RuneCountInString(a.Label) > 60 {
+		return ErrAddressLabelLength
+	}
+	return nil
+}
+
+// Summary retorna o endereço em uma linha
+func (a *UserAddress) Summary() string {
+	return fmt.Sprintf("%s, %s - %s, %s/%s (CEP %s)", a.Logradouro, a.Numero, a.Bairro, a.Cidade, a.Estado, a.CEP)
+}
+
+type UserAddressModel struct {
+	DB *sql.DB
+}
+
+func NewUserAddressModel(db *sql.DB) *UserAddressModel {
+	return &UserAddressModel{DB: db}
+}
+
+const userAddressColumns = `
+		SELECT id, user_id, label, cep, logradouro, numero, bairro, cidade, estado, is_default, created_at, updated_at
+		FROM user_addresses`
+
+func scanUserAddress(scanner interface{ Scan(...interface{}) error }, a *UserAddress) error {
+	return scanner.Scan(&a.ID, &a.UserID, &a.Label, &a.CEP, &a.Logradouro, &a.Numero,
+		&a.Bairro, &a.Cidade, &a.Estado, &a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// GetByUser lista os endereços do usuário, o padrão primeiro
+func (m *UserAddressModel) GetByUser(userID int) ([]UserAddress, error) {
+	rows, err := m.DB.Query(userAddressColumns+` WHERE user_id = $1 ORDER BY is_default DESC, label`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addresses []UserAddress
+	for rows.Next() {
+		var a UserAddress
+		if err := scanUserAddress(rows, &a); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+
+	return addresses, nil
+}
+
+// GetByIDAndUser busca um endereço do usuário
+func (m *UserAddressModel) GetByIDAndUser(id, userID int) (*UserAddress, error) {
+	a := &UserAddress{}
+	err := scanUserAddress(m.DB.QueryRow(userAddressColumns+` WHERE id = $1 AND user_id = $2`, id, userID), a)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAddressNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Create cadastra o endereço (já normalizado). O primeiro endereço do
+// usuário passa a ser o padrão.
+func (m *UserAddressModel) Create(a *UserAddress, ip string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Trava o usuário para contar os endereços sem corrida
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, a.UserID); err != nil {
+		return err
+	}
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM user_addresses WHERE user_id = $1`, a.UserID).Scan(&count); err != nil {
+		return err
+	}
+	if count >= MaxUserAddresses {
+		return ErrTooManyAddresses
+	}
+	a.IsDefault = count == 0
+
+	err = tx.QueryRow(`
+		INSERT INTO user_addresses (user_id, label, cep, logradouro, numero, bairro, cidade, estado, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`,
+		a.UserID, a.Label, a.CEP, a.Logradouro, a.Numero, a.Bairro, a.Cidade, a.Estado, a.IsDefault).
+		Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := recordProfileChange(tx, a.UserID, ProfileFieldProperty, "", a.Label+": "+a.Summary(), ip); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CreateIfMissing cadastra o endereço usado em uma solicitação, a menos que
+// o usuário já tenha o mesmo CEP e número ou tenha atingido o limite
+func (m *UserAddressModel) CreateIfMissing(a *UserAddress, ip string) error {
+	var exists bool
+	err := m.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM user_addresses
+		               WHERE user_id = $1 AND cep = $2 AND LOWER(numero) = LOWER($3))`,
+		a.UserID, a.CEP, a.Numero).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	err = m.Create(a, ip)
+	if errors.Is(err, ErrTooManyAddresses) {
+		return nil
+	}
+	return err
+}
+
+// Update grava as alterações de um endereço (já normalizado) do usuário
+func (m *UserAddressModel) Update(a *UserAddress, ip string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old := &UserAddress{}
+	err = scanUserAddress(tx.QueryRow(userAddressColumns+` WHERE id = $1 AND user_id = $2 FOR UPDATE`, a.ID, a.UserID), old)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAddressNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_addresses SET label = $1, cep = $2, logradouro = $3, numero = $4, bairro = $5,
+		       cidade = $6, estado = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8`,
+		a.Label, a.CEP, a.Logradouro, a.Numero, a.Bairro, a.Cidade, a.Estado, a.ID)
+	if err != nil {
+		return err
+	}
+
+	oldValue := old.Label + ": " + old.Summary()
+	newValue := a.Label + ": " + a.Summary()
+	if oldValue != newValue {
+		if err := recordProfileChange(tx, a.UserID, ProfileFieldProperty, oldValue, newValue, ip); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete remove um endereço do usuário. Se era o padrão, o mais antigo
+// dos restantes assume.
+func (m *UserAddressModel) Delete(id, userID int, ip string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	old := &UserAddress{}
+	err = scanUserAddress(tx.QueryRow(userAddressColumns+` WHERE id = $1 AND user_id = $2 FOR UPDATE`, id, userID), old)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAddressNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM user_addresses WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	if old.IsDefault {
+		_, err = tx.Exec(`
+			UPDATE user_addresses SET is_default = true
+			WHERE id = (SELECT id FROM user_addresses WHERE user_id = $1 ORDER BY created_at, id LIMIT 1)`, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := recordProfileChange(tx, userID, ProfileFieldProperty, old.Label+": "+old.Summary(), "", ip); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetDefault marca o endereço como padrão (pré-selecionado nas solicitações)
+func (m *UserAddressModel) SetDefault(id, userID int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE user_addresses SET is_default = (id = $1) WHERE user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrAddressNotFound
+	}
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM user_addresses WHERE id = $1 AND user_id = $2)`, id, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrAddressNotFound
+	}
+
+	return tx.Commit()
+}
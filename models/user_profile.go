@@ -0,0 +1,391 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Avisos por WhatsApp que o cliente pode desligar no perfil. O valor é a
+// coluna da tabela users.
+const (
+	NotifyRequestUpdates = "notify_request_updates"
+	NotifyQuotes         = "notify_quotes"
+	NotifyWarranty       = "notify_warranty"
+	NotifyMaintenance    = "notify_maintenance"
+)
+
+// Campos registrados no histórico de alterações do perfil
+const (
+	ProfileFieldName          = "nome"
+	ProfileFieldEmail         = "email"
+	ProfileFieldPendingEmail  = "email_pendente"
+	ProfileFieldPhone         = "telefone"
+	ProfileFieldAddress       = "endereco_contato"
+	ProfileFieldPassword      = "senha"
+	ProfileFieldNotifications = "notificacoes"
+	ProfileFieldProperty      = "imovel"
+)
+
+var (
+	ErrInvalidName  = errors.New("informe o nome completo (até 100 caracteres)")
+	ErrInvalidEmail = errors.New("e-mail inválido")
+	ErrEmailTaken   = errors.New("este e-mail já está em uso por outra conta")
+)
+
+// NotificationPreferences indica quais avisos o usuário aceita receber
+type NotificationPreferences struct {
+	RequestUpdates bool `json:"request_updates"`
+	Quotes         bool `json:"quotes"`
+	Warranty       bool `json:"warranty"`
+	Maintenance    bool `json:"maintenance"`
+}
+
+// Summary descreve as preferências para o histórico
+func (p NotificationPreferences) Summary() string {
+	var enabled []string
+	if p.RequestUpdates {
+		enabled = append(enabled, "solicitações")
+	}
+	if p.Quotes {
+		enabled = append(enabled, "orçamentos")
+	}
+	if p.Warranty {
+		enabled = append(enabled, "garantias")
+	}
+	if p.Maintenance {
+		enabled = append(enabled, "manutenções")
+	}
+	if len(enabled) == 0 {
+		return "nenhum aviso"
+	}
+	return strings.Join(enabled, ", ")
+}
+
+// UserProfile são os dados que o próprio usuário consulta e altera
+type UserProfile struct {
+	ID              int            `json:"id"`
+	Name            string         `json:"name"`
+	Email           string         `json:"email"`
+	PendingEmail    sql.NullString `json:"pending_email"`
+	Phone           string         `json:"phone"`
+	Address         string         `json:"address"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
+	PhoneVerifiedAt sql.NullTime   `json:"phone_verified_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+
+	Notifications NotificationPreferences `json:"notifications"`
+}
+
+// ProfileChange é uma alteração registrada no histórico do perfil
+type ProfileChange struct {
+	ID        int            `json:"id"`
+	Field     string         `json:"field"`
+	OldValue  sql.NullString `json:"old_value"`
+	NewValue  sql.NullString `json:"new_value"`
+	IP        sql.NullString `json:"ip"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// FieldLabel retorna o nome do campo para exibição
+func (c *ProfileChange) FieldLabel() string {
+	switch c.Field {
+	case ProfileFieldName:
+		return "Nome"
+	case ProfileFieldEmail:
+		return "E-mail"
+	case ProfileFieldPendingEmail:
+		return "Troca de e-mail solicitada"
+	case ProfileFieldPhone:
+		return "Telefone"
+	case ProfileFieldAddress:
+		return "Endereço de contato"
+	case ProfileFieldPassword:
+		return "Senha"
+	case ProfileFieldNotifications:
+		return "Avisos"
+	case ProfileFieldProperty:
+		return "Imóvel"
+	default:
+		return c.Field
+	}
+}
+
+// ValidateContact confere nome, e-mail e telefone (opcional) informados no
+// perfil e retorna os valores limpos
+func ValidateContact(name, email, phone string) (string, string, string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if utf8.RuneCountInString(name) < 3 || utf8.RuneCountInString(name) > 100 {
+		return "", "", "", ErrInvalidName
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email || len(email) > 100 {
+		return "", "", "", ErrInvalidEmail
+	}
+
+	phone = strings.TrimSpace(phone)
+	if phone != "" {
+		if _, err := NormalizePhone(phone); err != nil {
+			return "", "", "", err
+		}
+	}
+
+	return name, email, phone, nil
+}
+
+// GetProfile retorna os dados do perfil do usuário
+func (m *UserModel) GetProfile(userID int) (*UserProfile, error) {
+	p := &UserProfile{}
+	err := m.DB.QueryRow(`
+		SELECT id, name, email, pending_email, COALESCE(phone, ''), COALESCE(address, ''),
+		       email_verified_at, phone_verified_at, created_at,
+		       notify_request_updates, notify_quotes, notify_warranty, notify_maintenance
+		FROM users WHERE id = $1`, userID).Scan(
+		&p.ID, &p.Name, &p.Email, &p.PendingEmail, &p.Phone, &p.Address,
+		&p.EmailVerifiedAt, &p.PhoneVerifiedAt, &p.CreatedAt,
+		&p.Notifications.RequestUpdates, &p.Notifications.Quotes,
+		&p.Notifications.Warranty, &p.Notifications.Maintenance)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpdateContact grava nome, telefone e endereço de contato, registrando no
+// histórico cada campo alterado. Trocar o telefone exige confirmá-lo de
+// novo. Retorna quantos campos mudaram.
+func (m *UserModel) UpdateContact(userID int, name, phone, address, ip string) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var oldName, oldPhone, oldAddress string
+	err = tx.QueryRow(`
+		SELECT name, COALESCE(phone, ''), COALESCE(address, '')
+		FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&oldName, &oldPhone, &oldAddress)
+	if err != nil {
+		return 0, err
+	}
+
+	address = strings.TrimSpace(address)
+	changes := []struct{ field, old, new string }{
+		{ProfileFieldName, oldName, name},
+		{ProfileFieldPhone, oldPhone, phone},
+		{ProfileFieldAddress, oldAddress, address},
+	}
+
+	changed := 0
+	for _, c := range changes {
+		if c.old == c.new {
+			continue
+		}
+		if err := recordProfileChange(tx, userID, c.field, c.old, c.new, ip); err != nil {
+			return 0, err
+		}
+		changed++
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET name = $1, phone = NULLIF($2, ''), address = NULLIF($3, ''),
+		       phone_verified_at = CASE WHEN COALESCE(phone, '') = $2 THEN phone_verified_at END
+		WHERE id = $4`, name, phone, address, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, tx.Commit()
+}
+
+// RequestEmailChange guarda o novo e-mail como pendente. Ele só substitui
+// o atual em ConfirmEmailChange, pelo link enviado ao novo endereço.
+func (m *UserModel) RequestEmailChange(userID int, email, ip string) error {
+	taken, err := m.emailTaken(email, userID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var pending sql.NullString
+	err = tx.QueryRow(`SELECT pending_email FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&pending)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE users SET pending_email = $1 WHERE id = $2`, email, userID); err != nil {
+		return err
+	}
+	if err := recordProfileChange(tx, userID, ProfileFieldPendingEmail, pending.String, email, ip); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CancelEmailChange descarta o e-mail pendente
+func (m *UserModel) CancelEmailChange(userID int) error {
+	_, err := m.DB.Exec(`UPDATE users SET pending_email = NULL WHERE id = $1`, userID)
+	return err
+}
+
+// ConfirmEmailChange troca o e-mail pelo pendente (já confirmado pelo link)
+func (m *UserModel) ConfirmEmailChange(userID int, ip string) (string, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var pending sql.NullString
+	err = tx.QueryRow(`SELECT email, pending_email FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&current, &pending)
+	if err != nil {
+		return "", err
+	}
+	if !pending.Valid {
+		return "", ErrInvalidUserToken
+	}
+
+	var taken bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1) AND id <> $2)`,
+		pending.String, userID).Scan(&taken)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrEmailTaken
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET email = pending_email, pending_email = NULL, email_verified_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, userID)
+	if err != nil {
+		return "", err
+	}
+	if err := recordProfileChange(tx, userID, ProfileFieldEmail, current, pending.String, ip); err != nil {
+		return "", err
+	}
+
+	return pending.String, tx.Commit()
+}
+
+// CheckPassword confere a senha atual do usuário
+func (m *UserModel) CheckPassword(userID int, password string) (bool, error) {
+	var hash string
+	if err := m.DB.QueryRow(`SELECT password FROM users WHERE id = $1`, userID).Scan(&hash); err != nil {
+		return false, err
+	}
+	return m.ValidatePassword(password, hash), nil
+}
+
+// UpdateNotificationPreferences grava os avisos aceitos pelo usuário
+func (m *UserModel) UpdateNotificationPreferences(userID int, prefs NotificationPreferences, ip string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var old NotificationPreferences
+	err = tx.QueryRow(`
+		SELECT notify_request_updates, notify_quotes, notify_warranty, notify_maintenance
+		FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(
+		&old.RequestUpdates, &old.Quotes, &old.Warranty, &old.Maintenance)
+	if err != nil {
+		return err
+	}
+	if old == prefs {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET notify_request_updates = $1, notify_quotes = $2,
+		       notify_warranty = $3, notify_maintenance = $4
+		WHERE id = $5`, prefs.RequestUpdates, prefs.Quotes, prefs.Warranty, prefs.Maintenance, userID)
+	if err != nil {
+		return err
+	}
+	if err := recordProfileChange(tx, userID, ProfileFieldNotifications, old.Summary(), prefs.Summary(), ip); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// WantsNotification indica se o usuário aceita o aviso (ver Notify*)
+func (m *UserModel) WantsNotification(userID int, kind string) (bool, error) {
+	switch kind {
+	case NotifyRequestUpdates, NotifyQuotes, NotifyWarranty, NotifyMaintenance:
+	default:
+		return false, errors.New("tipo de aviso desconhecido: " + kind)
+	}
+
+	var wants bool
+	err := m.DB.QueryRow(`SELECT `+kind+` FROM users WHERE id = $1`, userID).Scan(&wants)
+	return wants, err
+}
+
+// RecordProfileChange registra no histórico uma alteração feita fora dos
+// métodos acima (ex.: troca de senha)
+func (m *UserModel) RecordProfileChange(userID int, field, oldValue, newValue, ip string) error {
+	return recordProfileChange(m.DB, userID, field, oldValue, newValue, ip)
+}
+
+// GetProfileChanges retorna as alterações mais recentes do perfil
+func (m *UserModel) GetProfileChanges(userID, limit int) ([]ProfileChange, error) {
+	rows, err := m.DB.Query(`
+		SELECT id, field, old_value, new_value, ip, created_at
+		FROM user_profile_changes
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []ProfileChange
+	for rows.Next() {
+		var c ProfileChange
+		if err := rows.Scan(&c.ID, &c.Field, &c.OldValue, &c.NewValue, &c.IP, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+func (m *UserModel) emailTaken(email string, exceptUserID int) (bool, error) {
+	var taken bool
+	err := m.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1) AND id <> $2)`,
+		email, exceptUserID).Scan(&taken)
+	return taken, err
+}
+
+func recordProfileChange(db interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, userID int, field, oldValue, newValue, ip string) error {
+	_, err := db.Exec(`
+		INSERT INTO user_profile_changes (user_id, field, old_value, new_value, ip)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''))`,
+		userID, field, oldValue, newValue, ip)
+	return err
+}
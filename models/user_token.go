@@ -0,0 +1,97 @@
+package models
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+// Finalidades dos links enviados por e-mail
+const (
+	UserTokenPasswordReset = "RESET_SENHA"
+	UserTokenVerifyEmail   = "VERIFICAR_EMAIL"
+	UserTokenEmailChange   = "TROCAR_EMAIL"
+)
+
+// Validade dos links
+const (
+	PasswordResetTTL = time.Hour
+	VerifyEmailTTL   = 48 * time.Hour
+	EmailChangeTTL   = 48 * time.Hour
+)
+
+var ErrInvalidUserToken = errors.New("link inválido, expirado ou já utilizado")
+
+type UserTokenModel struct {
+	DB *sql.DB
+}
+
+func NewUserTokenModel(db *sql.DB) *UserTokenModel {
+	return &UserTokenModel{DB: db}
+}
+
+// Create gera um link de uso único para o usuário e invalida os anteriores
+// da mesma finalidade. Retorna o token em claro, que vai apenas no e-mail.
+func (m *UserTokenModel) Create(userID int, purpose string, ttl time.Duration) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	plain := base64.RawURLEncoding.EncodeToString(buf)
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`, userID, purpose)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_tokens (user_id, purpose, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)`,
+		userID, purpose, hashAPIToken(plain), time.Now().Add(ttl))
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// Consume marca o token como usado e retorna o usuário dono dele. Tokens
+// expirados, já usados ou de outra finalidade retornam ErrInvalidUserToken.
+func (m *UserTokenModel) Consume(plain, purpose string) (int, error) {
+	var userID int
+	err := m.DB.QueryRow(`
+		UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id`, hashAPIToken(plain), purpose).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidUserToken
+	}
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+// IsValid indica se o token ainda pode ser usado, sem consumi-lo (para
+// exibir o formulário de nova senha)
+func (m *UserTokenModel) IsValid(plain, purpose string) (bool, error) {
+	var exists bool
+	err := m.DB.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP)`,
+		hashAPIToken(plain), purpose).Scan(&exists)
+	return exists, err
+}
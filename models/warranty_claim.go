@@ -0,0 +1,436 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"martins-pocos/constants"
+)
+
+// Situações de uma reclamação de garantia
+const (
+	ClaimStatusOpen     = "ABERTA"
+	ClaimStatusApproved = "APROVADA"
+	ClaimStatusRejected = "REJEITADA"
+)
+
+// Códigos dos tipos de garantia (seed de guarantee_types)
+const (
+	GuaranteeSecondAttempt = "SEGUNDA_TENTATIVA"
+	GuaranteeNone          = "SEM_GARANTIA"
+	GuaranteeCustom        = "PERSONALIZADA"
+)
+
+var (
+	ErrClaimAlreadyOpen = errors.New("já existe uma reclamação em análise para este contrato")
+	ErrClaimNotOpen     = errors.New("reclamação já foi analisada")
+)
+
+// WarrantyClaim é uma reclamação de garantia aberta pelo cliente sobre um contrato assinado
+type WarrantyClaim struct {
+	ID                 int            `json:"id"`
+	ContractID         int            `json:"contract_id"`
+	UserID             int            `json:"user_id"`
+	Description        string         `json:"description"`
+	Status             string         `json:"status"`
+	CoverageNote       sql.NullString `json:"coverage_note"`
+	DecisionReason     sql.NullString `json:"decision_reason"`
+	DecidedBy          sql.NullInt64  `json:"decided_by"`
+	DecidedAt          sql.NullTime   `json:"decided_at"`
+	FollowUpRequestID  sql.NullInt64  `json:"follow_up_request_id"`
+	FollowUpContractID sql.NullInt64  `json:"follow_up_contract_id"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+
+	// Campos relacionados expandidos
+	ContractNumber         string `json:"contract_number,omitempty"`
+	GuaranteeCode          string `json:"guarantee_code,omitempty"`
+	GuaranteeName          string `json:"guarantee_name,omitempty"`
+	ClientName             string `json:"client_name,omitempty"`
+	ClientPhone            string `json:"client_phone,omitempty"`
+	DeciderName            string `json:"decider_name,omitempty"`
+	FollowUpContractNumber string `json:"follow_up_contract_number,omitempty"`
+}
+
+// StatusLabel retorna o nome da situação para exibição
+func (c *WarrantyClaim) StatusLabel() string {
+	switch c.Status {
+	case ClaimStatusApproved:
+		return "Aprovada"
+	case ClaimStatusRejected:
+		return "Rejeitada"
+	default:
+		return "Em análise"
+	}
+}
+
+// StatusBadge retorna a classe do badge Bootstrap da situação
+func (c *WarrantyClaim) StatusBadge() string {
+	switch c.Status {
+	case ClaimStatusApproved:
+		return "bg-success"
+	case ClaimStatusRejected:
+		return "bg-danger"
+	default:
+		return "bg-warning text-dark"
+	}
+}
+
+// IsOpen indica se a reclamação ainda aguarda decisão
+func (c *WarrantyClaim) IsOpen() bool {
+	return c.Status == ClaimStatusOpen
+}
+
+// WarrantyCoverage é o resultado da verificação de cobertura de um contrato
+type WarrantyCoverage struct {
+	Covered   bool
+	Note      string
+	ExpiresAt sql.NullTime
+}
+
+// EvaluateWarrantyCoverage verifica se o contrato está coberto pela garantia na data informada.
+// O prazo conta a partir da última assinatura (quando o contrato passou a valer).
+func EvaluateWarrantyCoverage(contract *Contract, months int, at time.Time) WarrantyCoverage {
+	if contract.Status == nil || contract.Status.Code != "ASSINADO" {
+		return WarrantyCoverage{Note: "A garantia só pode ser acionada em contratos assinados por ambas as partes."}
+	}
+	if contract.GuaranteeType == nil || contract.GuaranteeType.Code == GuaranteeNone {
+		return WarrantyCoverage{Note: "Este contrato foi firmado sem garantia."}
+	}
+	if months <= 0 {
+		return WarrantyCoverage{Note: "O tipo de garantia deste contrato não possui prazo de cobertura."}
+	}
+
+	signedAt := contract.UpdatedAt
+	if contract.ClientSignedAt.Valid {
+		signedAt = contract.ClientSignedAt.Time
+	}
+	if contract.CompanySignedAt.Valid && contract.CompanySignedAt.Time.After(signedAt) {
+		signedAt = contract.CompanySignedAt.Time
+	}
+
+	expires := AddMonths(truncateDay(signedAt), months)
+	coverage := WarrantyCoverage{ExpiresAt: sql.NullTime{Time: expires, Valid: true}}
+
+	if truncateDay(at).After(expires) {
+		coverage.Note = fmt.Sprintf("O prazo de garantia encerrou em %s.", expires.Format("02/01/2006"))
+		return coverage
+	}
+
+	coverage.Covered = true
+	switch contract.GuaranteeType.Code {
+	case GuaranteeSecondAttempt:
+		coverage.Note = fmt.Sprintf("Garantia de segunda tentativa válida até %s. Se aprovada, uma nova tentativa é agendada sem custo.",
+			expires.Format("02/01/2006"))
+	case GuaranteeCustom:
+		terms := "conforme contrato"
+		if contract.GuaranteeCustom.Valid {
+			terms = contract.GuaranteeCustom.String
+		}
+		coverage.Note = fmt.Sprintf("Garantia personalizada válida até %s: %s", expires.Format("02/01/2006"), terms)
+	default:
+		coverage.Note = fmt.Sprintf("Garantia válida até %s.", expires.Format("02/01/2006"))
+	}
+	return coverage
+}
+
+type WarrantyClaimModel struct {
+	DB *sql.DB
+}
+
+func NewWarrantyClaimModel(db *sql.DB) *WarrantyClaimModel {
+	return &WarrantyClaimModel{DB: db}
+}
+
+// WarrantyMonths retorna o prazo de cobertura do tipo de garantia, ou fallback se não definido
+func (m *WarrantyClaimModel) WarrantyMonths(guaranteeTypeID, fallback int) (int, error) {
+	var months sql.NullInt64
+	err := m.DB.QueryRow("SELECT warranty_months FROM guarantee_types WHERE id = $1", guaranteeTypeID).Scan(&months)
+	if err != nil {
+		return 0, err
+	}
+	if !months.Valid {
+		return fallback, nil
+	}
+	return int(months.Int64), nil
+}
+
+// Create abre uma reclamação. Só é permitida uma reclamação em análise por contrato.
+func (m *WarrantyClaimModel) Create(claim *WarrantyClaim) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Trava o contrato para que duas reclamações simultâneas não passem pela verificação
+	if _, err := tx.Exec("SELECT id FROM contracts WHERE id = $1 FOR UPDATE", claim.ContractID); err != nil {
+		return err
+	}
+
+	var open int
+	err = tx.QueryRow("SELECT COUNT(*) FROM warranty_claims WHERE contract_id = $1 AND status = $2",
+		claim.ContractID, ClaimStatusOpen).Scan(&open)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return ErrClaimAlreadyOpen
+	}
+
+	claim.Status = ClaimStatusOpen
+	err = tx.QueryRow(`
+		INSERT INTO warranty_claims (contract_id, user_id, description, status, coverage_note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`,
+		claim.ContractID, claim.UserID, claim.Description, claim.Status, claim.CoverageNote,
+	).Scan(&claim.ID, &claim.CreatedAt, &claim.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO contract_history (contract_id, action, changed_by, changed_fields) VALUES ($1, $2, $3, $4)`,
+		claim.ContractID, "GARANTIA_ACIONADA", claim.UserID, fmt.Sprintf("Reclamação de garantia #%d aberta", claim.ID)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Approve aprova a reclamação. Na garantia de segunda tentativa, gera uma nova
+// solicitação (cópia do endereço da original) e um contrato de valor zero vinculados.
+func (m *WarrantyClaimModel) Approve(claimID int, reason string, actor Actor) (*WarrantyClaim, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		contractID, requestID int
+		status, description   string
+		contractNumber        string
+		guaranteeCode         string
+	)
+	err = tx.QueryRow(`
+		SELECT wc.contract_id, wc.status, wc.description, c.service_request_id, c.contract_number, gt.code
+		FROM warranty_claims wc
+		JOIN contracts c ON wc.contract_id = c.id
+		JOIN guarantee_types gt ON c.guarantee_type_id = gt.id
+		WHERE wc.id = $1
+		FOR UPDATE OF wc`, claimID).Scan(&contractID, &status, &description, &requestID, &contractNumber, &guaranteeCode)
+	if err != nil {
+		return nil, err
+	}
+	if status != ClaimStatusOpen {
+		return nil, ErrClaimNotOpen
+	}
+
+	before, err := auditSnapshot(tx, AuditEntityWarrantyClaim, claimID)
+	if err != nil {
+		return nil, err
+	}
+
+	var followUpRequest, followUpContract sql.NullInt64
+	if guaranteeCode == GuaranteeSecondAttempt {
+		var newRequestID int
+		err = tx.QueryRow(`
+			INSERT INTO service_requests (
+				user_id, full_name, service_type_id, description, cep, logradouro,
+				numero, bairro, cidade, estado, preferred_date, preferred_time, status_id,
+				latitude, longitude
+			)
+			SELECT user_id, full_name, service_type_id, $1, cep, logradouro,
+				numero, bairro, cidade, estado, CURRENT_DATE, preferred_time, $2,
+				latitude, longitude
+			FROM service_requests WHERE id = $3
+			RETURNING id`,
+			fmt.Sprintf("Segunda tentativa (garantia do contrato %s)\n\nReclamação: %s", contractNumber, description),
+			constants.StatusSolicitada, requestID,
+		).Scan(&newRequestID)
+		if err != nil {
+			return nil, err
+		}
+		followUpRequest = sql.NullInt64{Int64: int64(newRequestID), Valid: true}
+
+		// O novo contrato não gera nova garantia: a segunda tentativa é a própria garantia
+		var newContractID int
+		err = tx.QueryRow(`
+			INSERT INTO contracts (
+				service_request_id, contract_number, total_value, payment_conditions,
+				guarantee_type_id, additional_notes, status_id
+			)
+			SELECT $1, $2, 0, $3,
+				(SELECT id FROM guarantee_types WHERE code = $4),
+				$5,
+				(SELECT id FROM contract_status WHERE code = 'RASCUNHO')
+			RETURNING id`,
+			newRequestID, nextContractNumber(tx),
+			fmt.Sprintf("Sem custo - segunda tentativa coberta pela garantia do contrato %s.", contractNumber),
+			GuaranteeNone,
+			fmt.Sprintf("Gerado pela reclamação de garantia #%d.", claimID),
+		).Scan(&newContractID)
+		if err != nil {
+			return nil, err
+		}
+		followUpContract = sql.NullInt64{Int64: int64(newContractID), Valid: true}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE warranty_claims
+		SET status = $1, decision_reason = $2, decided_by = $3, decided_at = CURRENT_TIMESTAMP,
+		    follow_up_request_id = $4, follow_up_contract_id = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6`,
+		ClaimStatusApproved, nullableString(reason), actor.UserID, followUpRequest, followUpContract, claimID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO contract_history (contract_id, action, changed_by, changed_fields) VALUES ($1, $2, $3, $4)`,
+		contractID, "GARANTIA_APROVADA", actor.UserID, fmt.Sprintf("Reclamação de garantia #%d aprovada", claimID)); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, actor, AuditApprove, AuditEntityWarrantyClaim, claimID, before); err != nil {
+		return nil, err
+	}
+	if followUpRequest.Valid {
+		if err := recordAudit(tx, actor, AuditCreate, AuditEntityRequest, int(followUpRequest.Int64), sql.NullString{}); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx, actor, AuditCreate, AuditEntityContract, int(followUpContract.Int64), sql.NullString{}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return m.GetByID(claimID)
+}
+
+// Reject rejeita a reclamação com o motivo informado
+func (m *WarrantyClaimModel) Reject(claimID int, reason string, actor Actor) (*WarrantyClaim, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, AuditEntityWarrantyClaim, claimID)
+	if err != nil {
+		return nil, err
+	}
+
+	var contractID int
+	err = tx.QueryRow(`
+		UPDATE warranty_claims
+		SET status = $1, decision_reason = $2, decided_by = $3, decided_at = CURRENT_TIMESTAMP,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4 AND status = $5
+		RETURNING contract_id`,
+		ClaimStatusRejected, reason, actor.UserID, claimID, ClaimStatusOpen).Scan(&contractID)
+	if err == sql.ErrNoRows {
+		return nil, ErrClaimNotOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO contract_history (contract_id, action, changed_by, changed_fields) VALUES ($1, $2, $3, $4)`,
+		contractID, "GARANTIA_REJEITADA", actor.UserID, fmt.Sprintf("Reclamação de garantia #%d rejeitada: %s", claimID, reason)); err != nil {
+		return nil, err
+	}
+
+	if err := recordAudit(tx, actor, AuditReject, AuditEntityWarrantyClaim, claimID, before); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return m.GetByID(claimID)
+}
+
+const warrantyClaimSelectColumns = `
+	SELECT wc.id, wc.contract_id, wc.user_id, wc.description, wc.status, wc.coverage_note,
+	       wc.decision_reason, wc.decided_by, wc.decided_at,
+	       wc.follow_up_request_id, wc.follow_up_contract_id, wc.created_at, wc.updated_at,
+	       c.contract_number, gt.code, gt.name, u.name, COALESCE(u.phone, ''),
+	       COALESCE(d.name, ''), COALESCE(fc.contract_number, '')
+	FROM warranty_claims wc
+	JOIN contracts c ON wc.contract_id = c.id
+	JOIN guarantee_types gt ON c.guarantee_type_id = gt.id
+	JOIN users u ON wc.user_id = u.id
+	LEFT JOIN users d ON wc.decided_by = d.id
+	LEFT JOIN contracts fc ON wc.follow_up_contract_id = fc.id`
+
+func scanWarrantyClaim(scanner interface{ Scan(...interface{}) error }, claim *WarrantyClaim) error {
+	return scanner.Scan(
+		&claim.ID, &claim.ContractID, &claim.UserID, &claim.Description, &claim.Status, &claim.CoverageNote,
+		&claim.DecisionReason, &claim.DecidedBy, &claim.DecidedAt,
+		&claim.FollowUpRequestID, &claim.FollowUpContractID, &claim.CreatedAt, &claim.UpdatedAt,
+		&claim.ContractNumber, &claim.GuaranteeCode, &claim.GuaranteeName, &claim.ClientName, &claim.ClientPhone,
+		&claim.DeciderName, &claim.FollowUpContractNumber,
+	)
+}
+
+// GetByID busca uma reclamação pelo ID
+func (m *WarrantyClaimModel) GetByID(id int) (*WarrantyClaim, error) {
+	claim := &WarrantyClaim{}
+	if err := scanWarrantyClaim(m.DB.QueryRow(warrantyClaimSelectColumns+" WHERE wc.id = $1", id), claim); err != nil {
+		return nil, err
+	}
+	return claim, nil
+}
+
+// GetByContractID lista as reclamações de um contrato, da mais recente para a mais antiga
+func (m *WarrantyClaimModel) GetByContractID(contractID int) ([]WarrantyClaim, error) {
+	return m.query(warrantyClaimSelectColumns+" WHERE wc.contract_id = $1 ORDER BY wc.created_at DESC", contractID)
+}
+
+// GetByUserID lista as reclamações de um cliente
+func (m *WarrantyClaimModel) GetByUserID(userID int) ([]WarrantyClaim, error) {
+	return m.query(warrantyClaimSelectColumns+" WHERE wc.user_id = $1 ORDER BY wc.created_at DESC", userID)
+}
+
+// GetAll lista as reclamações, opcionalmente filtradas pela situação (em análise primeiro)
+func (m *WarrantyClaimModel) GetAll(status string) ([]WarrantyClaim, error) {
+	query := warrantyClaimSelectColumns + ` WHERE ($1 = '' OR wc.status = $1)
+		ORDER BY (wc.status = 'ABERTA') DESC, wc.created_at DESC`
+	return m.query(query, status)
+}
+
+// CountOpen retorna quantas reclamações aguardam decisão
+func (m *WarrantyClaimModel) CountOpen() (int, error) {
+	var count int
+	err := m.DB.QueryRow("SELECT COUNT(*) FROM warranty_claims WHERE status = $1", ClaimStatusOpen).Scan(&count)
+	return count, err
+}
+
+func (m *WarrantyClaimModel) query(query string, args ...interface{}) ([]WarrantyClaim, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var claims []WarrantyClaim
+	for rows.Next() {
+		var claim WarrantyClaim
+		if err := scanWarrantyClaim(rows, &claim); err != nil {
+			return nil, err
+		}
+		claims = append(claims, claim)
+	}
+	return claims, rows.Err()
+}
+
+func nullableString(s string) sql.NullString {
+	if s == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: s, Valid: true}
+}
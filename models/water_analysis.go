@@ -0,0 +1,421 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WaterParameter é um parâmetro de potabilidade com os limites da
+// Portaria GM/MS nº 888/2021. Parâmetros de presença/ausência usam
+// 0 = ausente e 1 = presente, com limite máximo 0.
+type WaterParameter struct {
+	Code            string
+	Name            string
+	Unit            string
+	MinLimit        sql.NullFloat64
+	MaxLimit        sql.NullFloat64
+	PresenceAbsence bool
+}
+
+func referenceLimit(v float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: v, Valid: true}
+}
+
+// WaterParameters é o catálogo de parâmetros disponíveis no laudo, na ordem de exibição
+var WaterParameters = []WaterParameter{
+	{Code: "coliformes_totais", Name: "Coliformes totais", Unit: "P/A", MaxLimit: referenceLimit(0), PresenceAbsence: true},
+	{Code: "e_coli", Name: "Escherichia coli", Unit: "P/A", MaxLimit: referenceLimit(0), PresenceAbsence: true},
+	{Code: "ph", Name: "pH", Unit: "-", MinLimit: referenceLimit(6.0), MaxLimit: referenceLimit(9.0)},
+	{Code: "turbidez", Name: "Turbidez", Unit: "uT", MaxLimit: referenceLimit(5)},
+	{Code: "cor", Name: "Cor aparente", Unit: "uH", MaxLimit: referenceLimit(15)},
+	{Code: "nitrato", Name: "Nitrato (como N)", Unit: "mg/L", MaxLimit: referenceLimit(10)},
+	{Code: "nitrito", Name: "Nitrito (como N)", Unit: "mg/L", MaxLimit: referenceLimit(1)},
+	{Code: "ferro", Name: "Ferro", Unit: "mg/L", MaxLimit: referenceLimit(0.3)},
+	{Code: "manganes", Name: "Manganês", Unit: "mg/L", MaxLimit: referenceLimit(0.1)},
+	{Code: "dureza", Name: "Dureza total", Unit: "mg/L CaCO3", MaxLimit: referenceLimit(300)},
+	{Code: "cloreto", Name: "Cloreto", Unit: "mg/L", MaxLimit: referenceLimit(250)},
+	{Code: "fluoreto", Name: "Fluoreto", Unit: "mg/L", MaxLimit: referenceLimit(1.5)},
+	{Code: "sulfato", Name: "Sulfato", Unit: "mg/L", MaxLimit: referenceLimit(250)},
+	{Code: "sodio", Name: "Sódio", Unit: "mg/L", MaxLimit: referenceLimit(200)},
+	{Code: "std", Name: "Sólidos dissolvidos totais", Unit: "mg/L", MaxLimit: referenceLimit(500)},
+	{Code: "condutividade", Name: "Condutividade elétrica", Unit: "µS/cm"},
+}
+
+// GetWaterParameter busca um parâmetro do catálogo pelo código
+func GetWaterParameter(code string) (WaterParameter, bool) {
+	for _, p := range WaterParameters {
+		if p.Code == code {
+			return p, true
+		}
+	}
+	return WaterParameter{}, false
+}
+
+// LimitDescription descreve a faixa de referência (ex: "6,0 a 9,0", "≤ 5", "Ausência")
+func (p WaterParameter) LimitDescription() string {
+	return describeLimits(p.MinLimit, p.MaxLimit, p.PresenceAbsence)
+}
+
+// WaterAnalysisResult é o valor medido de um parâmetro no laudo
+type WaterAnalysisResult struct {
+	ID            int             `json:"id"`
+	AnalysisID    int             `json:"analysis_id"`
+	ParameterCode string          `json:"parameter_code"`
+	Value         float64         `json:"value"`
+	Unit          string          `json:"unit"`
+	MinLimit      sql.NullFloat64 `json:"min_limit"`
+	MaxLimit      sql.NullFloat64 `json:"max_limit"`
+
+	// Preenchidos a partir do catálogo
+	ParameterName   string `json:"parameter_name,omitempty"`
+	PresenceAbsence bool   `json:"presence_absence,omitempty"`
+}
+
+// Compliant indica se o valor está dentro dos limites de referência
+func (r *WaterAnalysisResult) Compliant() bool {
+	if r.MinLimit.Valid && r.Value < r.MinLimit.Float64 {
+		return false
+	}
+	if r.MaxLimit.Valid && r.Value > r.MaxLimit.Float64 {
+		return false
+	}
+	return true
+}
+
+// HasLimit indica se o parâmetro possui limite de referência
+func (r *WaterAnalysisResult) HasLimit() bool {
+	return r.MinLimit.Valid || r.MaxLimit.Valid
+}
+
+// DisplayValue formata o valor para o laudo
+func (r *WaterAnalysisResult) DisplayValue() string {
+	if r.PresenceAbsence {
+		if r.Value > 0 {
+			return "Presença"
+		}
+		return "Ausência"
+	}
+	return formatDecimal(r.Value)
+}
+
+// LimitDescription descreve a faixa de referência do resultado
+func (r *WaterAnalysisResult) LimitDescription() string {
+	return describeLimits(r.MinLimit, r.MaxLimit, r.PresenceAbsence)
+}
+
+// WaterAnalysis é um laudo de análise da água de um cliente
+type WaterAnalysis struct {
+	ID               int            `json:"id"`
+	UserID           int            `json:"user_id"`
+	ServiceRequestID sql.NullInt64  `json:"service_request_id"`
+	WellID           sql.NullInt64  `json:"well_id"`
+	SampleDate       time.Time      `json:"sample_date"`
+	Laboratory       sql.NullString `json:"laboratory"`
+	ReportNumber     sql.NullString `json:"report_number"`
+	Notes            sql.NullString `json:"notes"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+
+	// Campos relacionados expandidos
+	OwnerName          string                `json:"owner_name,omitempty"`
+	OwnerEmail         string                `json:"owner_email,omitempty"`
+	WellIdentification string                `json:"well_identification,omitempty"`
+	ResultCount        int                   `json:"result_count"`
+	NonCompliantCount  int                   `json:"non_compliant_count"`
+	Results            []WaterAnalysisResult `json:"results,omitempty"`
+}
+
+// Compliant indica se todos os parâmetros analisados atendem ao padrão de potabilidade
+func (a *WaterAnalysis) Compliant() bool {
+	return a.NonCompliantCount == 0
+}
+
+// NewWaterAnalysisResult cria um resultado copiando unidade e limites do catálogo
+func NewWaterAnalysisResult(parameter WaterParameter, value float64) WaterAnalysisResult {
+	return WaterAnalysisResult{
+		ParameterCode:   parameter.Code,
+		Value:           value,
+		Unit:            parameter.Unit,
+		MinLimit:        parameter.MinLimit,
+		MaxLimit:        parameter.MaxLimit,
+		ParameterName:   parameter.Name,
+		PresenceAbsence: parameter.PresenceAbsence,
+	}
+}
+
+type WaterAnalysisModel struct {
+	DB *sql.DB
+}
+
+func NewWaterAnalysisModel(db *sql.DB) *WaterAnalysisModel {
+	return &WaterAnalysisModel{DB: db}
+}
+
+const waterAnalysisSelectColumns = `
+		SELECT a.id, a.user_id, a.service_request_id, a.well_id, a.sample_date,
+		       a.laboratory, a.report_number, a.notes, a.created_at, a.updated_at,
+		       u.name, u.email, COALESCE(w.identification, ''),
+		       (SELECT COUNT(*) FROM water_analysis_results r WHERE r.analysis_id = a.id),
+		       (SELECT COUNT(*) FROM water_analysis_results r WHERE r.analysis_id = a.id
+		          AND ((r.min_limit IS NOT NULL AND r.value < r.min_limit)
+		            OR (r.max_limit IS NOT NULL AND r.value > r.max_limit)))
+		FROM water_analyses a
+		JOIN users u ON a.user_id = u.id
+		LEFT JOIN wells w ON a.well_id = w.id`
+
+func scanWaterAnalysis(scanner interface{ Scan(...interface{}) error }, a *WaterAnalysis) error {
+	return scanner.Scan(
+		&a.ID, &a.UserID, &a.ServiceRequestID, &a.WellID, &a.SampleDate,
+		&a.Laboratory, &a.ReportNumber, &a.Notes, &a.CreatedAt, &a.UpdatedAt,
+		&a.OwnerName, &a.OwnerEmail, &a.WellIdentification,
+		&a.ResultCount, &a.NonCompliantCount,
+	)
+}
+
+// Create registra o laudo e seus resultados
+func (m *WaterAnalysisModel) Create(analysis *WaterAnalysis, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO water_analyses (user_id, service_request_id, well_id, sample_date, laboratory, report_number, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at`
+
+	err = tx.QueryRow(
+		query,
+		analysis.UserID, analysis.ServiceRequestID, analysis.WellID, analysis.SampleDate,
+		analysis.Laboratory, analysis.ReportNumber, analysis.Notes,
+	).Scan(&analysis.ID, &analysis.CreatedAt, &analysis.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := insertWaterResults(tx, analysis); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, actor, AuditCreate, AuditEntityAnalysis, analysis.ID, sql.NullString{}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update atualiza o laudo e substitui todos os resultados
+func (m *WaterAnalysisModel) Update(analysis *WaterAnalysis, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(tx, AuditEntityAnalysis, analysis.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE water_analyses SET
+			well_id = $1, sample_date = $2, laboratory = $3, report_number = $4, notes = $5,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $6`
+
+	result, err := tx.Exec(
+		query,
+		analysis.WellID, analysis.SampleDate, analysis.Laboratory,
+		analysis.ReportNumber, analysis.Notes, analysis.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := tx.Exec(`DELETE FROM water_analysis_results WHERE analysis_id = $1`, analysis.ID); err != nil {
+		return err
+	}
+
+	if err := insertWaterResults(tx, analysis); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, actor, AuditUpdate, AuditEntityAnalysis, analysis.ID, before); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertWaterResults(tx *sql.Tx, analysis *WaterAnalysis) error {
+	query := `
+		INSERT INTO water_analysis_results (analysis_id, parameter_code, value, unit, min_limit, max_limit)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+
+	for i := range analysis.Results {
+		result := &analysis.Results[i]
+		result.AnalysisID = analysis.ID
+		err := tx.QueryRow(
+			query,
+			analysis.ID, result.ParameterCode, result.Value, result.Unit, result.MinLimit, result.MaxLimit,
+		).Scan(&result.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetByID busca um laudo com seus resultados
+func (m *WaterAnalysisModel) GetByID(id int) (*WaterAnalysis, error) {
+	analysis := &WaterAnalysis{}
+	row := m.DB.QueryRow(waterAnalysisSelectColumns+` WHERE a.id = $1`, id)
+	if err := scanWaterAnalysis(row, analysis); err != nil {
+		return nil, err
+	}
+	return analysis, m.loadResults(analysis)
+}
+
+// GetByIDAndUser busca um laudo garantindo que pertence ao cliente
+func (m *WaterAnalysisModel) GetByIDAndUser(id, userID int) (*WaterAnalysis, error) {
+	analysis := &WaterAnalysis{}
+	row := m.DB.QueryRow(waterAnalysisSelectColumns+` WHERE a.id = $1 AND a.user_id = $2`, id, userID)
+	if err := scanWaterAnalysis(row, analysis); err != nil {
+		return nil, err
+	}
+	return analysis, m.loadResults(analysis)
+}
+
+// GetByServiceRequestID busca o laudo gerado por uma solicitação
+func (m *WaterAnalysisModel) GetByServiceRequestID(serviceRequestID int) (*WaterAnalysis, error) {
+	analysis := &WaterAnalysis{}
+	row := m.DB.QueryRow(waterAnalysisSelectColumns+` WHERE a.service_request_id = $1`, serviceRequestID)
+	err := scanWaterAnalysis(row, analysis)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return analysis, m.loadResults(analysis)
+}
+
+// GetByUserID retorna os laudos do cliente, mais recentes primeiro
+func (m *WaterAnalysisModel) GetByUserID(userID int) ([]WaterAnalysis, error) {
+	return m.query(waterAnalysisSelectColumns+` WHERE a.user_id = $1 ORDER BY a.sample_date DESC, a.id DESC`, userID)
+}
+
+// GetAll retorna todos os laudos com paginação
+func (m *WaterAnalysisModel) GetAll(limit, offset int) ([]WaterAnalysis, int, error) {
+	var total int
+	if err := m.DB.QueryRow(`SELECT COUNT(*) FROM water_analyses`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	analyses, err := m.query(waterAnalysisSelectColumns+` ORDER BY a.sample_date DESC, a.id DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return analyses, total, nil
+}
+
+// GetWellHistory retorna os laudos do poço em ordem cronológica, com resultados
+func (m *WaterAnalysisModel) GetWellHistory(wellID int) ([]WaterAnalysis, error) {
+	analyses, err := m.query(waterAnalysisSelectColumns+` WHERE a.well_id = $1 ORDER BY a.sample_date, a.id`, wellID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range analyses {
+		if err := m.loadResults(&analyses[i]); err != nil {
+			return nil, err
+		}
+	}
+	return analyses, nil
+}
+
+func (m *WaterAnalysisModel) loadResults(analysis *WaterAnalysis) error {
+	rows, err := m.DB.Query(`
+		SELECT id, analysis_id, parameter_code, value, unit, min_limit, max_limit
+		FROM water_analysis_results
+		WHERE analysis_id = $1`, analysis.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byCode := make(map[string]WaterAnalysisResult)
+	for rows.Next() {
+		var r WaterAnalysisResult
+		if err := rows.Scan(&r.ID, &r.AnalysisID, &r.ParameterCode, &r.Value, &r.Unit, &r.MinLimit, &r.MaxLimit); err != nil {
+			return err
+		}
+		r.ParameterName = r.ParameterCode
+		if p, ok := GetWaterParameter(r.ParameterCode); ok {
+			r.ParameterName = p.Name
+			r.PresenceAbsence = p.PresenceAbsence
+		}
+		byCode[r.ParameterCode] = r
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Mantém a ordem do catálogo
+	analysis.Results = nil
+	for _, p := range WaterParameters {
+		if r, ok := byCode[p.Code]; ok {
+			analysis.Results = append(analysis.Results, r)
+			delete(byCode, p.Code)
+		}
+	}
+	for _, r := range byCode {
+		analysis.Results = append(analysis.Results, r)
+	}
+	return nil
+}
+
+func (m *WaterAnalysisModel) query(query string, args ...interface{}) ([]WaterAnalysis, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analyses []WaterAnalysis
+	for rows.Next() {
+		var analysis WaterAnalysis
+		if err := scanWaterAnalysis(rows, &analysis); err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, analysis)
+	}
+	return analyses, nil
+}
+
+func describeLimits(min, max sql.NullFloat64, presenceAbsence bool) string {
+	switch {
+	case presenceAbsence:
+		return "Ausência"
+	case min.Valid && max.Valid:
+		return fmt.Sprintf("%s a %s", formatDecimal(min.Float64), formatDecimal(max.Float64))
+	case max.Valid:
+		return "≤ " + formatDecimal(max.Float64)
+	case min.Valid:
+		return "≥ " + formatDecimal(min.Float64)
+	default:
+		return "—"
+	}
+}
+
+// formatDecimal formata com vírgula decimal, sem zeros à direita
+func formatDecimal(v float64) string {
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.4f", v), "0"), ".")
+	return strings.Replace(s, ".", ",", 1)
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Well representa um poço perfurado e sua ficha técnica
+type Well struct {
+	ID               int             `json:"id"`
+	UserID           int             `json:"user_id"`
+	ServiceRequestID sql.NullInt64   `json:"service_request_id"`
+	ContractID       sql.NullInt64   `json:"contract_id"`
+	Identification   string          `json:"identification"`
+	DepthM           sql.NullFloat64 `json:"depth_m"`
+	DiameterMm       sql.NullFloat64 `json:"diameter_mm"`
+	StaticLevelM     sql.NullFloat64 `json:"static_level_m"`
+	DynamicLevelM    sql.NullFloat64 `json:"dynamic_level_m"`
+	FlowRateM3h      sql.NullFloat64 `json:"flow_rate_m3h"`
+	CasingMaterial   sql.NullString  `json:"casing_material"`
+	PumpModel        sql.NullString  `json:"pump_model"`
+	Latitude         sql.NullFloat64 `json:"latitude"`
+	Longitude        sql.NullFloat64 `json:"longitude"`
+	DrillingDate     sql.NullTime    `json:"drilling_date"`
+	Notes            sql.NullString  `json:"notes"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+
+	// Campos relacionados expandidos
+	OwnerName      string `json:"owner_name,omitempty"`
+	OwnerEmail     string `json:"owner_email,omitempty"`
+	ContractNumber string `json:"contract_number,omitempty"`
+	Address        string `json:"address,omitempty"`
+}
+
+// HasCoordinates indica se o poço possui localização GPS
+func (w *Well) HasCoordinates() bool {
+	return w.Latitude.Valid && w.Longitude.Valid
+}
+
+// DrawdownM retorna o rebaixamento (nível dinâmico - nível estático)
+func (w *Well) DrawdownM() float64 {
+	if !w.StaticLevelM.Valid || !w.DynamicLevelM.Valid {
+		return 0
+	}
+	return w.DynamicLevelM.Float64 - w.StaticLevelM.Float64
+}
+
+type WellModel struct {
+	DB *sql.DB
+}
+
+func NewWellModel(db *sql.DB) *WellModel {
+	return &WellModel{DB: db}
+}
+
+const wellSelectColumns = `
+		SELECT w.id, w.user_id, w.service_request_id, w.contract_id, w.identification,
+		       w.depth_m, w.diameter_mm, w.static_level_m, w.dynamic_level_m, w.flow_rate_m3h,
+		       w.casing_material, w.pump_model, w.latitude, w.longitude, w.drilling_date,
+		       w.notes, w.created_at, w.updated_at,
+		       u.name, u.email, COALESCE(c.contract_number, ''),
+		       COALESCE(sr.logradouro || ', ' || sr.numero || ' - ' || sr.bairro || ', ' || sr.cidade || '/' || sr.estado, '')
+		FROM wells w
+		JOIN users u ON w.user_id = u.id
+		LEFT JOIN contracts c ON w.contract_id = c.id
+		LEFT JOIN service_requests sr ON w.service_request_id = sr.id`
+
+func scanWell(scanner interface{ Scan(...interface{}) error }, well *Well) error {
+	return scanner.Scan(
+		&well.ID, &well.UserID, &well.ServiceRequestID, &well.ContractID, &well.Identification,
+		&well.DepthM, &well.DiameterMm, &well.StaticLevelM, &well.DynamicLevelM, &well.FlowRateM3h,
+		&well.CasingMaterial, &well.PumpModel, &well.Latitude, &well.Longitude, &well.DrillingDate,
+		&well.Notes, &well.CreatedAt, &well.UpdatedAt,
+		&well.OwnerName, &well.OwnerEmail, &well.ContractNumber, &well.Address,
+	)
+}
+
+// Create registra um novo poço
+func (m *WellModel) Create(well *Well, actor Actor) error {
+	query := `
+		INSERT INTO wells (
+			user_id, service_request_id, contract_id, identification, depth_m, diameter_mm,
+			static_level_m, dynamic_level_m, flow_rate_m3h, casing_material, pump_model,
+			latitude, longitude, drilling_date, notes
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at, updated_at`
+
+	return withAudit(m.DB, actor, AuditCreate, AuditEntityWell, 0, func(tx *sql.Tx) (int, error) {
+		err := tx.QueryRow(
+			query,
+			well.UserID, well.ServiceRequestID, well.ContractID, well.Identification,
+			well.DepthM, well.DiameterMm, well.StaticLevelM, well.DynamicLevelM, well.FlowRateM3h,
+			well.CasingMaterial, well.PumpModel, well.Latitude, well.Longitude,
+			well.DrillingDate, well.Notes,
+		).Scan(&well.ID, &well.CreatedAt, &well.UpdatedAt)
+		return well.ID, err
+	})
+}
+
+// Update atualiza a ficha técnica do poço
+func (m *WellModel) Update(well *Well, actor Actor) error {
+	query := `
+		UPDATE wells SET
+			identification = $1, depth_m = $2, diameter_mm = $3, static_level_m = $4,
+			dynamic_level_m = $5, flow_rate_m3h = $6, casing_material = $7, pump_model = $8,
+			latitude = $9, longitude = $10, drilling_date = $11, notes = $12, contract_id = $13,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $14`
+
+	return withAudit(m.DB, actor, AuditUpdate, AuditEntityWell, well.ID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(
+			query,
+			well.Identification, well.DepthM, well.DiameterMm, well.StaticLevelM,
+			well.DynamicLevelM, well.FlowRateM3h, well.CasingMaterial, well.PumpModel,
+			well.Latitude, well.Longitude, well.DrillingDate, well.Notes, well.ContractID,
+			well.ID,
+		))
+	})
+}
+
+// GetByID busca um poço pelo ID
+func (m *WellModel) GetByID(id int) (*Well, error) {
+	well := &Well{}
+	row := m.DB.QueryRow(wellSelectColumns+` WHERE w.id = $1`, id)
+	if err := scanWell(row, well); err != nil {
+		return nil, err
+	}
+	return well, nil
+}
+
+// GetByIDAndUser busca um poço garantindo que pertence ao cliente
+func (m *WellModel) GetByIDAndUser(id, userID int) (*Well, error) {
+	well := &Well{}
+	row := m.DB.QueryRow(wellSelectColumns+` WHERE w.id = $1 AND w.user_id = $2`, id, userID)
+	if err := scanWell(row, well); err != nil {
+		return nil, err
+	}
+	return well, nil
+}
+
+// GetByServiceRequestID busca o poço gerado por uma solicitação
+func (m *WellModel) GetByServiceRequestID(serviceRequestID int) (*Well, error) {
+	well := &Well{}
+	row := m.DB.QueryRow(wellSelectColumns+` WHERE w.service_request_id = $1`, serviceRequestID)
+	err := scanWell(row, well)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return well, nil
+}
+
+// GetByUserID retorna todos os poços de um cliente
+func (m *WellModel) GetByUserID(userID int) ([]Well, error) {
+	return m.query(wellSelectColumns+` WHERE w.user_id = $1 ORDER BY w.drilling_date DESC NULLS LAST, w.created_at DESC`, userID)
+}
+
+// GetAll retorna todos os poços cadastrados com paginação
+func (m *WellModel) GetAll(limit, offset int) ([]Well, int, error) {
+	var total int
+	if err := m.DB.QueryRow(`SELECT COUNT(*) FROM wells`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	wells, err := m.query(wellSelectColumns+` ORDER BY w.created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return wells, total, nil
+}
+
+func (m *WellModel) query(query string, args ...interface{}) ([]Well, error) {
+	rows, err := m.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var wells []Well
+	for rows.Next() {
+		var well Well
+		if err := scanWell(rows, &well); err != nil {
+			return nil, err
+		}
+		wells = append(wells, well)
+	}
+	return wells, nil
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// DrillingInterval é uma camada geológica atravessada na perfuração (perfil litológico)
+type DrillingInterval struct {
+	ID           int            `json:"id"`
+	WellID       int            `json:"well_id"`
+	DepthFromM   float64        `json:"depth_from_m"`
+	DepthToM     float64        `json:"depth_to_m"`
+	Lithology    string         `json:"lithology"`
+	WaterEntry   bool           `json:"water_entry"`
+	Observations sql.NullString `json:"observations"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// ThicknessM retorna a espessura da camada
+func (i *DrillingInterval) ThicknessM() float64 {
+	return i.DepthToM - i.DepthFromM
+}
+
+var (
+	ErrIntervalInvalid     = errors.New("intervalo inválido: a profundidade final deve ser maior que a inicial")
+	ErrIntervalOverlap     = errors.New("intervalo sobrepõe uma camada já registrada")
+	ErrIntervalBeyondDepth = errors.New("intervalo ultrapassa a profundidade total do poço")
+	ErrIntervalNoLithology = errors.New("informe a litologia da camada")
+)
+
+// ValidateDrillingInterval verifica se o intervalo é coerente e não sobrepõe os existentes.
+// Intervalos que apenas se tocam (ex: 0-10 e 10-20) são permitidos.
+func ValidateDrillingInterval(existing []DrillingInterval, candidate DrillingInterval, wellDepth sql.NullFloat64) error {
+	if candidate.Lithology == "" {
+		return ErrIntervalNoLithology
+	}
+
+	if candidate.DepthFromM < 0 || candidate.DepthToM <= candidate.DepthFromM {
+		return ErrIntervalInvalid
+	}
+
+	if wellDepth.Valid && candidate.DepthToM > wellDepth.Float64 {
+		return ErrIntervalBeyondDepth
+	}
+
+	for _, interval := range existing {
+		if candidate.ID != 0 && interval.ID == candidate.ID {
+			continue
+		}
+		if candidate.DepthFromM < interval.DepthToM && interval.DepthFromM < candidate.DepthToM {
+			return ErrIntervalOverlap
+		}
+	}
+
+	return nil
+}
+
+// GetDrillingLog retorna o perfil litológico do poço ordenado por profundidade
+func (m *WellModel) GetDrillingLog(wellID int) ([]DrillingInterval, error) {
+	return queryDrillingLog(m.DB, wellID)
+}
+
+// AddDrillingInterval valida e registra uma nova camada no perfil do poço
+func (m *WellModel) AddDrillingInterval(interval *DrillingInterval, actor Actor) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Bloqueia o poço para evitar inserções concorrentes sobrepostas
+	var depth sql.NullFloat64
+	err = tx.QueryRow(`SELECT depth_m FROM wells WHERE id = $1 FOR UPDATE`, interval.WellID).Scan(&depth)
+	if err != nil {
+		return err
+	}
+
+	existing, err := queryDrillingLog(tx, interval.WellID)
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateDrillingInterval(existing, *interval, depth); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO well_drilling_log (well_id, depth_from_m, depth_to_m, lithology, water_entry, observations)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	err = tx.QueryRow(
+		query,
+		interval.WellID, interval.DepthFromM, interval.DepthToM,
+		interval.Lithology, interval.WaterEntry, interval.Observations,
+	).Scan(&interval.ID, &interval.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, actor, AuditCreate, AuditEntityDrillingInterval, interval.ID, sql.NullString{}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteDrillingInterval remove uma camada do perfil
+func (m *WellModel) DeleteDrillingInterval(wellID, intervalID int, actor Actor) error {
+	return withAudit(m.DB, actor, AuditDelete, AuditEntityDrillingInterval, intervalID, func(tx *sql.Tx) (int, error) {
+		return 0, requireAffected(tx.Exec(`DELETE FROM well_drilling_log WHERE id = $1 AND well_id = $2`, intervalID, wellID))
+	})
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryDrillingLog(db queryer, wellID int) ([]DrillingInterval, error) {
+	query := `
+		SELECT id, well_id, depth_from_m, depth_to_m, lithology, water_entry, observations, created_at
+		FROM well_drilling_log
+		WHERE well_id = $1
+		ORDER BY depth_from_m`
+
+	rows, err := db.Query(query, wellID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var intervals []DrillingInterval
+	for rows.Next() {
+		var i DrillingInterval
+		err := rows.Scan(&i.ID, &i.WellID, &i.DepthFromM, &i.DepthToM, &i.Lithology,
+			&i.WaterEntry, &i.Observations, &i.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		intervals = append(intervals, i)
+	}
+	return intervals, nil
+}
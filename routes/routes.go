@@ -14,21 +14,67 @@ import (
 
 func SetupRoutes() *mux.Router {
 	r := mux.NewRouter()
+	r.Use(middleware.Recover)
+	r.Use(middleware.CSRF)
 
 	// Initialize models
 	userModel := models.NewUserModel(config.GetDB())
 	serviceModel := models.NewServiceModel(config.GetDB())
 	contractModel := models.NewContractModel(config.GetDB())
+	wellModel := models.NewWellModel(config.GetDB())
+	waterAnalysisModel := models.NewWaterAnalysisModel(config.GetDB())
+	maintenancePlanModel := models.NewMaintenancePlanModel(config.GetDB())
+	warrantyClaimModel := models.NewWarrantyClaimModel(config.GetDB())
+	materialModel := models.NewMaterialModel(config.GetDB())
+	quoteModel := models.NewQuoteModel(config.GetDB())
+	pricingModel := models.NewPricingModel(config.GetDB())
+	roleModel := models.NewRoleModel(config.GetDB())
+	userTokenModel := models.NewUserTokenModel(config.GetDB())
+	phoneCodeModel := models.NewPhoneCodeModel(config.GetDB())
+	loginAttemptModel := models.NewLoginAttemptModel(config.GetDB())
+	twoFactorModel := models.NewTwoFactorModel(config.GetDB())
+	apiTokenModel := models.NewAPITokenModel(config.GetDB())
+	sessionModel := models.NewSessionModel(config.GetDB())
+	userAddressModel := models.NewUserAddressModel(config.GetDB())
+	privacyModel := models.NewPrivacyModel(config.GetDB())
+	auditModel := models.NewAuditModel(config.GetDB())
+	catalogModel := models.NewCatalogModel(config.GetDB())
 
 	// Initialize services
 	whatsappService := services.NewWhatsAppService()
+	mailer := services.NewMailer()
+	maintenanceScheduler := services.NewMaintenanceScheduler(maintenancePlanModel, serviceModel, whatsappService)
+	maintenanceScheduler.Start()
+	privacyPurgeJob := services.NewPrivacyPurgeJob(privacyModel)
+	privacyPurgeJob.Start()
+	requestTrashPurgeJob := services.NewRequestTrashPurgeJob(serviceModel, config.GetRequestTrashRetentionDays())
+	requestTrashPurgeJob.Start()
 
 	// Initialize controllers
 	homeController := controllers.NewHomeController()
-	authController := controllers.NewAuthController(userModel)
-	serviceController := controllers.NewServiceController(serviceModel)
-	adminController := controllers.NewAdminController(serviceModel, whatsappService)
-	contractController := controllers.NewContractController(contractModel, serviceModel)
+	authController := controllers.NewAuthController(userModel, userTokenModel, phoneCodeModel, loginAttemptModel, twoFactorModel, mailer, whatsappService)
+	serviceController := controllers.NewServiceController(serviceModel, userAddressModel)
+	adminController := controllers.NewAdminController(serviceModel, whatsappService, materialModel)
+	contractController := controllers.NewContractController(contractModel, serviceModel, materialModel)
+	routeController := controllers.NewRouteController(serviceModel, userModel, whatsappService)
+	wellController := controllers.NewWellController(wellModel, serviceModel, contractModel, maintenancePlanModel)
+	maintenanceController := controllers.NewMaintenanceController(maintenancePlanModel, wellModel, maintenanceScheduler)
+	waterAnalysisController := controllers.NewWaterAnalysisController(waterAnalysisModel, serviceModel, wellModel)
+	warrantyController := controllers.NewWarrantyController(warrantyClaimModel, contractModel, serviceModel, whatsappService)
+	materialController := controllers.NewMaterialController(materialModel)
+	quoteController := controllers.NewQuoteController(quoteModel, serviceModel, materialModel, whatsappService)
+	pricingController := controllers.NewPricingController(pricingModel, serviceModel, wellModel, quoteModel)
+	apiController := controllers.NewAPIController(serviceModel, contractModel, userModel, apiTokenModel, loginAttemptModel, twoFactorModel)
+	apiTokenController := controllers.NewAPITokenController(apiTokenModel)
+	roleController := controllers.NewRoleController(roleModel)
+	securityController := controllers.NewSecurityController(loginAttemptModel, sessionModel)
+	twoFactorController := controllers.NewTwoFactorController(twoFactorModel, loginAttemptModel)
+	sessionController := controllers.NewSessionController(sessionModel)
+	profileController := controllers.NewProfileController(userModel, userAddressModel, userTokenModel, loginAttemptModel, mailer)
+	privacyController := controllers.NewPrivacyController(privacyModel, userModel, loginAttemptModel, mailer)
+	auditController := controllers.NewAuditController(auditModel)
+	catalogController := controllers.NewCatalogController(catalogModel)
+	serviceFieldController := controllers.NewServiceFieldController(serviceModel)
 
 	// Static files
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("static/"))))
@@ -40,81 +86,407 @@ func SetupRoutes() *mux.Router {
 	r.HandleFunc("/register", authController.RegisterPage).Methods("GET")
 	r.HandleFunc("/register", authController.Register).Methods("POST")
 	r.HandleFunc("/logout", middleware.RequireAuth(authController.Logout))
+	r.HandleFunc("/esqueci-senha", authController.ForgotPasswordPage).Methods("GET")
+	r.HandleFunc("/esqueci-senha", authController.ForgotPassword).Methods("POST")
+	r.HandleFunc("/redefinir-senha", authController.ResetPasswordPage).Methods("GET")
+	r.HandleFunc("/redefinir-senha", authController.ResetPassword).Methods("POST")
+	r.HandleFunc("/verificar-email", authController.VerifyEmail).Methods("GET")
+	r.HandleFunc("/verificar-email/reenviar", authController.ResendVerification).Methods("POST")
+	r.HandleFunc("/conta/perfil/confirmar-email", profileController.ConfirmEmailChange).Methods("GET")
+	r.HandleFunc("/login/whatsapp", authController.WhatsAppLoginPage).Methods("GET")
+	r.HandleFunc("/login/whatsapp", authController.WhatsAppSendCode).Methods("POST")
+	r.HandleFunc("/login/whatsapp/codigo", authController.WhatsAppCodePage).Methods("GET")
+	r.HandleFunc("/login/whatsapp/codigo", authController.WhatsAppLogin).Methods("POST")
+	r.HandleFunc("/login/2fa", authController.TwoFactorLoginPage).Methods("GET")
+	r.HandleFunc("/login/2fa", authController.TwoFactorLogin).Methods("POST")
+	r.HandleFunc("/verificar-telefone", authController.VerifyPhonePage).Methods("GET")
+	r.HandleFunc("/verificar-telefone", authController.VerifyPhone).Methods("POST")
 
-	// ========== ADMIN ROUTES (Protected + Admin Only) ==========
+	// ========== API v1 (JSON) ==========
+	// Autenticação pela sessão ou por token Bearer; falhas respondem 401/403 em JSON.
+	// Tokens só alcançam as rotas cobertas pelos seus escopos.
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/openapi.json", apiController.OpenAPI).Methods("GET")
+	api.HandleFunc("/auth/token", apiController.IssueToken).Methods("POST")
+	api.HandleFunc("/auth/refresh", apiController.RefreshToken).Methods("POST")
+	api.HandleFunc("/auth/revoke", apiController.RevokeToken).Methods("POST")
+	api.HandleFunc("/me", 
+		middleware.APIRequireAuth(apiController.Me)).Methods("GET")
+	api.HandleFunc("/service-types", 
+		middleware.APIRequireAuth(apiController.ServiceTypes)).Methods("GET")
+	api.HandleFunc("/statuses", 
+		middleware.APIRequireAuth(apiController.Statuses)).Methods("GET")
+	api.HandleFunc("/service-requests", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeRequestsRead, apiController.ListServiceRequests))).Methods("GET")
+	api.HandleFunc("/service-requests", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeRequestsWrite, apiController.CreateServiceRequest))).Methods("POST")
+	api.HandleFunc("/service-requests/{id:[0-9]+}/cancel", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeRequestsWrite, apiController.CancelServiceRequest))).Methods("POST")
+	api.HandleFunc("/service-requests/{id:[0-9]+}", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeRequestsRead, apiController.GetServiceRequest))).Methods("GET")
+	api.HandleFunc("/service-requests/{id:[0-9]+}", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeRequestsWrite, apiController.UpdateServiceRequest))).Methods("PUT")
+	api.HandleFunc("/service-requests/{id:[0-9]+}", 
+		middleware.APIRequireAuth(middleware.APIRequirePermission(models.PermRequestsDelete, middleware.APIRequireScope(models.ScopeRequestsWrite, middleware.APIRequireRecentTwoFactor(apiController.DeleteServiceRequest))))).Methods("DELETE")
+	api.HandleFunc("/contracts", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeContractsRead, apiController.ListContracts))).Methods("GET")
+	api.HandleFunc("/contracts/{id:[0-9]+}/observations", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeContractsRead, apiController.ListObservations))).Methods("GET")
+	api.HandleFunc("/contracts/{id:[0-9]+}/observations", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeContractsWrite, apiController.AddObservation))).Methods("POST")
+	api.HandleFunc("/contracts/{id:[0-9]+}/observations/{obs_id:[0-9]+}", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeContractsWrite, apiController.DeleteObservation))).Methods("DELETE")
+	api.HandleFunc("/contracts/{id:[0-9]+}/sign", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeContractsWrite, middleware.APIRequireRecentTwoFactor(apiController.SignContract)))).Methods("POST")
+	api.HandleFunc("/contracts/{id:[0-9]+}", 
+		middleware.APIRequireAuth(middleware.APIRequireScope(models.ScopeContractsRead, apiController.GetContract))).Methods("GET")
+
+	// Tokens de API da conta (cliente e gestor)
+	r.HandleFunc("/conta/tokens", 
+		middleware.RequireAuth(apiTokenController.ListTokens)).Methods("GET")
+	r.HandleFunc("/conta/tokens", 
+		middleware.RequireAuth(apiTokenController.CreateToken)).Methods("POST")
+	r.HandleFunc("/conta/tokens/{id:[0-9]+}/revogar", 
+		middleware.RequireAuth(apiTokenController.RevokeToken)).Methods("POST")
+
+	// Verificação em duas etapas (contas da empresa)
+	r.HandleFunc("/conta/2fa", 
+		middleware.RequireAuth(twoFactorController.Page)).Methods("GET")
+	r.HandleFunc("/conta/2fa/ativar", 
+		middleware.RequireAuth(twoFactorController.Enable)).Methods("POST")
+	r.HandleFunc("/conta/2fa/codigos", 
+		middleware.RequireAuth(twoFactorController.RegenerateCodes)).Methods("POST")
+	r.HandleFunc("/conta/2fa/desativar", 
+		middleware.RequireAuth(twoFactorController.Disable)).Methods("POST")
+
+	// Perfil: dados de contato, senha, imóveis e avisos
+	r.HandleFunc("/conta/perfil", 
+		middleware.RequireAuth(profileController.Page)).Methods("GET")
+	r.HandleFunc("/conta/perfil", 
+		middleware.RequireAuth(profileController.UpdateContact)).Methods("POST")
+	r.HandleFunc("/conta/perfil/cancelar-email", 
+		middleware.RequireAuth(profileController.CancelEmailChange)).Methods("POST")
+	r.HandleFunc("/conta/perfil/senha", 
+		middleware.RequireAuth(profileController.ChangePassword)).Methods("POST")
+	r.HandleFunc("/conta/perfil/avisos", 
+		middleware.RequireAuth(profileController.UpdateNotifications)).Methods("POST")
+	r.HandleFunc("/conta/perfil/enderecos", 
+		middleware.RequireAuth(profileController.CreateAddress)).Methods("POST")
+	r.HandleFunc("/conta/perfil/enderecos/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(profileController.UpdateAddress)).Methods("POST")
+	r.HandleFunc("/conta/perfil/enderecos/{id:[0-9]+}/padrao", 
+		middleware.RequireAuth(profileController.SetDefaultAddress)).Methods("POST")
+	r.HandleFunc("/conta/perfil/enderecos/{id:[0-9]+}/excluir", 
+		middleware.RequireAuth(profileController.DeleteAddress)).Methods("POST")
+
+	// Sessões abertas da conta
+	r.HandleFunc("/conta/sessoes", 
+		middleware.RequireAuth(sessionController.ListSessions)).Methods("GET")
+	r.HandleFunc("/conta/sessoes/encerrar-outras", 
+		middleware.RequireAuth(sessionController.RevokeOtherSessions)).Methods("POST")
+	r.HandleFunc("/conta/sessoes/{id:[0-9a-f]{64}}/encerrar", 
+		middleware.RequireAuth(sessionController.RevokeSession)).Methods("POST")
+
+	// Privacidade (LGPD): exportação dos dados e pedido de exclusão
+	r.HandleFunc("/conta/privacidade", 
+		middleware.RequireAuth(privacyController.Page)).Methods("GET")
+	r.HandleFunc("/conta/privacidade/exportar", 
+		middleware.RequireAuth(privacyController.Export)).Methods("POST")
+	r.HandleFunc("/conta/privacidade/excluir", 
+		middleware.RequireAuth(privacyController.RequestErasure)).Methods("POST")
+	r.HandleFunc("/conta/privacidade/excluir/cancelar", 
+		middleware.RequireAuth(privacyController.CancelErasure)).Methods("POST")
+
+	// ========== ADMIN ROUTES (Protected + Permission) ==========
+	// Cada rota exige uma permissão do papel do usuário (tabela role_permissions)
 	// IMPORTANTE: Rotas ADMIN devem vir ANTES das rotas CLIENT para evitar conflitos!
 	
 	// Dashboard Admin
 	r.HandleFunc("/dashboard/admin", 
-		middleware.RequireAuth(middleware.RequireAdmin(adminController.AdminDashboard))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRequestsView, adminController.AdminDashboard))).Methods("GET")
 	
 	// Admin service request management
 	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}", 
-		middleware.RequireAuth(middleware.RequireAdmin(adminController.VerSolicitacaoAdmin))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRequestsView, adminController.VerSolicitacaoAdmin))).Methods("GET")
 	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/editar", 
-		middleware.RequireAuth(middleware.RequireAdmin(adminController.EditarSolicitacaoAdmin))).Methods("GET", "POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRequestsManage, adminController.EditarSolicitacaoAdmin))).Methods("GET", "POST")
 	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/deletar", 
-		middleware.RequireAuth(middleware.RequireAdmin(adminController.DeletarSolicitacao))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRequestsDelete, twoFactorController.RequireRecent(adminController.DeletarSolicitacao)))).Methods("POST")
+	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/restaurar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRequestsDelete, adminController.RestaurarSolicitacao))).Methods("POST")
+	r.HandleFunc("/admin/lixeira", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRequestsDelete, adminController.Lixeira))).Methods("GET")
 	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/criar-contrato", 
-		middleware.RequireAuth(middleware.RequireAdmin(contractController.CreateContract))).Methods("GET", "POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsManage, contractController.CreateContract))).Methods("GET", "POST")
+	
+	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/poco", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWellsManage, wellController.CreateWellFromRequest))).Methods("GET", "POST")
 	
 	// Status update
 	r.HandleFunc("/admin/update-status", 
-		middleware.RequireAuth(middleware.RequireAdmin(adminController.UpdateStatus))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRequestsManage, adminController.UpdateStatus))).Methods("POST")
 	
 	// Admin contract routes - ORDEM IMPORTANTE!
 	// Lista deve vir ANTES dos detalhes com {id}
 	r.HandleFunc("/admin/contratos", 
-		middleware.RequireAuth(middleware.RequireAdmin(contractController.ListContracts))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsView, contractController.ListContracts))).Methods("GET")
 	r.HandleFunc("/admin/contratos/{id:[0-9]+}/editar", 
-		middleware.RequireAuth(middleware.RequireAdmin(contractController.EditContract))).Methods("GET", "POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsManage, contractController.EditContract))).Methods("GET", "POST")
 	r.HandleFunc("/admin/contratos/{id:[0-9]+}/enviar-assinatura", 
-		middleware.RequireAuth(middleware.RequireAdmin(contractController.SendForSignature))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsManage, contractController.SendForSignature))).Methods("POST")
 	r.HandleFunc("/admin/contratos/{id:[0-9]+}/assinar-empresa", 
-		middleware.RequireAuth(middleware.RequireAdmin(contractController.SignContractCompany))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsSignCompany, twoFactorController.RequireRecent(contractController.SignContractCompany)))).Methods("POST")
+	
+	// Materiais do contrato (antes das assinaturas)
+	r.HandleFunc("/admin/contratos/{id:[0-9]+}/itens", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsManage, materialController.AddContractItem))).Methods("POST")
+	r.HandleFunc("/admin/contratos/{id:[0-9]+}/itens/{item_id:[0-9]+}/deletar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsManage, materialController.DeleteContractItem))).Methods("POST")
 	
 	// ⚠️ NOVA ROTA: Admin resolve observação do cliente
 	r.HandleFunc("/admin/contratos/{id:[0-9]+}/observacao/{obs_id:[0-9]+}/resolver", 
-		middleware.RequireAuth(middleware.RequireAdmin(contractController.ResolveObservation))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsManage, contractController.ResolveObservation))).Methods("POST")
 	
 	r.HandleFunc("/admin/contratos/{id:[0-9]+}", 
-		middleware.RequireAuth(middleware.RequireAdmin(contractController.ViewContract))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermContractsView, contractController.ViewContract))).Methods("GET")
+	
+	// Roteirização das visitas do dia
+	r.HandleFunc("/admin/rotas", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRoutesManage, routeController.DailyRoute))).Methods("GET")
+	r.HandleFunc("/admin/rotas/imprimir", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRoutesManage, routeController.PrintRoute))).Methods("GET")
+	r.HandleFunc("/admin/rotas/enviar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermRoutesManage, routeController.SendRouteWhatsApp))).Methods("POST")
+	
+	// Poços (ficha técnica)
+	r.HandleFunc("/admin/pocos", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWellsManage, wellController.AdminListWells))).Methods("GET")
+	r.HandleFunc("/admin/pocos/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWellsManage, wellController.EditWell))).Methods("GET", "POST")
+	r.HandleFunc("/admin/pocos/{id:[0-9]+}/perfil", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWellsManage, wellController.AddDrillingInterval))).Methods("POST")
+	r.HandleFunc("/admin/pocos/{id:[0-9]+}/perfil/{interval_id:[0-9]+}/deletar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWellsManage, wellController.DeleteDrillingInterval))).Methods("POST")
+	r.HandleFunc("/admin/pocos/{id:[0-9]+}/pdf", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWellsManage, wellController.AdminWellPDF))).Methods("GET")
+	r.HandleFunc("/admin/pocos/{id:[0-9]+}/manutencao", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaintenanceManage, maintenanceController.CreatePlan))).Methods("GET", "POST")
+	r.HandleFunc("/admin/pocos/{id:[0-9]+}/qualidade", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAnalysesManage, waterAnalysisController.AdminWellQuality))).Methods("GET")
+	r.HandleFunc("/admin/pocos/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWellsManage, wellController.AdminViewWell))).Methods("GET")
+	
+	// Manutenção preventiva
+	r.HandleFunc("/admin/manutencoes", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaintenanceManage, maintenanceController.UpcomingMaintenance))).Methods("GET")
+	r.HandleFunc("/admin/manutencoes/executar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaintenanceManage, maintenanceController.RunScheduler))).Methods("POST")
+	r.HandleFunc("/admin/manutencoes/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaintenanceManage, maintenanceController.EditPlan))).Methods("GET", "POST")
+	
+	// Análises da água (laudos)
+	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/analise", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAnalysesManage, waterAnalysisController.CreateFromRequest))).Methods("GET", "POST")
+	r.HandleFunc("/admin/analises", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAnalysesManage, waterAnalysisController.AdminListAnalyses))).Methods("GET")
+	r.HandleFunc("/admin/analises/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAnalysesManage, waterAnalysisController.EditAnalysis))).Methods("GET", "POST")
+	r.HandleFunc("/admin/analises/{id:[0-9]+}/pdf", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAnalysesManage, waterAnalysisController.AdminAnalysisPDF))).Methods("GET")
+	r.HandleFunc("/admin/analises/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAnalysesManage, waterAnalysisController.AdminViewAnalysis))).Methods("GET")
+	
+	// Materiais e estoque
+	r.HandleFunc("/admin/materiais", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaterialsManage, materialController.ListMaterials))).Methods("GET")
+	r.HandleFunc("/admin/materiais/novo", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaterialsManage, materialController.CreateMaterial))).Methods("GET", "POST")
+	r.HandleFunc("/admin/materiais/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaterialsManage, materialController.EditMaterial))).Methods("GET", "POST")
+	r.HandleFunc("/admin/materiais/{id:[0-9]+}/movimentar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaterialsManage, materialController.AddMovement))).Methods("POST")
+	r.HandleFunc("/admin/materiais/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermMaterialsManage, materialController.ViewMaterial))).Methods("GET")
+	
+	// Reclamações de garantia
+	r.HandleFunc("/admin/garantias", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWarrantyManage, warrantyController.AdminListClaims))).Methods("GET")
+	r.HandleFunc("/admin/garantias/{id:[0-9]+}/aprovar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWarrantyManage, warrantyController.ApproveClaim))).Methods("POST")
+	r.HandleFunc("/admin/garantias/{id:[0-9]+}/rejeitar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWarrantyManage, warrantyController.RejectClaim))).Methods("POST")
+	r.HandleFunc("/admin/garantias/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermWarrantyManage, warrantyController.AdminViewClaim))).Methods("GET")
+
+	// Orçamentos
+	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/orcamento", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.CreateQuote))).Methods("POST")
+	r.HandleFunc("/admin/orcamentos", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.ListQuotes))).Methods("GET")
+	r.HandleFunc("/admin/orcamentos/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.UpdateQuote))).Methods("POST")
+	r.HandleFunc("/admin/orcamentos/{id:[0-9]+}/itens", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.AddItem))).Methods("POST")
+	r.HandleFunc("/admin/orcamentos/{id:[0-9]+}/itens/{item_id:[0-9]+}/deletar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.DeleteItem))).Methods("POST")
+	r.HandleFunc("/admin/orcamentos/{id:[0-9]+}/enviar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.SendQuote))).Methods("POST")
+	r.HandleFunc("/admin/orcamentos/{id:[0-9]+}/converter", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.ConvertQuote))).Methods("POST")
+	r.HandleFunc("/admin/orcamentos/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermQuotesManage, quoteController.ViewQuote))).Methods("GET")
+
+	// Tabelas de preço e preço sugerido
+	r.HandleFunc("/admin/solicitacao/{id:[0-9]+}/preco", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.SuggestQuote))).Methods("GET", "POST")
+	r.HandleFunc("/admin/precos", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.ListPriceTables))).Methods("GET")
+	r.HandleFunc("/admin/precos/novo", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.CreatePriceTable))).Methods("POST")
+	r.HandleFunc("/admin/precos/distancias", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.SaveCityDistance))).Methods("POST")
+	r.HandleFunc("/admin/precos/distancias/{id:[0-9]+}/deletar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.DeleteCityDistance))).Methods("POST")
+	r.HandleFunc("/admin/precos/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.UpdatePriceTable))).Methods("POST")
+	r.HandleFunc("/admin/precos/{id:[0-9]+}/regras", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.AddPriceRule))).Methods("POST")
+	r.HandleFunc("/admin/precos/{id:[0-9]+}/regras/{rule_id:[0-9]+}/deletar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.DeletePriceRule))).Methods("POST")
+	r.HandleFunc("/admin/precos/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPricingManage, pricingController.ViewPriceTable))).Methods("GET")
+	
+	// Usuários, papéis e permissões
+	r.HandleFunc("/admin/usuarios", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermUsersManageRoles, roleController.ListUsers))).Methods("GET")
+	r.HandleFunc("/admin/usuarios/papeis/{id:[0-9]+}/permissoes", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermUsersManageRoles, roleController.UpdateRolePermissions))).Methods("POST")
+	r.HandleFunc("/admin/usuarios/{id:[0-9]+}/papel", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermUsersManageRoles, roleController.AssignRole))).Methods("POST")
+	
+	// Tentativas de login e desbloqueio
+	r.HandleFunc("/admin/acessos", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermUsersSecurity, securityController.LoginAttempts))).Methods("GET")
+	r.HandleFunc("/admin/acessos/desbloquear", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermUsersSecurity, securityController.Unlock))).Methods("POST")
+	r.HandleFunc("/admin/acessos/desbloquear-ip", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermUsersSecurity, securityController.UnlockIP))).Methods("POST")
+	r.HandleFunc("/admin/usuarios/{id:[0-9]+}/sessoes/encerrar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermUsersSecurity, securityController.RevokeUserSessions))).Methods("POST")
+	
+	// Pedidos de exclusão de dados (LGPD)
+	r.HandleFunc("/admin/privacidade", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPrivacyManage, privacyController.AdminErasureRequests))).Methods("GET")
+	r.HandleFunc("/admin/privacidade/{id:[0-9]+}/aprovar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPrivacyManage, twoFactorController.RequireRecent(privacyController.AdminApproveErasure)))).Methods("POST")
+	r.HandleFunc("/admin/privacidade/{id:[0-9]+}/recusar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermPrivacyManage, privacyController.AdminRejectErasure))).Methods("POST")
+	
+	// Registro de auditoria
+	r.HandleFunc("/admin/auditoria", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAuditView, auditController.List))).Methods("GET")
+	r.HandleFunc("/admin/auditoria/exportar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermAuditView, auditController.Export))).Methods("GET")
+	
+	// Campos adicionais do formulário por tipo de serviço; registrados antes
+	// das rotas genéricas de cadastro para "campos" não ser lido como {catalog}
+	r.HandleFunc("/admin/cadastros/tipos-servico/{id:[0-9]+}/campos", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, serviceFieldController.List))).Methods("GET")
+	r.HandleFunc("/admin/cadastros/tipos-servico/{id:[0-9]+}/campos/novo", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, serviceFieldController.Create))).Methods("GET", "POST")
+	r.HandleFunc("/admin/cadastros/tipos-servico/{id:[0-9]+}/campos/ordenar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, serviceFieldController.Reorder))).Methods("POST")
+	r.HandleFunc("/admin/cadastros/campos/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, serviceFieldController.Edit))).Methods("GET", "POST")
+	r.HandleFunc("/admin/cadastros/campos/{id:[0-9]+}/ativo", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, serviceFieldController.SetActive))).Methods("POST")
+
+	// Cadastros de valores (tipos de serviço, status e tipos de garantia)
+	r.HandleFunc("/admin/cadastros", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, catalogController.Index))).Methods("GET")
+	r.HandleFunc("/admin/cadastros/{catalog}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, catalogController.List))).Methods("GET")
+	r.HandleFunc("/admin/cadastros/{catalog}/novo", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, catalogController.Create))).Methods("GET", "POST")
+	r.HandleFunc("/admin/cadastros/{catalog}/ordenar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, catalogController.Reorder))).Methods("POST")
+	r.HandleFunc("/admin/cadastros/{catalog}/{id:[0-9]+}/editar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, catalogController.Edit))).Methods("GET", "POST")
+	r.HandleFunc("/admin/cadastros/{catalog}/{id:[0-9]+}/ativo", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermCatalogsManage, catalogController.SetActive))).Methods("POST")
 	
 	// ========== CLIENT ROUTES (Protected) ==========
 	// Rotas CLIENT vêm DEPOIS das rotas ADMIN
 	
 	// Dashboard Cliente
 	r.HandleFunc("/dashboard/cliente", 
-		middleware.RequireAuth(middleware.RequireClient(serviceController.ClienteDashboard))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, serviceController.ClienteDashboard))).Methods("GET")
 	
 	// Service request management
 	r.HandleFunc("/solicitar-servico", 
-		middleware.RequireAuth(middleware.RequireClient(serviceController.SolicitarServico))).Methods("GET", "POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, serviceController.SolicitarServico))).Methods("GET", "POST")
 	r.HandleFunc("/solicitacao/{id:[0-9]+}/editar", 
-		middleware.RequireAuth(middleware.RequireClient(serviceController.EditarSolicitacao))).Methods("GET", "POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, serviceController.EditarSolicitacao))).Methods("GET", "POST")
 	r.HandleFunc("/solicitacao/{id:[0-9]+}/cancelar", 
-		middleware.RequireAuth(middleware.RequireClient(serviceController.CancelarSolicitacao))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, serviceController.CancelarSolicitacao))).Methods("POST")
 	r.HandleFunc("/solicitacao/{id:[0-9]+}", 
-		middleware.RequireAuth(middleware.RequireClient(serviceController.VerSolicitacao))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, serviceController.VerSolicitacao))).Methods("GET")
 	
 	// Client contract routes - ORDEM IMPORTANTE!
 	// Rotas mais específicas DEVEM vir ANTES das genéricas
 	r.HandleFunc("/contratos", 
-		middleware.RequireAuth(middleware.RequireClient(contractController.ClientContracts))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, contractController.ClientContracts))).Methods("GET")
 	
 	// ⚠️ NOVAS ROTAS: Observações do cliente (DEVEM vir ANTES de /contratos/{id})
 	r.HandleFunc("/contratos/{id:[0-9]+}/observacao", 
-		middleware.RequireAuth(middleware.RequireClient(contractController.AddClientObservation))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, contractController.AddClientObservation))).Methods("POST")
 	r.HandleFunc("/contratos/{id:[0-9]+}/observacao/{obs_id:[0-9]+}/deletar", 
-		middleware.RequireAuth(middleware.RequireClient(contractController.DeleteClientObservation))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, contractController.DeleteClientObservation))).Methods("POST")
 	
 	// Assinar contrato
 	r.HandleFunc("/contratos/{id:[0-9]+}/assinar", 
-		middleware.RequireAuth(middleware.RequireClient(contractController.ClientSignContract))).Methods("POST")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, contractController.ClientSignContract))).Methods("POST")
+	
+	// Acionar garantia do contrato
+	r.HandleFunc("/contratos/{id:[0-9]+}/garantia", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, warrantyController.ClientOpenClaim))).Methods("GET", "POST")
 	
 	// Ver contrato (rota genérica deve vir POR ÚLTIMO)
 	r.HandleFunc("/contratos/{id:[0-9]+}", 
-		middleware.RequireAuth(middleware.RequireClient(contractController.ClientViewContract))).Methods("GET")
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, contractController.ClientViewContract))).Methods("GET")
+
+	// Poços do cliente
+	r.HandleFunc("/pocos", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, wellController.ClientWells))).Methods("GET")
+	r.HandleFunc("/pocos/{id:[0-9]+}/qualidade", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, waterAnalysisController.ClientWellQuality))).Methods("GET")
+	r.HandleFunc("/pocos/{id:[0-9]+}/pdf", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, wellController.ClientWellPDF))).Methods("GET")
+	r.HandleFunc("/pocos/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, wellController.ClientViewWell))).Methods("GET")
+
+
+	// Análises da água do cliente
+	r.HandleFunc("/analises", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, waterAnalysisController.ClientAnalyses))).Methods("GET")
+	r.HandleFunc("/analises/{id:[0-9]+}/pdf", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, waterAnalysisController.ClientAnalysisPDF))).Methods("GET")
+	r.HandleFunc("/analises/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, waterAnalysisController.ClientViewAnalysis))).Methods("GET")
+
+	// Reclamações de garantia do cliente
+	r.HandleFunc("/garantias", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, warrantyController.ClientClaims))).Methods("GET")
+
+	// Orçamentos do cliente
+	r.HandleFunc("/orcamentos", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, quoteController.ClientQuotes))).Methods("GET")
+	r.HandleFunc("/orcamentos/{id:[0-9]+}/aceitar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, quoteController.ClientAcceptQuote))).Methods("POST")
+	r.HandleFunc("/orcamentos/{id:[0-9]+}/recusar", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, quoteController.ClientRejectQuote))).Methods("POST")
+	r.HandleFunc("/orcamentos/{id:[0-9]+}", 
+		middleware.RequireAuth(middleware.RequirePermission(models.PermClientPortal, quoteController.ClientViewQuote))).Methods("GET")
 
 	return r
 }
\ No newline at end of file
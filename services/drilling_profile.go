@@ -0,0 +1,139 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"math"
+	"strings"
+
+	"martins-pocos/models"
+)
+
+// Lithology é um tipo de camada geológica com a cor usada no perfil
+type Lithology struct {
+	Name  string
+	Color string
+}
+
+// Lithologies lista as litologias mais comuns na região, na ordem do formulário
+var Lithologies = []Lithology{
+	{"Solo", "#8d6e63"},
+	{"Argila", "#d7a86e"},
+	{"Areia", "#f3e5ab"},
+	{"Arenito", "#e0b878"},
+	{"Cascalho", "#bcaaa4"},
+	{"Rocha alterada", "#a1887f"},
+	{"Basalto", "#546e7a"},
+	{"Granito", "#e8a0a0"},
+	{"Outro", "#cfd8dc"},
+}
+
+// LithologyColor retorna a cor hexadecimal da litologia (cinza para desconhecidas)
+func LithologyColor(name string) string {
+	for _, l := range Lithologies {
+		if strings.EqualFold(l.Name, name) {
+			return l.Color
+		}
+	}
+	return "#cfd8dc"
+}
+
+// LithologyRGB retorna a cor da litologia em componentes 0-255
+func LithologyRGB(name string) (int, int, int) {
+	var r, g, b int
+	fmt.Sscanf(LithologyColor(name), "#%02x%02x%02x", &r, &g, &b)
+	return r, g, b
+}
+
+// ProfileDepth define a profundidade representada no perfil:
+// a do poço, ou a base da camada mais profunda se maior
+func ProfileDepth(intervals []models.DrillingInterval, totalDepth sql.NullFloat64) float64 {
+	depth := 0.0
+	if totalDepth.Valid {
+		depth = totalDepth.Float64
+	}
+	for _, interval := range intervals {
+		depth = math.Max(depth, interval.DepthToM)
+	}
+	return depth
+}
+
+// DepthTickStep escolhe um passo "redondo" para a régua de profundidade
+func DepthTickStep(depth float64) float64 {
+	for _, step := range []float64{5, 10, 20, 25, 50, 100} {
+		if depth/step <= 12 {
+			return step
+		}
+	}
+	return 200
+}
+
+// RenderDrillingProfileSVG desenha a coluna litológica do poço em SVG
+func RenderDrillingProfileSVG(intervals []models.DrillingInterval, totalDepth, staticLevel sql.NullFloat64) string {
+	depth := ProfileDepth(intervals, totalDepth)
+	if depth <= 0 {
+		return ""
+	}
+
+	const (
+		width      = 360.0
+		height     = 520.0
+		top        = 20.0
+		axisX      = 50.0
+		columnX    = 60.0
+		columnW    = 70.0
+		labelX     = 145.0
+		bottomPad  = 20.0
+		plotHeight = height - top - bottomPad
+	)
+
+	scale := plotHeight / depth
+	y := func(m float64) float64 { return top + m*scale }
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" class="drilling-profile" role="img" aria-label="Perfil litológico">`, width, height)
+
+	// Régua de profundidade
+	fmt.Fprintf(&sb, `<line x1="%.0f" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#495057" stroke-width="1"/>`, axisX, y(0), axisX, y(depth))
+	step := DepthTickStep(depth)
+	for m := 0.0; m <= depth+1e-9; m += step {
+		fmt.Fprintf(&sb, `<line x1="%.0f" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#495057"/>`, axisX-5, y(m), axisX, y(m))
+		fmt.Fprintf(&sb, `<text x="%.0f" y="%.1f" font-size="10" text-anchor="end" fill="#495057">%.0f m</text>`, axisX-8, y(m)+3, m)
+	}
+
+	// Contorno do poço
+	fmt.Fprintf(&sb, `<rect x="%.0f" y="%.1f" width="%.0f" height="%.1f" fill="#f8f9fa" stroke="#adb5bd"/>`, columnX, y(0), columnW, y(depth)-y(0))
+
+	// Camadas
+	for _, interval := range intervals {
+		y1, y2 := y(interval.DepthFromM), y(interval.DepthToM)
+		fmt.Fprintf(&sb, `<rect x="%.0f" y="%.1f" width="%.0f" height="%.1f" fill="%s" stroke="#495057" stroke-width="0.5"><title>%s: %.2f – %.2f m</title></rect>`,
+			columnX, y1, columnW, y2-y1, LithologyColor(interval.Lithology),
+			html.EscapeString(interval.Lithology), interval.DepthFromM, interval.DepthToM)
+
+		if interval.WaterEntry {
+			mid := (y1 + y2) / 2
+			fmt.Fprintf(&sb, `<path d="M %.0f %.1f l 8 -5 l 0 10 z" fill="#0d6efd"><title>Entrada de água</title></path>`, columnX+columnW+2, mid)
+		}
+
+		// Rótulo apenas se a camada tiver altura suficiente
+		if y2-y1 >= 11 {
+			label := html.EscapeString(interval.Lithology)
+			if interval.WaterEntry {
+				label += " 💧"
+			}
+			fmt.Fprintf(&sb, `<text x="%.0f" y="%.1f" font-size="11" fill="#212529">%s</text>`, labelX, (y1+y2)/2+4, label)
+		}
+	}
+
+	// Nível estático
+	if staticLevel.Valid && staticLevel.Float64 <= depth {
+		ys := y(staticLevel.Float64)
+		fmt.Fprintf(&sb, `<line x1="%.0f" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#0d6efd" stroke-width="1.5" stroke-dasharray="5,3"/>`, columnX-4, ys, width-10, ys)
+		fmt.Fprintf(&sb, `<text x="%.0f" y="%.1f" font-size="10" text-anchor="end" fill="#0d6efd">NE %.2f m</text>`, width-10, ys-3, staticLevel.Float64)
+	}
+
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Email é uma mensagem de texto simples
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envia e-mails. Em produção usa SMTP; em desenvolvimento as
+// mensagens são gravadas em arquivo (ver NewMailer).
+type Mailer interface {
+	Send(msg Email) error
+}
+
+// NewMailer escolhe o envio conforme SMTP_HOST. Sem a variável, grava as
+// mensagens em MAIL_DIR (padrão tmp/mail) e registra no log.
+func NewMailer() Mailer {
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		from := os.Getenv("MAIL_FROM")
+		if from == "" {
+			from = os.Getenv("SMTP_USER")
+		}
+		return &SMTPMailer{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+
+	dir := os.Getenv("MAIL_DIR")
+	if dir == "" {
+		dir = filepath.Join("tmp", "mail")
+	}
+	return &FileMailer{Dir: dir}
+}
+
+// ============================================
+// SMTP
+// ============================================
+
+// SMTPMailer envia pelo servidor SMTP configurado (STARTTLS quando oferecido)
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Email) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	addr := m.Host + ":" + m.Port
+	if err := smtp.SendMail(addr, auth, m.From, []string{msg.To}, buildMessage(m.From, msg)); err != nil {
+		log.Printf("❌ Erro ao enviar e-mail para %s: %v", msg.To, err)
+		return err
+	}
+
+	log.Printf("📧 E-mail enviado para %s: %s", msg.To, msg.Subject)
+	return nil
+}
+
+// ============================================
+// ARQUIVO (desenvolvimento)
+// ============================================
+
+// FileMailer grava cada mensagem como .eml em Dir, para inspeção local
+type FileMailer struct {
+	Dir string
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]+`)
+
+func (m *FileMailer) Send(msg Email) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102-150405.000000"), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, buildMessage("nao-responda@localhost", msg), 0o644); err != nil {
+		return err
+	}
+
+	log.Printf("📧 E-mail para %s gravado em %s: %s", msg.To, path, msg.Subject)
+	return nil
+}
+
+// buildMessage monta a mensagem RFC 5322 em texto simples UTF-8
+func buildMessage(from string, msg Email) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"martins-pocos/models"
+)
+
+// Código do tipo de serviço usado nas solicitações geradas (seed de service_types)
+const maintenanceServiceTypeCode = "manutencao"
+
+// MaintenanceRunResult resume uma execução do agendador
+type MaintenanceRunResult struct {
+	RemindersSent   int
+	RequestsCreated int
+	Errors          []string
+}
+
+// MaintenanceScheduler gera as solicitações de manutenção vencidas e envia
+// lembretes aos clientes pelo WhatsApp antes do vencimento
+type MaintenanceScheduler struct {
+	PlanModel       *models.MaintenancePlanModel
+	ServiceModel    *models.ServiceModel
+	WhatsAppService *WhatsAppService
+	Interval        time.Duration
+
+	mu sync.Mutex
+}
+
+func NewMaintenanceScheduler(planModel *models.MaintenancePlanModel, serviceModel *models.ServiceModel, whatsappService *WhatsAppService) *MaintenanceScheduler {
+	return &MaintenanceScheduler{
+		PlanModel:       planModel,
+		ServiceModel:    serviceModel,
+		WhatsAppService: whatsappService,
+		Interval:        time.Hour,
+	}
+}
+
+// Start executa o agendador em segundo plano, uma vez na inicialização e depois a cada Interval
+func (s *MaintenanceScheduler) Start() {
+	go func() {
+		for {
+			result := s.RunOnce(time.Now())
+			if result.RemindersSent > 0 || result.RequestsCreated > 0 || len(result.Errors) > 0 {
+				log.Printf("🔧 Manutenções: %d solicitação(ões) gerada(s), %d lembrete(s) enviado(s), %d erro(s)",
+					result.RequestsCreated, result.RemindersSent, len(result.Errors))
+			}
+			time.Sleep(s.Interval)
+		}
+	}()
+}
+
+// RunOnce processa os planos na data informada. Execuções simultâneas são serializadas.
+func (s *MaintenanceScheduler) RunOnce(now time.Time) MaintenanceRunResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result MaintenanceRunResult
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	s.sendReminders(today, &result)
+	s.generateRequests(today, &result)
+
+	return result
+}
+
+func (s *MaintenanceScheduler) sendReminders(today time.Time, result *MaintenanceRunResult) {
+	plans, err := s.PlanModel.GetPendingReminders(today)
+	if err != nil {
+		result.Errors = append(result.Errors, "buscar lembretes: "+err.Error())
+		return
+	}
+
+	for _, plan := range plans {
+		if plan.OwnerPhone == "" || !plan.OwnerWantsReminders {
+			// Sem telefone (ou com os lembretes desligados no perfil) não há
+			// aviso; marca para não tentar a cada execução
+			s.PlanModel.MarkReminderSent(plan.ID, plan.NextDueDate)
+			continue
+		}
+
+		if err := s.WhatsAppService.SendMessage(plan.OwnerPhone, BuildMaintenanceReminder(&plan)); err != nil {
+			log.Printf("⚠️ Erro ao enviar lembrete do plano %d: %v", plan.ID, err)
+			result.Errors = append(result.Errors, fmt.Sprintf("lembrete do plano %d: %v", plan.ID, err))
+			continue
+		}
+
+		if err := s.PlanModel.MarkReminderSent(plan.ID, plan.NextDueDate); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("registrar lembrete do plano %d: %v", plan.ID, err))
+			continue
+		}
+		result.RemindersSent++
+	}
+}
+
+func (s *MaintenanceScheduler) generateRequests(today time.Time, result *MaintenanceRunResult) {
+	plans, err := s.PlanModel.GetDue(today)
+	if err != nil {
+		result.Errors = append(result.Errors, "buscar planos vencidos: "+err.Error())
+		return
+	}
+	if len(plans) == 0 {
+		return
+	}
+
+	serviceType, err := s.ServiceModel.GetServiceTypeByCode(maintenanceServiceTypeCode)
+	if err != nil {
+		result.Errors = append(result.Errors, "tipo de serviço de manutenção não encontrado: "+err.Error())
+		return
+	}
+
+	for _, plan := range plans {
+		requestID, err := s.PlanModel.GenerateRequest(plan.ID, serviceType.ID, today)
+		if err != nil {
+			log.Printf("⚠️ Erro ao gerar solicitação do plano %d: %v", plan.ID, err)
+			result.Errors = append(result.Errors, fmt.Sprintf("plano %d (%s): %v", plan.ID, plan.WellIdentification, err))
+			continue
+		}
+		if requestID > 0 {
+			log.Printf("🔧 Solicitação #%d gerada pelo plano de manutenção %d", requestID, plan.ID)
+			result.RequestsCreated++
+		}
+	}
+}
+
+// BuildMaintenanceReminder monta a mensagem de WhatsApp avisando o cliente da manutenção
+func BuildMaintenanceReminder(plan *models.MaintenancePlan) string {
+	var sb strings.Builder
+
+	sb.WriteString("🔧 *Lembrete de Manutenção Preventiva*\n\n")
+	sb.WriteString(fmt.Sprintf("Olá %s!\n\n", plan.OwnerName))
+	sb.WriteString(fmt.Sprintf("A manutenção *%s* do seu poço *%s* está prevista para *%s*.\n\n",
+		plan.Title, plan.WellIdentification, plan.NextDueDate.Format("02/01/2006")))
+
+	if items := plan.ChecklistItems(); len(items) > 0 {
+		sb.WriteString("📋 *O que será verificado:*\n")
+		for _, item := range items {
+			sb.WriteString(fmt.Sprintf("• %s\n", item))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("Abriremos a solicitação automaticamente e entraremos em contato para confirmar o horário.\n\n")
+	sb.WriteString("_Martins Poços - Sistema Automatizado_")
+
+	return sb.String()
+}
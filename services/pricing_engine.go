@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"martins-pocos/models"
+)
+
+// PricingInput são os dados técnicos da obra usados no cálculo do preço
+type PricingInput struct {
+	DepthM         float64 `json:"depth_m"`
+	DiameterMm     float64 `json:"diameter_mm"`
+	Terrain        string  `json:"terrain"`
+	DistanceKm     float64 `json:"distance_km"`     // só ida, da base até o local
+	DistanceSource string  `json:"distance_source"` // de onde veio a distância (exibição)
+}
+
+// PricingLine é uma linha do orçamento sugerido com a memória de cálculo
+type PricingLine struct {
+	ItemType    string  `json:"item_type"`
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	Unit        string  `json:"unit"`
+	UnitPrice   float64 `json:"unit_price"`
+	Detail      string  `json:"detail"`
+}
+
+// Total retorna o valor da linha
+func (l PricingLine) Total() float64 {
+	return roundCents(l.Quantity * l.UnitPrice)
+}
+
+// PricingResult é o orçamento sugerido pela tabela de preços
+type PricingResult struct {
+	Table              *models.PriceTable `json:"-"`
+	Input              PricingInput       `json:"input"`
+	DiameterMultiplier float64            `json:"diameter_multiplier"`
+	TerrainMultiplier  float64            `json:"terrain_multiplier"`
+	BillableKm         float64            `json:"billable_km"`
+	Lines              []PricingLine      `json:"lines"`
+	Warnings           []string           `json:"warnings"`
+}
+
+// Total retorna a soma das linhas sugeridas
+func (r *PricingResult) Total() float64 {
+	var total float64
+	for _, line := range r.Lines {
+		total += line.Total()
+	}
+	return roundCents(total)
+}
+
+// QuoteItems converte as linhas sugeridas em linhas de orçamento
+func (r *PricingResult) QuoteItems() []models.QuoteItem {
+	items := make([]models.QuoteItem, 0, len(r.Lines))
+	for _, line := range r.Lines {
+		items = append(items, models.QuoteItem{
+			ItemType:    line.ItemType,
+			Description: line.Description,
+			Quantity:    line.Quantity,
+			Unit:        line.Unit,
+			UnitPrice:   line.UnitPrice,
+		})
+	}
+	return items
+}
+
+// SuggestPrice calcula o orçamento sugerido aplicando as regras da tabela:
+// taxa fixa, faixas de profundidade (progressivas, cada metro pelo preço da sua
+// faixa), multiplicadores de diâmetro e terreno sobre a perfuração e deslocamento
+// por km além da franquia.
+func SuggestPrice(table *models.PriceTable, input PricingInput) *PricingResult {
+	result := &PricingResult{Table: table, Input: input}
+
+	if table.BaseFee > 0 {
+		result.Lines = append(result.Lines, PricingLine{
+			ItemType:    models.QuoteItemOther,
+			Description: "Mobilização de equipamento",
+			Quantity:    1,
+			Unit:        "un",
+			UnitPrice:   table.BaseFee,
+			Detail:      "Taxa fixa da tabela",
+		})
+	}
+
+	diameterMult, diameterDetail, ok := DiameterMultiplier(table.RulesOfType(models.PriceRuleDiameter), input.DiameterMm)
+	if !ok {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Nenhuma faixa de diâmetro cobre %s mm; multiplicador 1 aplicado.", formatNumber(input.DiameterMm)))
+	}
+	terrainMult, terrainDetail, ok := TerrainMultiplier(table.RulesOfType(models.PriceRuleTerrain), input.Terrain)
+	if !ok {
+		if input.Terrain == "" {
+			result.Warnings = append(result.Warnings, "Terreno não informado; multiplicador 1 aplicado.")
+		} else {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Terreno %s sem multiplicador cadastrado; multiplicador 1 aplicado.", models.TerrainName(input.Terrain)))
+		}
+	}
+	result.DiameterMultiplier = diameterMult
+	result.TerrainMultiplier = terrainMult
+
+	segments, uncovered := DepthSegments(table.RulesOfType(models.PriceRuleDepth), input.DepthM)
+	if uncovered > 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%s m sem faixa de profundidade cadastrada não foram cobrados.", formatNumber(uncovered)))
+	}
+	for _, segment := range segments {
+		details := []string{fmt.Sprintf("R$ %.2f/m", segment.PricePerMeter)}
+		if diameterDetail != "" {
+			details = append(details, diameterDetail)
+		}
+		if terrainDetail != "" {
+			details = append(details, terrainDetail)
+		}
+		result.Lines = append(result.Lines, PricingLine{
+			ItemType:    models.QuoteItemDrilling,
+			Description: fmt.Sprintf("Perfuração de %s a %s m", formatNumber(segment.FromM), formatNumber(segment.ToM)),
+			Quantity:    segment.Meters(),
+			Unit:        "m",
+			UnitPrice:   roundCents(segment.PricePerMeter * diameterMult * terrainMult),
+			Detail:      strings.Join(details, " × "),
+		})
+	}
+
+	result.BillableKm = BillableKm(input.DistanceKm, table.RoundTrip, table.FreeKm)
+	if result.BillableKm > 0 && table.TravelRatePerKm > 0 {
+		detail := fmt.Sprintf("%s km", formatNumber(input.DistanceKm))
+		if table.RoundTrip {
+			detail += " × 2 (ida e volta)"
+		}
+		if table.FreeKm > 0 {
+			detail += fmt.Sprintf(" − %s km de franquia", formatNumber(table.FreeKm))
+		}
+		if input.DistanceSource != "" {
+			detail += " • " + input.DistanceSource
+		}
+		result.Lines = append(result.Lines, PricingLine{
+			ItemType:    models.QuoteItemTravel,
+			Description: "Deslocamento da equipe",
+			Quantity:    result.BillableKm,
+			Unit:        "km",
+			UnitPrice:   table.TravelRatePerKm,
+			Detail:      detail,
+		})
+	}
+
+	return result
+}
+
+// DepthSegment é o trecho da perfuração cobrado pelo preço de uma faixa
+type DepthSegment struct {
+	FromM         float64
+	ToM           float64
+	PricePerMeter float64
+}
+
+// Meters retorna a extensão do trecho
+func (s DepthSegment) Meters() float64 {
+	return s.ToM - s.FromM
+}
+
+// DepthSegments divide a profundidade pelas faixas cadastradas. Cada metro é
+// cobrado pelo preço da faixa em que está; metros fora de qualquer faixa são
+// devolvidos em uncovered.
+func DepthSegments(rules []models.PriceRule, depthM float64) ([]DepthSegment, float64) {
+	if depthM <= 0 {
+		return nil, 0
+	}
+
+	sorted := make([]models.PriceRule, len(rules))
+	copy(sorted, rules)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinValue.Float64 < sorted[j].MinValue.Float64 })
+
+	var segments []DepthSegment
+	covered := 0.0
+	for _, rule := range sorted {
+		from := rule.MinValue.Float64
+		to := depthM
+		if rule.MaxValue.Valid && rule.MaxValue.Float64 < depthM {
+			to = rule.MaxValue.Float64
+		}
+		// Faixas sobrepostas: o trecho já cobrado pela faixa anterior não é cobrado de novo
+		if from < covered {
+			from = covered
+		}
+		if to <= from {
+			continue
+		}
+		segments = append(segments, DepthSegment{FromM: from, ToM: to, PricePerMeter: rule.Value})
+		covered = to
+	}
+
+	billed := 0.0
+	for _, segment := range segments {
+		billed += segment.Meters()
+	}
+	return segments, math.Max(depthM-billed, 0)
+}
+
+// DiameterMultiplier busca o multiplicador da faixa que contém o diâmetro.
+// Sem regras cadastradas não há ajuste; ok é falso só quando há regras e
+// nenhuma cobre o diâmetro informado.
+func DiameterMultiplier(rules []models.PriceRule, diameterMm float64) (float64, string, bool) {
+	if len(rules) == 0 {
+		return 1, "", true
+	}
+	for _, rule := range rules {
+		if rule.Contains(diameterMm) {
+			return rule.Value, fmt.Sprintf("%s (diâmetro %s mm)", formatNumber(rule.Value), formatNumber(diameterMm)), true
+		}
+	}
+	return 1, "", false
+}
+
+// TerrainMultiplier busca o multiplicador do terreno. Sem regras cadastradas
+// não há ajuste; ok é falso só quando há regras e nenhuma para o terreno.
+func TerrainMultiplier(rules []models.PriceRule, terrain string) (float64, string, bool) {
+	if len(rules) == 0 {
+		return 1, "", true
+	}
+	for _, rule := range rules {
+		if rule.Terrain.String == terrain {
+			return rule.Value, fmt.Sprintf("%s (terreno %s)", formatNumber(rule.Value), strings.ToLower(models.TerrainName(terrain))), true
+		}
+	}
+	return 1, "", false
+}
+
+// BillableKm calcula os km cobrados: ida (ou ida e volta) menos a franquia
+func BillableKm(distanceKm float64, roundTrip bool, freeKm float64) float64 {
+	km := distanceKm
+	if roundTrip {
+		km *= 2
+	}
+	return math.Round(math.Max(km-freeKm, 0)*10) / 10
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// formatNumber formata números sem casas decimais desnecessárias
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
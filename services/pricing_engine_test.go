@@ -0,0 +1,333 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+	"reflect"
+	"testing"
+
+	"martins-pocos/models"
+)
+
+// open marca faixas sem limite superior
+const open = -1
+
+func rangeRule(ruleType string, min, max, value float64) models.PriceRule {
+	rule := models.PriceRule{
+		RuleType: ruleType,
+		MinValue: sql.NullFloat64{Float64: min, Valid: true},
+		Value:    value,
+	}
+	if max != open {
+		rule.MaxValue = sql.NullFloat64{Float64: max, Valid: true}
+	}
+	return rule
+}
+
+func depthRule(min, max, pricePerMeter float64) models.PriceRule {
+	return rangeRule(models.PriceRuleDepth, min, max, pricePerMeter)
+}
+
+func diameterRule(min, max, multiplier float64) models.PriceRule {
+	return rangeRule(models.PriceRuleDiameter, min, max, multiplier)
+}
+
+func terrainRule(terrain string, multiplier float64) models.PriceRule {
+	return models.PriceRule{
+		RuleType: models.PriceRuleTerrain,
+		Terrain:  sql.NullString{String: terrain, Valid: true},
+		Value:    multiplier,
+	}
+}
+
+func TestDepthSegments(t *testing.T) {
+	tiers := []models.PriceRule{depthRule(0, 50, 100), depthRule(50, 100, 150), depthRule(100, open, 200)}
+
+	tests := []struct {
+		name      string
+		rules     []models.PriceRule
+		depth     float64
+		segments  []DepthSegment
+		uncovered float64
+	}{
+		{
+			name:  "sem profundidade",
+			rules: tiers,
+			depth: 0,
+		},
+		{
+			name:      "sem faixas",
+			rules:     nil,
+			depth:     50,
+			uncovered: 50,
+		},
+		{
+			name:     "faixa única sem limite",
+			rules:    []models.PriceRule{depthRule(0, open, 100)},
+			depth:    80,
+			segments: []DepthSegment{{0, 80, 100}},
+		},
+		{
+			name:  "faixas progressivas",
+			rules: tiers,
+			depth: 120,
+			segments: []DepthSegment{
+				{0, 50, 100},
+				{50, 100, 150},
+				{100, 120, 200},
+			},
+		},
+		{
+			name:     "profundidade no limite da faixa",
+			rules:    tiers,
+			depth:    50,
+			segments: []DepthSegment{{0, 50, 100}},
+		},
+		{
+			name:     "profundidade logo após o limite",
+			rules:    tiers,
+			depth:    50.5,
+			segments: []DepthSegment{{0, 50, 100}, {50, 50.5, 150}},
+		},
+		{
+			name:  "faixas fora de ordem",
+			rules: []models.PriceRule{tiers[2], tiers[0], tiers[1]},
+			depth: 120,
+			segments: []DepthSegment{
+				{0, 50, 100},
+				{50, 100, 150},
+				{100, 120, 200},
+			},
+		},
+		{
+			name:      "lacuna entre faixas",
+			rules:     []models.PriceRule{depthRule(0, 50, 100), depthRule(80, open, 200)},
+			depth:     100,
+			segments:  []DepthSegment{{0, 50, 100}, {80, 100, 200}},
+			uncovered: 30,
+		},
+		{
+			name:      "profundidade dentro da lacuna",
+			rules:     []models.PriceRule{depthRule(0, 50, 100), depthRule(80, open, 200)},
+			depth:     60,
+			segments:  []DepthSegment{{0, 50, 100}},
+			uncovered: 10,
+		},
+		{
+			name:      "primeira faixa não começa no zero",
+			rules:     []models.PriceRule{depthRule(10, open, 100)},
+			depth:     30,
+			segments:  []DepthSegment{{10, 30, 100}},
+			uncovered: 10,
+		},
+		{
+			name:     "faixas sobrepostas",
+			rules:    []models.PriceRule{depthRule(0, 60, 100), depthRule(50, 100, 150)},
+			depth:    80,
+			segments: []DepthSegment{{0, 60, 100}, {60, 80, 150}},
+		},
+		{
+			name:     "faixa contida em outra",
+			rules:    []models.PriceRule{depthRule(0, 100, 100), depthRule(20, 50, 150)},
+			depth:    80,
+			segments: []DepthSegment{{0, 80, 100}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments, uncovered := DepthSegments(tt.rules, tt.depth)
+			if !reflect.DeepEqual(segments, tt.segments) {
+				t.Errorf("segmentos = %v, esperado %v", segments, tt.segments)
+			}
+			if math.Abs(uncovered-tt.uncovered) > 1e-9 {
+				t.Errorf("não cobertos = %v, esperado %v", uncovered, tt.uncovered)
+			}
+		})
+	}
+}
+
+func TestDepthSegmentsKeepsRulesOrder(t *testing.T) {
+	rules := []models.PriceRule{depthRule(50, open, 150), depthRule(0, 50, 100)}
+	DepthSegments(rules, 80)
+	if rules[0].MinValue.Float64 != 50 {
+		t.Error("DepthSegments não deve reordenar as regras recebidas")
+	}
+}
+
+func TestDiameterMultiplier(t *testing.T) {
+	tiers := []models.PriceRule{diameterRule(0, 150, 1), diameterRule(150, 250, 1.2), diameterRule(250, open, 1.5)}
+
+	tests := []struct {
+		name       string
+		rules      []models.PriceRule
+		diameter   float64
+		multiplier float64
+		detail     string
+		ok         bool
+	}{
+		{"sem regras", nil, 150, 1, "", true},
+		{"primeira faixa", tiers, 100, 1, "1 (diâmetro 100 mm)", true},
+		{"mínimo da faixa é incluído", tiers, 150, 1.2, "1.2 (diâmetro 150 mm)", true},
+		{"máximo da faixa é excluído", tiers, 249.9, 1.2, "1.2 (diâmetro 249.9 mm)", true},
+		{"faixa sem limite", tiers, 300, 1.5, "1.5 (diâmetro 300 mm)", true},
+		{"nenhuma faixa cobre", []models.PriceRule{diameterRule(100, 200, 1.1)}, 50, 1, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			multiplier, detail, ok := DiameterMultiplier(tt.rules, tt.diameter)
+			if multiplier != tt.multiplier || detail != tt.detail || ok != tt.ok {
+				t.Errorf("DiameterMultiplier = (%v, %q, %v), esperado (%v, %q, %v)",
+					multiplier, detail, ok, tt.multiplier, tt.detail, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTerrainMultiplier(t *testing.T) {
+	rules := []models.PriceRule{terrainRule("SEDIMENTAR", 1), terrainRule("CRISTALINO", 1.8)}
+
+	tests := []struct {
+		name       string
+		rules      []models.PriceRule
+		terrain    string
+		multiplier float64
+		detail     string
+		ok         bool
+	}{
+		{"sem regras", nil, "CRISTALINO", 1, "", true},
+		{"terreno cadastrado", rules, "CRISTALINO", 1.8, "1.8 (terreno cristalino (rocha))", true},
+		{"terreno sem regra", rules, "MISTO", 1, "", false},
+		{"terreno não informado", rules, "", 1, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			multiplier, detail, ok := TerrainMultiplier(tt.rules, tt.terrain)
+			if multiplier != tt.multiplier || detail != tt.detail || ok != tt.ok {
+				t.Errorf("TerrainMultiplier = (%v, %q, %v), esperado (%v, %q, %v)",
+					multiplier, detail, ok, tt.multiplier, tt.detail, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBillableKm(t *testing.T) {
+	tests := []struct {
+		name      string
+		distance  float64
+		roundTrip bool
+		freeKm    float64
+		want      float64
+	}{
+		{"só ida sem franquia", 30, false, 0, 30},
+		{"ida e volta", 30, true, 0, 60},
+		{"ida e volta com franquia", 30, true, 50, 10},
+		{"dentro da franquia", 20, true, 50, 0},
+		{"exatamente na franquia", 25, false, 25, 0},
+		{"logo acima da franquia", 25.1, false, 25, 0.1},
+		{"arredonda para baixo", 12.34, false, 0, 12.3},
+		{"arredonda para cima", 12.36, false, 0, 12.4},
+		{"sem distância", 0, true, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BillableKm(tt.distance, tt.roundTrip, tt.freeKm); got != tt.want {
+				t.Errorf("BillableKm(%v, %v, %v) = %v, esperado %v", tt.distance, tt.roundTrip, tt.freeKm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestPrice(t *testing.T) {
+	table := &models.PriceTable{
+		BaseFee:         500,
+		TravelRatePerKm: 3,
+		FreeKm:          20,
+		RoundTrip:       true,
+		Rules: []models.PriceRule{
+			depthRule(0, 50, 100),
+			depthRule(50, open, 150),
+			diameterRule(0, 200, 1.2),
+			terrainRule("CRISTALINO", 1.5),
+		},
+	}
+	input := PricingInput{DepthM: 80, DiameterMm: 150, Terrain: "CRISTALINO", DistanceKm: 40}
+
+	result := SuggestPrice(table, input)
+
+	if len(result.Warnings) != 0 {
+		t.Errorf("avisos inesperados: %v", result.Warnings)
+	}
+	if result.DiameterMultiplier != 1.2 || result.TerrainMultiplier != 1.5 {
+		t.Errorf("multiplicadores = %v e %v, esperado 1.2 e 1.5", result.DiameterMultiplier, result.TerrainMultiplier)
+	}
+	if result.BillableKm != 60 {
+		t.Errorf("km cobrados = %v, esperado 60", result.BillableKm)
+	}
+
+	want := []struct {
+		itemType  string
+		quantity  float64
+		unitPrice float64
+	}{
+		{models.QuoteItemOther, 1, 500},
+		{models.QuoteItemDrilling, 50, 180},
+		{models.QuoteItemDrilling, 30, 270},
+		{models.QuoteItemTravel, 60, 3},
+	}
+	if len(result.Lines) != len(want) {
+		t.Fatalf("linhas = %d, esperado %d: %+v", len(result.Lines), len(want), result.Lines)
+	}
+	for i, w := range want {
+		line := result.Lines[i]
+		if line.ItemType != w.itemType || line.Quantity != w.quantity || line.UnitPrice != w.unitPrice {
+			t.Errorf("linha %d = %s %v × %v, esperado %s %v × %v",
+				i, line.ItemType, line.Quantity, line.UnitPrice, w.itemType, w.quantity, w.unitPrice)
+		}
+	}
+
+	if detail := result.Lines[1].Detail; detail != "R$ 100.00/m × 1.2 (diâmetro 150 mm) × 1.5 (terreno cristalino (rocha))" {
+		t.Errorf("memória de cálculo da perfuração = %q", detail)
+	}
+	if total := result.Total(); total != 17780 {
+		t.Errorf("total = %v, esperado 17780", total)
+	}
+	if items := result.QuoteItems(); len(items) != len(result.Lines) {
+		t.Errorf("itens do orçamento = %d, esperado %d", len(items), len(result.Lines))
+	}
+}
+
+func TestSuggestPriceWarnings(t *testing.T) {
+	table := &models.PriceTable{
+		TravelRatePerKm: 3,
+		FreeKm:          20,
+		Rules: []models.PriceRule{
+			depthRule(0, 50, 100),
+			diameterRule(0, 100, 1.2),
+			terrainRule("CRISTALINO", 1.5),
+		},
+	}
+	input := PricingInput{DepthM: 70, DiameterMm: 150, DistanceKm: 10}
+
+	result := SuggestPrice(table, input)
+
+	if len(result.Warnings) != 3 {
+		t.Errorf("avisos = %v, esperado diâmetro, terreno e metros não cobertos", result.Warnings)
+	}
+	if result.DiameterMultiplier != 1 || result.TerrainMultiplier != 1 {
+		t.Errorf("multiplicadores = %v e %v, esperado 1 e 1", result.DiameterMultiplier, result.TerrainMultiplier)
+	}
+	// Sem taxa fixa e dentro da franquia: só a perfuração coberta pela faixa
+	if len(result.Lines) != 1 {
+		t.Fatalf("linhas = %+v, esperado só a perfuração", result.Lines)
+	}
+	if line := result.Lines[0]; line.Quantity != 50 || line.UnitPrice != 100 {
+		t.Errorf("perfuração = %v × %v, esperado 50 × 100", line.Quantity, line.UnitPrice)
+	}
+	if total := result.Total(); total != 5000 {
+		t.Errorf("total = %v, esperado 5000", total)
+	}
+}
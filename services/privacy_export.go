@@ -0,0 +1,214 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"martins-pocos/models"
+)
+
+// Prefixo das assinaturas gravadas pelo canvas do navegador
+const signatureDataURLPrefix = "data:image/png;base64,"
+
+const exportReadme = `EXPORTAÇÃO DOS SEUS DADOS - MARTINS POÇOS ARTESIANOS
+
+Gerada em %s, a seu pedido, conforme a Lei Geral de Proteção de Dados (Lei 13.709/2018).
+
+Conteúdo:
+  dados.json        todos os dados abaixo em um único arquivo (JSON)
+  cadastro.csv      seus dados de cadastro e preferências de aviso
+  imoveis.csv       endereços de imóveis salvos no perfil
+  solicitacoes.csv  solicitações de serviço
+  contratos.csv     contratos e situação das assinaturas
+  observacoes.csv   observações enviadas sobre os contratos
+  garantias.csv     reclamações de garantia
+  historico.csv     alterações feitas no perfil
+  assinaturas/      imagens das suas assinaturas nos contratos
+
+Os arquivos CSV usam ponto e vírgula como separador e codificação UTF-8.
+`
+
+// WriteDataExportZIP grava a exportação dos dados do titular em um arquivo
+// ZIP com JSON, planilhas CSV e as imagens das assinaturas
+func WriteDataExportZIP(w io.Writer, export *models.DataExport) error {
+	zw := zip.NewWriter(w)
+
+	// As assinaturas viram arquivos PNG; o JSON guarda só o nome
+	signatures := map[string][]byte{}
+	for i := range export.Contracts {
+		c := &export.Contracts[i]
+		if !strings.HasPrefix(c.ClientSignature, signatureDataURLPrefix) {
+			continue
+		}
+		img, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(c.ClientSignature, signatureDataURLPrefix))
+		if err != nil {
+			continue
+		}
+		c.SignatureFile = fmt.Sprintf("assinaturas/contrato-%s.png", safeFileName(c.Number))
+		signatures[c.SignatureFile] = img
+	}
+
+	if err := writeZipFile(zw, "LEIA-ME.txt", []byte(fmt.Sprintf(exportReadme, export.GeneratedAt.Format("02/01/2006 15:04")))); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeZipFile(zw, "dados.json", data); err != nil {
+		return err
+	}
+
+	u := export.User
+	sheets := []struct {
+		name   string
+		header []string
+		rows   [][]string
+	}{
+		{"cadastro.csv", []string{"id", "nome", "email", "telefone", "endereco", "email_confirmado_em", "telefone_confirmado_em", "avisos", "criado_em"},
+			[][]string{{strconv.Itoa(u.ID), u.Name, u.Email, u.Phone, u.Address, formatTimePtr(u.EmailVerifiedAt), formatTimePtr(u.PhoneVerifiedAt), u.Notifications.Summary(), formatTime(u.CreatedAt)}}},
+		{"imoveis.csv", []string{"nome", "cep", "logradouro", "numero", "bairro", "cidade", "estado", "padrao", "criado_em"}, addressRows(export.Addresses)},
+		{"solicitacoes.csv", []string{"id", "tipo_servico", "situacao", "nome", "descricao", "cep", "logradouro", "numero", "bairro", "cidade", "estado", "data_preferencial", "horario_preferencial", "latitude", "longitude", "criada_em"}, requestRows(export.ServiceRequests)},
+		{"contratos.csv", []string{"id", "solicitacao_id", "numero", "valor_total", "condicoes_pagamento", "garantia", "exigencias_cliente", "situacao", "assinado_cliente_em", "assinado_empresa_em", "arquivo_assinatura", "criado_em"}, contractRows(export.Contracts)},
+		{"observacoes.csv", []string{"contrato", "observacao", "resolvida", "criada_em"}, observationRows(export.Observations)},
+		{"garantias.csv", []string{"contrato", "descricao", "situacao", "motivo_decisao", "aberta_em"}, warrantyRows(export.WarrantyClaims)},
+		{"historico.csv", []string{"campo", "valor_anterior", "valor_novo", "ip", "alterado_em"}, profileChangeRows(export.ProfileChanges)},
+	}
+	for _, sheet := range sheets {
+		if err := writeZipCSV(zw, sheet.name, sheet.header, sheet.rows); err != nil {
+			return err
+		}
+	}
+
+	for name, img := range signatures {
+		if err := writeZipFile(zw, name, img); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name string, content []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(content)
+	return err
+}
+
+func writeZipCSV(zw *zip.Writer, name string, header []string, rows [][]string) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	// BOM para o Excel reconhecer o UTF-8
+	if _, err := f.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Comma = ';'
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func addressRows(addresses []models.ExportedAddress) [][]string {
+	var rows [][]string
+	for _, a := range addresses {
+		rows = append(rows, []string{a.Label, a.CEP, a.Logradouro, a.Numero, a.Bairro, a.Cidade, a.Estado, yesNo(a.IsDefault), formatTime(a.CreatedAt)})
+	}
+	return rows
+}
+
+func requestRows(requests []models.ExportedRequest) [][]string {
+	var rows [][]string
+	for _, r := range requests {
+		rows = append(rows, []string{strconv.Itoa(r.ID), r.ServiceType, r.Status, r.FullName, r.Description,
+			r.CEP, r.Logradouro, r.Numero, r.Bairro, r.Cidade, r.Estado, r.PreferredDate, r.PreferredTime,
+			formatFloatPtr(r.Latitude), formatFloatPtr(r.Longitude), formatTime(r.CreatedAt)})
+	}
+	return rows
+}
+
+func contractRows(contracts []models.ExportedContract) [][]string {
+	var rows [][]string
+	for _, c := range contracts {
+		rows = append(rows, []string{strconv.Itoa(c.ID), strconv.Itoa(c.ServiceRequestID), c.Number,
+			strconv.FormatFloat(c.TotalValue, 'f', 2, 64), c.PaymentConditions, c.Guarantee, c.ClientRequirements, c.Status,
+			formatTimePtr(c.ClientSignedAt), formatTimePtr(c.CompanySignedAt), c.SignatureFile, formatTime(c.CreatedAt)})
+	}
+	return rows
+}
+
+func observationRows(observations []models.ExportedObservation) [][]string {
+	var rows [][]string
+	for _, o := range observations {
+		rows = append(rows, []string{o.ContractNumber, o.Observation, yesNo(o.Resolved), formatTime(o.CreatedAt)})
+	}
+	return rows
+}
+
+func warrantyRows(claims []models.ExportedWarrantyClaim) [][]string {
+	var rows [][]string
+	for _, w := range claims {
+		rows = append(rows, []string{w.ContractNumber, w.Description, w.Status, w.DecisionReason, formatTime(w.CreatedAt)})
+	}
+	return rows
+}
+
+func profileChangeRows(changes []models.ExportedProfileChange) [][]string {
+	var rows [][]string
+	for _, c := range changes {
+		rows = append(rows, []string{c.Field, c.OldValue, c.NewValue, c.IP, formatTime(c.CreatedAt)})
+	}
+	return rows
+}
+
+func formatTime(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+func formatFloatPtr(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', 6, 64)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "sim"
+	}
+	return "não"
+}
+
+// safeFileName mantém só letras, números e hífens do nome do arquivo
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return '-'
+	}, s)
+}
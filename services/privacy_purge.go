@@ -0,0 +1,37 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"martins-pocos/models"
+)
+
+// PrivacyPurgeJob elimina os contratos guardados das contas anonimizadas
+// quando termina o prazo legal de guarda
+type PrivacyPurgeJob struct {
+	PrivacyModel *models.PrivacyModel
+	Interval     time.Duration
+}
+
+func NewPrivacyPurgeJob(privacyModel *models.PrivacyModel) *PrivacyPurgeJob {
+	return &PrivacyPurgeJob{
+		PrivacyModel: privacyModel,
+		Interval:     6 * time.Hour,
+	}
+}
+
+// Start executa o expurgo em segundo plano, uma vez na inicialização e depois a cada Interval
+func (j *PrivacyPurgeJob) Start() {
+	go func() {
+		for {
+			purged, err := j.PrivacyModel.PurgeExpired(time.Now())
+			if err != nil {
+				log.Printf("❌ Erro no expurgo de dados (LGPD): %v", err)
+			} else if purged > 0 {
+				log.Printf("🔒 LGPD: dados guardados de %d conta(s) eliminados após o prazo de guarda", purged)
+			}
+			time.Sleep(j.Interval)
+		}
+	}()
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"martins-pocos/models"
+)
+
+// RequestTrashPurgeJob elimina de vez as solicitações que passaram mais de
+// RetentionDays dias na lixeira
+type RequestTrashPurgeJob struct {
+	ServiceModel  *models.ServiceModel
+	RetentionDays int
+	Interval      time.Duration
+}
+
+func NewRequestTrashPurgeJob(serviceModel *models.ServiceModel, retentionDays int) *RequestTrashPurgeJob {
+	return &RequestTrashPurgeJob{
+		ServiceModel:  serviceModel,
+		RetentionDays: retentionDays,
+		Interval:      6 * time.Hour,
+	}
+}
+
+// Start executa o expurgo em segundo plano, uma vez na inicialização e depois a cada Interval
+func (j *RequestTrashPurgeJob) Start() {
+	go func() {
+		for {
+			cutoff := time.Now().AddDate(0, 0, -j.RetentionDays)
+			purged, err := j.ServiceModel.PurgeDeleted(cutoff)
+			if err != nil {
+				log.Printf("❌ Erro no expurgo da lixeira de solicitações: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("🗑️ Lixeira: %d solicitação(ões) eliminada(s) após %d dias", purged, j.RetentionDays)
+			}
+			time.Sleep(j.Interval)
+		}
+	}()
+}
@@ -0,0 +1,436 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// GeoPoint representa uma coordenada geográfica
+type GeoPoint struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// RouteStop é uma visita a ser incluída na rota do técnico
+type RouteStop struct {
+	RequestID int      `json:"request_id"`
+	Label     string   `json:"label"`
+	Address   string   `json:"address"`
+	Time      string   `json:"time"`
+	Point     GeoPoint `json:"point"`
+}
+
+// RouteLeg é um trecho da rota já ordenada
+type RouteLeg struct {
+	Order      int       `json:"order"`
+	Stop       RouteStop `json:"stop"`
+	DistanceKm float64   `json:"distance_km"`
+	Minutes    float64   `json:"minutes"`
+}
+
+// DailyRoute é o resultado da roteirização de um dia
+type DailyRoute struct {
+	Base          GeoPoint   `json:"base"`
+	Legs          []RouteLeg `json:"legs"`
+	ReturnKm      float64    `json:"return_km"`
+	ReturnMinutes float64    `json:"return_minutes"`
+	TotalKm       float64    `json:"total_km"`
+	TotalMinutes  float64    `json:"total_minutes"`
+	EstimatorName string     `json:"estimator"`
+}
+
+// DistanceEstimator calcula distância (km) e tempo (minutos) entre dois pontos.
+// Permite trocar a estimativa por linha reta por um motor de rotas real.
+type DistanceEstimator interface {
+	Estimate(from, to GeoPoint) (distanceKm, minutes float64, err error)
+	Name() string
+}
+
+// MatrixEstimator é implementado pelos estimadores que calculam a matriz
+// completa entre vários pontos numa única consulta
+type MatrixEstimator interface {
+	EstimateMatrix(points []GeoPoint) (distancesKm, minutes [][]float64, err error)
+}
+
+// ============================================
+// ESTIMADOR HAVERSINE
+// ============================================
+
+const earthRadiusKm = 6371.0
+
+// HaversineEstimator estima a distância em linha reta corrigida por um fator de estrada
+type HaversineEstimator struct {
+	RoadFactor      float64
+	AverageSpeedKmh float64
+}
+
+// NewHaversineEstimator cria o estimador padrão para estradas rurais
+func NewHaversineEstimator() *HaversineEstimator {
+	return &HaversineEstimator{
+		RoadFactor:      1.3,
+		AverageSpeedKmh: 50,
+	}
+}
+
+func (e *HaversineEstimator) Name() string {
+	return "haversine"
+}
+
+func (e *HaversineEstimator) Estimate(from, to GeoPoint) (float64, float64, error) {
+	distance := HaversineKm(from, to) * e.RoadFactor
+	minutes := distance / e.AverageSpeedKmh * 60
+	return distance, minutes, nil
+}
+
+// HaversineKm retorna a distância em linha reta entre dois pontos
+func HaversineKm(from, to GeoPoint) float64 {
+	lat1 := from.Lat * math.Pi / 180
+	lat2 := to.Lat * math.Pi / 180
+	dLat := (to.Lat - from.Lat) * math.Pi / 180
+	dLng := (to.Lng - from.Lng) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return earthRadiusKm * c
+}
+
+// ============================================
+// ESTIMADOR OSRM (motor de rotas externo)
+// ============================================
+
+// OSRMEstimator consulta um servidor OSRM para obter distâncias por estrada
+type OSRMEstimator struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+type osrmRouteResponse struct {
+	Code   string `json:"code"`
+	Routes []struct {
+		Distance float64 `json:"distance"`
+		Duration float64 `json:"duration"`
+	} `json:"routes"`
+}
+
+// osrmTableResponse é a resposta do serviço /table; trechos sem rota vêm nulos
+type osrmTableResponse struct {
+	Code      string       `json:"code"`
+	Distances [][]*float64 `json:"distances"`
+	Durations [][]*float64 `json:"durations"`
+}
+
+func (e *OSRMEstimator) Name() string {
+	return "osrm"
+}
+
+// get consulta o OSRM e decodifica a resposta; status diferente de 200 é erro
+func (e *OSRMEstimator) get(endpoint string, result interface{}) error {
+	resp, err := e.Client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OSRM respondeu %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// osrmCoordinates monta a lista "lng,lat;lng,lat" usada nas URLs do OSRM
+func osrmCoordinates(points []GeoPoint) string {
+	parts := make([]string, len(points))
+	for i, point := range points {
+		parts[i] = fmt.Sprintf("%f,%f", point.Lng, point.Lat)
+	}
+	return strings.Join(parts, ";")
+}
+
+func (e *OSRMEstimator) Estimate(from, to GeoPoint) (float64, float64, error) {
+	endpoint := fmt.Sprintf("%s/route/v1/driving/%s?overview=false",
+		strings.TrimRight(e.BaseURL, "/"), osrmCoordinates([]GeoPoint{from, to}))
+
+	var result osrmRouteResponse
+	if err := e.get(endpoint, &result); err != nil {
+		return 0, 0, err
+	}
+
+	if result.Code != "Ok" || len(result.Routes) == 0 {
+		return 0, 0, fmt.Errorf("OSRM sem rota: %s", result.Code)
+	}
+
+	return result.Routes[0].Distance / 1000, result.Routes[0].Duration / 60, nil
+}
+
+// EstimateMatrix obtém todas as distâncias e tempos com uma única chamada
+// ao serviço /table do OSRM
+func (e *OSRMEstimator) EstimateMatrix(points []GeoPoint) ([][]float64, [][]float64, error) {
+	endpoint := fmt.Sprintf("%s/table/v1/driving/%s?annotations=distance,duration",
+		strings.TrimRight(e.BaseURL, "/"), osrmCoordinates(points))
+
+	var result osrmTableResponse
+	if err := e.get(endpoint, &result); err != nil {
+		return nil, nil, err
+	}
+
+	n := len(points)
+	if result.Code != "Ok" || len(result.Distances) != n || len(result.Durations) != n {
+		return nil, nil, fmt.Errorf("OSRM sem matriz: %s", result.Code)
+	}
+
+	distances := make([][]float64, n)
+	minutes := make([][]float64, n)
+	for i := 0; i < n; i++ {
+		if len(result.Distances[i]) != n || len(result.Durations[i]) != n {
+			return nil, nil, fmt.Errorf("OSRM devolveu matriz incompleta")
+		}
+		distances[i] = make([]float64, n)
+		minutes[i] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			if result.Distances[i][j] == nil || result.Durations[i][j] == nil {
+				return nil, nil, fmt.Errorf("OSRM sem rota entre os pontos %d e %d", i, j)
+			}
+			distances[i][j] = *result.Distances[i][j] / 1000
+			minutes[i][j] = *result.Durations[i][j] / 60
+		}
+	}
+
+	return distances, minutes, nil
+}
+
+// NewDistanceEstimator escolhe o estimador conforme ROUTING_ENGINE_URL.
+// Sem a variável, usa a estimativa haversine.
+func NewDistanceEstimator() DistanceEstimator {
+	if url := os.Getenv("ROUTING_ENGINE_URL"); url != "" {
+		return &OSRMEstimator{
+			BaseURL: url,
+			Client:  &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+	return NewHaversineEstimator()
+}
+
+// ============================================
+// PLANEJADOR DE ROTAS
+// ============================================
+
+// RoutePlanner ordena as visitas do dia partindo e retornando à base
+type RoutePlanner struct {
+	Estimator DistanceEstimator
+	fallback  *HaversineEstimator
+}
+
+func NewRoutePlanner(estimator DistanceEstimator) *RoutePlanner {
+	return &RoutePlanner{
+		Estimator: estimator,
+		fallback:  NewHaversineEstimator(),
+	}
+}
+
+// Plan ordena as paradas com vizinho mais próximo seguido de melhoria 2-opt
+func (p *RoutePlanner) Plan(base GeoPoint, stops []RouteStop) *DailyRoute {
+	route := &DailyRoute{Base: base, EstimatorName: p.Estimator.Name()}
+	if len(stops) == 0 {
+		return route
+	}
+
+	// Índice 0 é a base; paradas começam em 1
+	points := make([]GeoPoint, len(stops)+1)
+	points[0] = base
+	for i, stop := range stops {
+		points[i+1] = stop.Point
+	}
+
+	distances, minutes, estimator := p.buildMatrix(points)
+	route.EstimatorName = estimator
+
+	order := nearestNeighbour(distances)
+	order = twoOpt(order, distances)
+
+	previous := 0
+	for i, idx := range order {
+		route.Legs = append(route.Legs, RouteLeg{
+			Order:      i + 1,
+			Stop:       stops[idx-1],
+			DistanceKm: distances[previous][idx],
+			Minutes:    minutes[previous][idx],
+		})
+		route.TotalKm += distances[previous][idx]
+		route.TotalMinutes += minutes[previous][idx]
+		previous = idx
+	}
+
+	route.ReturnKm = distances[previous][0]
+	route.ReturnMinutes = minutes[previous][0]
+	route.TotalKm += route.ReturnKm
+	route.TotalMinutes += route.ReturnMinutes
+
+	return route
+}
+
+// buildMatrix calcula a matriz de distâncias/tempos entre todos os pontos e
+// retorna o nome do estimador usado. Se o motor de rotas falhar, a matriz
+// inteira é refeita com haversine em vez de insistir em cada par.
+func (p *RoutePlanner) buildMatrix(points []GeoPoint) ([][]float64, [][]float64, string) {
+	var distances, minutes [][]float64
+	var err error
+	if matrix, ok := p.Estimator.(MatrixEstimator); ok {
+		distances, minutes, err = matrix.EstimateMatrix(points)
+	} else {
+		distances, minutes, err = pairwiseMatrix(p.Estimator, points)
+	}
+	if err == nil {
+		return distances, minutes, p.Estimator.Name()
+	}
+
+	log.Printf("⚠️ Motor de rotas falhou (%v), usando haversine", err)
+	distances, minutes, _ = pairwiseMatrix(p.fallback, points)
+	return distances, minutes, p.fallback.Name()
+}
+
+// pairwiseMatrix estima par a par e interrompe no primeiro erro
+func pairwiseMatrix(estimator DistanceEstimator, points []GeoPoint) ([][]float64, [][]float64, error) {
+	n := len(points)
+	distances := make([][]float64, n)
+	minutes := make([][]float64, n)
+	for i := range points {
+		distances[i] = make([]float64, n)
+		minutes[i] = make([]float64, n)
+	}
+
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			d, t, err := estimator.Estimate(points[i], points[j])
+			if err != nil {
+				return nil, nil, err
+			}
+			distances[i][j] = d
+			minutes[i][j] = t
+		}
+	}
+
+	return distances, minutes, nil
+}
+
+// nearestNeighbour monta uma rota inicial sempre indo à parada mais próxima
+func nearestNeighbour(distances [][]float64) []int {
+	n := len(distances)
+	visited := make([]bool, n)
+	visited[0] = true
+
+	order := make([]int, 0, n-1)
+	current := 0
+	for len(order) < n-1 {
+		next := -1
+		for j := 1; j < n; j++ {
+			if visited[j] {
+				continue
+			}
+			if next == -1 || distances[current][j] < distances[current][next] {
+				next = j
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+		current = next
+	}
+
+	return order
+}
+
+// twoOpt inverte trechos da rota enquanto houver redução da distância total
+func twoOpt(order []int, distances [][]float64) []int {
+	best := append([]int{}, order...)
+	bestDistance := tourDistance(best, distances)
+
+	improved := true
+	for improved {
+		improved = false
+		for i := 0; i < len(best)-1; i++ {
+			for j := i + 1; j < len(best); j++ {
+				candidate := append([]int{}, best[:i]...)
+				for k := j; k >= i; k-- {
+					candidate = append(candidate, best[k])
+				}
+				candidate = append(candidate, best[j+1:]...)
+
+				if d := tourDistance(candidate, distances); d < bestDistance-1e-9 {
+					best = candidate
+					bestDistance = d
+					improved = true
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// tourDistance soma a distância base -> paradas -> base
+func tourDistance(order []int, distances [][]float64) float64 {
+	total := 0.0
+	previous := 0
+	for _, idx := range order {
+		total += distances[previous][idx]
+		previous = idx
+	}
+	return total + distances[previous][0]
+}
+
+// ============================================
+// RESUMO PARA WHATSAPP
+// ============================================
+
+// BuildRouteSummary monta a mensagem de WhatsApp com o roteiro do técnico
+func BuildRouteSummary(technicianName string, date time.Time, route *DailyRoute, unlocated []RouteStop) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("🚚 *Roteiro do Dia - %s*\n\n", date.Format("02/01/2006")))
+	sb.WriteString(fmt.Sprintf("Olá %s! Segue a ordem das visitas:\n\n", technicianName))
+
+	for _, leg := range route.Legs {
+		sb.WriteString(fmt.Sprintf("*%d.* %s", leg.Order, leg.Stop.Label))
+		if leg.Stop.Time != "" {
+			sb.WriteString(fmt.Sprintf(" (%s)", leg.Stop.Time))
+		}
+		sb.WriteString(fmt.Sprintf("\n📍 %s\n", leg.Stop.Address))
+		sb.WriteString(fmt.Sprintf("🛣️ %.1f km • ~%.0f min\n", leg.DistanceKm, leg.Minutes))
+		sb.WriteString(fmt.Sprintf("🗺️ https://maps.google.com/?q=%f,%f\n\n", leg.Stop.Point.Lat, leg.Stop.Point.Lng))
+	}
+
+	if len(unlocated) > 0 {
+		sb.WriteString("⚠️ *Visitas sem coordenadas:*\n")
+		for _, stop := range unlocated {
+			sb.WriteString(fmt.Sprintf("• %s - %s\n", stop.Label, stop.Address))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("🏠 Retorno à base: %.1f km\n", route.ReturnKm))
+	sb.WriteString(fmt.Sprintf("📊 *Total:* %.1f km • ~%s\n\n", route.TotalKm, FormatMinutes(route.TotalMinutes)))
+	sb.WriteString("_Martins Poços - Sistema Automatizado_")
+
+	return sb.String()
+}
+
+// FormatMinutes formata minutos como "1h25" ou "40 min"
+func FormatMinutes(minutes float64) string {
+	total := int(math.Round(minutes))
+	if total < 60 {
+		return fmt.Sprintf("%d min", total)
+	}
+	return fmt.Sprintf("%dh%02d", total/60, total%60)
+}
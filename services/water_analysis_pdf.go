@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"martins-pocos/models"
+	"martins-pocos/utils"
+)
+
+// BuildWaterAnalysisPDF gera o laudo de qualidade da água em PDF
+func BuildWaterAnalysisPDF(analysis *models.WaterAnalysis) []byte {
+	doc := utils.NewPDFDocument()
+	doc.AddPage()
+
+	const margin = 50.0
+	y := 60.0
+
+	doc.SetFillColor(13, 110, 253)
+	doc.Rect(0, 0, doc.Width, 36, true, false)
+	doc.SetFillColor(255, 255, 255)
+	doc.Text(margin, 24, 14, true, "Martins Poços - Laudo de Análise da Água")
+	doc.SetFillColor(33, 37, 41)
+
+	title := fmt.Sprintf("Laudo #%d", analysis.ID)
+	if analysis.ReportNumber.Valid {
+		title = "Laudo nº " + analysis.ReportNumber.String
+	}
+	doc.Text(margin, y, 16, true, title)
+	y += 18
+	doc.Text(margin, y, 10, false, fmt.Sprintf("Cliente: %s (%s)", analysis.OwnerName, analysis.OwnerEmail))
+	y += 14
+	if analysis.WellIdentification != "" {
+		doc.Text(margin, y, 10, false, "Poço: "+analysis.WellIdentification)
+		y += 14
+	}
+	doc.Text(margin, y, 10, false, "Data da coleta: "+analysis.SampleDate.Format("02/01/2006"))
+	y += 14
+	if analysis.Laboratory.Valid {
+		doc.Text(margin, y, 10, false, "Laboratório: "+analysis.Laboratory.String)
+		y += 14
+	}
+
+	// Conclusão
+	y += 8
+	if analysis.Compliant() {
+		doc.SetFillColor(209, 231, 221)
+	} else {
+		doc.SetFillColor(248, 215, 218)
+	}
+	doc.Rect(margin, y, doc.Width-2*margin, 28, true, false)
+	doc.SetFillColor(33, 37, 41)
+	if analysis.Compliant() {
+		doc.Text(margin+10, y+18, 11, true, "CONFORME - todos os parâmetros analisados atendem ao padrão de potabilidade")
+	} else {
+		doc.Text(margin+10, y+18, 11, true, fmt.Sprintf("NÃO CONFORME - %d parâmetro(s) fora do padrão de potabilidade", analysis.NonCompliantCount))
+	}
+	y += 48
+
+	// Tabela de resultados
+	cols := []float64{margin, margin + 190, margin + 280, margin + 340, margin + 430}
+	doc.SetFillColor(233, 236, 239)
+	doc.Rect(margin, y-12, doc.Width-2*margin, 18, true, false)
+	doc.SetFillColor(33, 37, 41)
+	for i, header := range []string{"Parâmetro", "Resultado", "Unidade", "VMP", "Situação"} {
+		doc.Text(cols[i]+4, y, 9, true, header)
+	}
+	y += 18
+
+	for _, result := range analysis.Results {
+		if y > doc.Height-70 {
+			doc.AddPage()
+			y = 60
+		}
+		doc.SetFillColor(33, 37, 41)
+		doc.Text(cols[0]+4, y, 9, false, result.ParameterName)
+		doc.Text(cols[1]+4, y, 9, !result.Compliant(), result.DisplayValue())
+		doc.Text(cols[2]+4, y, 9, false, result.Unit)
+		doc.Text(cols[3]+4, y, 9, false, result.LimitDescription())
+		switch {
+		case !result.HasLimit():
+			doc.SetFillColor(108, 117, 125)
+			doc.Text(cols[4]+4, y, 9, false, "Informativo")
+		case result.Compliant():
+			doc.SetFillColor(25, 135, 84)
+			doc.Text(cols[4]+4, y, 9, true, "Conforme")
+		default:
+			doc.SetFillColor(220, 53, 69)
+			doc.Text(cols[4]+4, y, 9, true, "Não conforme")
+		}
+		doc.SetStrokeColor(222, 226, 230)
+		doc.Line(margin, y+5, doc.Width-margin, y+5, 0.5)
+		y += 17
+	}
+
+	if analysis.Notes.Valid {
+		y += 10
+		doc.SetFillColor(33, 37, 41)
+		doc.Text(margin, y, 11, true, "Observações")
+		y += 15
+		for _, line := range doc.WrapText(analysis.Notes.String, 10, doc.Width-2*margin) {
+			doc.Text(margin, y, 10, false, line)
+			y += 13
+		}
+	}
+
+	doc.SetFillColor(108, 117, 125)
+	doc.Text(margin, doc.Height-38, 8, false, "VMP: valor máximo permitido conforme Portaria GM/MS nº 888/2021 (padrão de potabilidade).")
+	doc.Text(margin, doc.Height-25, 8, false, "Documento gerado em "+time.Now().Format("02/01/2006 15:04"))
+
+	return doc.Bytes()
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strings"
+	"time"
+
+	"martins-pocos/models"
+)
+
+// ParameterPoint é o valor de um parâmetro em um laudo
+type ParameterPoint struct {
+	AnalysisID int
+	Date       time.Time
+	Value      float64
+	Compliant  bool
+}
+
+// ParameterSeries é o histórico de um parâmetro em um poço
+type ParameterSeries struct {
+	Parameter models.WaterParameter
+	Points    []ParameterPoint
+}
+
+// NonCompliantCount conta as medições fora do padrão
+func (s *ParameterSeries) NonCompliantCount() int {
+	count := 0
+	for _, p := range s.Points {
+		if !p.Compliant {
+			count++
+		}
+	}
+	return count
+}
+
+// BuildParameterSeries agrupa os resultados dos laudos por parâmetro, na ordem do catálogo.
+// Os laudos devem estar em ordem cronológica.
+func BuildParameterSeries(analyses []models.WaterAnalysis) []ParameterSeries {
+	byCode := make(map[string]*ParameterSeries)
+	for _, analysis := range analyses {
+		for _, result := range analysis.Results {
+			series, ok := byCode[result.ParameterCode]
+			if !ok {
+				parameter, known := models.GetWaterParameter(result.ParameterCode)
+				if !known {
+					continue
+				}
+				series = &ParameterSeries{Parameter: parameter}
+				byCode[result.ParameterCode] = series
+			}
+			series.Points = append(series.Points, ParameterPoint{
+				AnalysisID: analysis.ID,
+				Date:       analysis.SampleDate,
+				Value:      result.Value,
+				Compliant:  result.Compliant(),
+			})
+		}
+	}
+
+	var all []ParameterSeries
+	for _, parameter := range models.WaterParameters {
+		if series, ok := byCode[parameter.Code]; ok {
+			all = append(all, *series)
+		}
+	}
+	return all
+}
+
+// RenderParameterChartSVG desenha o histórico do parâmetro com a faixa de referência
+func RenderParameterChartSVG(series ParameterSeries) string {
+	if len(series.Points) == 0 {
+		return ""
+	}
+
+	const (
+		width  = 420.0
+		height = 180.0
+		left   = 45.0
+		right  = 15.0
+		top    = 15.0
+		bottom = 30.0
+	)
+	plotW := width - left - right
+	plotH := height - top - bottom
+
+	// Escala vertical inclui os valores e os limites
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, p := range series.Points {
+		lo = math.Min(lo, p.Value)
+		hi = math.Max(hi, p.Value)
+	}
+	if series.Parameter.MinLimit.Valid {
+		lo = math.Min(lo, series.Parameter.MinLimit.Float64)
+		hi = math.Max(hi, series.Parameter.MinLimit.Float64)
+	}
+	if series.Parameter.MaxLimit.Valid {
+		lo = math.Min(lo, series.Parameter.MaxLimit.Float64)
+		hi = math.Max(hi, series.Parameter.MaxLimit.Float64)
+	}
+	if series.Parameter.PresenceAbsence {
+		lo, hi = 0, 1
+	}
+	if lo > 0 && lo < hi*0.5 {
+		lo = 0
+	}
+	padding := (hi - lo) * 0.1
+	if padding == 0 {
+		padding = math.Max(math.Abs(hi)*0.1, 1)
+	}
+	if !series.Parameter.PresenceAbsence {
+		hi += padding
+		if lo != 0 {
+			lo -= padding
+		}
+	}
+
+	yAt := func(v float64) float64 { return top + plotH - (v-lo)/(hi-lo)*plotH }
+	xAt := func(i int) float64 {
+		if len(series.Points) == 1 {
+			return left + plotW/2
+		}
+		return left + float64(i)*plotW/float64(len(series.Points)-1)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %.0f %.0f" class="quality-chart" role="img" aria-label="%s">`,
+		width, height, html.EscapeString(series.Parameter.Name))
+
+	// Área de conformidade
+	if series.Parameter.MinLimit.Valid || series.Parameter.MaxLimit.Valid {
+		bandTop, bandBottom := top, top+plotH
+		if series.Parameter.MaxLimit.Valid {
+			bandTop = yAt(series.Parameter.MaxLimit.Float64)
+		}
+		if series.Parameter.MinLimit.Valid {
+			bandBottom = yAt(series.Parameter.MinLimit.Float64)
+		}
+		fmt.Fprintf(&sb, `<rect x="%.0f" y="%.1f" width="%.0f" height="%.1f" fill="#d1e7dd" opacity="0.6"/>`,
+			left, bandTop, plotW, math.Max(bandBottom-bandTop, 1))
+		for _, l := range []struct {
+			valid bool
+			value float64
+		}{
+			{series.Parameter.MinLimit.Valid, series.Parameter.MinLimit.Float64},
+			{series.Parameter.MaxLimit.Valid, series.Parameter.MaxLimit.Float64},
+		} {
+			if l.valid {
+				fmt.Fprintf(&sb, `<line x1="%.0f" y1="%.1f" x2="%.0f" y2="%.1f" stroke="#198754" stroke-dasharray="4,3"/>`,
+					left, yAt(l.value), left+plotW, yAt(l.value))
+			}
+		}
+	}
+
+	// Eixos
+	fmt.Fprintf(&sb, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="#adb5bd"/>`, left, top, left, top+plotH)
+	fmt.Fprintf(&sb, `<line x1="%.0f" y1="%.0f" x2="%.0f" y2="%.0f" stroke="#adb5bd"/>`, left, top+plotH, left+plotW, top+plotH)
+	if series.Parameter.PresenceAbsence {
+		fmt.Fprintf(&sb, `<text x="%.0f" y="%.1f" font-size="9" text-anchor="end" fill="#6c757d">Aus.</text>`, left-4, yAt(0)+3)
+		fmt.Fprintf(&sb, `<text x="%.0f" y="%.1f" font-size="9" text-anchor="end" fill="#6c757d">Pres.</text>`, left-4, yAt(1)+3)
+	} else {
+		for _, v := range []float64{lo, (lo + hi) / 2, hi} {
+			fmt.Fprintf(&sb, `<text x="%.0f" y="%.1f" font-size="9" text-anchor="end" fill="#6c757d">%s</text>`,
+				left-4, yAt(v)+3, chartNumber(v))
+		}
+	}
+
+	// Linha da série
+	var path []string
+	for i, p := range series.Points {
+		path = append(path, fmt.Sprintf("%.1f,%.1f", xAt(i), yAt(p.Value)))
+	}
+	if len(path) > 1 {
+		fmt.Fprintf(&sb, `<polyline points="%s" fill="none" stroke="#0d6efd" stroke-width="2"/>`, strings.Join(path, " "))
+	}
+
+	// Pontos e datas
+	step := int(math.Ceil(float64(len(series.Points)) / 6))
+	for i, p := range series.Points {
+		color := "#198754"
+		if !p.Compliant {
+			color = "#dc3545"
+		}
+		fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="4" fill="%s"><title>%s: %s %s</title></circle>`,
+			xAt(i), yAt(p.Value), color, p.Date.Format("02/01/2006"), chartNumber(p.Value), html.EscapeString(series.Parameter.Unit))
+		if i%step == 0 || i == len(series.Points)-1 {
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%.0f" font-size="9" text-anchor="middle" fill="#6c757d">%s</text>`,
+				xAt(i), top+plotH+14, p.Date.Format("01/2006"))
+		}
+	}
+
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
+
+func chartNumber(v float64) string {
+	s := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+	return strings.Replace(s, ".", ",", 1)
+}
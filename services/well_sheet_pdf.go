@@ -0,0 +1,216 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"martins-pocos/models"
+	"martins-pocos/utils"
+)
+
+// BuildWellSheetPDF gera a ficha técnica do poço em PDF, com o perfil litológico
+func BuildWellSheetPDF(well *models.Well, intervals []models.DrillingInterval) []byte {
+	doc := utils.NewPDFDocument()
+	doc.AddPage()
+
+	const margin = 50.0
+	y := 60.0
+
+	// Cabeçalho
+	doc.SetFillColor(13, 110, 253)
+	doc.Rect(0, 0, doc.Width, 36, true, false)
+	doc.SetFillColor(255, 255, 255)
+	doc.Text(margin, 24, 14, true, "Martins Poços - Ficha Técnica do Poço")
+	doc.SetFillColor(33, 37, 41)
+
+	doc.Text(margin, y, 16, true, well.Identification)
+	y += 18
+	doc.Text(margin, y, 10, false, fmt.Sprintf("Proprietário: %s (%s)", well.OwnerName, well.OwnerEmail))
+	y += 14
+	if well.Address != "" {
+		doc.Text(margin, y, 10, false, "Endereço: "+well.Address)
+		y += 14
+	}
+	if well.ContractNumber != "" {
+		doc.Text(margin, y, 10, false, "Contrato: "+well.ContractNumber)
+		y += 14
+	}
+	if well.HasCoordinates() {
+		doc.Text(margin, y, 10, false, fmt.Sprintf("Coordenadas: %.6f, %.6f", well.Latitude.Float64, well.Longitude.Float64))
+		y += 14
+	}
+
+	y += 10
+	y = pdfSection(doc, margin, y, "Dados Construtivos")
+	drillingDate := "—"
+	if well.DrillingDate.Valid {
+		drillingDate = well.DrillingDate.Time.Format("02/01/2006")
+	}
+	y = pdfSpecRow(doc, margin, y, [][2]string{
+		{"Profundidade", pdfFloat(well.DepthM, 2, "m")},
+		{"Diâmetro", pdfFloat(well.DiameterMm, 0, "mm")},
+		{"Perfuração", drillingDate},
+	})
+	y = pdfSpecRow(doc, margin, y, [][2]string{
+		{"Revestimento", pdfString(well.CasingMaterial)},
+		{"Bomba", pdfString(well.PumpModel)},
+	})
+
+	y = pdfSection(doc, margin, y, "Hidráulica")
+	y = pdfSpecRow(doc, margin, y, [][2]string{
+		{"Nível estático", pdfFloat(well.StaticLevelM, 2, "m")},
+		{"Nível dinâmico", pdfFloat(well.DynamicLevelM, 2, "m")},
+		{"Rebaixamento", fmt.Sprintf("%.2f m", well.DrawdownM())},
+		{"Vazão", pdfFloat(well.FlowRateM3h, 2, "m³/h")},
+	})
+
+	if well.Notes.Valid {
+		y = pdfSection(doc, margin, y, "Observações")
+		for _, line := range doc.WrapText(well.Notes.String, 10, doc.Width-2*margin) {
+			doc.Text(margin, y, 10, false, line)
+			y += 13
+		}
+		y += 8
+	}
+
+	y = pdfSection(doc, margin, y, "Perfil Litológico")
+	if len(intervals) == 0 {
+		doc.Text(margin, y, 10, false, "Nenhuma camada registrada.")
+	} else {
+		drawPDFDrillingProfile(doc, margin, y, intervals, well.DepthM, well.StaticLevelM)
+	}
+
+	doc.SetFillColor(108, 117, 125)
+	doc.Text(margin, doc.Height-25, 8, false, "Documento gerado em "+time.Now().Format("02/01/2006 15:04"))
+
+	return doc.Bytes()
+}
+
+// drawPDFDrillingProfile desenha a coluna litológica e a tabela de camadas
+func drawPDFDrillingProfile(doc *utils.PDFDocument, x, top float64, intervals []models.DrillingInterval, totalDepth, staticLevel sql.NullFloat64) {
+	depth := ProfileDepth(intervals, totalDepth)
+
+	const columnW = 60.0
+	axisX := x + 35
+	columnX := axisX + 8
+	tableX := columnX + columnW + 40
+
+	// Altura disponível até o rodapé, limitada para poços rasos não ficarem esticados
+	height := doc.Height - top - 60
+	if height > 420 {
+		height = 420
+	}
+	if height < 120 {
+		doc.AddPage()
+		top = 60
+		height = 420
+	}
+	scale := height / depth
+	yAt := func(m float64) float64 { return top + m*scale }
+
+	// Régua
+	doc.SetStrokeColor(73, 80, 87)
+	doc.Line(axisX, yAt(0), axisX, yAt(depth), 0.8)
+	step := DepthTickStep(depth)
+	doc.SetFillColor(73, 80, 87)
+	for m := 0.0; m <= depth+1e-9; m += step {
+		doc.Line(axisX-4, yAt(m), axisX, yAt(m), 0.8)
+		label := fmt.Sprintf("%.0f m", m)
+		doc.Text(axisX-6-doc.TextWidth(label, 8), yAt(m)+3, 8, false, label)
+	}
+
+	// Camadas
+	for _, interval := range intervals {
+		r, g, b := LithologyRGB(interval.Lithology)
+		doc.SetFillColor(r, g, b)
+		doc.SetStrokeColor(73, 80, 87)
+		doc.Rect(columnX, yAt(interval.DepthFromM), columnW, yAt(interval.DepthToM)-yAt(interval.DepthFromM), true, true)
+		if interval.WaterEntry {
+			doc.SetFillColor(13, 110, 253)
+			mid := (yAt(interval.DepthFromM) + yAt(interval.DepthToM)) / 2
+			doc.Rect(columnX+columnW+3, mid-3, 6, 6, true, false)
+		}
+	}
+
+	// Nível estático
+	if staticLevel.Valid && staticLevel.Float64 <= depth {
+		doc.SetStrokeColor(13, 110, 253)
+		doc.DashedLine(columnX-4, yAt(staticLevel.Float64), columnX+columnW+4, yAt(staticLevel.Float64), 1.2)
+		doc.SetFillColor(13, 110, 253)
+		doc.Text(columnX+columnW+12, yAt(staticLevel.Float64)+3, 8, true, fmt.Sprintf("NE %.2f m", staticLevel.Float64))
+	}
+
+	// Tabela de camadas
+	doc.SetFillColor(33, 37, 41)
+	rowY := top + 8
+	doc.Text(tableX, rowY, 9, true, "De (m)")
+	doc.Text(tableX+50, rowY, 9, true, "Até (m)")
+	doc.Text(tableX+100, rowY, 9, true, "Litologia")
+	doc.Text(tableX+200, rowY, 9, true, "Água")
+	doc.SetStrokeColor(173, 181, 189)
+	doc.Line(tableX, rowY+4, doc.Width-x, rowY+4, 0.5)
+	rowY += 16
+
+	for _, interval := range intervals {
+		if rowY > doc.Height-50 {
+			break
+		}
+		r, g, b := LithologyRGB(interval.Lithology)
+		doc.SetFillColor(r, g, b)
+		doc.Rect(tableX+90, rowY-7, 7, 7, true, false)
+
+		doc.SetFillColor(33, 37, 41)
+		doc.Text(tableX, rowY, 9, false, fmt.Sprintf("%.2f", interval.DepthFromM))
+		doc.Text(tableX+50, rowY, 9, false, fmt.Sprintf("%.2f", interval.DepthToM))
+		doc.Text(tableX+100, rowY, 9, false, interval.Lithology)
+		if interval.WaterEntry {
+			doc.Text(tableX+200, rowY, 9, false, "Sim")
+		}
+		rowY += 13
+
+		if interval.Observations.Valid {
+			doc.SetFillColor(108, 117, 125)
+			for _, line := range doc.WrapText(interval.Observations.String, 8, doc.Width-x-tableX-100) {
+				doc.Text(tableX+100, rowY, 8, false, line)
+				rowY += 10
+			}
+			rowY += 3
+		}
+	}
+}
+
+func pdfSection(doc *utils.PDFDocument, x, y float64, title string) float64 {
+	doc.SetFillColor(13, 110, 253)
+	doc.Text(x, y, 12, true, title)
+	doc.SetStrokeColor(13, 110, 253)
+	doc.Line(x, y+4, doc.Width-x, y+4, 0.8)
+	doc.SetFillColor(33, 37, 41)
+	return y + 22
+}
+
+func pdfSpecRow(doc *utils.PDFDocument, x, y float64, specs [][2]string) float64 {
+	colW := (doc.Width - 2*x) / float64(len(specs))
+	for i, spec := range specs {
+		cx := x + float64(i)*colW
+		doc.SetFillColor(108, 117, 125)
+		doc.Text(cx, y, 8, false, spec[0])
+		doc.SetFillColor(33, 37, 41)
+		doc.Text(cx, y+14, 11, true, spec[1])
+	}
+	return y + 32
+}
+
+func pdfFloat(v sql.NullFloat64, decimals int, unit string) string {
+	if !v.Valid {
+		return "—"
+	}
+	return fmt.Sprintf("%.*f %s", decimals, v.Float64, unit)
+}
+
+func pdfString(v sql.NullString) string {
+	if !v.Valid {
+		return "—"
+	}
+	return v.String
+}
@@ -10,9 +10,31 @@ document.querySelectorAll(".service-option").forEach((option) => {
     if (radio) {
       radio.checked = true;
     }
+    mostrarCamposAdicionais();
   });
 });
 
+// Campos adicionais: mostra e habilita só os do tipo de serviço escolhido.
+// Os dos outros tipos ficam desabilitados para não serem enviados nem validados.
+function mostrarCamposAdicionais() {
+  const selected = document.querySelector('input[name="service_type"]:checked');
+  const code = selected ? selected.value : "";
+
+  document.querySelectorAll(".custom-fields").forEach((group) => {
+    const active = group.dataset.serviceType === code;
+    group.classList.toggle("d-none", !active);
+    group.querySelectorAll("input, select").forEach((input) => {
+      input.disabled = !active;
+      input.required = active && input.hasAttribute("data-required");
+    });
+  });
+}
+
+document.querySelectorAll('input[name="service_type"]').forEach((radio) => {
+  radio.addEventListener("change", mostrarCamposAdicionais);
+});
+mostrarCamposAdicionais();
+
 // CEP Auto-format
 const cepInput = document.getElementById("cep");
 if (cepInput) {
@@ -47,6 +69,25 @@ if (searchCepBtn) {
   });
 }
 
+// Imóveis salvos no perfil: preenche o endereço com o imóvel escolhido
+const savedAddress = document.getElementById("savedAddress");
+if (savedAddress) {
+  const preencherImovel = function () {
+    const option = savedAddress.options[savedAddress.selectedIndex];
+    const saveRow = document.getElementById("saveAddressRow");
+    if (saveRow) saveRow.classList.toggle("d-none", !!option.value);
+    if (!option.value) return;
+
+    ["cep", "logradouro", "numero", "bairro", "cidade", "estado"].forEach((campo) => {
+      const input = document.getElementById(campo);
+      if (input) input.value = option.dataset[campo] || "";
+    });
+  };
+
+  savedAddress.addEventListener("change", preencherImovel);
+  preencherImovel();
+}
+
 // Função para buscar CEP
 async function buscarCEP(cep) {
   const cepLimpo = cep.replace(/\D/g, "");
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PDFDocument gera documentos PDF simples (texto, linhas e retângulos)
+// sem dependências externas. As coordenadas usam o canto superior esquerdo
+// da página como origem, em pontos (1/72 pol).
+type PDFDocument struct {
+	Width  float64
+	Height float64
+	pages  []*bytes.Buffer
+}
+
+// NewPDFDocument cria um documento A4 retrato
+func NewPDFDocument() *PDFDocument {
+	return &PDFDocument{Width: 595.28, Height: 841.89}
+}
+
+// AddPage inicia uma nova página
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// PageCount retorna o número de páginas
+func (d *PDFDocument) PageCount() int {
+	return len(d.pages)
+}
+
+func (d *PDFDocument) current() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text escreve um texto com a linha de base em (x, y)
+func (d *PDFDocument) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.current(), "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font, size, x, d.Height-y, escapePDFText(text))
+}
+
+// TextWidth estima a largura do texto em Helvetica
+func (d *PDFDocument) TextWidth(text string, size float64) float64 {
+	return float64(len([]rune(text))) * size * 0.5
+}
+
+// WrapText quebra o texto em linhas que cabem na largura informada
+func (d *PDFDocument) WrapText(text string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		line := ""
+		for _, word := range words {
+			candidate := strings.TrimSpace(line + " " + word)
+			if line != "" && d.TextWidth(candidate, size) > width {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// SetFillColor define a cor de preenchimento (0-255)
+func (d *PDFDocument) SetFillColor(r, g, b int) {
+	fmt.Fprintf(d.current(), "%.3f %.3f %.3f rg\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// SetStrokeColor define a cor das linhas (0-255)
+func (d *PDFDocument) SetStrokeColor(r, g, b int) {
+	fmt.Fprintf(d.current(), "%.3f %.3f %.3f RG\n", float64(r)/255, float64(g)/255, float64(b)/255)
+}
+
+// Line desenha uma linha entre dois pontos
+func (d *PDFDocument) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.current(), "%.2f w %.2f %.2f m %.2f %.2f l S\n",
+		width, x1, d.Height-y1, x2, d.Height-y2)
+}
+
+// DashedLine desenha uma linha tracejada
+func (d *PDFDocument) DashedLine(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.current(), "[4 3] 0 d %.2f w %.2f %.2f m %.2f %.2f l S [] 0 d\n",
+		width, x1, d.Height-y1, x2, d.Height-y2)
+}
+
+// Rect desenha um retângulo a partir do canto superior esquerdo
+func (d *PDFDocument) Rect(x, y, w, h float64, fill, stroke bool) {
+	op := "S"
+	switch {
+	case fill && stroke:
+		op = "B"
+	case fill:
+		op = "f"
+	}
+	fmt.Fprintf(d.current(), "0.5 w %.2f %.2f %.2f %.2f re %s\n", x, d.Height-y-h, w, h, op)
+}
+
+// Bytes monta o arquivo PDF completo
+func (d *PDFDocument) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+
+	writeObj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: catálogo, 2: árvore de páginas, 3-4: fontes, depois pares página/conteúdo
+	pageCount := len(d.pages)
+	var kids []string
+	for i := 0; i < pageCount; i++ {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+i*2))
+	}
+
+	writeObj("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	writeObj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		writeObj(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			d.Width, d.Height, 6+i*2))
+		writeObj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// winAnsiExtras mapeia caracteres fora do Latin-1 para a codificação WinAnsi
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// textFallbacks substitui símbolos sem equivalente na codificação WinAnsi
+var textFallbacks = map[rune]string{
+	'≤': "<=", '≥': ">=", '≠': "!=", '→': "->",
+}
+
+// escapePDFText converte o texto para WinAnsi e escapa caracteres especiais
+func escapePDFText(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(byte(r))
+		case r < 0x80:
+			sb.WriteByte(byte(r))
+		case r >= 0xA0 && r <= 0xFF:
+			sb.WriteByte(byte(r))
+		default:
+			if b, ok := winAnsiExtras[r]; ok {
+				sb.WriteByte(b)
+			} else if fallback, ok := textFallbacks[r]; ok {
+				sb.WriteString(fallback)
+			} else {
+				sb.WriteByte('?')
+			}
+		}
+	}
+	return sb.String()
+}
@@ -11,6 +11,7 @@ type JSONResponse struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Meta    *PageMeta   `json:"meta,omitempty"`
 }
 
 // Função genérica para escrever JSON
@@ -36,3 +37,28 @@ func SendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
 		Error:   message,
 	})
 }
+
+// Metadados de paginação das listagens da API (limit/offset)
+type PageMeta struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+// Resposta de listagem paginada
+func SendPaginatedResponse(w http.ResponseWriter, data interface{}, meta PageMeta) {
+	WriteJSON(w, http.StatusOK, JSONResponse{
+		Success: true,
+		Data:    data,
+		Meta:    &meta,
+	})
+}
+
+// Resposta de recurso criado (201)
+func SendCreatedResponse(w http.ResponseWriter, message string, data interface{}) {
+	WriteJSON(w, http.StatusCreated, JSONResponse{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}